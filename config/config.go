@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"multipass/pkg/utils"
@@ -12,15 +13,20 @@ import (
 )
 
 type Config struct {
-	DatabaseURL        string           `mapstructure:"database"`
-	RedisURL           string           `mapstructure:"redis_url"`
-	JWT                *JWTConfig       `mapstructure:"-"`
-	STATIC             string           `mapstructure:"static"`
-	LogFilePath        string           `mapstructure:"log_file_path"`
-	ProfilePicturePath string           `mapstructure:"profile_picture_path"`
-	ProfilePictureBase string           `mapstructure:"profile_picture_base"`
-	WebAuthn           *webauthn.Config `mapstructure:"webauthn"`
-	Email              *EMAILConfig     `mapstructure:"email"`
+	DatabaseURL        string            `mapstructure:"database"`
+	RedisURL           string            `mapstructure:"redis_url"`
+	JWT                *JWTConfig        `mapstructure:"-"`
+	STATIC             string            `mapstructure:"static"`
+	LogFilePath        string            `mapstructure:"log_file_path"`
+	ProfilePicturePath string            `mapstructure:"profile_picture_path"`
+	ProfilePictureBase string            `mapstructure:"profile_picture_base"`
+	WebAuthn           *webauthn.Config  `mapstructure:"webauthn"`
+	Email              *EMAILConfig      `mapstructure:"email"`
+	Jobs               *JobsConfig       `mapstructure:"jobs"`
+	DataExport         *DataExportConfig `mapstructure:"data_export"`
+	Security           *SecurityConfig   `mapstructure:"security"`
+	MFA                *MFAConfig        `mapstructure:"mfa"`
+	EmailLogin         *EmailLoginConfig `mapstructure:"email_login"`
 }
 
 type JWTConfig struct {
@@ -39,6 +45,40 @@ type EMAILConfig struct {
 	FrontendURL string `json:"base_url"`
 }
 
+type JobsConfig struct {
+	CooccurrenceInterval   time.Duration `mapstructure:"cooccurrence_interval"`
+	CooccurrenceMinSupport int           `mapstructure:"cooccurrence_min_support"`
+	ImportRetention        time.Duration `mapstructure:"import_retention"`
+	AccountDeletionGrace   time.Duration `mapstructure:"account_deletion_grace"`
+}
+
+// DataExportConfig locates the "download my data" archives and sets how long
+// their download link stays valid before they are deleted.
+type DataExportConfig struct {
+	Path string        `mapstructure:"path"`
+	TTL  time.Duration `mapstructure:"ttl"`
+}
+
+// SecurityConfig controls how account security events are reported.
+type SecurityConfig struct {
+	AlertEmails bool `mapstructure:"alert_emails"`
+}
+
+// MFAConfig sets the issuer shown in authenticator apps and bounds the
+// one-time code checks of two-factor authentication.
+type MFAConfig struct {
+	Issuer       string        `mapstructure:"issuer"`
+	ChallengeTTL time.Duration `mapstructure:"challenge_ttl"`
+	MaxAttempts  int           `mapstructure:"max_attempts"`
+}
+
+// EmailLoginConfig sets how long an emailed sign-in link and code stay valid
+// and how many wrong codes are allowed.
+type EmailLoginConfig struct {
+	TTL         time.Duration `mapstructure:"ttl"`
+	MaxAttempts int           `mapstructure:"max_attempts"`
+}
+
 func LoadConfig() (*Config, error) {
 	err := godotenv.Load()
 	if err != nil {
@@ -159,6 +199,106 @@ func LoadConfig() (*Config, error) {
 		RPOrigins:     []string{rpOrigins},
 	}
 
+	// Background jobs (optional, defaults apply)
+	cooccurrenceMinSupport := 5
+	if v := os.Getenv("COOCCURRENCE_MIN_SUPPORT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 2 {
+			return nil, fmt.Errorf("COOCCURRENCE_MIN_SUPPORT must be an integer of at least 2")
+		}
+		cooccurrenceMinSupport = n
+	}
+
+	cooccurrenceInterval, err := positiveDuration("COOCCURRENCE_INTERVAL", 6*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	importRetention, err := positiveDuration("IMPORT_RETENTION", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	// Purging before the grace period ends would void the emailed cancel link
+	accountDeletionGrace, err := positiveDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	jobsConfig := &JobsConfig{
+		CooccurrenceInterval:   cooccurrenceInterval,
+		CooccurrenceMinSupport: cooccurrenceMinSupport,
+		ImportRetention:        importRetention,
+		AccountDeletionGrace:   accountDeletionGrace,
+	}
+
+	// Data exports (optional, defaults apply)
+	dataExportPath := os.Getenv("DATA_EXPORT_PATH")
+	if dataExportPath == "" {
+		dataExportPath = "./data/exports"
+	}
+	dataExportTTL, err := positiveDuration("DATA_EXPORT_TTL", 48*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	dataExportConfig := &DataExportConfig{
+		Path: dataExportPath,
+		TTL:  dataExportTTL,
+	}
+
+	// Security alerts (optional, emailed by default)
+	alertEmails := true
+	if v := os.Getenv("SECURITY_ALERT_EMAILS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("SECURITY_ALERT_EMAILS must be true or false")
+		}
+		alertEmails = b
+	}
+	securityConfig := &SecurityConfig{
+		AlertEmails: alertEmails,
+	}
+
+	// Two-factor authentication (optional, defaults apply)
+	mfaIssuer := os.Getenv("TOTP_ISSUER")
+	if mfaIssuer == "" {
+		mfaIssuer = rpDisplayName
+	}
+	mfaMaxAttempts := 5
+	if v := os.Getenv("MFA_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("MFA_MAX_ATTEMPTS must be a positive integer")
+		}
+		mfaMaxAttempts = n
+	}
+	mfaChallengeTTL, err := positiveDuration("MFA_CHALLENGE_TTL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	mfaConfig := &MFAConfig{
+		Issuer:       mfaIssuer,
+		ChallengeTTL: mfaChallengeTTL,
+		MaxAttempts:  mfaMaxAttempts,
+	}
+
+	// Passwordless email login (optional, defaults apply)
+	emailLoginMaxAttempts := 5
+	if v := os.Getenv("EMAIL_LOGIN_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("EMAIL_LOGIN_MAX_ATTEMPTS must be a positive integer")
+		}
+		emailLoginMaxAttempts = n
+	}
+	emailLoginTTL, err := positiveDuration("EMAIL_LOGIN_TTL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	emailLoginConfig := &EmailLoginConfig{
+		TTL:         emailLoginTTL,
+		MaxAttempts: emailLoginMaxAttempts,
+	}
+
 	jwt := &JWTConfig{
 		AccessTokenSecret:  jwtAccessSecret,
 		RefreshTokenSecret: refreshSecret,
@@ -176,5 +316,25 @@ func LoadConfig() (*Config, error) {
 		ProfilePictureBase: profilePictureBase,
 		WebAuthn:           webAuthnConfig,
 		Email:              emailConfig,
+		Jobs:               jobsConfig,
+		DataExport:         dataExportConfig,
+		Security:           securityConfig,
+		MFA:                mfaConfig,
+		EmailLogin:         emailLoginConfig,
 	}, nil
 }
+
+// positiveDuration reads an optional duration from the environment, using
+// fallback when it isn't set. Durations of zero or less are rejected: tickers
+// panic on them and TTLs would expire immediately.
+func positiveDuration(name string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration such as 30m or 24h", name)
+	}
+	return d, nil
+}
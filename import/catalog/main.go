@@ -0,0 +1,144 @@
+// Command catalog fills the catalog from offline dataset exports.
+//
+// The tmdb mode imports TMDB movie details exports (a JSON array or JSONL,
+// optionally gzipped):
+//
+//	go run ./import/catalog -file movies.jsonl.gz [-batch 500] [-cast 15] [-dry-run] [-resume]
+//
+// The imdb mode enriches catalog movies with the runtime, rating, directors
+// and writers of the IMDb non-commercial datasets:
+//
+//	go run ./import/catalog -mode imdb -basics title.basics.tsv.gz -ratings title.ratings.tsv.gz \
+//		-principals title.principals.tsv.gz [-names name.basics.tsv.gz] [-unmatched unmatched.tsv] [-dry-run]
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"multipass/internal/importer"
+	"multipass/internal/store"
+	"multipass/pkg/logging"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	var (
+		mode       = flag.String("mode", "tmdb", "tmdb imports movies, imdb enriches the movies already imported")
+		file       = flag.String("file", "", "TMDB export to import (.json, .jsonl, optionally .gz)")
+		batchSize  = flag.Int("batch", importer.DefaultBatchSize, "movies written per transaction")
+		castLimit  = flag.Int("cast", importer.DefaultCastLimit, "first billed cast members kept per movie")
+		dryRun     = flag.Bool("dry-run", false, "read and plan the import without writing anything")
+		resume     = flag.Bool("resume", false, "continue an interrupted import from its checkpoint")
+		checkpoint = flag.String("checkpoint", "", "checkpoint file (default: <file>.checkpoint)")
+		basics     = flag.String("basics", "", "IMDb title.basics.tsv(.gz)")
+		ratings    = flag.String("ratings", "", "IMDb title.ratings.tsv(.gz)")
+		principals = flag.String("principals", "", "IMDb title.principals.tsv(.gz)")
+		names      = flag.String("names", "", "optional IMDb name.basics.tsv(.gz), to store crew names")
+		unmatched  = flag.String("unmatched", "", "optional file receiving the unmatched movies as TSV")
+		logFile    = flag.String("log", "", "optional log file")
+	)
+	flag.Parse()
+
+	switch *mode {
+	case "tmdb":
+		if *file == "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+	case "imdb":
+		if *basics == "" || *ratings == "" || *principals == "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+	default:
+		log.Printf("Unknown mode %q", *mode)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: Could not load .env file: %v", err)
+	}
+	connStr := os.Getenv("DATABASE_URL")
+	if connStr == "" {
+		log.Fatal("DATABASE_URL is not set in environment variables")
+	}
+
+	logger, err := logging.NewAppLogger(*logFile, slog.LevelInfo)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer logger.Close()
+
+	db, err := store.Open(connStr, logger)
+	if err != nil {
+		log.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	// Interrupting keeps every committed batch, -resume picks up from there
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opts := importer.Options{
+		BatchSize:      *batchSize,
+		CastLimit:      *castLimit,
+		DryRun:         *dryRun,
+		Resume:         *resume,
+		CheckpointPath: *checkpoint,
+	}
+
+	if *mode == "imdb" {
+		enricher := importer.NewIMDbEnricher(store.NewImportRepository(db, logger), opts, logger)
+		summary, err := enricher.Run(ctx, importer.IMDbFiles{
+			Basics:     *basics,
+			Ratings:    *ratings,
+			Principals: *principals,
+			Names:      *names,
+		})
+		if summary != nil {
+			summary.Print(os.Stdout)
+			if *unmatched != "" {
+				if err := writeUnmatched(*unmatched, summary); err != nil {
+					logger.Error("Failed to write unmatched movies", err)
+				}
+			}
+		}
+		if err != nil {
+			logger.Error("IMDb enrichment failed", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	imp := importer.NewTMDBImporter(
+		store.NewMovieRepository(db, nil, logger),
+		store.NewImportRepository(db, logger),
+		opts,
+		logger,
+	)
+
+	summary, err := imp.Run(ctx, *file)
+	if summary != nil {
+		summary.Print(os.Stdout)
+	}
+	if err != nil {
+		logger.Error("Import failed", err)
+		os.Exit(1)
+	}
+}
+
+func writeUnmatched(path string, summary *importer.IMDbSummary) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	summary.WriteUnmatched(f)
+	return f.Close()
+}
@@ -3,11 +3,16 @@ package api
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
+	"multipass/internal/exporter"
 	"multipass/internal/model"
 	"multipass/internal/service"
+	"multipass/internal/store"
 	"multipass/pkg/apperror"
 	"multipass/pkg/common"
 	"multipass/pkg/cookieutils"
@@ -15,6 +20,7 @@ import (
 	"multipass/pkg/logging"
 	"multipass/pkg/response"
 	"multipass/pkg/utils"
+	"multipass/pkg/validator"
 )
 
 type AccountHandler struct {
@@ -40,7 +46,7 @@ func NewAccountHandler(service service.UserAccountService,
 
 // SignUp handles user registration
 func (h *AccountHandler) SignUp(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := ctxutils.SetClientInfo(r.Context(), utils.GetClientInfo(r))
 
 	metaData := common.Envelop{
 		"op":     "AccountHandler.SignUp",
@@ -83,7 +89,7 @@ func (h *AccountHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 
 // Login handles user login authentication route
 func (h *AccountHandler) Login(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := ctxutils.SetClientInfo(r.Context(), utils.GetClientInfo(r))
 	metaData := common.Envelop{
 		"method": r.Method,
 		"path":   r.URL.Path,
@@ -105,6 +111,18 @@ func (h *AccountHandler) Login(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	h.writeAuthResult(w, r, result, metaData)
+}
+
+// writeAuthResult sets the refresh token cookie and answers with the access
+// token, or with the mfa token when the login needs a second factor
+func (h *AccountHandler) writeAuthResult(w http.ResponseWriter, r *http.Request, result *common.AuthResult, metaData common.Envelop) {
+	// Two-factor accounts get no tokens until the code is checked
+	if result.MFAToken != "" {
+		h.writeMFARequired(w, r, result, metaData)
+		return
+	}
+
 	// Prepare response
 	resp := &common.AuthResponse{
 		Success: true,
@@ -128,7 +146,7 @@ func (h *AccountHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 // Refresh handles user token refresh request
 func (h *AccountHandler) Refresh(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := ctxutils.SetClientInfo(r.Context(), utils.GetClientInfo(r))
 	metadata := common.Envelop{
 		"method": r.Method,
 		"path":   r.URL.Path,
@@ -177,7 +195,7 @@ func (h *AccountHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 
 // Logout handles user logout request
 func (h *AccountHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := ctxutils.SetClientInfo(r.Context(), utils.GetClientInfo(r))
 	op := "AccountHandler.Logout"
 	metaData := common.Envelop{
 		"op":     op,
@@ -217,6 +235,98 @@ func (h *AccountHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	h.Logger.Info("User logout request fully processed", metaData)
 }
 
+// -----------------------------------------------------------
+// SESSIONS
+// -----------------------------------------------------------
+
+// HandleGetSessions lists the devices signed in to the account, flagging the
+// one making the request
+// Route: GET /api/account/sessions
+func (h *AccountHandler) HandleGetSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Without the cookie no session is flagged as current
+	refreshTokenPlaintext := ""
+	if cookie, err := r.Cookie("refresh_token"); err == nil {
+		refreshTokenPlaintext = cookie.Value
+	}
+
+	sessions, err := h.service.SessionsService(ctx, refreshTokenPlaintext)
+	if h.ErrorHandler.HandleAppError(w, r, err, "SessionsService") {
+		return
+	}
+
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": sessions}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+}
+
+// HandleRevokeSession signs one device out
+// Route: DELETE /api/account/sessions/{id}
+func (h *AccountHandler) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleRevokeSession",
+	}
+
+	sessionID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_session_id")
+		return
+	}
+
+	err = h.service.RevokeSessionService(ctx, sessionID)
+	if h.ErrorHandler.HandleAppError(w, r, err, "RevokeSessionService") {
+		return
+	}
+
+	resp := common.CollectionSuccess{
+		Success: true,
+		Message: "Session signed out",
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+	h.Logger.Info(fmt.Sprintf("RevokeSession signed out session %d", sessionID))
+}
+
+// HandleRevokeOtherSessions signs out every device except the one making
+// the request
+// Route: DELETE /api/account/sessions
+func (h *AccountHandler) HandleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleRevokeOtherSessions",
+	}
+
+	cookie, err := cookieutils.GetCookie(r, h.Logger, "refresh_token")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrUnauthorized(err, h.Logger, metaData), "refresh_token_cookie")
+		return
+	}
+
+	revoked, err := h.service.RevokeOtherSessionsService(ctx, cookie.Value)
+	if h.ErrorHandler.HandleAppError(w, r, err, "RevokeOtherSessionsService") {
+		return
+	}
+
+	resp := common.CollectionSuccess{
+		Success: true,
+		Message: fmt.Sprintf("Signed out of %d other sessions", revoked),
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+	h.Logger.Info("successfully signed out other sessions", "meta", metaData)
+}
+
 // HandleSaveToCollection saves movie to favorite/watchlist collections
 func (h *AccountHandler) HandleSaveToCollection(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -279,28 +389,37 @@ func (h *AccountHandler) HandleRemoveMovieFromCollection(w http.ResponseWriter,
 	}
 }
 
-// HandleGetFavorites retrieves user's favorite collection
+// HandleGetFavorites retrieves a page of user's favorite collection
 func (h *AccountHandler) HandleGetFavorites(w http.ResponseWriter, r *http.Request) {
+	h.handleCollectionPage(w, r, store.RelFavorites, "AccountHandler.HandleGetFavorites")
+}
+
+// HandleGetWatchlist retrieves a page of user's watchlist collection
+func (h *AccountHandler) HandleGetWatchlist(w http.ResponseWriter, r *http.Request) {
+	h.handleCollectionPage(w, r, store.RelWatchlist, "AccountHandler.HandleGetWatchlist")
+}
+
+// handleCollectionPage writes one cursor-paginated page of the given user collection.
+func (h *AccountHandler) handleCollectionPage(w http.ResponseWriter, r *http.Request, list string, op string) {
+	ctx := r.Context()
 	metaData := common.Envelop{
 		"method": r.Method,
 		"path":   r.URL.Path,
-		"op":     "AccountHandler.HandleGetUserProfile",
+		"op":     op,
 	}
 
-	accountDetails, err := h.handleUserDetails(r, metaData)
-	if err != nil {
-		if h.ErrorHandler.HandleAppError(w, r, err, "GetFavorites_AccountDetailsService") {
-			return
-		}
+	page, err := h.pageParams(r, metaData)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_page_params") {
+		return
 	}
 
-	resp := &common.MoviesResponse{
-		Success: true,
-		Movies:  accountDetails.Favorites,
-		Count:   len(accountDetails.Favorites),
+	moviePage, err := h.service.CollectionPageService(ctx, list, page)
+	if h.ErrorHandler.HandleAppError(w, r, err, "CollectionPageService") {
+		return
 	}
+
 	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{
-		"data": resp,
+		"data": newMoviesResponse(moviePage),
 	}); err != nil {
 		if h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer") {
 			return
@@ -308,33 +427,181 @@ func (h *AccountHandler) HandleGetFavorites(w http.ResponseWriter, r *http.Reque
 	}
 }
 
-// HandleGetFavorites retrieves user's watchlist collection
-func (h *AccountHandler) HandleGetWatchlist(w http.ResponseWriter, r *http.Request) {
+// HandleExportCollections streams the user's favorites and watchlist as a
+// ?format=csv|json|letterboxd download, or only one of them with ?list=
+func (h *AccountHandler) HandleExportCollections(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	metaData := common.Envelop{
 		"method": r.Method,
 		"path":   r.URL.Path,
-		"op":     "AccountHandler.HandleGetUserProfile",
+		"op":     "AccountHandler.HandleExportCollections",
 	}
 
-	accountDetails, err := h.handleUserDetails(r, metaData)
+	query := r.URL.Query()
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = exporter.FormatCSV
+	}
+	list := query.Get("list")
+	metaData["format"] = format
+	metaData["list"] = list
+
+	if !slices.Contains(exporter.Formats, format) {
+		err := fmt.Errorf("format must be 'csv', 'json' or 'letterboxd'")
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrBadRequest(err, h.Logger, metaData), "invalid_export_format")
+		return
+	}
+	if format == exporter.FormatLetterboxd && list == "" {
+		err := fmt.Errorf("letterboxd exports hold one list, set list to 'favorites' or 'watchlist'")
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrBadRequest(err, h.Logger, metaData), "invalid_export_list")
+		return
+	}
+
+	out := &countingWriter{w: w}
+	ew, err := exporter.NewWriter(out, format)
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrBadRequest(err, h.Logger, metaData), "exporter.NewWriter")
+		return
+	}
+
+	w.Header().Set("Content-Type", exporter.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exporter.FileName(format, list)))
+
+	err = h.service.ExportCollectionsService(ctx, list, ew.Write)
+	if err == nil {
+		err = ew.Close()
+	}
 	if err != nil {
-		if h.ErrorHandler.HandleAppError(w, r, err, "GetWatchlist_AccountDetailsService") {
+		if out.n == 0 {
+			w.Header().Del("Content-Disposition")
+			h.ErrorHandler.HandleAppError(w, r, err, "ExportCollectionsService")
 			return
 		}
+		// The response has started, the client is left with a truncated file
+		h.Logger.Error("Collection export failed while streaming", err, "meta", metaData)
+		return
 	}
 
-	resp := &common.MoviesResponse{
-		Success: true,
-		Movies:  accountDetails.Watchlist,
-		Count:   len(accountDetails.Watchlist),
+	h.Logger.Info("successfully exported collections", "meta", metaData)
+}
+
+// HandleGetDiary retrieves a page of the user's watch diary, optionally between ?from= and ?to= (YYYY-MM-DD)
+func (h *AccountHandler) HandleGetDiary(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleGetDiary",
 	}
 
-	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{
-		"data": resp,
-	}); err != nil {
-		if h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer") {
-			return
-		}
+	page, err := h.pageParams(r, metaData)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_page_params") {
+		return
+	}
+
+	query := r.URL.Query()
+	rng, err := validator.ParseDiaryRange(query.Get("from"), query.Get("to"))
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidDateFormat(err, h.Logger, metaData), "invalid_diary_range")
+		return
+	}
+
+	diary, err := h.service.DiaryPageService(ctx, rng, page)
+	if h.ErrorHandler.HandleAppError(w, r, err, "DiaryPageService") {
+		return
+	}
+
+	resp := common.DiaryResponse{
+		Success:    true,
+		Entries:    diary.Entries,
+		Count:      len(diary.Entries),
+		NextCursor: diary.NextCursor,
+		HasMore:    diary.HasMore,
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+}
+
+// HandleLogWatch adds a watch to the user's diary
+func (h *AccountHandler) HandleLogWatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := utils.DecodeRequest[common.DiaryRequest](w, r, "LogWatch Request")
+	if err != nil {
+		return
+	}
+
+	entry, err := h.service.LogWatchService(ctx, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, "LogWatchService") {
+		return
+	}
+
+	if err := h.Responder.WriteJSON(w, http.StatusCreated, common.Envelop{"data": entry}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+}
+
+// HandleUpdateDiaryEntry changes the date, rewatch flag or note of a diary entry
+func (h *AccountHandler) HandleUpdateDiaryEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleUpdateDiaryEntry",
+	}
+
+	entryID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_entry_id")
+		return
+	}
+
+	req, err := utils.DecodeRequest[common.DiaryRequest](w, r, "UpdateDiaryEntry Request")
+	if err != nil {
+		return
+	}
+
+	entry, err := h.service.UpdateDiaryEntryService(ctx, entryID, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, "UpdateDiaryEntryService") {
+		return
+	}
+
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": entry}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+}
+
+// HandleDeleteDiaryEntry removes a diary entry
+func (h *AccountHandler) HandleDeleteDiaryEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleDeleteDiaryEntry",
+	}
+
+	entryID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_entry_id")
+		return
+	}
+
+	err = h.service.DeleteDiaryEntryService(ctx, entryID)
+	if h.ErrorHandler.HandleAppError(w, r, err, "DeleteDiaryEntryService") {
+		return
+	}
+
+	resp := common.CollectionSuccess{
+		Success: true,
+		Message: "Diary entry deleted",
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
 	}
 }
 
@@ -599,6 +866,82 @@ func (h *AccountHandler) HandleConfirmPasswordReset(w http.ResponseWriter, r *ht
 	h.Logger.Info("Password reset successfully confirmed and consumed", metaData)
 }
 
+// -----------------------------------------------------------
+// ACCOUNT DELETION
+// -----------------------------------------------------------
+
+// HandleDeleteAccount deletes the user's account after re-checking their
+// password and signs them out. The account can be restored from the emailed
+// link until it is purged.
+// Route: DELETE /api/account
+func (h *AccountHandler) HandleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"op":     "AccountHandler.HandleDeleteAccount",
+		"method": r.Method,
+		"path":   r.URL.Path,
+	}
+
+	req, err := utils.DecodeRequest[common.AccountDeleteRequest](w, r, "DeleteAccount Request")
+	if err != nil {
+		return
+	}
+
+	if err := h.service.DeleteAccountService(ctx, req); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, err, "DeleteAccountService")
+		return
+	}
+
+	cookieutils.SetCookie(w, h.Logger, "refresh_token", "", "/", -1, time.Now().Add(-24*time.Hour))
+
+	resp := common.GenericResponse{
+		Success: true,
+		Message: "Your account has been deleted. Check your email to restore it within the grace period.",
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+	h.Logger.Info("Account deletion successfully processed", metaData)
+}
+
+// HandleCancelAccountDeletion restores a deleted account from the token of
+// the emailed cancel link.
+// Route: POST /api/account/delete/cancel
+func (h *AccountHandler) HandleCancelAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"op":     "AccountHandler.HandleCancelAccountDeletion",
+		"method": r.Method,
+		"path":   r.URL.Path,
+	}
+
+	req, err := utils.DecodeRequest[common.AccountRestoreRequest](w, r, "CancelAccountDeletion Request")
+	if err != nil {
+		return
+	}
+
+	if req.Token == "" {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrTokenMissing(errors.New("missing account deletion token"), h.Logger, metaData), "missing_token")
+		return
+	}
+
+	if err := h.service.CancelAccountDeletion(ctx, req.Token); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, err, "CancelAccountDeletion")
+		return
+	}
+
+	resp := common.GenericResponse{
+		Success: true,
+		Message: "Your account has been restored. You can now log in.",
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+	h.Logger.Info("Account deletion successfully cancelled", metaData)
+}
+
 // src/internals/api/account_handler.go (Additions)
 
 // -----------------------------------------------------------
@@ -959,3 +1302,16 @@ func (h *AccountHandler) HandleConfirmPasswordReset(w http.ResponseWriter, r *ht
 
 // 	h.Logger.Info("user logged in", "email", user.Email)
 // }
+
+// countingWriter counts the bytes written through it, to tell whether a
+// streamed response has started.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"multipass/internal/store"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+	"multipass/pkg/response"
+	"multipass/pkg/utils"
+)
+
+type ActorHandler struct {
+	BaseHandler
+	actorStore store.ActorStore
+}
+
+func NewActorHandler(actorStore store.ActorStore, logger logging.Logger, responder response.Writer) *ActorHandler {
+	return &ActorHandler{
+		actorStore: actorStore,
+		BaseHandler: BaseHandler{
+			Logger:       logger,
+			Responder:    responder,
+			ErrorHandler: apperror.NewBaseErrorHandler(logger, responder),
+		},
+	}
+}
+
+// GetActorByID handles get actor profile, filmography and collaborators route
+func (h *ActorHandler) HandleGetActorByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	order, _ := utils.GetQueryParam(r, "order")
+	errMeta := common.Envelop{
+		"method":   r.Method,
+		"path":     r.URL.Path,
+		"order_by": order,
+	}
+
+	// 1: Read {id} from the route pattern
+	id, err := utils.GetPathID(r, "id")
+	if err != nil {
+		errMeta["actor_id"] = r.PathValue("id")
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidActorID(err, h.Logger, errMeta), "params_actor_id")
+		return
+	}
+
+	// 2: Get actor profile
+	profile, err := h.actorStore.GetActorProfile(ctx, id, order)
+	if h.ErrorHandler.HandleAppError(w, r, err, "failed to get actor profile") {
+		return
+	}
+
+	// 3: Send Back Response
+	err = h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": profile})
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("GetActorByID successfully sent actor: %s", profile.Name()))
+}
+
+// SearchActors handles search actors by name route
+func (h *ActorHandler) HandleSearchActors(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query, err := utils.GetQueryParam(r, "q")
+	if err != nil || query == "" {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrBadRequest(
+			errors.New("query parameters 'q' is required and cannot be empty"),
+			h.Logger,
+			nil,
+		), "empty_query")
+		return
+	}
+
+	errMeta := common.Envelop{
+		"method":       r.Method,
+		"path":         r.URL.Path,
+		"search_query": query,
+	}
+
+	page, err := h.pageParams(r, errMeta)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_page_params") {
+		return
+	}
+
+	actors, err := h.actorStore.SearchActors(ctx, query, page.Limit)
+	if h.ErrorHandler.HandleAppError(w, r, err, "failed to search actors") {
+		return
+	}
+
+	err = h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{
+		"data":  actors,
+		"count": len(actors),
+	})
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+
+	h.Logger.Info("SearchActors successfully sent results")
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"multipass/internal/service"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+	"multipass/pkg/response"
+	"multipass/pkg/utils"
+)
+
+type AdminHandler struct {
+	BaseHandler
+	service service.AdminCatalogService
+}
+
+func NewAdminHandler(service service.AdminCatalogService, logger logging.Logger, responder response.Writer) *AdminHandler {
+	return &AdminHandler{
+		service: service,
+		BaseHandler: BaseHandler{
+			Logger:       logger,
+			Responder:    responder,
+			ErrorHandler: apperror.NewBaseErrorHandler(logger, responder),
+		},
+	}
+}
+
+// HandleCreateMovie adds a movie to the catalog
+func (h *AdminHandler) HandleCreateMovie(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := utils.DecodeRequest[common.AdminMovieRequest](w, r, "CreateMovie Request")
+	if err != nil {
+		return
+	}
+
+	resp, err := h.service.CreateMovie(ctx, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, "CreateMovie") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusCreated, resp)
+	h.Logger.Info(fmt.Sprintf("CreateMovie successfully created movie: %d", resp.Movie.ID))
+}
+
+// HandleUpdateMovie edits the columns of a catalog movie
+func (h *AdminHandler) HandleUpdateMovie(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AdminHandler.HandleUpdateMovie",
+	}
+
+	movieID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_movie_id")
+		return
+	}
+
+	req, err := utils.DecodeRequest[common.AdminMovieRequest](w, r, "UpdateMovie Request")
+	if err != nil {
+		return
+	}
+
+	resp, err := h.service.UpdateMovie(ctx, movieID, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, "UpdateMovie") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, resp)
+}
+
+// HandleDeleteMovie soft deletes a catalog movie
+func (h *AdminHandler) HandleDeleteMovie(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AdminHandler.HandleDeleteMovie",
+	}
+
+	movieID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_movie_id")
+		return
+	}
+
+	resp, err := h.service.DeleteMovie(ctx, movieID)
+	if h.ErrorHandler.HandleAppError(w, r, err, "DeleteMovie") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, resp)
+}
+
+// HandleAttachRelations links genres, actors and keywords to a catalog movie
+func (h *AdminHandler) HandleAttachRelations(w http.ResponseWriter, r *http.Request) {
+	h.handleRelations(w, r, "AttachRelations", h.service.AttachRelations)
+}
+
+// HandleDetachRelations unlinks genres, actors and keywords from a catalog movie
+func (h *AdminHandler) HandleDetachRelations(w http.ResponseWriter, r *http.Request) {
+	h.handleRelations(w, r, "DetachRelations", h.service.DetachRelations)
+}
+
+func (h *AdminHandler) handleRelations(
+	w http.ResponseWriter,
+	r *http.Request,
+	op string,
+	apply func(ctx context.Context, movieID int, req *common.CatalogRelationsRequest) (*common.CatalogChangeResponse, error),
+) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AdminHandler." + op,
+	}
+
+	movieID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_movie_id")
+		return
+	}
+
+	req, err := utils.DecodeRequest[common.CatalogRelationsRequest](w, r, op+" Request")
+	if err != nil {
+		return
+	}
+
+	resp, err := apply(ctx, movieID, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, op) {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, resp)
+}
+
+// HandleGetMovieHistory lists the recorded changes of a catalog movie, newest first
+func (h *AdminHandler) HandleGetMovieHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AdminHandler.HandleGetMovieHistory",
+	}
+
+	movieID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_movie_id")
+		return
+	}
+
+	page, err := h.pageParams(r, metaData)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_page_params") {
+		return
+	}
+
+	history, err := h.service.MovieHistory(ctx, movieID, page)
+	if h.ErrorHandler.HandleAppError(w, r, err, "MovieHistory") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, history)
+}
+
+// HandleRevertEntry undoes one recorded catalog change
+func (h *AdminHandler) HandleRevertEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AdminHandler.HandleRevertEntry",
+	}
+
+	entryID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_entry_id")
+		return
+	}
+
+	resp, err := h.service.RevertEntry(ctx, int64(entryID))
+	if h.ErrorHandler.HandleAppError(w, r, err, "RevertEntry") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, resp)
+}
+
+func (h *AdminHandler) writeData(w http.ResponseWriter, r *http.Request, status int, data any) {
+	if err := h.Responder.WriteJSON(w, status, common.Envelop{"data": data}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+	}
+}
@@ -1,9 +1,13 @@
 package api
 
 import (
+	"net/http"
+
 	"multipass/pkg/apperror"
+	"multipass/pkg/common"
 	"multipass/pkg/logging"
 	"multipass/pkg/response"
+	"multipass/pkg/utils"
 )
 
 type BaseHandler struct {
@@ -11,3 +15,24 @@ type BaseHandler struct {
 	Responder    response.Writer
 	ErrorHandler apperror.ErrorHandler
 }
+
+// pageParams reads the cursor pagination query parameters of list endpoints.
+func (h *BaseHandler) pageParams(r *http.Request, meta common.Envelop) (common.PageParams, error) {
+	page, err := utils.GetPageParams(r)
+	if err != nil {
+		return page, apperror.ErrInvalidPageLimit(err, h.Logger, meta)
+	}
+	return page, nil
+}
+
+// newMoviesResponse wraps a store page into the movies list response payload.
+func newMoviesResponse(page *common.MoviePage) common.MoviesResponse {
+	return common.MoviesResponse{
+		Success:     true,
+		Movies:      page.Movies,
+		Count:       len(page.Movies),
+		NextCursor:  page.NextCursor,
+		HasMore:     page.HasMore,
+		Suggestions: page.Suggestions,
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"multipass/internal/service"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+	"multipass/pkg/response"
+)
+
+type DataExportHandler struct {
+	BaseHandler
+	service service.UserDataExportService
+}
+
+func NewDataExportHandler(service service.UserDataExportService, logger logging.Logger, responder response.Writer) *DataExportHandler {
+	return &DataExportHandler{
+		service: service,
+		BaseHandler: BaseHandler{
+			Logger:       logger,
+			Responder:    responder,
+			ErrorHandler: apperror.NewBaseErrorHandler(logger, responder),
+		},
+	}
+}
+
+// HandleRequestDataExport starts building an archive of the user's data; its
+// download link is emailed once ready
+func (h *DataExportHandler) HandleRequestDataExport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	export, err := h.service.RequestDataExport(ctx)
+	if h.ErrorHandler.HandleAppError(w, r, err, "RequestDataExport") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusAccepted, export)
+	h.Logger.Info(fmt.Sprintf("RequestDataExport queued export %d", export.ID))
+}
+
+// HandleGetDataExport returns the status of the user's last data export
+func (h *DataExportHandler) HandleGetDataExport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	export, err := h.service.LatestDataExport(ctx)
+	if h.ErrorHandler.HandleAppError(w, r, err, "LatestDataExport") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, export)
+}
+
+// HandleDownloadDataExport streams the archive an emailed ?token= link points
+// to. The link is used up once the whole archive was sent.
+func (h *DataExportHandler) HandleDownloadDataExport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "DataExportHandler.HandleDownloadDataExport",
+	}
+
+	download, err := h.service.OpenDataExport(ctx, r.URL.Query().Get("token"))
+	if h.ErrorHandler.HandleAppError(w, r, err, "OpenDataExport") {
+		return
+	}
+	defer download.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", download.FileName))
+	w.Header().Set("Cache-Control", "no-store")
+	if download.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(download.Size, 10))
+	}
+
+	if _, err := io.Copy(w, download.File); err != nil {
+		// The response has started, the client is left with a truncated archive
+		// but the link still works to try again
+		h.Logger.Error("Data export download failed while streaming", err, "meta", metaData)
+		return
+	}
+
+	if err := h.service.CompleteDataExportDownload(ctx, download); err != nil {
+		h.Logger.Error("Failed to consume data export link", err, "meta", metaData)
+		return
+	}
+
+	h.Logger.Info("successfully downloaded data export", "meta", metaData)
+}
+
+func (h *DataExportHandler) writeData(w http.ResponseWriter, r *http.Request, status int, data any) {
+	if err := h.Responder.WriteJSON(w, status, common.Envelop{"data": data}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+	}
+}
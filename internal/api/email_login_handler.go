@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/ctxutils"
+	"multipass/pkg/utils"
+)
+
+// -----------------------------------------------------------
+// PASSWORDLESS EMAIL LOGIN
+// -----------------------------------------------------------
+
+// HandleRequestEmailLogin emails a sign-in link and code. It answers the same
+// whether or not the address has an account.
+// Route: POST /api/account/login/email
+func (h *AccountHandler) HandleRequestEmailLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleRequestEmailLogin",
+	}
+
+	req, err := utils.DecodeRequest[common.SendOTPRequest](w, r, "RequestEmailLogin Request")
+	if err != nil {
+		return
+	}
+
+	if err := h.service.RequestEmailLoginService(ctx, req); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, err, "RequestEmailLoginService")
+		return
+	}
+
+	resp := common.GenericResponse{
+		Success: true,
+		Message: "If an account exists for this email, a sign-in link and code have been sent to it.",
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusAccepted, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+	h.Logger.Info("Email login request processed (silent fail applied)", "meta", metaData)
+}
+
+// HandleMagicLinkLogin signs in with the token of an emailed link.
+// Route: POST /api/account/login/email/link
+func (h *AccountHandler) HandleMagicLinkLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := ctxutils.SetClientInfo(r.Context(), utils.GetClientInfo(r))
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleMagicLinkLogin",
+	}
+
+	req, err := utils.DecodeRequest[common.MagicLinkLoginRequest](w, r, "MagicLinkLogin Request")
+	if err != nil {
+		return
+	}
+
+	if req.Token == "" {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrTokenMissing(errors.New("missing sign-in link token"), h.Logger, metaData), "missing_token")
+		return
+	}
+
+	result, err := h.service.MagicLinkLoginService(ctx, req.Token)
+	if h.ErrorHandler.HandleAppError(w, r, err, "MagicLinkLoginService") {
+		return
+	}
+
+	h.writeAuthResult(w, r, result, metaData)
+}
+
+// HandleEmailCodeLogin signs in with the emailed code, from any device.
+// Route: POST /api/account/login/email/code
+func (h *AccountHandler) HandleEmailCodeLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := ctxutils.SetClientInfo(r.Context(), utils.GetClientInfo(r))
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleEmailCodeLogin",
+	}
+
+	req, err := utils.DecodeRequest[common.OTPRequest](w, r, "EmailCodeLogin Request")
+	if err != nil {
+		return
+	}
+
+	result, err := h.service.EmailCodeLoginService(ctx, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, "EmailCodeLoginService") {
+		return
+	}
+
+	h.writeAuthResult(w, r, result, metaData)
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"multipass/internal/model"
+	"multipass/internal/service"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+	"multipass/pkg/response"
+	"multipass/pkg/utils"
+)
+
+// maxImportUpload bounds uploaded exports; the largest ones are a few MB
+const maxImportUpload = 10 << 20
+
+type CollectionImportHandler struct {
+	BaseHandler
+	service service.UserCollectionImportService
+}
+
+func NewCollectionImportHandler(service service.UserCollectionImportService, logger logging.Logger, responder response.Writer) *CollectionImportHandler {
+	return &CollectionImportHandler{
+		service: service,
+		BaseHandler: BaseHandler{
+			Logger:       logger,
+			Responder:    responder,
+			ErrorHandler: apperror.NewBaseErrorHandler(logger, responder),
+		},
+	}
+}
+
+// HandleStartImport uploads a Letterboxd, IMDb or Trakt export into one of
+// the user's collections. Small exports answer with their preview, larger
+// ones are matched in the background and answer 202 Accepted.
+func (h *CollectionImportHandler) HandleStartImport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "CollectionImportHandler.HandleStartImport",
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportUpload)
+	if err := r.ParseMultipartForm(maxImportUpload); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.ErrorHandler.HandleAppError(w, r, apperror.ErrFileTooLarge(errors.New(apperror.ErrFileTooLargeMsg), h.Logger, metaData), "ParseMultipartForm")
+			return
+		}
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrBadRequest(err, h.Logger, metaData), "ParseMultipartForm")
+		return
+	}
+
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrBadRequest(err, h.Logger, metaData), "FormFile")
+		return
+	}
+	defer file.Close()
+
+	resp, err := h.service.StartImport(ctx, file, fileHeader.Filename, r.FormValue("format"), r.FormValue("collection"))
+	if h.ErrorHandler.HandleAppError(w, r, err, "StartImport") {
+		return
+	}
+
+	status := http.StatusCreated
+	if resp.Import.Status == model.ImportStatusProcessing {
+		status = http.StatusAccepted
+	}
+	h.writeData(w, r, status, resp)
+	h.Logger.Info(fmt.Sprintf("StartImport created import %d with %d rows", resp.Import.ID, resp.Import.Total))
+}
+
+// HandleGetImport returns an import's progress, and its preview once matched
+func (h *CollectionImportHandler) HandleGetImport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "CollectionImportHandler.HandleGetImport",
+	}
+
+	importID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_import_id")
+		return
+	}
+
+	resp, err := h.service.GetImport(ctx, importID)
+	if h.ErrorHandler.HandleAppError(w, r, err, "GetImport") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, resp)
+}
+
+// HandleCommitImport saves the matched rows and the user's choices for the
+// ambiguous ones into the import's collection
+func (h *CollectionImportHandler) HandleCommitImport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "CollectionImportHandler.HandleCommitImport",
+	}
+
+	importID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_import_id")
+		return
+	}
+
+	req, err := utils.DecodeRequest[common.CollectionImportCommitRequest](w, r, "CommitImport Request")
+	if err != nil {
+		return
+	}
+
+	resp, err := h.service.CommitImport(ctx, importID, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, "CommitImport") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, resp)
+	h.Logger.Info(fmt.Sprintf("CommitImport added %d of %d movies from import %d", resp.Added, resp.Selected, importID))
+}
+
+// HandleDiscardImport deletes an import without saving anything
+func (h *CollectionImportHandler) HandleDiscardImport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "CollectionImportHandler.HandleDiscardImport",
+	}
+
+	importID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_import_id")
+		return
+	}
+
+	err = h.service.DiscardImport(ctx, importID)
+	if h.ErrorHandler.HandleAppError(w, r, err, "DiscardImport") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, common.CollectionSuccess{Success: true, Message: "Import discarded"})
+}
+
+func (h *CollectionImportHandler) writeData(w http.ResponseWriter, r *http.Request, status int, data any) {
+	if err := h.Responder.WriteJSON(w, status, common.Envelop{"data": data}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+	}
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"multipass/internal/service"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+	"multipass/pkg/response"
+	"multipass/pkg/utils"
+)
+
+type ListHandler struct {
+	BaseHandler
+	service service.UserListService
+}
+
+func NewListHandler(service service.UserListService, logger logging.Logger, responder response.Writer) *ListHandler {
+	return &ListHandler{
+		service: service,
+		BaseHandler: BaseHandler{
+			Logger:       logger,
+			Responder:    responder,
+			ErrorHandler: apperror.NewBaseErrorHandler(logger, responder),
+		},
+	}
+}
+
+// HandleGetUserLists lists the authenticated user's lists without their items
+func (h *ListHandler) HandleGetUserLists(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	lists, err := h.service.UserLists(ctx)
+	if h.ErrorHandler.HandleAppError(w, r, err, "UserLists") {
+		return
+	}
+
+	resp := common.ListsResponse{
+		Success: true,
+		Lists:   lists,
+		Count:   len(lists),
+	}
+	h.writeData(w, r, http.StatusOK, resp)
+}
+
+// HandleCreateList creates a list for the authenticated user
+func (h *ListHandler) HandleCreateList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := utils.DecodeRequest[common.ListRequest](w, r, "CreateList Request")
+	if err != nil {
+		return
+	}
+
+	list, err := h.service.CreateList(ctx, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, "CreateList") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusCreated, list)
+	h.Logger.Info(fmt.Sprintf("CreateList successfully created list: %d", list.ID))
+}
+
+// HandleGetList returns one of the authenticated user's lists with its items
+func (h *ListHandler) HandleGetList(w http.ResponseWriter, r *http.Request) {
+	h.handleGetList(w, r, "GetList")
+}
+
+// HandleGetPublicList returns a public list with its items
+func (h *ListHandler) HandleGetPublicList(w http.ResponseWriter, r *http.Request) {
+	h.handleGetList(w, r, "GetPublicList")
+}
+
+func (h *ListHandler) handleGetList(w http.ResponseWriter, r *http.Request, op string) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ListHandler." + op,
+	}
+
+	listID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_list_id")
+		return
+	}
+
+	list, err := h.service.GetList(ctx, listID)
+	if h.ErrorHandler.HandleAppError(w, r, err, op) {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, list)
+}
+
+// HandleGetSharedList returns the list a share link points to
+func (h *ListHandler) HandleGetSharedList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	list, err := h.service.SharedList(ctx, r.PathValue("token"))
+	if h.ErrorHandler.HandleAppError(w, r, err, "SharedList") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, list)
+}
+
+// HandleUpdateList renames one of the authenticated user's lists and changes its visibility
+func (h *ListHandler) HandleUpdateList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ListHandler.HandleUpdateList",
+	}
+
+	listID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_list_id")
+		return
+	}
+
+	req, err := utils.DecodeRequest[common.ListRequest](w, r, "UpdateList Request")
+	if err != nil {
+		return
+	}
+
+	list, err := h.service.UpdateList(ctx, listID, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, "UpdateList") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, list)
+}
+
+// HandleDeleteList removes one of the authenticated user's lists
+func (h *ListHandler) HandleDeleteList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ListHandler.HandleDeleteList",
+	}
+
+	listID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_list_id")
+		return
+	}
+
+	err = h.service.DeleteList(ctx, listID)
+	if h.ErrorHandler.HandleAppError(w, r, err, "DeleteList") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, common.CollectionSuccess{Success: true, Message: "List deleted"})
+}
+
+// HandleAddListItem appends a movie to one of the authenticated user's lists
+func (h *ListHandler) HandleAddListItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ListHandler.HandleAddListItem",
+	}
+
+	listID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_list_id")
+		return
+	}
+
+	req, err := utils.DecodeRequest[common.ListItemRequest](w, r, "AddListItem Request")
+	if err != nil {
+		return
+	}
+
+	item, err := h.service.AddItem(ctx, listID, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, "AddListItem") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusCreated, item)
+}
+
+// HandleUpdateListItem edits the note of a list item and/or moves it within the list
+func (h *ListHandler) HandleUpdateListItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ListHandler.HandleUpdateListItem",
+	}
+
+	listID, movieID, ok := h.listItemIDs(w, r, metaData)
+	if !ok {
+		return
+	}
+
+	req, err := utils.DecodeRequest[common.ListItemUpdateRequest](w, r, "UpdateListItem Request")
+	if err != nil {
+		return
+	}
+
+	item, err := h.service.UpdateItem(ctx, listID, movieID, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, "UpdateListItem") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, item)
+}
+
+// HandleRemoveListItem removes a movie from one of the authenticated user's lists
+func (h *ListHandler) HandleRemoveListItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ListHandler.HandleRemoveListItem",
+	}
+
+	listID, movieID, ok := h.listItemIDs(w, r, metaData)
+	if !ok {
+		return
+	}
+
+	err := h.service.RemoveItem(ctx, listID, movieID)
+	if h.ErrorHandler.HandleAppError(w, r, err, "RemoveListItem") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, common.CollectionSuccess{Success: true, Message: "Movie removed from list"})
+}
+
+// listItemIDs reads the list and movie ids of an item route. It writes the
+// error response and reports false when either is invalid.
+func (h *ListHandler) listItemIDs(w http.ResponseWriter, r *http.Request, metaData common.Envelop) (int, int, bool) {
+	listID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_list_id")
+		return 0, 0, false
+	}
+	movieID, err := utils.GetPathID(r, "movieId")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_movie_id")
+		return 0, 0, false
+	}
+	return listID, movieID, true
+}
+
+// HandleCreateShareLink issues a new read-only share link for one of the
+// authenticated user's lists. The previous link stops working.
+func (h *ListHandler) HandleCreateShareLink(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ListHandler.HandleCreateShareLink",
+	}
+
+	listID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_list_id")
+		return
+	}
+
+	resp, err := h.service.CreateShareLink(ctx, listID)
+	if h.ErrorHandler.HandleAppError(w, r, err, "CreateShareLink") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusCreated, resp)
+}
+
+// HandleRevokeShareLink disables the share link of one of the authenticated user's lists
+func (h *ListHandler) HandleRevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ListHandler.HandleRevokeShareLink",
+	}
+
+	listID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_list_id")
+		return
+	}
+
+	err = h.service.RevokeShareLink(ctx, listID)
+	if h.ErrorHandler.HandleAppError(w, r, err, "RevokeShareLink") {
+		return
+	}
+
+	h.writeData(w, r, http.StatusOK, common.CollectionSuccess{Success: true, Message: "Share link revoked"})
+}
+
+func (h *ListHandler) writeData(w http.ResponseWriter, r *http.Request, status int, data any) {
+	if err := h.Responder.WriteJSON(w, status, common.Envelop{"data": data}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+	}
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/ctxutils"
+	"multipass/pkg/utils"
+)
+
+// -----------------------------------------------------------
+// TWO-FACTOR AUTHENTICATION
+// -----------------------------------------------------------
+
+// MFATokenHeader carries the mfa token when the body holds a passkey assertion
+const MFATokenHeader = "X-MFA-Token"
+
+// writeMFARequired answers a correct password on a two-factor account with the
+// token to send along with the second factor, and the factors accepted
+func (h *AccountHandler) writeMFARequired(w http.ResponseWriter, r *http.Request, result *common.AuthResult, metaData common.Envelop) {
+	message := "Enter the code from your authenticator app to finish signing in"
+	if !slices.Contains(result.MFAMethods, model.MFAMethodTOTP) {
+		message = "Use your passkey to finish signing in"
+	} else if slices.Contains(result.MFAMethods, model.MFAMethodWebAuthn) {
+		message = "Use your passkey or enter the code from your authenticator app to finish signing in"
+	}
+
+	resp := common.MFARequiredResponse{
+		Success:     true,
+		Message:     message,
+		MFARequired: true,
+		MFAToken:    result.MFAToken,
+		MFAMethods:  result.MFAMethods,
+		ExpiresAt:   result.ExpiresAt,
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+	h.Logger.Info("password accepted, second factor required", "meta", metaData)
+}
+
+// HandleMFALogin finishes a password login with an authenticator app or
+// recovery code and issues the tokens.
+// Route: POST /api/account/login/mfa
+func (h *AccountHandler) HandleMFALogin(w http.ResponseWriter, r *http.Request) {
+	ctx := ctxutils.SetClientInfo(r.Context(), utils.GetClientInfo(r))
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleMFALogin",
+	}
+
+	req, err := utils.DecodeRequest[common.MFALoginRequest](w, r, "MFALogin Request")
+	if err != nil {
+		return
+	}
+
+	if req.MFAToken == "" {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrTokenMissing(errors.New("missing mfa token"), h.Logger, metaData), "missing_token")
+		return
+	}
+
+	result, err := h.service.MFALoginService(ctx, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, "MFALoginService") {
+		return
+	}
+
+	h.writeAuthResult(w, r, result, metaData)
+}
+
+// HandleGetTOTPStatus tells whether two-factor authentication is on and how
+// many recovery codes are left.
+// Route: GET /api/account/mfa/totp
+func (h *AccountHandler) HandleGetTOTPStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleGetTOTPStatus",
+	}
+
+	status, err := h.service.TOTPStatusService(ctx)
+	if h.ErrorHandler.HandleAppError(w, r, err, "TOTPStatusService") {
+		return
+	}
+
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": status}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+	}
+}
+
+// HandleEnrollTOTP starts setting up an authenticator app: the secret, its
+// otpauth:// URI and a base64 PNG QR code to scan.
+// Route: POST /api/account/mfa/totp
+func (h *AccountHandler) HandleEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleEnrollTOTP",
+	}
+
+	enrollment, err := h.service.EnrollTOTPService(ctx)
+	if h.ErrorHandler.HandleAppError(w, r, err, "EnrollTOTPService") {
+		return
+	}
+
+	// The secret must not be cached anywhere on the way
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.Responder.WriteJSON(w, http.StatusCreated, common.Envelop{"data": enrollment}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+	h.Logger.Info("TOTP enrollment started", "meta", metaData)
+}
+
+// HandleConfirmTOTP enables two-factor authentication with a first code from
+// the app and returns the recovery codes, shown only this once.
+// Route: POST /api/account/mfa/totp/verify
+func (h *AccountHandler) HandleConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleConfirmTOTP",
+	}
+
+	req, err := utils.DecodeRequest[common.VerifyOTPRequest](w, r, "ConfirmTOTP Request")
+	if err != nil {
+		return
+	}
+
+	codes, err := h.service.ConfirmTOTPService(ctx, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, "ConfirmTOTPService") {
+		return
+	}
+
+	resp := common.RecoveryCodesResponse{
+		Success:       true,
+		Message:       "Two-factor authentication is on. Store these recovery codes somewhere safe, each works once.",
+		RecoveryCodes: codes,
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+	h.Logger.Info("TOTP enrollment confirmed", "meta", metaData)
+}
+
+// HandleDisableTOTP turns two-factor authentication off after re-checking the
+// password and a current or recovery code.
+// Route: DELETE /api/account/mfa/totp
+func (h *AccountHandler) HandleDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleDisableTOTP",
+	}
+
+	req, err := utils.DecodeRequest[common.TOTPDisableRequest](w, r, "DisableTOTP Request")
+	if err != nil {
+		return
+	}
+
+	if err := h.service.DisableTOTPService(ctx, req); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, err, "DisableTOTPService")
+		return
+	}
+
+	resp := common.GenericResponse{
+		Success: true,
+		Message: "Two-factor authentication is off.",
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+	h.Logger.Info("TOTP disabled", "meta", metaData)
+}
+
+// -----------------------------------------------------------
+// PASSKEY SECOND STEP
+// -----------------------------------------------------------
+
+// HandleGetPasskeyMFA tells whether password logins require a passkey and how
+// many passkeys are registered.
+// Route: GET /api/account/mfa/webauthn
+func (h *AccountHandler) HandleGetPasskeyMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleGetPasskeyMFA",
+	}
+
+	status, err := h.service.PasskeyMFAStatusService(ctx)
+	if h.ErrorHandler.HandleAppError(w, r, err, "PasskeyMFAStatusService") {
+		return
+	}
+
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": status}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+	}
+}
+
+// HandleSetPasskeyMFA makes password logins require a passkey, or stop
+// requiring one, after re-checking the password.
+// Route: PUT /api/account/mfa/webauthn
+func (h *AccountHandler) HandleSetPasskeyMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandleSetPasskeyMFA",
+	}
+
+	req, err := utils.DecodeRequest[common.PasskeyMFAPolicyRequest](w, r, "SetPasskeyMFA Request")
+	if err != nil {
+		return
+	}
+
+	if err := h.service.SetPasskeyMFAService(ctx, req); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, err, "SetPasskeyMFAService")
+		return
+	}
+
+	message := "Signing in with your password no longer asks for a passkey."
+	if *req.Required {
+		message = "Signing in with your password now asks for a passkey too."
+	}
+	resp := common.GenericResponse{
+		Success: true,
+		Message: message,
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+	h.Logger.Info("passkey second step updated", "meta", metaData)
+}
+
+// HandlePasskeyMFABegin returns the assertion options for the passkey step of
+// a password login, limited to the user's own passkeys.
+// Route: POST /api/account/mfa/webauthn/begin
+func (h *AccountHandler) HandlePasskeyMFABegin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandlePasskeyMFABegin",
+	}
+
+	req, err := utils.DecodeRequest[common.PasskeyMFABeginRequest](w, r, "PasskeyMFABegin Request")
+	if err != nil {
+		return
+	}
+
+	if req.MFAToken == "" {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrTokenMissing(errors.New("missing mfa token"), h.Logger, metaData), "missing_token")
+		return
+	}
+
+	options, err := h.service.BeginPasskeyMFAService(ctx, req.MFAToken)
+	if h.ErrorHandler.HandleAppError(w, r, err, "BeginPasskeyMFAService") {
+		return
+	}
+
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": options}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+	}
+}
+
+// HandlePasskeyMFAFinish finishes a password login with the passkey assertion
+// in the body and issues the tokens. The mfa token goes in the X-MFA-Token header.
+// Route: POST /api/account/mfa/webauthn/finish
+func (h *AccountHandler) HandlePasskeyMFAFinish(w http.ResponseWriter, r *http.Request) {
+	ctx := ctxutils.SetClientInfo(r.Context(), utils.GetClientInfo(r))
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "AccountHandler.HandlePasskeyMFAFinish",
+	}
+
+	mfaToken := r.Header.Get(MFATokenHeader)
+	if mfaToken == "" {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrTokenMissing(errors.New("missing mfa token"), h.Logger, metaData), "missing_token")
+		return
+	}
+
+	result, err := h.service.FinishPasskeyMFAService(ctx, r, mfaToken)
+	if h.ErrorHandler.HandleAppError(w, r, err, "FinishPasskeyMFAService") {
+		return
+	}
+
+	h.writeAuthResult(w, r, result, metaData)
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	"multipass/internal/store"
 	"multipass/pkg/apperror"
@@ -14,6 +16,9 @@ import (
 	"multipass/pkg/utils"
 )
 
+// alsoSavedLimit is how many co-occurring movies are attached to movie details.
+const alsoSavedLimit = 10
+
 type MovieHandler struct {
 	BaseHandler
 	movieStore store.MovieStore
@@ -33,17 +38,23 @@ func NewMovieHandler(movieStore store.MovieStore, logger logging.Logger, respond
 // GetTopMovies handles get top movies route
 func (h *MovieHandler) HandleGetTopMovies(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	movies, err := h.movieStore.GetTopMovies(ctx)
-	if h.ErrorHandler.HandleAppError(w, r, err, "failed to fetch top movies") {
+	errMeta := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+	}
+
+	page, err := h.pageParams(r, errMeta)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_page_params") {
 		return
 	}
 
-	resp := common.MoviesResponse{
-		Success: true,
-		Movies:  movies,
-		Count:   len(movies),
+	moviePage, err := h.movieStore.GetTopMovies(ctx, page)
+	if h.ErrorHandler.HandleAppError(w, r, err, "failed to fetch top movies") {
+		return
 	}
 
+	resp := newMoviesResponse(moviePage)
+
 	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
 		if h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer") {
 			return
@@ -51,8 +62,8 @@ func (h *MovieHandler) HandleGetTopMovies(w http.ResponseWriter, r *http.Request
 	}
 
 	firstMovieTitle := ""
-	if len(movies) > 0 {
-		firstMovieTitle = movies[0].Title
+	if len(resp.Movies) > 0 {
+		firstMovieTitle = resp.Movies[0].Title
 	}
 
 	h.Logger.Info(fmt.Sprintf("GetTopMovies successfully sent top movies: first_title: %s", firstMovieTitle))
@@ -108,6 +119,13 @@ func (h *MovieHandler) HandleGetMovieByID(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// "Users who saved this also saved" is best effort and must not fail the details page
+	alsoSaved, err := h.movieStore.GetAlsoSavedMovies(ctx, id, alsoSavedLimit)
+	if err != nil {
+		h.Logger.Error("Failed to get also saved movies", err, "meta", errMeta)
+	}
+	movie.AlsoSaved = alsoSaved
+
 	// 3: Send Back Response
 	err = h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": movie})
 	if err != nil {
@@ -118,6 +136,48 @@ func (h *MovieHandler) HandleGetMovieByID(w http.ResponseWriter, r *http.Request
 	h.Logger.Info(fmt.Sprintf("GetMovieByID successfully sent movie: %s", movie.Title))
 }
 
+// GetSimilarMovies handles "more like this" movies route
+func (h *MovieHandler) HandleGetSimilarMovies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	errMeta := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+	}
+
+	// 1: Read {id} from the route pattern
+	id, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, errMeta), "params_movie_id")
+		return
+	}
+
+	page, err := h.pageParams(r, errMeta)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_page_params") {
+		return
+	}
+
+	// 2: Get similar movies
+	movies, err := h.movieStore.GetSimilarMovies(ctx, id, page.Limit)
+	if h.ErrorHandler.HandleAppError(w, r, err, "failed to get similar movies") {
+		return
+	}
+
+	resp := common.MoviesResponse{
+		Success: true,
+		Movies:  movies,
+		Count:   len(movies),
+	}
+
+	// 3: Send Back Response
+	err = h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp})
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("GetSimilarMovies successfully sent %d movies for movie: %d", len(movies), id))
+}
+
 // SearchMovies handles search movies by order/genre/query route
 func (h *MovieHandler) HandleSearchMovies(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -158,17 +218,18 @@ func (h *MovieHandler) HandleSearchMovies(w http.ResponseWriter, r *http.Request
 		genre = &genreInt
 	}
 
-	movies, err := h.movieStore.SearchMovieByName(ctx, query, order, genre)
-	if h.ErrorHandler.HandleAppError(w, r, err, "failed to search movies") {
+	page, err := h.pageParams(r, errMeta)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_page_params") {
 		return
 	}
 
-	resp := common.MoviesResponse{
-		Success: true,
-		Movies:  movies,
-		Count:   len(movies),
+	moviePage, err := h.movieStore.SearchMovieByName(ctx, query, order, genre, page)
+	if h.ErrorHandler.HandleAppError(w, r, err, "failed to search movies") {
+		return
 	}
 
+	resp := newMoviesResponse(moviePage)
+
 	err = h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp})
 	if err != nil {
 		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
@@ -177,6 +238,173 @@ func (h *MovieHandler) HandleSearchMovies(w http.ResponseWriter, r *http.Request
 	h.Logger.Info("SearchMovies successfully sent results")
 }
 
+// GetMoviesByGenre handles get movies of a genre route
+func (h *MovieHandler) HandleGetMoviesByGenre(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	order, _ := utils.GetQueryParam(r, "order")
+	errMeta := common.Envelop{
+		"method":   r.Method,
+		"path":     r.URL.Path,
+		"order_by": order,
+	}
+
+	// 1: Read {id} from the route pattern
+	genreID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		errMeta["genre_id"] = r.PathValue("id")
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidGenreID(err, h.Logger, errMeta), "params_genre_id")
+		return
+	}
+
+	page, err := h.pageParams(r, errMeta)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_page_params") {
+		return
+	}
+
+	// 2: Get the requested page of movies
+	moviePage, err := h.movieStore.GetMoviesByGenre(ctx, genreID, order, page)
+	if h.ErrorHandler.HandleAppError(w, r, err, "failed to fetch movies by genre") {
+		return
+	}
+
+	// 3: Send Back Response
+	err = h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": newMoviesResponse(moviePage)})
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("GetMoviesByGenre successfully sent movies for genre: %d", genreID))
+}
+
+// DiscoverMovies handles multi-filter movie browsing with facet counts route
+func (h *MovieHandler) HandleDiscoverMovies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	order, _ := utils.GetQueryParam(r, "order")
+	errMeta := common.Envelop{
+		"method":   r.Method,
+		"path":     r.URL.Path,
+		"order_by": order,
+		"query":    r.URL.RawQuery,
+	}
+
+	// 1: Parse filters and pagination
+	filter, err := h.parseDiscoverFilter(r, errMeta)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_discover_filter") {
+		return
+	}
+
+	page, err := h.pageParams(r, errMeta)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_page_params") {
+		return
+	}
+
+	// 2: Query movies and facets
+	result, err := h.movieStore.DiscoverMovies(ctx, filter, order, page)
+	if h.ErrorHandler.HandleAppError(w, r, err, "failed to discover movies") {
+		return
+	}
+
+	// 3: Send Back Response
+	resp := common.DiscoverResponse{
+		MoviesResponse: newMoviesResponse(result.Page),
+		Facets:         result.Facets,
+	}
+	err = h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp})
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("DiscoverMovies successfully sent %d movies", resp.Count))
+}
+
+// parseDiscoverFilter reads the discover query parameters:
+// genres=1,2&genre_mode=any|all&year_from=&year_to=&score_min=&score_max=&language=&actor=&keyword=
+func (h *MovieHandler) parseDiscoverFilter(r *http.Request, meta common.Envelop) (*common.DiscoverFilter, error) {
+	q := r.URL.Query()
+	filter := &common.DiscoverFilter{
+		Language: strings.TrimSpace(q.Get("language")),
+		Keyword:  strings.TrimSpace(q.Get("keyword")),
+	}
+
+	if raw := q.Get("genres"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || id < 1 {
+				meta["genre_id"] = part
+				return nil, apperror.ErrInvalidGenreID(fmt.Errorf("invalid genre id %q", part), h.Logger, meta)
+			}
+			// genre_mode=all counts distinct matches, repeats would never match
+			if !slices.Contains(filter.GenreIDs, id) {
+				filter.GenreIDs = append(filter.GenreIDs, id)
+			}
+		}
+	}
+
+	switch mode := q.Get("genre_mode"); mode {
+	case "", "any":
+	case "all":
+		filter.MatchAllGenres = true
+	default:
+		return nil, apperror.ErrBadRequest(fmt.Errorf("genre_mode must be 'any' or 'all', got %q", mode), h.Logger, meta)
+	}
+
+	var err error
+	if filter.YearFrom, err = optionalInt(q.Get("year_from")); err != nil {
+		return nil, apperror.ErrBadRequest(fmt.Errorf("invalid year_from: %w", err), h.Logger, meta)
+	}
+	if filter.YearTo, err = optionalInt(q.Get("year_to")); err != nil {
+		return nil, apperror.ErrBadRequest(fmt.Errorf("invalid year_to: %w", err), h.Logger, meta)
+	}
+	if filter.YearFrom != nil && filter.YearTo != nil && *filter.YearFrom > *filter.YearTo {
+		return nil, apperror.ErrBadRequest(errors.New("year_from must not be after year_to"), h.Logger, meta)
+	}
+
+	if filter.ScoreMin, err = optionalScore(q.Get("score_min")); err != nil {
+		return nil, apperror.ErrMovieRatingOutOfBounds(fmt.Errorf("invalid score_min: %w", err), h.Logger, meta)
+	}
+	if filter.ScoreMax, err = optionalScore(q.Get("score_max")); err != nil {
+		return nil, apperror.ErrMovieRatingOutOfBounds(fmt.Errorf("invalid score_max: %w", err), h.Logger, meta)
+	}
+
+	if filter.ActorID, err = optionalInt(q.Get("actor")); err != nil || (filter.ActorID != nil && *filter.ActorID < 1) {
+		meta["actor_id"] = q.Get("actor")
+		return nil, apperror.ErrInvalidActorID(fmt.Errorf("invalid actor id %q", q.Get("actor")), h.Logger, meta)
+	}
+
+	return filter, nil
+}
+
+// optionalInt parses raw when present.
+func optionalInt(raw string) (*int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// optionalScore parses raw when present and checks it is a valid 0-10 score.
+func optionalScore(raw string) (*float64, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, err
+	}
+	if v < 0 || v > 10 {
+		return nil, fmt.Errorf("score %v out of range", v)
+	}
+	return &v, nil
+}
+
 // GetAllGenres handles get all genres route
 func (h *MovieHandler) HandleGetAllGenres(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
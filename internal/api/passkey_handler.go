@@ -16,6 +16,10 @@ import (
 
 const WEBAUTHN_COOKIE_NAME string = "sid"
 
+// WEBAUTHN_DISCOVERABLE_COOKIE_NAME keeps the ceremony of a login without an
+// email apart, as autofill starts one while the email flow may start another
+const WEBAUTHN_DISCOVERABLE_COOKIE_NAME string = "dsid"
+
 type WebAuthnHandler struct {
 	service service.WebAuthnService
 	BaseHandler
@@ -196,3 +200,168 @@ func (h *WebAuthnHandler) WebAuthnAuthenticationEndHandler(w http.ResponseWriter
 
 	h.Logger.Info("successfully processed user webauthn login end request", "meta", metaData)
 }
+
+// USERNAMELESS LOGIN
+
+// WebAuthnDiscoverableAuthenticationBeginHandler starts a login without an
+// email. ?mediation=conditional returns options for the autofill of the email
+// field (navigator.credentials.get with mediation "conditional").
+// Route: POST /api/passkey/authentication-discoverable-begin
+func (h *WebAuthnHandler) WebAuthnDiscoverableAuthenticationBeginHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "WebAuthnHandler.WebAuthnDiscoverableAuthenticationBeginHandler",
+	}
+
+	// STEP 1: WEBAUTHN DISCOVERABLE LOGIN START SERVICE
+	result, err := h.service.WebAuthnDiscoverableLoginStartService(ctx, r.URL.Query().Get("mediation"))
+	if h.ErrorHandler.HandleAppError(w, r, err, "WebAuthnDiscoverableLoginStartService") {
+		return
+	}
+
+	// STEP 2: SET COOKIE
+	cookieutils.SetCookie(w, h.Logger, WEBAUTHN_DISCOVERABLE_COOKIE_NAME, result.Token, "/api/passkey", 3600, time.Now().Add(1*time.Hour))
+
+	// STEP 3: RETURN RESPONSE
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": result.Options}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+
+	h.Logger.Info("successfully processed user webauthn discoverable start request", "meta", metaData)
+}
+
+// WebAuthnDiscoverableAuthenticationEndHandler finishes a login without an
+// email, for the user the passkey belongs to.
+// Route: POST /api/passkey/authentication-discoverable-end
+func (h *WebAuthnHandler) WebAuthnDiscoverableAuthenticationEndHandler(w http.ResponseWriter, r *http.Request) {
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "WebAuthnHandler.WebAuthnDiscoverableAuthenticationEndHandler",
+	}
+
+	// STEP 1: EXTRACT SESSION KEY FROM COOKIE
+	cookie, err := cookieutils.GetCookie(r, h.Logger, WEBAUTHN_DISCOVERABLE_COOKIE_NAME)
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrUnauthorized(err, h.Logger, metaData), "webauthn_cookie_missing")
+		return
+	}
+
+	// STEP 2: WEBAUTHN DISCOVERABLE LOGIN END SERVICE
+	result, err := h.service.WebAuthnDiscoverableLoginEndService(w, r, cookie.Value)
+	if h.ErrorHandler.HandleAppError(w, r, err, "WebAuthnDiscoverableLoginEndService") {
+		return
+	}
+
+	// STEP 3: SWAP THE CEREMONY COOKIE FOR THE SESSION COOKIE
+	cookieutils.SetCookie(w, h.Logger, WEBAUTHN_DISCOVERABLE_COOKIE_NAME, "", "/api/passkey", -1, time.Unix(0, 0))
+	cookieutils.SetCookie(w, h.Logger, WEBAUTHN_COOKIE_NAME, result.Token, "/", 3600, time.Now().Add(1*time.Hour))
+
+	// STEP 4: RETURN RESPONSE
+	resp := &common.WebAuthnAuthResponse{
+		Success: true,
+		JWT:     result.JWT,
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+
+	h.Logger.Info("successfully processed user webauthn discoverable login end request", "meta", metaData)
+}
+
+// -----------------------------------------------------------
+// PASSKEY MANAGEMENT
+// -----------------------------------------------------------
+
+// HandleListPasskeys returns the user's passkeys with their authenticator,
+// nickname and last use.
+// Route: GET /api/passkey/credentials
+func (h *WebAuthnHandler) HandleListPasskeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "WebAuthnHandler.HandleListPasskeys",
+	}
+
+	passkeys, err := h.service.ListPasskeysService(ctx)
+	if h.ErrorHandler.HandleAppError(w, r, err, "ListPasskeysService") {
+		return
+	}
+
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": passkeys}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+	}
+}
+
+// HandleRenamePasskey sets the nickname of a passkey.
+// Route: PUT /api/passkey/credentials/{id}
+func (h *WebAuthnHandler) HandleRenamePasskey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "WebAuthnHandler.HandleRenamePasskey",
+	}
+
+	passkeyID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_passkey_id")
+		return
+	}
+
+	req, err := utils.DecodeRequest[common.PasskeyRenameRequest](w, r, "RenamePasskey Request")
+	if err != nil {
+		return
+	}
+
+	if err := h.service.RenamePasskeyService(ctx, passkeyID, req); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, err, "RenamePasskeyService")
+		return
+	}
+
+	resp := common.GenericResponse{
+		Success: true,
+		Message: "Passkey renamed",
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+	}
+}
+
+// HandleDeletePasskey deletes a passkey. The last one of an account that
+// can't sign in without it is kept.
+// Route: DELETE /api/passkey/credentials/{id}
+func (h *WebAuthnHandler) HandleDeletePasskey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "WebAuthnHandler.HandleDeletePasskey",
+	}
+
+	passkeyID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_passkey_id")
+		return
+	}
+
+	if err := h.service.DeletePasskeyService(ctx, passkeyID); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, err, "DeletePasskeyService")
+		return
+	}
+
+	resp := common.GenericResponse{
+		Success: true,
+		Message: "Passkey deleted",
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, metaData), "response writer")
+		return
+	}
+	h.Logger.Info("passkey deleted", "meta", metaData)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"multipass/internal/service"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+	"multipass/pkg/response"
+)
+
+type RecommendationHandler struct {
+	BaseHandler
+	service service.UserRecommendationService
+}
+
+func NewRecommendationHandler(service service.UserRecommendationService, logger logging.Logger, responder response.Writer) *RecommendationHandler {
+	return &RecommendationHandler{
+		service: service,
+		BaseHandler: BaseHandler{
+			Logger:       logger,
+			Responder:    responder,
+			ErrorHandler: apperror.NewBaseErrorHandler(logger, responder),
+		},
+	}
+}
+
+// HandleGetRecommendations retrieves personalized movie recommendations for the authenticated user
+func (h *RecommendationHandler) HandleGetRecommendations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "RecommendationHandler.HandleGetRecommendations",
+	}
+
+	page, err := h.pageParams(r, metaData)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_page_params") {
+		return
+	}
+
+	result, err := h.service.RecommendForUser(ctx, page.Limit)
+	if h.ErrorHandler.HandleAppError(w, r, err, "RecommendForUser") {
+		return
+	}
+
+	resp := common.RecommendationsResponse{
+		Success:         true,
+		Recommendations: result.Recommendations,
+		Count:           len(result.Recommendations),
+		ColdStart:       result.ColdStart,
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("GetRecommendations successfully sent %d recommendations", resp.Count))
+}
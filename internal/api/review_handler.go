@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"multipass/internal/model"
+	"multipass/internal/service"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+	"multipass/pkg/response"
+	"multipass/pkg/utils"
+)
+
+type ReviewHandler struct {
+	BaseHandler
+	service service.UserReviewService
+}
+
+func NewReviewHandler(service service.UserReviewService, logger logging.Logger, responder response.Writer) *ReviewHandler {
+	return &ReviewHandler{
+		service: service,
+		BaseHandler: BaseHandler{
+			Logger:       logger,
+			Responder:    responder,
+			ErrorHandler: apperror.NewBaseErrorHandler(logger, responder),
+		},
+	}
+}
+
+// HandleGetMovieReviews lists the reviews of a movie, newest first
+func (h *ReviewHandler) HandleGetMovieReviews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ReviewHandler.HandleGetMovieReviews",
+	}
+
+	movieID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_movie_id")
+		return
+	}
+
+	page, err := h.pageParams(r, metaData)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_page_params") {
+		return
+	}
+
+	reviews, rating, err := h.service.MovieReviews(ctx, movieID, page)
+	if h.ErrorHandler.HandleAppError(w, r, err, "MovieReviews") {
+		return
+	}
+
+	resp := newReviewsResponse(reviews)
+	resp.CommunityRating = rating
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("GetMovieReviews successfully sent %d reviews for movie: %d", resp.Count, movieID))
+}
+
+// HandleCreateReview posts the authenticated user's review of a movie
+func (h *ReviewHandler) HandleCreateReview(w http.ResponseWriter, r *http.Request) {
+	h.handleWriteReview(w, r, http.StatusCreated, "CreateReview", h.service.CreateReview)
+}
+
+// HandleUpdateReview edits the authenticated user's review of a movie
+func (h *ReviewHandler) HandleUpdateReview(w http.ResponseWriter, r *http.Request) {
+	h.handleWriteReview(w, r, http.StatusOK, "UpdateReview", h.service.UpdateReview)
+}
+
+func (h *ReviewHandler) handleWriteReview(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	op string,
+	write func(ctx context.Context, movieID int, req *common.ReviewRequest) (*model.Review, error),
+) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ReviewHandler." + op,
+	}
+
+	movieID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_movie_id")
+		return
+	}
+
+	req, err := utils.DecodeRequest[common.ReviewRequest](w, r, op+" Request")
+	if err != nil {
+		return
+	}
+
+	review, err := write(ctx, movieID, req)
+	if h.ErrorHandler.HandleAppError(w, r, err, op) {
+		return
+	}
+
+	if err := h.Responder.WriteJSON(w, status, common.Envelop{"data": review}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("%s successfully saved review for movie: %d", op, movieID))
+}
+
+// HandleDeleteReview removes the authenticated user's review of a movie
+func (h *ReviewHandler) HandleDeleteReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ReviewHandler.HandleDeleteReview",
+	}
+
+	movieID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_movie_id")
+		return
+	}
+
+	err = h.service.DeleteReview(ctx, movieID)
+	if h.ErrorHandler.HandleAppError(w, r, err, "DeleteReview") {
+		return
+	}
+
+	resp := common.CollectionSuccess{
+		Success: true,
+		Message: "Review deleted",
+	}
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+}
+
+// HandleGetUserReviews lists the reviews the authenticated user wrote
+func (h *ReviewHandler) HandleGetUserReviews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ReviewHandler.HandleGetUserReviews",
+	}
+
+	page, err := h.pageParams(r, metaData)
+	if h.ErrorHandler.HandleAppError(w, r, err, "invalid_page_params") {
+		return
+	}
+
+	reviews, err := h.service.UserReviews(ctx, page)
+	if h.ErrorHandler.HandleAppError(w, r, err, "UserReviews") {
+		return
+	}
+
+	resp := newReviewsResponse(reviews)
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": resp}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+}
+
+// HandleGetReviewHistory lists the previous versions of one of the authenticated user's reviews
+func (h *ReviewHandler) HandleGetReviewHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	metaData := common.Envelop{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"op":     "ReviewHandler.HandleGetReviewHistory",
+	}
+
+	reviewID, err := utils.GetPathID(r, "id")
+	if err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInvalidIDParameter(err, h.Logger, metaData), "params_review_id")
+		return
+	}
+
+	revisions, err := h.service.ReviewHistory(ctx, reviewID)
+	if h.ErrorHandler.HandleAppError(w, r, err, "ReviewHistory") {
+		return
+	}
+
+	if err := h.Responder.WriteJSON(w, http.StatusOK, common.Envelop{"data": common.Envelop{
+		"revisions": revisions,
+		"count":     len(revisions),
+	}}); err != nil {
+		h.ErrorHandler.HandleAppError(w, r, apperror.ErrInternalServer(err, h.Logger, nil), "response writer")
+		return
+	}
+}
+
+func newReviewsResponse(page *common.ReviewPage) common.ReviewsResponse {
+	return common.ReviewsResponse{
+		Success:    true,
+		Reviews:    page.Reviews,
+		Count:      len(page.Reviews),
+		NextCursor: page.NextCursor,
+		HasMore:    page.HasMore,
+	}
+}
@@ -6,10 +6,12 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"multipass/config"
 	"multipass/internal/api"
 	"multipass/internal/cache"
+	"multipass/internal/jobs"
 	"multipass/internal/middleware"
 	"multipass/internal/service"
 	"multipass/pkg/apperror"
@@ -26,15 +28,25 @@ import (
 )
 
 type Application struct {
-	Config          *config.Config
-	Logger          logging.Logger
-	Responder       response.Writer
-	DB              *pgxpool.Pool
-	Redis           *redis.Client
-	MovieHandler    *api.MovieHandler
-	AccountHandler  *api.AccountHandler
-	WebAuthnHandler *api.WebAuthnHandler
-	AuthMiddleware  *middleware.AuthMiddleware
+	Config                *config.Config
+	Logger                logging.Logger
+	Responder             response.Writer
+	DB                    *pgxpool.Pool
+	Redis                 *redis.Client
+	MovieHandler          *api.MovieHandler
+	ActorHandler          *api.ActorHandler
+	RecommendationHandler *api.RecommendationHandler
+	ReviewHandler         *api.ReviewHandler
+	ListHandler           *api.ListHandler
+	ImportHandler         *api.CollectionImportHandler
+	DataExportHandler     *api.DataExportHandler
+	AdminHandler          *api.AdminHandler
+	AccountHandler        *api.AccountHandler
+	WebAuthnHandler       *api.WebAuthnHandler
+	AuthMiddleware        *middleware.AuthMiddleware
+	AdminMiddleware       *middleware.AdminMiddleware
+	Jobs                  *jobs.Scheduler
+	Tasks                 *jobs.Runner
 }
 
 func NewApplication() (*Application, error) {
@@ -102,7 +114,19 @@ func NewApplication() (*Application, error) {
 		appLogger.Error("Error creating WebAuthn, Error initialing Passkey engine", err)
 	}
 	passkeyStore := store.NewPasskeyRepository(db, appLogger)
-	passkeyService := service.NewPasskeyService(passkeyStore, webAuthnManager, tokenManager, appLogger)
+
+	// Ceremony sessions live in Redis so any instance can finish a ceremony;
+	// the in-memory fallback only works while a single instance serves traffic
+	var ceremonyStore store.CeremonySessionStore
+	ceremonyTTL := store.CeremonyTimeout(cfg.WebAuthn)
+	if redisClient != nil {
+		ceremonyStore = store.NewRedisCeremonySessionStore(redisClient, ceremonyTTL, appLogger)
+	} else {
+		appLogger.Warn("Redis unavailable, keeping WebAuthn ceremony sessions in memory")
+		ceremonyStore = store.NewMemoryCeremonySessionStore(ceremonyTTL)
+	}
+
+	passkeyService := service.NewPasskeyService(passkeyStore, ceremonyStore, webAuthnManager, tokenManager, appLogger)
 	webAuthnHandler := api.NewWebAuthnHandler(passkeyService, appLogger, jsonWriter)
 	/* ￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
 	           # AUTH MIDDLEWARE
@@ -119,10 +143,22 @@ func NewApplication() (*Application, error) {
 
 	emailSender := service.NewEmailService(cfg, appLogger)
 
+	// Custom lists can also be targeted through the collection endpoints
+	listStore := store.NewListRepository(db, appLogger)
+
+	securityEventStore := store.NewSecurityEventRepository(db, appLogger)
+	mfaStore := store.NewMFARepository(db, appLogger)
+
 	accountService := service.NewAccountService(
 		accountStore,
 		tokenStore,
+		securityEventStore,
+		mfaStore,
+		passkeyStore,
+		ceremonyStore,
+		listStore,
 		*tokenManager,
+		webAuthnManager,
 		emailSender,
 		appLogger,
 		cfg,
@@ -139,7 +175,7 @@ func NewApplication() (*Application, error) {
 	/* ￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
 	           # MOVIES SETUP
 		__________________________________________*/
-	movieStore := store.NewMovieRepository(db, appLogger)
+	movieStore := store.NewMovieRepository(db, redisClient, appLogger)
 	if movieStore == nil {
 		appLogger.Fatalf("Failed to initialize movie store: %+v", err)
 	}
@@ -148,6 +184,81 @@ func NewApplication() (*Application, error) {
 	if movieHandler == nil {
 		appLogger.Fatal("Failed to initialize movie handler", nil)
 	}
+
+	/* ￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
+	           # ACTORS SETUP
+		__________________________________________*/
+	actorStore := store.NewActorRepository(db, appLogger)
+	actorHandler := api.NewActorHandler(actorStore, appLogger, jsonWriter)
+	if actorHandler == nil {
+		appLogger.Fatal("Failed to initialize actor handler", nil)
+	}
+
+	/* ￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
+	           # RECOMMENDATIONS SETUP
+		__________________________________________*/
+	recommendationStore := store.NewRecommendationRepository(db, appLogger)
+	recommendationService := service.NewRecommendationService(recommendationStore, movieStore, appLogger)
+	recommendationHandler := api.NewRecommendationHandler(recommendationService, appLogger, jsonWriter)
+
+	/* ￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
+	           # REVIEWS SETUP
+		__________________________________________*/
+	reviewStore := store.NewReviewRepository(db, appLogger)
+	reviewService := service.NewReviewService(reviewStore, movieStore, appLogger)
+	reviewHandler := api.NewReviewHandler(reviewService, appLogger, jsonWriter)
+
+	/* ￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
+	           # LISTS SETUP
+		__________________________________________*/
+	listService := service.NewListService(listStore, tokenManager, cfg.Email.FrontendURL, appLogger)
+	listHandler := api.NewListHandler(listService, appLogger, jsonWriter)
+
+	/* ￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
+	           # COLLECTION IMPORTS SETUP
+		__________________________________________*/
+	// Large exports are matched on a couple of workers outside the request
+	taskRunner := jobs.NewRunner(2, appLogger)
+	collectionImportStore := store.NewCollectionImportRepository(db, appLogger)
+	collectionImportService := service.NewCollectionImportService(collectionImportStore, taskRunner, appLogger)
+	importHandler := api.NewCollectionImportHandler(collectionImportService, appLogger, jsonWriter)
+
+	/* ￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
+	           # DATA EXPORTS SETUP
+		__________________________________________*/
+	dataExportStore := store.NewDataExportRepository(db, appLogger)
+	dataExportService := service.NewDataExportService(
+		dataExportStore,
+		accountStore,
+		listStore,
+		reviewStore,
+		passkeyStore,
+		tokenManager,
+		emailSender,
+		taskRunner,
+		cfg,
+		appLogger,
+	)
+	dataExportHandler := api.NewDataExportHandler(dataExportService, appLogger, jsonWriter)
+
+	/* ￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
+	           # ADMIN CATALOG SETUP
+		__________________________________________*/
+	adminMW := middleware.NewAdminMiddleware(accountStore, appLogger, jsonWriter)
+	catalogStore := store.NewCatalogRepository(db, appLogger)
+	catalogService := service.NewCatalogService(catalogStore, appLogger)
+	adminHandler := api.NewAdminHandler(catalogService, appLogger, jsonWriter)
+
+	/* ￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
+	           # BACKGROUND JOBS SETUP
+		__________________________________________*/
+	scheduler := jobs.NewScheduler(appLogger)
+	scheduler.Every(cfg.Jobs.CooccurrenceInterval, jobs.NewCooccurrenceJob(movieStore, cfg.Jobs.CooccurrenceMinSupport, appLogger))
+	scheduler.Every(time.Hour, jobs.NewCollectionImportCleanupJob(collectionImportStore, cfg.Jobs.ImportRetention, appLogger))
+	scheduler.Every(time.Hour, jobs.NewDataExportCleanupJob(dataExportStore, appLogger))
+	scheduler.Every(time.Hour, jobs.NewRetiredTokenCleanupJob(tokenStore, appLogger))
+	scheduler.Every(time.Hour, jobs.NewAccountPurgeJob(accountStore, cfg.Jobs.AccountDeletionGrace, cfg.ProfilePicturePath, cfg.ProfilePictureBase, appLogger))
+
 	// accountHandler := api.NewAccountHandler(
 	// 	accountStore,
 	// 	tokenStore,
@@ -161,15 +272,25 @@ func NewApplication() (*Application, error) {
 		__________________________________________*/
 
 	app := &Application{
-		Config:          cfg,
-		Logger:          appLogger,
-		Responder:       jsonWriter,
-		Redis:           redisClient,
-		DB:              db,
-		MovieHandler:    movieHandler,
-		AccountHandler:  accountHandler,
-		WebAuthnHandler: webAuthnHandler,
-		AuthMiddleware:  authMW,
+		Config:                cfg,
+		Logger:                appLogger,
+		Responder:             jsonWriter,
+		Redis:                 redisClient,
+		DB:                    db,
+		MovieHandler:          movieHandler,
+		ActorHandler:          actorHandler,
+		RecommendationHandler: recommendationHandler,
+		ReviewHandler:         reviewHandler,
+		ListHandler:           listHandler,
+		ImportHandler:         importHandler,
+		DataExportHandler:     dataExportHandler,
+		AdminHandler:          adminHandler,
+		AccountHandler:        accountHandler,
+		WebAuthnHandler:       webAuthnHandler,
+		AuthMiddleware:        authMW,
+		AdminMiddleware:       adminMW,
+		Jobs:                  scheduler,
+		Tasks:                 taskRunner,
 	}
 	return app, nil
 }
@@ -7,6 +7,9 @@ import (
 	"encoding/base32"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
 	"time"
 
 	"multipass/internal/model"
@@ -27,17 +30,33 @@ const (
 	EmailVerificationScope string = "verification"
 	PasswordResetScope     string = "reset"
 	OTPScope               string = "otp"
+	ListShareScope         string = "list_share"
+	DataExportScope        string = "data_export"
+	AccountDeletionScope   string = "account_deletion"
+	MagicLinkScope         string = "magic_link"
+	LoginCodeScope         string = "login_code"
 	// EmailVerification         = TokenType("email_verification")
 	// PasswordReset             = TokenType("password_reset")
 	RefreshTokenLength int = 32
+	LoginCodeDigits    int = 6
 )
 
 type Token struct {
+	ID        int       `json:"-"`
 	Plaintext string    `json:"token"`
 	Hash      []byte    `json:"-"`
 	UserID    int       `json:"-"`
 	Expiry    time.Time `json:"expiry"`
 	Scope     string    `json:"-"`
+	// Created is when the session began; rotated refresh tokens keep it
+	Created time.Time `json:"-"`
+	// Family groups a refresh token with the tokens it was rotated from,
+	// ParentID is the token it replaced and Retired when it was rotated
+	Family   int64      `json:"-"`
+	ParentID int        `json:"-"`
+	Retired  *time.Time `json:"-"`
+	// Attempts counts the wrong guesses at a short code
+	Attempts int `json:"-"`
 }
 
 type CustomClaims struct {
@@ -88,7 +107,12 @@ func (tm *TokenManager) CreateJWT(user *model.User) (string, error) {
 }
 
 // CreateRefreshToken generates a new cryptographically secure opaque refresh token.
+// It expires after ttl, or after the refresh TTL when ttl isn't positive.
 func (tm *TokenManager) CreateRefreshToken(userID int, ttl time.Duration, scope string) (*Token, error) {
+	if ttl <= 0 {
+		ttl = tm.RefreshTTL
+	}
+
 	randomBytes := make([]byte, RefreshTokenLength)
 	_, err := rand.Read(randomBytes)
 	if err != nil {
@@ -106,13 +130,43 @@ func (tm *TokenManager) CreateRefreshToken(userID int, ttl time.Duration, scope
 		Plaintext: plainText,
 		Hash:      hash[:],
 		UserID:    int(userID),
-		Expiry:    time.Now().Add(tm.RefreshTTL),
+		Expiry:    time.Now().Add(ttl),
 		Scope:     scope,
+		Created:   time.Now(),
 	}
 
 	return token, nil
 }
 
+// CreateLoginCode generates a numeric sign-in code to type in. Its hash is
+// keyed with the refresh secret and bound to the user, so the few possible
+// codes can't be reversed from a leaked hash.
+func (tm *TokenManager) CreateLoginCode(userID int, ttl time.Duration) (*Token, error) {
+	upper := big.NewInt(int64(math.Pow10(LoginCodeDigits)))
+	n, err := rand.Int(rand.Reader, upper)
+	if err != nil {
+		tm.Logger.Error("Failed to generate random login code", err)
+		return nil, fmt.Errorf("failed to generate login code: %w", err)
+	}
+	code := fmt.Sprintf("%0*d", LoginCodeDigits, n.Int64())
+
+	return &Token{
+		Plaintext: code,
+		Hash:      tm.HashLoginCode(userID, code),
+		UserID:    userID,
+		Expiry:    time.Now().Add(ttl),
+		Scope:     LoginCodeScope,
+		Created:   time.Now(),
+	}, nil
+}
+
+// HashLoginCode hashes a login code as typed by the user.
+func (tm *TokenManager) HashLoginCode(userID int, code string) []byte {
+	mac := hmac.New(sha256.New, tm.RefreshSecret)
+	fmt.Fprintf(mac, "%d:%s", userID, strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+	return mac.Sum(nil)
+}
+
 // ValidateJWT parses and validates a JWT access token.
 func (tm *TokenManager) ValidateJWT(tokenStr string) (*CustomClaims, error) {
 	op := "tokens.ValidateJWT"
@@ -0,0 +1,60 @@
+package totp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+const (
+	RecoveryCodeCount = 10
+
+	// Lowercase base32 without the look-alike 0, 1, 8 and 9
+	recoveryAlphabet   = "abcdefghijklmnopqrstuvwxyz234567"
+	recoveryCodeLength = 10
+)
+
+// GenerateRecoveryCodes returns n one-time recovery codes, formatted
+// "xxxxx-xxxxx", and their hashes to store.
+func GenerateRecoveryCodes(n int) ([]string, [][]byte, error) {
+	codes := make([]string, 0, n)
+	hashes := make([][]byte, 0, n)
+
+	b := make([]byte, recoveryCodeLength)
+	for range n {
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		for i := range b {
+			// 256 is a multiple of 32, there is no modulo bias
+			b[i] = recoveryAlphabet[int(b[i])%len(recoveryAlphabet)]
+		}
+		code := string(b[:5]) + "-" + string(b[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode hashes a recovery code as typed by the user, ignoring case,
+// spaces and dashes.
+func HashRecoveryCode(code string) []byte {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return sum[:]
+}
+
+// LooksLikeRecoveryCode tells a recovery code from an authenticator code.
+func LooksLikeRecoveryCode(code string) bool {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(normalized) != recoveryCodeLength {
+		return false
+	}
+	for _, c := range normalized {
+		if !strings.ContainsRune(recoveryAlphabet, c) {
+			return false
+		}
+	}
+	return true
+}
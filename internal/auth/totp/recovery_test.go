@@ -0,0 +1,75 @@
+package totp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"regexp"
+	"testing"
+)
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := sha256.Sum256([]byte("abcde23456"))
+
+	for _, code := range []string{
+		"abcde-23456",
+		"abcde23456",
+		"ABCDE-23456",
+		"AbCdE 23456",
+		" abcde - 23456 ",
+	} {
+		if got := HashRecoveryCode(code); !bytes.Equal(got, want[:]) {
+			t.Errorf("HashRecoveryCode(%q) = %x, want %x", code, got, want)
+		}
+	}
+
+	if bytes.Equal(HashRecoveryCode("abcde-23457"), want[:]) {
+		t.Error("different codes hash the same")
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != RecoveryCodeCount || len(hashes) != RecoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), RecoveryCodeCount)
+	}
+
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q is not formatted xxxxx-xxxxx", code)
+		}
+		if !LooksLikeRecoveryCode(code) {
+			t.Errorf("LooksLikeRecoveryCode(%q) = false", code)
+		}
+		if !bytes.Equal(hashes[i], HashRecoveryCode(code)) {
+			t.Errorf("hash of code %q does not match HashRecoveryCode", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q generated twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestLooksLikeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"abcde-23456", true},
+		{"ABCDE 23456", true},
+		{"123456", false},
+		{"abcde-23450", false},
+		{"abcde-2345", false},
+		{"abcde-234567", false},
+	}
+	for _, tt := range tests {
+		if got := LooksLikeRecoveryCode(tt.code); got != tt.want {
+			t.Errorf("LooksLikeRecoveryCode(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
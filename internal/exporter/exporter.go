@@ -0,0 +1,214 @@
+// Package exporter writes a user's collections as files other services can
+// import: a plain CSV, a JSON array, or the Letterboxd import CSV.
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"multipass/internal/model"
+)
+
+const (
+	FormatCSV        = "csv"
+	FormatJSON       = "json"
+	FormatLetterboxd = "letterboxd"
+)
+
+// Formats lists the supported export formats.
+var Formats = []string{FormatCSV, FormatJSON, FormatLetterboxd}
+
+// Writer writes the movies of one or more collections, one at a time.
+// Close must be called to complete the file, even when nothing was written.
+type Writer interface {
+	Write(list string, m *model.Movie) error
+	Close() error
+}
+
+// Record is one exported collection entry.
+type Record struct {
+	List      string     `json:"list"`
+	TMDB_ID   int        `json:"tmdb_id"`
+	IMDbID    *string    `json:"imdb_id"`
+	Title     string     `json:"title"`
+	Year      int        `json:"year"`
+	TimeAdded *time.Time `json:"time_added"`
+}
+
+func newRecord(list string, m *model.Movie) Record {
+	return Record{
+		List:      list,
+		TMDB_ID:   m.TMDB_ID,
+		IMDbID:    m.IMDbID,
+		Title:     m.Title,
+		Year:      m.ReleaseYear,
+		TimeAdded: m.TimeAdded,
+	}
+}
+
+// NewWriter returns a writer producing the given format on w.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatJSON:
+		return &jsonWriter{w: bufio.NewWriter(w)}, nil
+	case FormatLetterboxd:
+		return &letterboxdWriter{w: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// ContentType returns the media type of a format.
+func ContentType(format string) string {
+	if format == FormatJSON {
+		return "application/json; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// FileName names the export of a list, or of all collections when list is empty.
+func FileName(format string, list string) string {
+	if list == "" {
+		list = "collections"
+	}
+	switch format {
+	case FormatJSON:
+		return list + ".json"
+	case FormatLetterboxd:
+		return list + "-letterboxd.csv"
+	}
+	return list + ".csv"
+}
+
+// csvWriter writes one row per entry, with the collection it belongs to.
+type csvWriter struct {
+	w       *csv.Writer
+	started bool
+}
+
+func (c *csvWriter) Write(list string, m *model.Movie) error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	r := newRecord(list, m)
+	return c.w.Write([]string{
+		r.List,
+		strconv.Itoa(r.TMDB_ID),
+		deref(r.IMDbID),
+		r.Title,
+		formatYear(r.Year),
+		formatTime(r.TimeAdded),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) header() error {
+	if c.started {
+		return nil
+	}
+	c.started = true
+	return c.w.Write([]string{"list", "tmdb_id", "imdb_id", "title", "year", "time_added"})
+}
+
+// jsonWriter writes a JSON array of records, one element at a time.
+type jsonWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func (j *jsonWriter) Write(list string, m *model.Movie) error {
+	sep := ",\n"
+	if j.count == 0 {
+		sep = "[\n"
+	}
+	j.count++
+
+	b, err := json.Marshal(newRecord(list, m))
+	if err != nil {
+		return err
+	}
+	if _, err := j.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err = j.w.Write(b)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	end := "\n]\n"
+	if j.count == 0 {
+		end = "[]\n"
+	}
+	if _, err := j.w.WriteString(end); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+// letterboxdWriter writes the columns of the Letterboxd import format. That
+// format has no date added, a watched date would log a diary entry instead.
+type letterboxdWriter struct {
+	w       *csv.Writer
+	started bool
+}
+
+func (l *letterboxdWriter) Write(_ string, m *model.Movie) error {
+	if err := l.header(); err != nil {
+		return err
+	}
+	tmdbID := ""
+	if m.TMDB_ID > 0 {
+		tmdbID = strconv.Itoa(m.TMDB_ID)
+	}
+	return l.w.Write([]string{tmdbID, deref(m.IMDbID), m.Title, formatYear(m.ReleaseYear)})
+}
+
+func (l *letterboxdWriter) Close() error {
+	if err := l.header(); err != nil {
+		return err
+	}
+	l.w.Flush()
+	return l.w.Error()
+}
+
+func (l *letterboxdWriter) header() error {
+	if l.started {
+		return nil
+	}
+	l.started = true
+	return l.w.Write([]string{"tmdbID", "imdbID", "Title", "Year"})
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatYear(year int) string {
+	if year == 0 {
+		return ""
+	}
+	return strconv.Itoa(year)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
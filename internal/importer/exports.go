@@ -0,0 +1,287 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"multipass/internal/model"
+)
+
+// MaxExportRows bounds the movies read from one uploaded export.
+const MaxExportRows = 20000
+
+var (
+	ErrUnknownExport  = errors.New("the file is not a Letterboxd CSV, IMDb CSV or Trakt JSON export")
+	ErrExportTooLarge = fmt.Errorf("exports can hold at most %d movies", MaxExportRows)
+	ErrEmptyExport    = errors.New("the export holds no movies")
+)
+
+// exportDateLayouts are the date formats used by the supported exports.
+var exportDateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05.000Z", "2006-01-02"}
+
+// ReadExport reads the movies of a Letterboxd CSV, IMDb ratings or list CSV,
+// or Trakt JSON export. An empty source is detected from the file name and
+// content. It returns the source actually read.
+func ReadExport(r io.Reader, fileName string, source string) (string, []model.CollectionImportRow, error) {
+	buf := bufio.NewReader(r)
+	if source == "" {
+		var err error
+		if source, err = detectExport(buf, fileName); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var (
+		rows []model.CollectionImportRow
+		err  error
+	)
+	switch source {
+	case model.ImportSourceLetterboxd:
+		rows, err = readLetterboxd(buf)
+	case model.ImportSourceIMDb:
+		rows, err = readIMDbCSV(buf)
+	case model.ImportSourceTrakt:
+		rows, err = readTrakt(buf)
+	default:
+		return "", nil, ErrUnknownExport
+	}
+	if err != nil {
+		return source, nil, err
+	}
+	if len(rows) == 0 {
+		return source, nil, ErrEmptyExport
+	}
+	return source, rows, nil
+}
+
+// detectExport tells the exports apart by their first line: Trakt exports
+// are JSON, IMDb CSVs start with a Const column, Letterboxd ones have Name or
+// Title and Year, like the CSV exports of this app.
+func detectExport(buf *bufio.Reader, fileName string) (string, error) {
+	first, err := peekNonSpace(buf)
+	if err != nil {
+		return "", ErrEmptyExport
+	}
+	if first == '[' || strings.EqualFold(filepath.Ext(fileName), ".json") {
+		return model.ImportSourceTrakt, nil
+	}
+
+	head, _ := buf.Peek(4096)
+	line, _, _ := bytes.Cut(head, []byte("\n"))
+	header := strings.ToLower(string(line))
+	switch {
+	case strings.Contains(header, "const"):
+		return model.ImportSourceIMDb, nil
+	case strings.Contains(header, "letterboxd uri"), strings.Contains(header, "year") &&
+		(strings.Contains(header, "name") || strings.Contains(header, "title")):
+		return model.ImportSourceLetterboxd, nil
+	}
+	return "", ErrUnknownExport
+}
+
+// csvRecords reads a CSV export by header name, calling fn for every record.
+// A required column may list alternative names separated by "|".
+func csvRecords(r io.Reader, required []string, fn func(get func(string) string) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return ErrEmptyExport
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Excel adds a byte order mark to the first column
+		name = strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")
+		columns[strings.ToLower(name)] = i
+	}
+	for _, names := range required {
+		found := false
+		for name := range strings.SplitSeq(names, "|") {
+			_, ok := columns[name]
+			found = found || ok
+		}
+		if !found {
+			return fmt.Errorf("%w: missing the %q column", ErrUnknownExport, names)
+		}
+	}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		get := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if err := fn(get); err != nil {
+			return err
+		}
+	}
+}
+
+// readLetterboxd reads watched.csv, watchlist.csv, ratings.csv, likes or diary.csv,
+// as well as files in the Letterboxd import format and the CSV exports of this
+// app. Diary rows are dated by their watch date rather than their logging date.
+func readLetterboxd(r io.Reader) ([]model.CollectionImportRow, error) {
+	var rows []model.CollectionImportRow
+	err := csvRecords(r, []string{"name|title"}, func(get func(string) string) error {
+		row := model.CollectionImportRow{
+			Title:  firstOf(get, "name", "title"),
+			Year:   parseYear(get("year")),
+			IMDbID: optional(firstOf(get, "imdbid", "imdb_id")),
+		}
+		if tmdbID, err := strconv.Atoi(firstOf(get, "tmdbid", "tmdb_id")); err == nil && tmdbID > 0 {
+			row.TMDB_ID = &tmdbID
+		}
+		for _, column := range []string{"watched date", "watcheddate", "date", "time_added"} {
+			if row.Date = parseExportDate(get(column)); row.Date != nil {
+				break
+			}
+		}
+		return appendRow(&rows, row)
+	})
+	return rows, err
+}
+
+// firstOf returns the first non-empty value of the given columns.
+func firstOf(get func(string) string, columns ...string) string {
+	for _, column := range columns {
+		if v := get(column); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// readIMDbCSV reads the ratings export or a list export of IMDb. Series and
+// episodes are skipped, the catalog only holds movies.
+func readIMDbCSV(r io.Reader) ([]model.CollectionImportRow, error) {
+	var rows []model.CollectionImportRow
+	err := csvRecords(r, []string{"const", "title"}, func(get func(string) string) error {
+		titleType := strings.ToLower(get("title type"))
+		if strings.Contains(titleType, "series") || strings.Contains(titleType, "episode") {
+			return nil
+		}
+
+		row := model.CollectionImportRow{
+			Title:  get("title"),
+			Year:   parseYear(get("year")),
+			IMDbID: optional(get("const")),
+		}
+		for _, column := range []string{"date rated", "created", "date added"} {
+			if row.Date = parseExportDate(get(column)); row.Date != nil {
+				break
+			}
+		}
+		return appendRow(&rows, row)
+	})
+	return rows, err
+}
+
+// traktItem is an entry of the Trakt watched, history, watchlist or ratings exports.
+type traktItem struct {
+	Type          string      `json:"type"`
+	Movie         *traktMovie `json:"movie"`
+	WatchedAt     string      `json:"watched_at"`
+	LastWatchedAt string      `json:"last_watched_at"`
+	ListedAt      string      `json:"listed_at"`
+	RatedAt       string      `json:"rated_at"`
+	CollectedAt   string      `json:"collected_at"`
+}
+
+type traktMovie struct {
+	Title string `json:"title"`
+	Year  *int   `json:"year"`
+	IDs   struct {
+		TMDB *int   `json:"tmdb"`
+		IMDb string `json:"imdb"`
+	} `json:"ids"`
+}
+
+// readTrakt streams a Trakt JSON export. Shows and episodes are skipped.
+func readTrakt(r io.Reader) ([]model.CollectionImportRow, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("%w: expected a JSON array", ErrUnknownExport)
+	}
+
+	var rows []model.CollectionImportRow
+	for dec.More() {
+		var item traktItem
+		if err := dec.Decode(&item); err != nil {
+			return nil, fmt.Errorf("movie %d: %w", len(rows)+1, err)
+		}
+		if item.Movie == nil || (item.Type != "" && item.Type != "movie") {
+			continue
+		}
+
+		row := model.CollectionImportRow{
+			Title:   strings.TrimSpace(item.Movie.Title),
+			Year:    item.Movie.Year,
+			TMDB_ID: item.Movie.IDs.TMDB,
+			IMDbID:  optional(item.Movie.IDs.IMDb),
+		}
+		for _, date := range []string{item.WatchedAt, item.LastWatchedAt, item.ListedAt, item.RatedAt, item.CollectedAt} {
+			if row.Date = parseExportDate(date); row.Date != nil {
+				break
+			}
+		}
+		if err := appendRow(&rows, row); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// appendRow numbers and appends a row that names a movie somehow.
+func appendRow(rows *[]model.CollectionImportRow, row model.CollectionImportRow) error {
+	if row.Title == "" && row.TMDB_ID == nil && row.IMDbID == nil {
+		return nil
+	}
+	if len(*rows) == MaxExportRows {
+		return ErrExportTooLarge
+	}
+	row.Row = len(*rows) + 1
+	*rows = append(*rows, row)
+	return nil
+}
+
+func parseYear(s string) *int {
+	year, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || year < 1870 || year > 2200 {
+		return nil
+	}
+	return &year
+}
+
+func parseExportDate(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	for _, layout := range exportDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,500 @@
+package importer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"multipass/internal/model"
+	"multipass/internal/store"
+	"multipass/pkg/logging"
+)
+
+const (
+	imdbNull = `\N`
+	// imdbYearTolerance absorbs festival premieres and late releases, which
+	// make TMDB and IMDb disagree on the year by one
+	imdbYearTolerance = 1
+	// imdbMaxLine is far above the longest line of the datasets
+	imdbMaxLine = 1 << 20
+)
+
+// imdbTitleTypes are the title.basics types a catalog movie can match.
+var imdbTitleTypes = map[string]bool{"movie": true, "tvMovie": true}
+
+// imdbCrewCategories maps title.principals categories to crew roles.
+var imdbCrewCategories = map[string]string{
+	"director": model.CrewDirector,
+	"writer":   model.CrewWriter,
+}
+
+// IMDbFiles are the paths of the IMDb non-commercial datasets
+// (https://datasets.imdbws.com), gzipped or not. Names is optional.
+type IMDbFiles struct {
+	Basics     string
+	Ratings    string
+	Principals string
+	Names      string
+}
+
+// UnmatchedMovie is a catalog movie the enrichment found no single IMDb title for.
+type UnmatchedMovie struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	ReleaseYear *int   `json:"release_year"`
+	Reason      string `json:"reason"`
+}
+
+const (
+	unmatchedNoTitle   = "no title with this name and year"
+	unmatchedNoYear    = "no release year"
+	unmatchedUnknownID = "imdb id not in title.basics"
+	unmatchedAmbiguous = "several titles match"
+	unmatchedTaken     = "title already linked to another movie"
+)
+
+// IMDbSummary is the report of an enrichment run.
+type IMDbSummary struct {
+	DryRun    bool             `json:"dry_run"`
+	Movies    int              `json:"movies"`
+	Matched   int              `json:"matched"`
+	ByID      int              `json:"by_id"`
+	Rated     int              `json:"rated"`
+	Directors int              `json:"directors"`
+	Writers   int              `json:"writers"`
+	Named     int              `json:"named"`
+	Unmatched []UnmatchedMovie `json:"unmatched"`
+}
+
+// imdbCandidate is an IMDb title that may be a catalog movie.
+type imdbCandidate struct {
+	tconst   string
+	yearDiff int
+	byID     bool
+}
+
+// imdbTitle is what the enrichment keeps of a candidate title.
+type imdbTitle struct {
+	runtime *int
+	rating  *float32
+	votes   *int
+	crew    []model.CrewMember
+}
+
+// IMDbEnricher fills the runtime, IMDb rating and crew of catalog movies from
+// the IMDb datasets. The datasets hold millions of rows, so every file is
+// streamed once and only the rows of candidate titles are kept.
+type IMDbEnricher struct {
+	store  store.ImportStore
+	opts   Options
+	logger logging.Logger
+}
+
+func NewIMDbEnricher(importStore store.ImportStore, opts Options, logger logging.Logger) *IMDbEnricher {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	return &IMDbEnricher{
+		store:  importStore,
+		opts:   opts,
+		logger: logger,
+	}
+}
+
+// Run matches every catalog movie to an IMDb title and writes what the
+// datasets know about it. A movie already linked to an IMDb id keeps it,
+// the others are matched on their title and release year.
+func (en *IMDbEnricher) Run(ctx context.Context, files IMDbFiles) (*IMDbSummary, error) {
+	movies, err := en.store.GetCatalogTitles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summary := &IMDbSummary{DryRun: en.opts.DryRun, Movies: len(movies)}
+
+	byTitle := make(map[string][]int)
+	byID := make(map[string]int)
+	for i, m := range movies {
+		if m.IMDbID != nil {
+			byID[*m.IMDbID] = i
+			continue
+		}
+		if key := normalizeTitle(m.Title); key != "" {
+			byTitle[key] = append(byTitle[key], i)
+		}
+	}
+
+	// STEP 1: FIND CANDIDATES
+	candidates := make([][]imdbCandidate, len(movies))
+	titles := make(map[string]*imdbTitle)
+	err = readTSV(ctx, files.Basics, func(row tsvRow) error {
+		if !imdbTitleTypes[row.get("titleType")] {
+			return nil
+		}
+		tconst := row.get("tconst")
+
+		var matches []imdbCandidate
+		if i, ok := byID[tconst]; ok {
+			matches = append(matches, imdbCandidate{tconst: tconst, byID: true})
+			candidates[i] = append(candidates[i], matches[0])
+		}
+
+		year, err := strconv.Atoi(row.get("startYear"))
+		if err == nil {
+			seen := make(map[int]bool)
+			for _, col := range []string{"primaryTitle", "originalTitle"} {
+				for _, i := range byTitle[normalizeTitle(row.get(col))] {
+					if seen[i] || movies[i].ReleaseYear == nil {
+						continue
+					}
+					seen[i] = true
+					diff := abs(*movies[i].ReleaseYear - year)
+					if diff > imdbYearTolerance {
+						continue
+					}
+					c := imdbCandidate{tconst: tconst, yearDiff: diff}
+					matches = append(matches, c)
+					candidates[i] = append(candidates[i], c)
+				}
+			}
+		}
+
+		if len(matches) > 0 {
+			t := &imdbTitle{}
+			if runtime, err := strconv.Atoi(row.get("runtimeMinutes")); err == nil && runtime > 0 {
+				t.runtime = &runtime
+			}
+			titles[tconst] = t
+		}
+		return nil
+	})
+	if err != nil {
+		return summary, fmt.Errorf("title.basics: %w", err)
+	}
+	en.logger.Info("IMDb candidate titles found", "titles", len(titles))
+
+	// STEP 2: RATINGS
+	// Read before choosing, the vote count breaks ties between same-named titles
+	err = readTSV(ctx, files.Ratings, func(row tsvRow) error {
+		t, ok := titles[row.get("tconst")]
+		if !ok {
+			return nil
+		}
+		if rating, err := strconv.ParseFloat(row.get("averageRating"), 32); err == nil {
+			r := float32(rating)
+			t.rating = &r
+		}
+		if votes, err := strconv.Atoi(row.get("numVotes")); err == nil {
+			t.votes = &votes
+		}
+		return nil
+	})
+	if err != nil {
+		return summary, fmt.Errorf("title.ratings: %w", err)
+	}
+
+	// STEP 3: CHOOSE ONE TITLE PER MOVIE AND ONE MOVIE PER TITLE
+	chosen := make([]string, len(movies))
+	reasons := make([]string, len(movies))
+	claims := make(map[string][]int)
+	for i, m := range movies {
+		tconst, reason := chooseCandidate(m, candidates[i], titles)
+		if tconst == "" {
+			reasons[i] = reason
+			continue
+		}
+		chosen[i] = tconst
+		claims[tconst] = append(claims[tconst], i)
+	}
+	for _, claimants := range claims {
+		if len(claimants) < 2 {
+			continue
+		}
+		// A movie already linked to the title keeps it, title matches give way
+		keeper := -1
+		for _, i := range claimants {
+			if movies[i].IMDbID != nil {
+				keeper = i
+			}
+		}
+		for _, i := range claimants {
+			if i == keeper {
+				continue
+			}
+			chosen[i] = ""
+			if keeper >= 0 {
+				reasons[i] = unmatchedTaken
+			} else {
+				reasons[i] = unmatchedAmbiguous
+			}
+		}
+	}
+
+	matched := make(map[string]*imdbTitle)
+	for i, tconst := range chosen {
+		if tconst == "" {
+			m := movies[i]
+			summary.Unmatched = append(summary.Unmatched, UnmatchedMovie{ID: m.ID, Title: m.Title, ReleaseYear: m.ReleaseYear, Reason: reasons[i]})
+			continue
+		}
+		matched[tconst] = titles[tconst]
+	}
+	// STEP 4: CREW
+	people := make(map[string]*string)
+	err = readTSV(ctx, files.Principals, func(row tsvRow) error {
+		t, ok := matched[row.get("tconst")]
+		if !ok {
+			return nil
+		}
+		role, ok := imdbCrewCategories[row.get("category")]
+		if !ok {
+			return nil
+		}
+		nconst := row.get("nconst")
+		if nconst == "" || slices.ContainsFunc(t.crew, func(c model.CrewMember) bool { return c.Role == role && c.IMDbID == nconst }) {
+			return nil
+		}
+		ordering, _ := strconv.Atoi(row.get("ordering"))
+		t.crew = append(t.crew, model.CrewMember{IMDbID: nconst, Role: role, Ordering: ordering})
+		people[nconst] = nil
+		return nil
+	})
+	if err != nil {
+		return summary, fmt.Errorf("title.principals: %w", err)
+	}
+
+	if files.Names != "" {
+		err = readTSV(ctx, files.Names, func(row tsvRow) error {
+			nconst := row.get("nconst")
+			if _, ok := people[nconst]; !ok {
+				return nil
+			}
+			people[nconst] = optional(row.get("primaryName"))
+			return nil
+		})
+		if err != nil {
+			return summary, fmt.Errorf("name.basics: %w", err)
+		}
+	}
+
+	// STEP 5: WRITE
+	var batch []model.IMDbEnrichment
+	flush := func() error {
+		if len(batch) == 0 || en.opts.DryRun {
+			batch = nil
+			return nil
+		}
+		if err := en.store.ApplyIMDbBatch(ctx, batch); err != nil {
+			return err
+		}
+		en.logger.Info("IMDb batch committed", "movies", len(batch))
+		batch = nil
+		return nil
+	}
+
+	for i, tconst := range chosen {
+		if tconst == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		t := matched[tconst]
+		e := model.IMDbEnrichment{
+			MovieID: movies[i].ID,
+			IMDbID:  tconst,
+			Runtime: t.runtime,
+			Rating:  t.rating,
+			Votes:   t.votes,
+			Crew:    make([]model.CrewMember, 0, len(t.crew)),
+		}
+		for _, c := range t.crew {
+			c.Name = people[c.IMDbID]
+			if c.Name != nil {
+				summary.Named++
+			}
+			if c.Role == model.CrewDirector {
+				summary.Directors++
+			} else {
+				summary.Writers++
+			}
+			e.Crew = append(e.Crew, c)
+		}
+
+		summary.Matched++
+		if movies[i].IMDbID != nil {
+			summary.ByID++
+		}
+		if t.rating != nil {
+			summary.Rated++
+		}
+
+		batch = append(batch, e)
+		if len(batch) >= en.opts.BatchSize {
+			if err := flush(); err != nil {
+				return summary, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return summary, err
+	}
+
+	return summary, nil
+}
+
+// chooseCandidate picks the title closest in year, then the most voted one.
+// It reports why nothing was chosen.
+func chooseCandidate(m model.CatalogTitle, candidates []imdbCandidate, titles map[string]*imdbTitle) (string, string) {
+	for _, c := range candidates {
+		if c.byID {
+			return c.tconst, ""
+		}
+	}
+	if len(candidates) == 0 {
+		if m.IMDbID != nil {
+			return "", unmatchedUnknownID
+		}
+		if m.ReleaseYear == nil {
+			return "", unmatchedNoYear
+		}
+		return "", unmatchedNoTitle
+	}
+
+	votes := func(c imdbCandidate) int {
+		if v := titles[c.tconst].votes; v != nil {
+			return *v
+		}
+		return 0
+	}
+	slices.SortFunc(candidates, func(a, b imdbCandidate) int {
+		if a.yearDiff != b.yearDiff {
+			return a.yearDiff - b.yearDiff
+		}
+		return votes(b) - votes(a)
+	})
+	if len(candidates) > 1 && candidates[0].yearDiff == candidates[1].yearDiff && votes(candidates[0]) == votes(candidates[1]) {
+		return "", unmatchedAmbiguous
+	}
+	return candidates[0].tconst, ""
+}
+
+// normalizeTitle keeps the lowercase letters and digits of a title, so
+// punctuation and spacing differences between TMDB and IMDb do not matter.
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.ReplaceAll(title, "&", "and")) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// tsvRow is one data row of an IMDb dataset, read by header name.
+type tsvRow struct {
+	columns map[string]int
+	fields  []string
+}
+
+// get returns the named field, or "" when it is missing or \N.
+func (r tsvRow) get(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.fields) || r.fields[i] == imdbNull {
+		return ""
+	}
+	return r.fields[i]
+}
+
+// readTSV streams an IMDb dataset, optionally gzip compressed, calling fn for
+// every row after the header. IMDb fields are never quoted.
+func readTSV(ctx context.Context, path string, fn func(tsvRow) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var src io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 64*1024), imdbMaxLine)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s is empty", path)
+	}
+
+	row := tsvRow{columns: make(map[string]int)}
+	for i, name := range strings.Split(scanner.Text(), "\t") {
+		row.columns[name] = i
+	}
+
+	for line := 1; scanner.Scan(); line++ {
+		if line%100_000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		row.fields = strings.Split(scanner.Text(), "\t")
+		if err := fn(row); err != nil {
+			return fmt.Errorf("line %d: %w", line+1, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Print writes the summary followed by the movies left unmatched.
+func (s *IMDbSummary) Print(w io.Writer) {
+	mode := "imdb enrichment"
+	if s.DryRun {
+		mode = "dry run (nothing was written)"
+	}
+	fmt.Fprintf(w, "%s: %d catalog movies\n", mode, s.Movies)
+	fmt.Fprintf(w, "matched: %d (%d by imdb id), rated: %d, unmatched: %d\n", s.Matched, s.ByID, s.Rated, len(s.Unmatched))
+	fmt.Fprintf(w, "crew: %d directors, %d writers (%d named)\n", s.Directors, s.Writers, s.Named)
+
+	if len(s.Unmatched) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	s.WriteUnmatched(tw)
+	tw.Flush()
+}
+
+// WriteUnmatched writes the unmatched movies as tab separated values.
+func (s *IMDbSummary) WriteUnmatched(w io.Writer) {
+	fmt.Fprintln(w, "id\ttitle\tyear\treason")
+	for _, m := range s.Unmatched {
+		year := ""
+		if m.ReleaseYear != nil {
+			year = strconv.Itoa(*m.ReleaseYear)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", m.ID, m.Title, year, m.Reason)
+	}
+}
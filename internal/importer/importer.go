@@ -0,0 +1,289 @@
+// Package importer reads offline exports: catalog datasets loaded into the
+// database, and the exports users bring from other services.
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"multipass/internal/model"
+	"multipass/internal/store"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+)
+
+const (
+	DefaultBatchSize = 500
+	DefaultCastLimit = 15
+)
+
+type Options struct {
+	// BatchSize is the number of movies written per transaction
+	BatchSize int
+	// CastLimit keeps the first billed cast members of every movie
+	CastLimit int
+	// DryRun reads and plans the whole file without writing anything
+	DryRun bool
+	// Resume skips the records committed by a previous, interrupted run
+	Resume bool
+	// CheckpointPath defaults to the input path with a .checkpoint suffix
+	CheckpointPath string
+}
+
+// EntitySummary counts what an import did to one kind of row.
+type EntitySummary struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Linked  int `json:"linked"`
+	Skipped int `json:"skipped"`
+}
+
+// Summary is the report of an import run.
+type Summary struct {
+	File     string        `json:"file"`
+	DryRun   bool          `json:"dry_run"`
+	Records  int           `json:"records"`
+	Resumed  int           `json:"resumed"`
+	Invalid  int           `json:"invalid"`
+	Movies   EntitySummary `json:"movies"`
+	Genres   EntitySummary `json:"genres"`
+	Actors   EntitySummary `json:"actors"`
+	Keywords EntitySummary `json:"keywords"`
+}
+
+// checkpoint records how far an import got. Offset counts records read,
+// valid or not, up to the end of the last committed batch.
+type checkpoint struct {
+	File    string  `json:"file"`
+	Offset  int     `json:"offset"`
+	Summary Summary `json:"summary"`
+}
+
+// TMDBImporter upserts TMDB movie records with their genres, cast and keywords.
+type TMDBImporter struct {
+	movies store.MovieStore
+	store  store.ImportStore
+	opts   Options
+	logger logging.Logger
+}
+
+func NewTMDBImporter(movieStore store.MovieStore, importStore store.ImportStore, opts Options, logger logging.Logger) *TMDBImporter {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.CastLimit <= 0 {
+		opts.CastLimit = DefaultCastLimit
+	}
+	return &TMDBImporter{
+		movies: movieStore,
+		store:  importStore,
+		opts:   opts,
+		logger: logger,
+	}
+}
+
+// Run imports every record of path. Movies are matched on tmdb_id: new ones
+// are inserted, known ones updated. A record repeating a tmdb_id already
+// seen in this run is skipped.
+func (im *TMDBImporter) Run(ctx context.Context, path string) (*Summary, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	checkpointPath := im.opts.CheckpointPath
+	if checkpointPath == "" {
+		checkpointPath = abs + ".checkpoint"
+	}
+	metaData := common.Envelop{
+		"op":         "importer.Run",
+		"file":       abs,
+		"dry_run":    im.opts.DryRun,
+		"batch_size": im.opts.BatchSize,
+	}
+
+	summary := &Summary{File: abs, DryRun: im.opts.DryRun}
+	offset := 0
+	if im.opts.Resume {
+		cp, err := readCheckpoint(checkpointPath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			im.logger.Warn("No checkpoint found, starting from the first record", "meta", metaData)
+		case err != nil:
+			return nil, err
+		case cp.File != abs:
+			return nil, fmt.Errorf("checkpoint %s belongs to %s, not %s", checkpointPath, cp.File, abs)
+		default:
+			offset = cp.Offset
+			*summary = cp.Summary
+			summary.DryRun = im.opts.DryRun
+			summary.Resumed = offset
+			metaData["offset"] = offset
+			im.logger.Info("Resuming import from checkpoint", "meta", metaData)
+		}
+	}
+
+	if err := im.store.LoadCatalogNames(ctx); err != nil {
+		return nil, err
+	}
+
+	records, err := openRecords(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer records.Close()
+
+	var (
+		read             int
+		inserts, updates []model.ImportMovie
+		seen             = make(map[int]struct{})
+	)
+
+	flush := func() error {
+		if len(inserts)+len(updates) == 0 {
+			return nil
+		}
+
+		plan := im.store.PlanBatch(inserts, updates)
+		if !im.opts.DryRun {
+			if err := im.store.ApplyBatch(ctx, plan); err != nil {
+				return err
+			}
+		}
+
+		summary.Movies.Created += len(plan.Inserts)
+		summary.Movies.Updated += len(plan.Updates)
+		summary.Genres.Created += len(plan.NewGenres)
+		summary.Genres.Linked += plan.GenreLinks
+		summary.Actors.Created += len(plan.NewActors)
+		summary.Actors.Linked += plan.CastLinks
+		summary.Keywords.Created += len(plan.NewKeywords)
+		summary.Keywords.Linked += plan.KeywordLinks
+		summary.Records = read
+		inserts, updates = nil, nil
+
+		if im.opts.DryRun {
+			return nil
+		}
+		im.logger.Info("Import batch committed", "records", read, "movies", summary.Movies.Created+summary.Movies.Updated)
+		return writeCheckpoint(checkpointPath, checkpoint{File: abs, Offset: read, Summary: *summary})
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		rec, err := records.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return summary, fmt.Errorf("record %d: %w", read+1, err)
+		}
+		read++
+		if read <= offset {
+			// Remember what was imported before, so duplicates are still skipped after a resume
+			if rec.ID > 0 {
+				seen[rec.ID] = struct{}{}
+			}
+			continue
+		}
+
+		movie, ok := rec.toImportMovie(im.opts.CastLimit)
+		if !ok {
+			summary.Invalid++
+			summary.Movies.Skipped++
+			im.logger.Warn("Skipping record without a tmdb id or title", "record", read, "tmdb_id", rec.ID)
+			continue
+		}
+		if _, dup := seen[movie.TMDB_ID]; dup {
+			summary.Movies.Skipped++
+			continue
+		}
+		seen[movie.TMDB_ID] = struct{}{}
+
+		exists, err := im.movies.DoesMovieExist(ctx, movie.TMDB_ID)
+		if err != nil {
+			return summary, err
+		}
+		if exists {
+			updates = append(updates, movie)
+		} else {
+			inserts = append(inserts, movie)
+		}
+
+		if len(inserts)+len(updates) >= im.opts.BatchSize {
+			if err := flush(); err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return summary, err
+	}
+	summary.Records = read
+
+	if !im.opts.DryRun {
+		if err := os.Remove(checkpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			im.logger.Warn("Failed to remove import checkpoint", "path", checkpointPath, "error", err)
+		}
+	}
+	return summary, nil
+}
+
+// Print writes the summary as a table, one row per entity.
+func (s *Summary) Print(w io.Writer) {
+	mode := "import"
+	if s.DryRun {
+		mode = "dry run (nothing was written)"
+	}
+	fmt.Fprintf(w, "%s: %s\n", mode, s.File)
+	fmt.Fprintf(w, "records read: %d (resumed after %d, invalid %d)\n\n", s.Records, s.Resumed, s.Invalid)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "entity\tcreated\tupdated\tlinked\tskipped\t")
+	for _, row := range []struct {
+		name string
+		EntitySummary
+	}{
+		{"movies", s.Movies},
+		{"genres", s.Genres},
+		{"actors", s.Actors},
+		{"keywords", s.Keywords},
+	} {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t\n", row.name, row.Created, row.Updated, row.Linked, row.Skipped)
+	}
+	tw.Flush()
+}
+
+func readCheckpoint(path string) (*checkpoint, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, fmt.Errorf("read checkpoint %s: %w", path, err)
+	}
+	return &cp, nil
+}
+
+// writeCheckpoint replaces the checkpoint file atomically so a crash never leaves half of one.
+func writeCheckpoint(path string, cp checkpoint) error {
+	b, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
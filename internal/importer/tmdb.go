@@ -0,0 +1,226 @@
+package importer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"multipass/internal/model"
+)
+
+const (
+	tmdbImageBase   = "https://image.tmdb.org/t/p"
+	tmdbPosterSize  = "w500"
+	tmdbProfileSize = "w185"
+	youtubeWatchURL = "https://www.youtube.com/watch?v="
+)
+
+// tmdbMovie is a TMDB movie details record, as returned by /movie/{id} with
+// append_to_response=credits,keywords,videos.
+type tmdbMovie struct {
+	ID               int         `json:"id"`
+	Title            string      `json:"title"`
+	Tagline          string      `json:"tagline"`
+	ReleaseDate      string      `json:"release_date"`
+	Overview         string      `json:"overview"`
+	VoteAverage      *float32    `json:"vote_average"`
+	Popularity       *float32    `json:"popularity"`
+	OriginalLanguage string      `json:"original_language"`
+	PosterPath       string      `json:"poster_path"`
+	Genres           []tmdbNamed `json:"genres"`
+	Credits          struct {
+		Cast []tmdbCast `json:"cast"`
+	} `json:"credits"`
+	Keywords struct {
+		Keywords []tmdbNamed `json:"keywords"`
+		Results  []tmdbNamed `json:"results"` // the shape used by TV exports
+	} `json:"keywords"`
+	Videos struct {
+		Results []tmdbVideo `json:"results"`
+	} `json:"videos"`
+}
+
+type tmdbNamed struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type tmdbCast struct {
+	Name        string `json:"name"`
+	ProfilePath string `json:"profile_path"`
+	Order       int    `json:"order"`
+}
+
+type tmdbVideo struct {
+	Key  string `json:"key"`
+	Site string `json:"site"`
+	Type string `json:"type"`
+}
+
+// recordReader streams TMDB records from a JSON array or a JSONL file,
+// optionally gzip compressed.
+type recordReader struct {
+	file  *os.File
+	gz    *gzip.Reader
+	dec   *json.Decoder
+	array bool
+}
+
+func openRecords(path string) (*recordReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rr := &recordReader{file: f}
+	var src io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		if rr.gz, err = gzip.NewReader(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("open gzip stream: %w", err)
+		}
+		src = rr.gz
+	}
+
+	buf := bufio.NewReader(src)
+	first, err := peekNonSpace(buf)
+	if err != nil && err != io.EOF {
+		rr.Close()
+		return nil, err
+	}
+
+	rr.dec = json.NewDecoder(buf)
+	if first == '[' {
+		if _, err := rr.dec.Token(); err != nil {
+			rr.Close()
+			return nil, err
+		}
+		rr.array = true
+	}
+	return rr, nil
+}
+
+// next decodes the next record. It returns io.EOF after the last one.
+func (rr *recordReader) next() (*tmdbMovie, error) {
+	if rr.array && !rr.dec.More() {
+		return nil, io.EOF
+	}
+
+	var m tmdbMovie
+	if err := rr.dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (rr *recordReader) Close() {
+	if rr.gz != nil {
+		rr.gz.Close()
+	}
+	rr.file.Close()
+}
+
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+			return b, r.UnreadByte()
+		}
+	}
+}
+
+// toImportMovie converts a TMDB record. It reports false for records that
+// cannot become a catalog movie.
+func (m *tmdbMovie) toImportMovie(castLimit int) (model.ImportMovie, bool) {
+	title := strings.TrimSpace(m.Title)
+	if m.ID <= 0 || title == "" {
+		return model.ImportMovie{}, false
+	}
+
+	im := model.ImportMovie{
+		TMDB_ID:    m.ID,
+		Title:      title,
+		Tagline:    strings.TrimSpace(m.Tagline),
+		Overview:   optional(m.Overview),
+		Score:      m.VoteAverage,
+		Popularity: m.Popularity,
+		Language:   optional(m.OriginalLanguage),
+	}
+
+	if len(m.ReleaseDate) >= 4 {
+		if year, err := strconv.Atoi(m.ReleaseDate[:4]); err == nil {
+			im.ReleaseYear = &year
+		}
+	}
+	if m.PosterPath != "" {
+		im.PosterURL = optional(fmt.Sprintf("%s/%s%s", tmdbImageBase, tmdbPosterSize, m.PosterPath))
+	}
+	for _, v := range m.Videos.Results {
+		if v.Site == "YouTube" && v.Type == "Trailer" && v.Key != "" {
+			im.TrailerURL = optional(youtubeWatchURL + v.Key)
+			break
+		}
+	}
+
+	for _, g := range m.Genres {
+		im.Genres = appendName(im.Genres, g.Name)
+	}
+
+	keywords := m.Keywords.Keywords
+	if len(keywords) == 0 {
+		keywords = m.Keywords.Results
+	}
+	for _, k := range keywords {
+		im.Keywords = appendName(im.Keywords, k.Name)
+	}
+
+	cast := slices.Clone(m.Credits.Cast)
+	slices.SortStableFunc(cast, func(a, b tmdbCast) int { return a.Order - b.Order })
+	seen := make(map[string]struct{})
+	for _, c := range cast {
+		if len(im.Cast) == castLimit {
+			break
+		}
+		if strings.TrimSpace(c.Name) == "" {
+			continue
+		}
+		var image *string
+		if c.ProfilePath != "" {
+			image = optional(fmt.Sprintf("%s/%s%s", tmdbImageBase, tmdbProfileSize, c.ProfilePath))
+		}
+		actor := model.NewImportActor(c.Name, image)
+		if _, ok := seen[actor.Key()]; ok {
+			continue
+		}
+		seen[actor.Key()] = struct{}{}
+		im.Cast = append(im.Cast, actor)
+	}
+
+	return im, true
+}
+
+// appendName appends a trimmed name unless it is empty or already present in another case.
+func appendName(names []string, name string) []string {
+	name = strings.TrimSpace(name)
+	if name == "" || slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, name) }) {
+		return names
+	}
+	return append(names, name)
+}
+
+func optional(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
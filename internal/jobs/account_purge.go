@@ -0,0 +1,93 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"multipass/internal/store"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+)
+
+// accountPurgeBatch bounds how many accounts one transaction deletes.
+const accountPurgeBatch = 100
+
+// AccountPurgeJob permanently deletes accounts whose deletion grace period is
+// over, along with their profile pictures and data export archives.
+type AccountPurgeJob struct {
+	store       store.AccountStore
+	grace       time.Duration
+	pictureDir  string
+	pictureBase string
+	logger      logging.Logger
+}
+
+func NewAccountPurgeJob(accountStore store.AccountStore, grace time.Duration, pictureDir, pictureBase string, logger logging.Logger) *AccountPurgeJob {
+	return &AccountPurgeJob{
+		store:       accountStore,
+		grace:       grace,
+		pictureDir:  pictureDir,
+		pictureBase: pictureBase,
+		logger:      logger,
+	}
+}
+
+func (j *AccountPurgeJob) Name() string {
+	return "account_purge"
+}
+
+func (j *AccountPurgeJob) Run(ctx context.Context) error {
+	// Without a grace period accounts would be gone before the cancel link is used
+	if j.grace <= 0 {
+		return fmt.Errorf("account deletion grace must be positive, got %s", j.grace)
+	}
+	deletedBefore := time.Now().Add(-j.grace)
+
+	total := 0
+	for {
+		purged, pictures, exports, err := j.store.PurgeDeletedUsers(ctx, deletedBefore, accountPurgeBatch)
+		if err != nil {
+			return err
+		}
+		total += purged
+
+		for _, url := range pictures {
+			j.removePicture(url)
+		}
+		for _, path := range exports {
+			j.removeFile(path, "Could not delete data export of purged account")
+		}
+
+		if purged < accountPurgeBatch {
+			break
+		}
+	}
+
+	j.logger.Info("Deleted accounts purged", "meta", common.Envelop{
+		"purged": total,
+		"grace":  j.grace.String(),
+	})
+	return nil
+}
+
+// removePicture deletes an uploaded profile picture; external URLs are skipped.
+func (j *AccountPurgeJob) removePicture(url string) {
+	if !strings.HasPrefix(url, j.pictureBase) {
+		return
+	}
+
+	path := filepath.Join(j.pictureDir, filepath.Base(strings.TrimPrefix(url, j.pictureBase)))
+	j.removeFile(path, "Could not delete profile picture of purged account")
+}
+
+// removeFile deletes a file of a purged account, one already gone is fine.
+func (j *AccountPurgeJob) removeFile(path, warning string) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		j.logger.Warn(warning, err, "file", path)
+	}
+}
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"multipass/internal/store"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+)
+
+// importStallTimeout is how long a processing import may go without
+// progress before its worker is considered gone (e.g. after a restart).
+const importStallTimeout = 30 * time.Minute
+
+// CollectionImportCleanupJob fails imports whose matching stopped and deletes
+// previews older than the retention period.
+type CollectionImportCleanupJob struct {
+	store     store.CollectionImportStore
+	retention time.Duration
+	logger    logging.Logger
+}
+
+func NewCollectionImportCleanupJob(importStore store.CollectionImportStore, retention time.Duration, logger logging.Logger) *CollectionImportCleanupJob {
+	return &CollectionImportCleanupJob{
+		store:     importStore,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+func (j *CollectionImportCleanupJob) Name() string {
+	return "collection_import_cleanup"
+}
+
+func (j *CollectionImportCleanupJob) Run(ctx context.Context) error {
+	now := time.Now()
+
+	failed, err := j.store.FailStaleCollectionImports(ctx, now.Add(-importStallTimeout), "matching was interrupted, upload the file again")
+	if err != nil {
+		return err
+	}
+
+	purged, err := j.store.PurgeCollectionImports(ctx, now.Add(-j.retention))
+	if err != nil {
+		return err
+	}
+
+	j.logger.Info("Collection imports cleaned up", "meta", common.Envelop{
+		"failed":    failed,
+		"purged":    purged,
+		"retention": j.retention.String(),
+	})
+	return nil
+}
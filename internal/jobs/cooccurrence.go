@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+
+	"multipass/internal/store"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+)
+
+// CooccurrenceJob recomputes "users who saved this also saved" scores from user_movies.
+type CooccurrenceJob struct {
+	store      store.MovieStore
+	minSupport int
+	logger     logging.Logger
+}
+
+// NewCooccurrenceJob creates the job. minSupport is the minimum number of
+// distinct users that must have saved both movies for a pair to be kept.
+func NewCooccurrenceJob(movieStore store.MovieStore, minSupport int, logger logging.Logger) *CooccurrenceJob {
+	return &CooccurrenceJob{
+		store:      movieStore,
+		minSupport: minSupport,
+		logger:     logger,
+	}
+}
+
+func (j *CooccurrenceJob) Name() string {
+	return "movie_cooccurrence"
+}
+
+func (j *CooccurrenceJob) Run(ctx context.Context) error {
+	pairs, err := j.store.RebuildCooccurrence(ctx, j.minSupport)
+	if err != nil {
+		return err
+	}
+
+	j.logger.Info("Movie co-occurrence rebuilt", "meta", common.Envelop{
+		"pairs":       pairs,
+		"min_support": j.minSupport,
+	})
+	return nil
+}
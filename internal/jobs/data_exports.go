@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"multipass/internal/store"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+)
+
+// dataExportBuildTimeout is how long an archive may take to build before its
+// worker is considered gone (e.g. after a restart).
+const dataExportBuildTimeout = time.Hour
+
+// DataExportCleanupJob deletes the archives that expired or were downloaded,
+// and fails exports whose build stopped.
+type DataExportCleanupJob struct {
+	store  store.DataExportStore
+	logger logging.Logger
+}
+
+func NewDataExportCleanupJob(exportStore store.DataExportStore, logger logging.Logger) *DataExportCleanupJob {
+	return &DataExportCleanupJob{
+		store:  exportStore,
+		logger: logger,
+	}
+}
+
+func (j *DataExportCleanupJob) Name() string {
+	return "data_export_cleanup"
+}
+
+func (j *DataExportCleanupJob) Run(ctx context.Context) error {
+	now := time.Now()
+
+	files, err := j.store.ExpireDataExports(ctx, now, now.Add(-dataExportBuildTimeout), "the archive could not be built, please request it again")
+	if err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, path := range files {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			j.logger.Error("Failed to delete data export archive", err, "file", path)
+			continue
+		}
+		deleted++
+	}
+
+	j.logger.Info("Data exports cleaned up", "meta", common.Envelop{
+		"expired": len(files),
+		"deleted": deleted,
+	})
+	return nil
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+)
+
+// Runner runs one-off tasks in the background, at most workers at a time.
+// Tasks queued beyond that wait for a free worker.
+type Runner struct {
+	logger logging.Logger
+	slots  chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRunner(workers int, logger logging.Logger) *Runner {
+	if workers <= 0 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		logger: logger,
+		slots:  make(chan struct{}, workers),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Go queues task. Its context is cancelled when the runner stops.
+func (r *Runner) Go(name string, task func(ctx context.Context) error) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		select {
+		case r.slots <- struct{}{}:
+			defer func() { <-r.slots }()
+		case <-r.ctx.Done():
+			return
+		}
+
+		meta := common.Envelop{"task": name}
+		started := time.Now()
+
+		defer func() {
+			if rec := recover(); rec != nil {
+				meta["panic"] = rec
+				r.logger.Error("Background task panicked", nil, "meta", meta)
+			}
+		}()
+
+		if err := task(r.ctx); err != nil {
+			r.logger.Error("Background task failed", err, "meta", meta)
+			return
+		}
+
+		meta["duration"] = time.Since(started).String()
+		r.logger.Info("Background task finished", "meta", meta)
+	}()
+}
+
+// Stop cancels running tasks, drops queued ones and waits for them to return.
+func (r *Runner) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
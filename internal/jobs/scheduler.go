@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+)
+
+// Job is a unit of background work that the Scheduler runs periodically.
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+type entry struct {
+	job      Job
+	interval time.Duration
+}
+
+// Scheduler runs registered jobs on a fixed interval until stopped.
+// Each job runs once right after Start and never overlaps with itself.
+type Scheduler struct {
+	logger  logging.Logger
+	entries []entry
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewScheduler(logger logging.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+// Every registers job to run every interval. It must be called before Start.
+// A job without a positive interval can't be ticked and is not registered.
+func (s *Scheduler) Every(interval time.Duration, job Job) {
+	if interval <= 0 {
+		s.logger.Error("Background job not scheduled", nil, "meta", common.Envelop{"job": job.Name(), "interval": interval.String()})
+		return
+	}
+	s.entries = append(s.entries, entry{job: job, interval: interval})
+}
+
+// Start launches one goroutine per registered job.
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go func(e entry) {
+			defer s.wg.Done()
+
+			ticker := time.NewTicker(e.interval)
+			defer ticker.Stop()
+
+			for {
+				s.run(ctx, e.job)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(e)
+	}
+
+	s.logger.Info("Background jobs started", "jobs", len(s.entries))
+}
+
+// Stop cancels running jobs and waits for them to return.
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	meta := common.Envelop{"job": job.Name()}
+	started := time.Now()
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			meta["panic"] = rec
+			s.logger.Error("Background job panicked", nil, "meta", meta)
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil {
+		s.logger.Error("Background job failed", err, "meta", meta)
+		return
+	}
+
+	meta["duration"] = time.Since(started).String()
+	s.logger.Info("Background job finished", "meta", meta)
+}
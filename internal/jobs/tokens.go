@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"multipass/internal/store"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+)
+
+// RetiredTokenCleanupJob deletes rotated refresh tokens once they expire.
+// Every refresh retires one, so active sessions would otherwise keep adding rows.
+type RetiredTokenCleanupJob struct {
+	store  store.TokenStore
+	logger logging.Logger
+}
+
+func NewRetiredTokenCleanupJob(tokenStore store.TokenStore, logger logging.Logger) *RetiredTokenCleanupJob {
+	return &RetiredTokenCleanupJob{
+		store:  tokenStore,
+		logger: logger,
+	}
+}
+
+func (j *RetiredTokenCleanupJob) Name() string {
+	return "retired_token_cleanup"
+}
+
+func (j *RetiredTokenCleanupJob) Run(ctx context.Context) error {
+	purged, err := j.store.PurgeRetiredTokens(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	j.logger.Info("Retired refresh tokens cleaned up", "meta", common.Envelop{
+		"purged": purged,
+	})
+	return nil
+}
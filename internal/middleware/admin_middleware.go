@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/ctxutils"
+	"multipass/pkg/logging"
+	"multipass/pkg/response"
+)
+
+// AdminChecker reports whether a user is an administrator.
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID int) (bool, error)
+}
+
+type AdminMiddleware struct {
+	Checker   AdminChecker
+	Logger    logging.Logger
+	Responder response.Writer
+}
+
+func NewAdminMiddleware(checker AdminChecker, logger logging.Logger, responder response.Writer) *AdminMiddleware {
+	return &AdminMiddleware{
+		Checker:   checker,
+		Logger:    logger,
+		Responder: responder,
+	}
+}
+
+// RequireAdmin lets only administrators through. It must run after
+// authentication, which puts the user in the request context. The flag is
+// read from the database on every request so revoking it takes effect at once.
+func (m *AdminMiddleware) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metaData := common.Envelop{
+			"op":   "AdminMiddleware.RequireAdmin",
+			"path": r.URL.Path,
+		}
+
+		user, err := ctxutils.GetUser(r.Context())
+		if err != nil {
+			metaData["warning"] = "User not found in context"
+			apperror.ErrMissingAuth(err, m.Logger, metaData).WriteJSONError(w, r, m.Responder)
+			return
+		}
+		metaData["user_id"] = user.UserID
+
+		isAdmin, err := m.Checker.IsAdmin(r.Context(), user.UserID)
+		if err != nil {
+			var appErr *apperror.AppError
+			if !errors.As(err, &appErr) {
+				appErr = apperror.ErrInternalServer(err, m.Logger, metaData)
+			}
+			appErr.WriteJSONError(w, r, m.Responder)
+			return
+		}
+		if !isAdmin {
+			apperror.ErrAdminOnly(nil, m.Logger, metaData).WriteJSONError(w, r, m.Responder)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -21,7 +21,7 @@ func CorsMiddleware(next http.Handler) http.Handler {
 
 		// Set allowed headers. 'Content-Type' is usually needed for JSON requests.
 		// 'Authorization' is crucial for sending JWT tokens. Add any other custom headers you use.
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-MFA-Token")
 
 		// Allow credentials (e.g., cookies, HTTP authentication headers). Set to "true" if your frontend sends them.
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
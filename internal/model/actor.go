@@ -14,3 +14,16 @@ type Actor struct {
 func (a Actor) Name() string {
 	return fmt.Sprintf("%s %s", a.FirstName, a.LastName)
 }
+
+// Collaborator is an actor who shares the screen with another actor, with the number of shared movies.
+type Collaborator struct {
+	Actor
+	SharedMovies int `json:"shared_movies"`
+}
+
+// ActorProfile is an actor with their filmography and frequent collaborators.
+type ActorProfile struct {
+	Actor
+	Filmography   []Movie        `json:"filmography"`
+	Collaborators []Collaborator `json:"collaborators"`
+}
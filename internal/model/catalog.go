@@ -0,0 +1,173 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Catalog history actions.
+const (
+	CatalogCreate = "create"
+	CatalogUpdate = "update"
+	CatalogDelete = "delete"
+	CatalogAttach = "attach"
+	CatalogDetach = "detach"
+	CatalogRevert = "revert"
+)
+
+// MovieSnapshot is the state of a movie and its genre, cast and keyword
+// links at one point in time, as stored in the catalog history.
+type MovieSnapshot struct {
+	TMDB_ID     int      `json:"tmdb_id"`
+	Title       string   `json:"title"`
+	Tagline     string   `json:"tagline"`
+	ReleaseYear int      `json:"release_year"`
+	Overview    *string  `json:"overview"`
+	Score       *float32 `json:"score"`
+	Popularity  *float32 `json:"popularity"`
+	Language    *string  `json:"language"`
+	PosterURL   *string  `json:"poster_url"`
+	TrailerURL  *string  `json:"trailer_url"`
+	Deleted     bool     `json:"deleted"`
+	GenreIDs    []int    `json:"genre_ids"`
+	ActorIDs    []int    `json:"actor_ids"`
+	KeywordIDs  []int    `json:"keyword_ids"`
+}
+
+// CatalogChange is one field that differs between two snapshots. Id sets
+// report the added and removed ids instead of both values.
+type CatalogChange struct {
+	Field   string `json:"field"`
+	Before  any    `json:"before,omitempty"`
+	After   any    `json:"after,omitempty"`
+	Added   []int  `json:"added,omitempty"`
+	Removed []int  `json:"removed,omitempty"`
+}
+
+// CatalogHistoryEntry is one recorded admin change of a movie.
+type CatalogHistoryEntry struct {
+	ID              int64           `json:"id"`
+	MovieID         int             `json:"movie_id"`
+	UserID          *int            `json:"user_id"`
+	UserName        *string         `json:"user_name"`
+	Action          string          `json:"action"`
+	Before          *MovieSnapshot  `json:"-"`
+	After           *MovieSnapshot  `json:"-"`
+	Changes         []CatalogChange `json:"changes"`
+	RevertedEntryID *int64          `json:"reverted_entry_id,omitempty"`
+	TimeCreated     time.Time       `json:"time_created"`
+}
+
+// Diff lists the fields that changed from s to next. A nil s (a creation) reports every field of next.
+func (s *MovieSnapshot) Diff(next *MovieSnapshot) []CatalogChange {
+	prev := s
+	if prev == nil {
+		prev = &MovieSnapshot{}
+	}
+
+	var changes []CatalogChange
+	value := func(field string, before, after any, equal bool) {
+		if !equal || s == nil {
+			changes = append(changes, CatalogChange{Field: field, Before: before, After: after})
+		}
+	}
+	set := func(field string, before, after []int) {
+		added, removed := setDifference(after, before), setDifference(before, after)
+		if len(added) > 0 || len(removed) > 0 {
+			changes = append(changes, CatalogChange{Field: field, Added: added, Removed: removed})
+		}
+	}
+
+	value("tmdb_id", prev.TMDB_ID, next.TMDB_ID, prev.TMDB_ID == next.TMDB_ID)
+	value("title", prev.Title, next.Title, prev.Title == next.Title)
+	value("tagline", prev.Tagline, next.Tagline, prev.Tagline == next.Tagline)
+	value("release_year", prev.ReleaseYear, next.ReleaseYear, prev.ReleaseYear == next.ReleaseYear)
+	value("overview", prev.Overview, next.Overview, equalPtr(prev.Overview, next.Overview))
+	value("score", prev.Score, next.Score, equalPtr(prev.Score, next.Score))
+	value("popularity", prev.Popularity, next.Popularity, equalPtr(prev.Popularity, next.Popularity))
+	value("language", prev.Language, next.Language, equalPtr(prev.Language, next.Language))
+	value("poster_url", prev.PosterURL, next.PosterURL, equalPtr(prev.PosterURL, next.PosterURL))
+	value("trailer_url", prev.TrailerURL, next.TrailerURL, equalPtr(prev.TrailerURL, next.TrailerURL))
+	if prev.Deleted != next.Deleted {
+		changes = append(changes, CatalogChange{Field: "deleted", Before: prev.Deleted, After: next.Deleted})
+	}
+	set("genre_ids", prev.GenreIDs, next.GenreIDs)
+	set("actor_ids", prev.ActorIDs, next.ActorIDs)
+	set("keyword_ids", prev.KeywordIDs, next.KeywordIDs)
+
+	return changes
+}
+
+// Revert undoes the change from before to after on top of s, the current
+// state. Only the fields that change touched are restored, so later edits of
+// other fields survive. Reverting a creation (nil before) deletes the movie.
+func (s MovieSnapshot) Revert(before, after *MovieSnapshot) MovieSnapshot {
+	if before == nil {
+		s.Deleted = true
+		return s
+	}
+
+	if before.TMDB_ID != after.TMDB_ID {
+		s.TMDB_ID = before.TMDB_ID
+	}
+	if before.Title != after.Title {
+		s.Title = before.Title
+	}
+	if before.Tagline != after.Tagline {
+		s.Tagline = before.Tagline
+	}
+	if before.ReleaseYear != after.ReleaseYear {
+		s.ReleaseYear = before.ReleaseYear
+	}
+	revertPtr(&s.Overview, before.Overview, after.Overview)
+	revertPtr(&s.Score, before.Score, after.Score)
+	revertPtr(&s.Popularity, before.Popularity, after.Popularity)
+	revertPtr(&s.Language, before.Language, after.Language)
+	revertPtr(&s.PosterURL, before.PosterURL, after.PosterURL)
+	revertPtr(&s.TrailerURL, before.TrailerURL, after.TrailerURL)
+	if before.Deleted != after.Deleted {
+		s.Deleted = before.Deleted
+	}
+	s.GenreIDs = revertSet(s.GenreIDs, before.GenreIDs, after.GenreIDs)
+	s.ActorIDs = revertSet(s.ActorIDs, before.ActorIDs, after.ActorIDs)
+	s.KeywordIDs = revertSet(s.KeywordIDs, before.KeywordIDs, after.KeywordIDs)
+
+	return s
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func revertPtr[T comparable](cur **T, before, after *T) {
+	if !equalPtr(before, after) {
+		*cur = before
+	}
+}
+
+// revertSet removes from cur the ids the change added and puts back the ids it removed.
+func revertSet(cur, before, after []int) []int {
+	added, removed := setDifference(after, before), setDifference(before, after)
+	out := setDifference(cur, added)
+	for _, id := range removed {
+		if !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// setDifference returns the ids of a that are not in b.
+func setDifference(a, b []int) []int {
+	var out []int
+	for _, id := range a {
+		if !slices.Contains(b, id) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
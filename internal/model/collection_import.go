@@ -0,0 +1,68 @@
+package model
+
+import "time"
+
+// Services whose exports can be imported into a user collection.
+const (
+	ImportSourceLetterboxd = "letterboxd"
+	ImportSourceIMDb       = "imdb"
+	ImportSourceTrakt      = "trakt"
+)
+
+// Collection import statuses. Processing imports are matched in the background.
+const (
+	ImportStatusProcessing = "processing"
+	ImportStatusReady      = "ready"
+	ImportStatusCommitted  = "committed"
+	ImportStatusFailed     = "failed"
+)
+
+// How a row of an export matched the catalog.
+const (
+	ImportRowMatched   = "matched"
+	ImportRowAmbiguous = "ambiguous"
+	ImportRowUnmatched = "unmatched"
+)
+
+// CollectionImport is an uploaded export and the preview of its matches.
+type CollectionImport struct {
+	ID            int                   `json:"id"`
+	UserID        int                   `json:"-"`
+	Source        string                `json:"source"`
+	Collection    string                `json:"collection"`
+	FileName      string                `json:"file_name"`
+	Status        string                `json:"status"`
+	Total         int                   `json:"total"`
+	Processed     int                   `json:"processed"`
+	Error         *string               `json:"error,omitempty"`
+	TimeCreated   time.Time             `json:"time_created"`
+	TimeCommitted *time.Time            `json:"time_committed,omitempty"`
+	Rows          []CollectionImportRow `json:"-"`
+}
+
+// CollectionImportRow is one movie of an export. Row numbers start at 1 and
+// count the movies of the file, not its lines.
+type CollectionImportRow struct {
+	Row     int        `json:"row"`
+	Title   string     `json:"title"`
+	Year    *int       `json:"year,omitempty"`
+	TMDB_ID *int       `json:"tmdb_id,omitempty"`
+	IMDbID  *string    `json:"imdb_id,omitempty"`
+	Date    *time.Time `json:"date,omitempty"` // watched, listed or rated on the source service
+	Status  string     `json:"status"`
+	// Match is set on matched rows, Candidates on ambiguous ones
+	Match      *ImportCandidate  `json:"match,omitempty"`
+	Candidates []ImportCandidate `json:"candidates,omitempty"`
+}
+
+// ImportCandidate is a catalog movie an export row may refer to. Score is
+// 1 for id matches, the title similarity otherwise.
+type ImportCandidate struct {
+	MovieID     int     `json:"movie_id"`
+	TMDB_ID     int     `json:"tmdb_id"`
+	IMDbID      *string `json:"imdb_id,omitempty"`
+	Title       string  `json:"title"`
+	ReleaseYear *int    `json:"release_year"`
+	PosterURL   *string `json:"poster_url"`
+	Score       float32 `json:"score"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// Data export statuses. Archives are built in the background, then downloaded
+// once through an emailed link before they expire.
+const (
+	DataExportProcessing = "processing"
+	DataExportReady      = "ready"
+	DataExportDownloaded = "downloaded"
+	DataExportExpired    = "expired"
+	DataExportFailed     = "failed"
+)
+
+// DataExport is a "download my data" archive of a user account.
+type DataExport struct {
+	ID             int        `json:"id"`
+	UserID         int        `json:"-"`
+	Status         string     `json:"status"`
+	FilePath       *string    `json:"-"`
+	Size           *int64     `json:"size_bytes,omitempty"`
+	Error          *string    `json:"error,omitempty"`
+	TimeCreated    time.Time  `json:"time_created"`
+	TimeReady      *time.Time `json:"time_ready,omitempty"`
+	TimeExpires    *time.Time `json:"time_expires,omitempty"`
+	TimeDownloaded *time.Time `json:"time_downloaded,omitempty"`
+}
+
+// LoginRecord is a refresh token issued when the user signed in.
+type LoginRecord struct {
+	ID           int        `json:"id"`
+	DeviceLabel  *string    `json:"device_label"`
+	UserAgent    *string    `json:"user_agent"`
+	IPAddress    *string    `json:"ip_address"`
+	TimeSignedIn time.Time  `json:"time_signed_in"`
+	TimeLastUsed *time.Time `json:"time_last_used"`
+	TimeExpires  time.Time  `json:"time_expires"`
+	Active       bool       `json:"active"`
+}
+
+// PasskeyInfo describes a registered passkey without its key material.
+type PasskeyInfo struct {
+	CredentialID    string   `json:"credential_id"`
+	AAGUID          string   `json:"aaguid"`
+	AttestationType string   `json:"attestation_type"`
+	Attachment      string   `json:"attachment,omitempty"`
+	Transports      []string `json:"transports"`
+	SignCount       uint32   `json:"sign_count"`
+	BackupEligible  bool     `json:"backup_eligible"`
+	BackupState     bool     `json:"backup_state"`
+}
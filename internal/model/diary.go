@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// DiaryEntry is one logged watch of a movie. A movie can be logged many times.
+type DiaryEntry struct {
+	ID        int       `json:"id"`
+	MovieID   int       `json:"movie_id"`
+	WatchedOn time.Time `json:"watched_on"`
+	Rewatch   bool      `json:"rewatch"`
+	Note      *string   `json:"note"`
+	TimeAdded time.Time `json:"time_added"`
+	Movie     *Movie    `json:"movie,omitempty"`
+}
@@ -0,0 +1,73 @@
+package model
+
+import "strings"
+
+// ImportMovie is a catalog movie read from an offline export, with its
+// genres, cast and keywords given by name.
+type ImportMovie struct {
+	TMDB_ID     int
+	Title       string
+	Tagline     string
+	ReleaseYear *int
+	Overview    *string
+	Score       *float32
+	Popularity  *float32
+	Language    *string
+	PosterURL   *string
+	TrailerURL  *string
+	Genres      []string
+	Cast        []ImportActor
+	Keywords    []string
+}
+
+// ImportActor is a cast member of an imported movie.
+type ImportActor struct {
+	FirstName string
+	LastName  string
+	ImageURL  *string
+}
+
+// NewImportActor splits a full name at its first space, the way actors are stored.
+func NewImportActor(name string, imageURL *string) ImportActor {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+	return ImportActor{FirstName: first, LastName: strings.TrimSpace(last), ImageURL: imageURL}
+}
+
+// Key identifies an actor by name. Actors have no external id, so two
+// actors with the same name are treated as one.
+func (a ImportActor) Key() string {
+	return strings.ToLower(strings.TrimSpace(a.FirstName + " " + a.LastName))
+}
+
+// Crew roles read from IMDb title.principals.
+const (
+	CrewDirector = "director"
+	CrewWriter   = "writer"
+)
+
+// CrewMember is a director or writer known by their IMDb id. Name is nil
+// when the enrichment ran without name.basics.
+type CrewMember struct {
+	IMDbID   string  `json:"imdb_id"`
+	Name     *string `json:"name"`
+	Role     string  `json:"-"`
+	Ordering int     `json:"-"`
+}
+
+// CatalogTitle is what the IMDb enrichment matches a catalog movie on.
+type CatalogTitle struct {
+	ID          int     `json:"id"`
+	Title       string  `json:"title"`
+	ReleaseYear *int    `json:"release_year"`
+	IMDbID      *string `json:"imdb_id"`
+}
+
+// IMDbEnrichment is what the IMDb datasets add to one catalog movie.
+type IMDbEnrichment struct {
+	MovieID int
+	IMDbID  string
+	Runtime *int
+	Rating  *float32
+	Votes   *int
+	Crew    []CrewMember
+}
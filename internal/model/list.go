@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// UserList is a named, manually ordered list of movies created by a user.
+type UserList struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"-"`
+	OwnerName    string     `json:"owner_name"`
+	Name         string     `json:"name"`
+	Description  *string    `json:"description"`
+	IsPublic     bool       `json:"is_public"`
+	HasShareLink bool       `json:"has_share_link"`
+	ItemCount    int        `json:"item_count"`
+	TimeCreated  time.Time  `json:"time_created"`
+	TimeUpdated  *time.Time `json:"time_updated,omitempty"`
+	Items        []ListItem `json:"items,omitempty"`
+}
+
+// ListItem is a movie in a user list. Items are ordered by Position.
+type ListItem struct {
+	Movie     Movie     `json:"movie"`
+	Note      *string   `json:"note"`
+	Position  float64   `json:"position"`
+	TimeAdded time.Time `json:"time_added"`
+}
@@ -0,0 +1,39 @@
+package model
+
+const (
+	// MFAMethodTOTP finishes a login with an authenticator app or recovery code
+	MFAMethodTOTP = "totp"
+	// MFAMethodWebAuthn finishes a login with a passkey assertion
+	MFAMethodWebAuthn = "webauthn"
+)
+
+// MFAPolicy is the second factors a password login must be finished with.
+// Any one of them is enough.
+type MFAPolicy struct {
+	TOTP    bool `json:"totp"`
+	Passkey bool `json:"passkey"`
+}
+
+// Methods lists the second factors the login can be finished with
+func (p MFAPolicy) Methods() []string {
+	methods := []string{}
+	if p.TOTP {
+		methods = append(methods, MFAMethodTOTP)
+	}
+	if p.Passkey {
+		methods = append(methods, MFAMethodWebAuthn)
+	}
+	return methods
+}
+
+// Required tells whether a password alone is not enough to sign in
+func (p MFAPolicy) Required() bool {
+	return p.TOTP || p.Passkey
+}
+
+// PasskeyMFAStatus tells whether logins require a passkey and how many the
+// user registered
+type PasskeyMFAStatus struct {
+	Required bool `json:"required"`
+	Passkeys int  `json:"passkeys"`
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Movie struct {
 	ID          int      `json:"id"`
 	TMDB_ID     int      `json:"tmdb_id"`
@@ -12,7 +14,21 @@ type Movie struct {
 	Language    *string  `json:"language"`
 	PosterURL   *string  `json:"poster_url"`
 	TrailerURL  *string  `json:"trailer_url"`
-	Casting     []Actor  `json:"casting"`
-	Genres      []Genre  `json:"genres"`
-	Keywords    []string `json:"keywords"`
+	// CommunityRating is only set on movie details, next to the TMDB score.
+	CommunityRating *CommunityRating `json:"community_rating,omitempty"`
+	// IMDb enrichment, only set on movie details of matched movies. IMDbID is
+	// also set on the movies of user collections.
+	IMDbID     *string      `json:"imdb_id,omitempty"`
+	Runtime    *int         `json:"runtime,omitempty"` // minutes
+	IMDbRating *float32     `json:"imdb_rating,omitempty"`
+	IMDbVotes  *int         `json:"imdb_votes,omitempty"`
+	Directors  []CrewMember `json:"directors,omitempty"`
+	Writers    []CrewMember `json:"writers,omitempty"`
+	Casting    []Actor      `json:"casting"`
+	Genres     []Genre      `json:"genres"`
+	Keywords   []string     `json:"keywords"`
+	// AlsoSaved is only set on movie details: movies often saved by the same users.
+	AlsoSaved []Movie `json:"also_saved,omitempty"`
+	// TimeAdded is only set when the movie is listed as part of a user collection.
+	TimeAdded *time.Time `json:"time_added,omitempty"`
 }
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Passkey describes a registered passkey, without its key material, for the
+// user's passkey list
+type Passkey struct {
+	ID             int        `json:"id"`
+	Nickname       *string    `json:"nickname"`
+	Authenticator  string     `json:"authenticator"`
+	SignCount      uint32     `json:"sign_count"`
+	BackupEligible bool       `json:"backup_eligible"`
+	BackupState    bool       `json:"backup_state"`
+	TimeCreated    time.Time  `json:"time_created"`
+	TimeLastUsed   *time.Time `json:"time_last_used"`
+}
@@ -0,0 +1,30 @@
+package model
+
+// Feature kinds a movie can share with another movie.
+const (
+	FeatureGenre   = "genre"
+	FeatureKeyword = "keyword"
+	FeatureCast    = "cast"
+)
+
+// MovieFeature is one genre, keyword or cast member attached to a movie.
+type MovieFeature struct {
+	MovieID   int
+	Kind      string
+	FeatureID int
+}
+
+// SavedMovie is a movie in one of the user's collections.
+type SavedMovie struct {
+	MovieID  int
+	Title    string
+	Relation string
+}
+
+// Recommendation is a suggested movie with the reason it was picked.
+type Recommendation struct {
+	Movie          Movie   `json:"movie"`
+	Score          float64 `json:"score"`
+	Reason         string  `json:"reason"`
+	BecauseMovieID *int    `json:"because_movie_id,omitempty"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+type Review struct {
+	ID               int        `json:"id"`
+	MovieID          int        `json:"movie_id"`
+	UserID           int        `json:"-"`
+	UserName         string     `json:"user_name"`
+	Rating           int        `json:"rating"`
+	Body             *string    `json:"body"`
+	ContainsSpoilers bool       `json:"contains_spoilers"`
+	TimeCreated      time.Time  `json:"time_created"`
+	TimeUpdated      *time.Time `json:"time_updated,omitempty"`
+	// Movie is only set when listing a user's own reviews.
+	Movie *Movie `json:"movie,omitempty"`
+}
+
+// ReviewRevision is a previous version of an edited review.
+type ReviewRevision struct {
+	Rating           int       `json:"rating"`
+	Body             *string   `json:"body"`
+	ContainsSpoilers bool      `json:"contains_spoilers"`
+	TimeWritten      time.Time `json:"time_written"`
+	TimeReplaced     time.Time `json:"time_replaced"`
+}
+
+// CommunityRating aggregates the ratings users gave a movie.
+type CommunityRating struct {
+	// Average is a Bayesian average: few ratings are pulled towards the site-wide mean.
+	Average *float64 `json:"average"`
+	Count   int      `json:"count"`
+}
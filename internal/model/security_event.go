@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+const (
+	// SecurityEventTokenReuse is a retired refresh token presented again
+	SecurityEventTokenReuse = "refresh_token_reuse"
+)
+
+// SecurityEvent is a security relevant event of an account.
+type SecurityEvent struct {
+	ID          int            `json:"id"`
+	UserID      int            `json:"-"`
+	Type        string         `json:"type"`
+	IPAddress   *string        `json:"ip_address"`
+	UserAgent   *string        `json:"user_agent"`
+	Details     map[string]any `json:"details"`
+	TimeCreated time.Time      `json:"time_created"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Session is a signed-in device, backed by its current refresh token.
+type Session struct {
+	ID           int        `json:"id"`
+	DeviceLabel  *string    `json:"device_label"`
+	UserAgent    *string    `json:"user_agent"`
+	IPAddress    *string    `json:"ip_address"`
+	TimeCreated  time.Time  `json:"time_created"`
+	TimeLastUsed *time.Time `json:"time_last_used"`
+	TimeExpires  time.Time  `json:"time_expires"`
+	Current      bool       `json:"current"`
+}
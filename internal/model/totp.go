@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+const (
+	// OTPPurpose2FA is the second step of a password login
+	OTPPurpose2FA = "2fa"
+	// OTPPurposeSensitiveAction guards enabling and disabling two-factor
+	// authentication
+	OTPPurposeSensitiveAction = "sensitive_action"
+)
+
+// TOTP is a user's authenticator app secret, enabled once confirmed.
+type TOTP struct {
+	UserID       int        `json:"-"`
+	Secret       string     `json:"-"`
+	LastStep     *int64     `json:"-"`
+	TimeCreated  time.Time  `json:"time_created"`
+	TimeEnabled  *time.Time `json:"time_enabled"`
+	TimeLastUsed *time.Time `json:"time_last_used"`
+}
+
+// TOTPStatus tells whether two-factor authentication is on and how many
+// recovery codes are left.
+type TOTPStatus struct {
+	Enabled           bool       `json:"enabled"`
+	TimeEnabled       *time.Time `json:"time_enabled"`
+	TimeLastUsed      *time.Time `json:"time_last_used"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// TOTPEnrollment is what an authenticator app needs to add the account: the
+// secret, the otpauth:// URI and that URI as a PNG QR code.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_url"`
+	QRCode []byte `json:"qr_png"`
+}
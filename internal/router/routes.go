@@ -2,9 +2,13 @@ package router
 
 import (
 	"net/http"
+	"slices"
+	"strings"
 
 	"multipass/internal/app"
 	"multipass/internal/middleware"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
 )
 
 type Router struct {
@@ -38,16 +42,63 @@ func (rt *Router) SetupRoutes(mux *http.ServeMux) {
 			http.HandlerFunc(rt.App.MovieHandler.HandleSearchMovies),
 		),
 	)
+	mux.Handle("/api/movies/discover",
+		middleware.CorsMiddleware(
+			http.HandlerFunc(rt.App.MovieHandler.HandleDiscoverMovies),
+		),
+	)
 	mux.Handle("/api/movies/{id}",
 		middleware.CorsMiddleware(
 			http.HandlerFunc(rt.App.MovieHandler.HandleGetMovieByID),
 		),
 	)
+	mux.Handle("/api/movies/{id}/similar",
+		middleware.CorsMiddleware(
+			http.HandlerFunc(rt.App.MovieHandler.HandleGetSimilarMovies),
+		),
+	)
+	// GET is public, writing reviews requires auth
+	mux.Handle("/api/movies/{id}/reviews",
+		middleware.CorsMiddleware(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet:    http.HandlerFunc(rt.App.ReviewHandler.HandleGetMovieReviews),
+				http.MethodPost:   rt.App.AuthMiddleware.Authenticate(http.HandlerFunc(rt.App.ReviewHandler.HandleCreateReview)),
+				http.MethodPut:    rt.App.AuthMiddleware.Authenticate(http.HandlerFunc(rt.App.ReviewHandler.HandleUpdateReview)),
+				http.MethodDelete: rt.App.AuthMiddleware.Authenticate(http.HandlerFunc(rt.App.ReviewHandler.HandleDeleteReview)),
+			}),
+		),
+	)
+	// GET: PUBLIC LISTS AND SHARE LINKS
+	mux.Handle("/api/lists/{id}",
+		middleware.CorsMiddleware(
+			http.HandlerFunc(rt.App.ListHandler.HandleGetPublicList),
+		),
+	)
+	mux.Handle("/api/lists/shared/{token}",
+		middleware.CorsMiddleware(
+			http.HandlerFunc(rt.App.ListHandler.HandleGetSharedList),
+		),
+	)
 	mux.Handle("/api/genres",
 		middleware.CorsMiddleware(
 			http.HandlerFunc(rt.App.MovieHandler.HandleGetAllGenres),
 		),
 	)
+	mux.Handle("/api/genres/{id}/movies",
+		middleware.CorsMiddleware(
+			http.HandlerFunc(rt.App.MovieHandler.HandleGetMoviesByGenre),
+		),
+	)
+	mux.Handle("/api/actors",
+		middleware.CorsMiddleware(
+			http.HandlerFunc(rt.App.ActorHandler.HandleSearchActors),
+		),
+	)
+	mux.Handle("/api/actors/{id}",
+		middleware.CorsMiddleware(
+			http.HandlerFunc(rt.App.ActorHandler.HandleGetActorByID),
+		),
+	)
 
 	// POST: REGISTER
 	mux.Handle("/api/account/register",
@@ -63,6 +114,42 @@ func (rt *Router) SetupRoutes(mux *http.ServeMux) {
 		),
 	)
 
+	// POST: SECOND LOGIN STEP (mfa token from the login response + code)
+	mux.Handle("/api/account/login/mfa",
+		middleware.CorsMiddleware(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.AccountHandler.HandleMFALogin),
+			}),
+		),
+	)
+
+	// POST: EMAIL A SIGN-IN LINK AND CODE (Public, silent for unknown emails)
+	mux.Handle("/api/account/login/email",
+		middleware.CorsMiddleware(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.AccountHandler.HandleRequestEmailLogin),
+			}),
+		),
+	)
+
+	// POST: SIGN IN FROM THE EMAILED LINK
+	mux.Handle("/api/account/login/email/link",
+		middleware.CorsMiddleware(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.AccountHandler.HandleMagicLinkLogin),
+			}),
+		),
+	)
+
+	// POST: SIGN IN WITH THE EMAILED CODE
+	mux.Handle("/api/account/login/email/code",
+		middleware.CorsMiddleware(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.AccountHandler.HandleEmailCodeLogin),
+			}),
+		),
+	)
+
 	// GET: EMAIL VERIFICATION (Public)
 	mux.Handle("/api/account/email/verify", // Cleaner path name
 		middleware.CorsMiddleware(
@@ -83,11 +170,46 @@ func (rt *Router) SetupRoutes(mux *http.ServeMux) {
 		),
 	)
 
+	// GET: DOWNLOAD MY DATA ARCHIVE (Public, single-use emailed link)
+	// GET only: HEAD requests and link prefetchers must not touch the archive
+	mux.Handle("/api/account/data-export/download",
+		middleware.CorsMiddleware(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet: http.HandlerFunc(rt.App.DataExportHandler.HandleDownloadDataExport),
+			}),
+		),
+	)
+
+	// POST: LOGOUT (refresh token cookie, works with an expired access token)
+	mux.Handle("/api/account/logout",
+		middleware.CorsMiddleware(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.AccountHandler.Logout),
+			}),
+		),
+	)
+
+	// POST: CANCEL ACCOUNT DELETION (Public, emailed link token)
+	mux.Handle("/api/account/delete/cancel",
+		middleware.CorsMiddleware(
+			http.HandlerFunc(rt.App.AccountHandler.HandleCancelAccountDeletion),
+		),
+	)
+
 	/*
 	 ---------------------------------
 	 * AUTHENTICATED ACCOUNT ENDPOINTS
 	 ---------------------------------
 	*/
+	// DELETE: DELETE ACCOUNT (password re-authentication)
+	mux.Handle("/api/account",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodDelete: http.HandlerFunc(rt.App.AccountHandler.HandleDeleteAccount),
+			}),
+		),
+	)
+
 	// REFRESH
 	mux.Handle("/api/account/refresh",
 		rt.App.AuthMiddleware.Authenticate(
@@ -118,6 +240,73 @@ func (rt *Router) SetupRoutes(mux *http.ServeMux) {
 		),
 	)
 
+	// GET/DELETE: SIGNED-IN DEVICES, OR SIGN OUT EVERYWHERE ELSE
+	mux.Handle("/api/account/sessions",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet:    http.HandlerFunc(rt.App.AccountHandler.HandleGetSessions),
+				http.MethodDelete: http.HandlerFunc(rt.App.AccountHandler.HandleRevokeOtherSessions),
+			}),
+		),
+	)
+
+	// GET/POST/DELETE: TWO-FACTOR STATUS, START AUTHENTICATOR SETUP, TURN OFF
+	mux.Handle("/api/account/mfa/totp",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet:    http.HandlerFunc(rt.App.AccountHandler.HandleGetTOTPStatus),
+				http.MethodPost:   http.HandlerFunc(rt.App.AccountHandler.HandleEnrollTOTP),
+				http.MethodDelete: http.HandlerFunc(rt.App.AccountHandler.HandleDisableTOTP),
+			}),
+		),
+	)
+
+	// POST: CONFIRM AUTHENTICATOR SETUP
+	mux.Handle("/api/account/mfa/totp/verify",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.AccountHandler.HandleConfirmTOTP),
+			}),
+		),
+	)
+
+	// GET/PUT: WHETHER PASSWORD LOGINS REQUIRE A PASSKEY
+	mux.Handle("/api/account/mfa/webauthn",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet: http.HandlerFunc(rt.App.AccountHandler.HandleGetPasskeyMFA),
+				http.MethodPut: http.HandlerFunc(rt.App.AccountHandler.HandleSetPasskeyMFA),
+			}),
+		),
+	)
+
+	// POST: SECOND LOGIN STEP WITH A PASSKEY (mfa token from the login response)
+	mux.Handle("/api/account/mfa/webauthn/begin",
+		middleware.CorsMiddleware(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.AccountHandler.HandlePasskeyMFABegin),
+			}),
+		),
+	)
+
+	// POST: FINISH THE PASSKEY LOGIN STEP (mfa token in the X-MFA-Token header)
+	mux.Handle("/api/account/mfa/webauthn/finish",
+		middleware.CorsMiddleware(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.AccountHandler.HandlePasskeyMFAFinish),
+			}),
+		),
+	)
+
+	// DELETE: SIGN OUT ONE DEVICE
+	mux.Handle("/api/account/sessions/{id}",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodDelete: http.HandlerFunc(rt.App.AccountHandler.HandleRevokeSession),
+			}),
+		),
+	)
+
 	// GET: FAVORITES
 	mux.Handle("/api/account/favorites",
 		rt.withAuthAndCORS(
@@ -132,6 +321,144 @@ func (rt *Router) SetupRoutes(mux *http.ServeMux) {
 		),
 	)
 
+	// GET: RECOMMENDATIONS
+	mux.Handle("/api/account/recommendations",
+		rt.withAuthAndCORS(
+			http.HandlerFunc(rt.App.RecommendationHandler.HandleGetRecommendations),
+		),
+	)
+
+	// GET/POST: WATCH DIARY
+	mux.Handle("/api/account/diary",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet:  http.HandlerFunc(rt.App.AccountHandler.HandleGetDiary),
+				http.MethodPost: http.HandlerFunc(rt.App.AccountHandler.HandleLogWatch),
+			}),
+		),
+	)
+
+	// PUT/DELETE: WATCH DIARY ENTRY
+	mux.Handle("/api/account/diary/{id}",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPut:    http.HandlerFunc(rt.App.AccountHandler.HandleUpdateDiaryEntry),
+				http.MethodDelete: http.HandlerFunc(rt.App.AccountHandler.HandleDeleteDiaryEntry),
+			}),
+		),
+	)
+
+	// GET: REVIEWS WRITTEN BY THE USER
+	mux.Handle("/api/account/reviews",
+		rt.withAuthAndCORS(
+			http.HandlerFunc(rt.App.ReviewHandler.HandleGetUserReviews),
+		),
+	)
+
+	// GET: EDIT HISTORY OF ONE OF THE USER'S REVIEWS
+	mux.Handle("/api/account/reviews/{id}/history",
+		rt.withAuthAndCORS(
+			http.HandlerFunc(rt.App.ReviewHandler.HandleGetReviewHistory),
+		),
+	)
+
+	// GET/POST: CUSTOM LISTS
+	mux.Handle("/api/account/lists",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet:  http.HandlerFunc(rt.App.ListHandler.HandleGetUserLists),
+				http.MethodPost: http.HandlerFunc(rt.App.ListHandler.HandleCreateList),
+			}),
+		),
+	)
+
+	// GET/PUT/DELETE: ONE CUSTOM LIST
+	mux.Handle("/api/account/lists/{id}",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet:    http.HandlerFunc(rt.App.ListHandler.HandleGetList),
+				http.MethodPut:    http.HandlerFunc(rt.App.ListHandler.HandleUpdateList),
+				http.MethodDelete: http.HandlerFunc(rt.App.ListHandler.HandleDeleteList),
+			}),
+		),
+	)
+
+	// POST: ADD MOVIE TO LIST
+	mux.Handle("/api/account/lists/{id}/items",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.ListHandler.HandleAddListItem),
+			}),
+		),
+	)
+
+	// PUT/DELETE: EDIT NOTE, REORDER OR REMOVE LIST ITEM
+	mux.Handle("/api/account/lists/{id}/items/{movieId}",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPut:    http.HandlerFunc(rt.App.ListHandler.HandleUpdateListItem),
+				http.MethodDelete: http.HandlerFunc(rt.App.ListHandler.HandleRemoveListItem),
+			}),
+		),
+	)
+
+	// POST/DELETE: CREATE OR REVOKE LIST SHARE LINK
+	mux.Handle("/api/account/lists/{id}/share",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost:   http.HandlerFunc(rt.App.ListHandler.HandleCreateShareLink),
+				http.MethodDelete: http.HandlerFunc(rt.App.ListHandler.HandleRevokeShareLink),
+			}),
+		),
+	)
+
+	// GET: EXPORT FAVORITES AND WATCHLIST
+	mux.Handle("/api/account/export",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet: http.HandlerFunc(rt.App.AccountHandler.HandleExportCollections),
+			}),
+		),
+	)
+
+	// GET/POST: DOWNLOAD MY DATA ARCHIVE STATUS OR REQUEST
+	mux.Handle("/api/account/data-export",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet:  http.HandlerFunc(rt.App.DataExportHandler.HandleGetDataExport),
+				http.MethodPost: http.HandlerFunc(rt.App.DataExportHandler.HandleRequestDataExport),
+			}),
+		),
+	)
+
+	// POST: IMPORT LETTERBOXD, IMDB OR TRAKT EXPORT
+	mux.Handle("/api/account/imports",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.ImportHandler.HandleStartImport),
+			}),
+		),
+	)
+
+	// GET/DELETE: IMPORT PROGRESS AND PREVIEW
+	mux.Handle("/api/account/imports/{id}",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet:    http.HandlerFunc(rt.App.ImportHandler.HandleGetImport),
+				http.MethodDelete: http.HandlerFunc(rt.App.ImportHandler.HandleDiscardImport),
+			}),
+		),
+	)
+
+	// POST: COMMIT IMPORT INTO ITS COLLECTION
+	mux.Handle("/api/account/imports/{id}/commit",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.ImportHandler.HandleCommitImport),
+			}),
+		),
+	)
+
 	// POST: SAVE MOVIE TO COLLECTION
 	mux.Handle("/api/account/save-to-collection",
 		rt.withAuthAndCORS(
@@ -146,6 +473,64 @@ func (rt *Router) SetupRoutes(mux *http.ServeMux) {
 		),
 	)
 
+	//￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
+	//         # ADMIN CATALOG ROUTES
+	//__________________________________________
+	// POST: CREATE MOVIE
+	mux.Handle("/api/admin/movies",
+		rt.withAdmin(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.AdminHandler.HandleCreateMovie),
+			}),
+		),
+	)
+
+	// PUT/DELETE: EDIT OR SOFT DELETE MOVIE
+	mux.Handle("/api/admin/movies/{id}",
+		rt.withAdmin(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPut:    http.HandlerFunc(rt.App.AdminHandler.HandleUpdateMovie),
+				http.MethodDelete: http.HandlerFunc(rt.App.AdminHandler.HandleDeleteMovie),
+			}),
+		),
+	)
+
+	// POST: ATTACH GENRES, CAST OR KEYWORDS
+	mux.Handle("/api/admin/movies/{id}/attach",
+		rt.withAdmin(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.AdminHandler.HandleAttachRelations),
+			}),
+		),
+	)
+
+	// POST: DETACH GENRES, CAST OR KEYWORDS
+	mux.Handle("/api/admin/movies/{id}/detach",
+		rt.withAdmin(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.AdminHandler.HandleDetachRelations),
+			}),
+		),
+	)
+
+	// GET: CATALOG HISTORY OF A MOVIE
+	mux.Handle("/api/admin/movies/{id}/history",
+		rt.withAdmin(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet: http.HandlerFunc(rt.App.AdminHandler.HandleGetMovieHistory),
+			}),
+		),
+	)
+
+	// POST: REVERT A CATALOG CHANGE
+	mux.Handle("/api/admin/history/{id}/revert",
+		rt.withAdmin(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.AdminHandler.HandleRevertEntry),
+			}),
+		),
+	)
+
 	mux.Handle("/api/passkey/registration-begin",
 		rt.withAuthAndCORS(
 			http.HandlerFunc(rt.App.WebAuthnHandler.WebAuthnRegistrationBeginHandler),
@@ -166,6 +551,39 @@ func (rt *Router) SetupRoutes(mux *http.ServeMux) {
 			http.HandlerFunc(rt.App.WebAuthnHandler.WebAuthnAuthenticationEndHandler),
 		),
 	)
+	mux.Handle("/api/passkey/authentication-discoverable-begin",
+		middleware.CorsMiddleware(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.WebAuthnHandler.WebAuthnDiscoverableAuthenticationBeginHandler),
+			}),
+		),
+	)
+	mux.Handle("/api/passkey/authentication-discoverable-end",
+		middleware.CorsMiddleware(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPost: http.HandlerFunc(rt.App.WebAuthnHandler.WebAuthnDiscoverableAuthenticationEndHandler),
+			}),
+		),
+	)
+
+	// GET: REGISTERED PASSKEYS
+	mux.Handle("/api/passkey/credentials",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodGet: http.HandlerFunc(rt.App.WebAuthnHandler.HandleListPasskeys),
+			}),
+		),
+	)
+
+	// PUT/DELETE: RENAME OR DELETE A PASSKEY
+	mux.Handle("/api/passkey/credentials/{id}",
+		rt.withAuthAndCORS(
+			rt.byMethod(map[string]http.Handler{
+				http.MethodPut:    http.HandlerFunc(rt.App.WebAuthnHandler.HandleRenamePasskey),
+				http.MethodDelete: http.HandlerFunc(rt.App.WebAuthnHandler.HandleDeletePasskey),
+			}),
+		),
+	)
 
 	//￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
 	//         # CATCH ALL CLIENT ROUTES
@@ -173,6 +591,7 @@ func (rt *Router) SetupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/movies", rt.App.CatchAllClientRoutesHandler)
 	mux.HandleFunc("/movies/", rt.App.CatchAllClientRoutesHandler)
 	mux.HandleFunc("/account/", rt.App.CatchAllClientRoutesHandler)
+	mux.HandleFunc("/lists/", rt.App.CatchAllClientRoutesHandler)
 	//￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
 	//         # STATIC ROUTE
 	//__________________________________________
@@ -185,6 +604,34 @@ func (rt *Router) withAuthAndCORS(next http.Handler) http.Handler {
 	return rt.App.AuthMiddleware.Authenticate(middleware.CorsMiddleware(next))
 }
 
+// Utility to restrict authenticated routes to administrators
+func (rt *Router) withAdmin(next http.Handler) http.Handler {
+	return rt.withAuthAndCORS(rt.App.AdminMiddleware.RequireAdmin(next))
+}
+
+// byMethod routes a request to the handler registered for its HTTP method.
+// Other methods get 405 Method Not Allowed.
+func (rt *Router) byMethod(handlers map[string]http.Handler) http.Handler {
+	allowed := make([]string, 0, len(handlers))
+	for method := range handlers {
+		allowed = append(allowed, method)
+	}
+	slices.Sort(allowed)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if next, ok := handlers[r.Method]; ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		apperror.NewAppError(http.StatusMethodNotAllowed, apperror.ErrMethodNotAllowedMsg, "Router.byMethod", nil, rt.App.Logger, common.Envelop{
+			"method": r.Method,
+			"path":   r.URL.Path,
+		}).WriteJSONError(w, r, rt.App.Responder)
+	})
+}
+
 // And ensure your main function uses this mux for ListenAndServe:
 // func main() {
 //     // ... your app setup ...
@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -28,6 +30,8 @@ import (
 	"multipass/pkg/validator"
 
 	"github.com/disintegration/imaging"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 type UserAccountService interface {
@@ -35,42 +39,83 @@ type UserAccountService interface {
 	AuthService(ctx context.Context, req *common.AuthRequest) (*common.AuthResult, error)
 	LogoutService(ctx context.Context, refreshTokenPlaintext string) error
 	RefreshService(ctx context.Context, refreshTokenPlaintext string) (*common.AuthResult, error)
+	SessionsService(ctx context.Context, refreshTokenPlaintext string) ([]model.Session, error)
+	RevokeSessionService(ctx context.Context, sessionID int) error
+	RevokeOtherSessionsService(ctx context.Context, refreshTokenPlaintext string) (int64, error)
 	AddToCollection(ctx context.Context, req *common.CollectionRequest) (*common.CollectionSuccess, error)
 	RemoveFromCollection(ctx context.Context, req *common.CollectionRequest) (*common.CollectionSuccess, error)
 	AccountDetailsService(ctx context.Context, email string) (*model.User, error)
+	CollectionPageService(ctx context.Context, list string, page common.PageParams) (*common.MoviePage, error)
+	ExportCollectionsService(ctx context.Context, list string, fn func(list string, m *model.Movie) error) error
+	LogWatchService(ctx context.Context, req *common.DiaryRequest) (*model.DiaryEntry, error)
+	DiaryPageService(ctx context.Context, rng common.DiaryRange, page common.PageParams) (*common.DiaryPage, error)
+	UpdateDiaryEntryService(ctx context.Context, entryID int, req *common.DiaryRequest) (*model.DiaryEntry, error)
+	DeleteDiaryEntryService(ctx context.Context, entryID int) error
 	DeleteTokenService(ctx context.Context, id int) error
 	UserUpdateService(ctx context.Context, userID int, req *common.UserUpdateRequest) error
 	UploadProfilePictureService(ctx context.Context, userID int, file multipart.File, fileHeader *multipart.FileHeader) (string, error)
 	VerifyEmail(ctx context.Context, plainTextToken string) error
 	RequestPasswordReset(ctx context.Context, email string) error
 	ConfirmPasswordReset(ctx context.Context, plainTextToken, newPassword string) error
+	DeleteAccountService(ctx context.Context, req *common.AccountDeleteRequest) error
+	CancelAccountDeletion(ctx context.Context, plainTextToken string) error
+	MFALoginService(ctx context.Context, req *common.MFALoginRequest) (*common.AuthResult, error)
+	TOTPStatusService(ctx context.Context) (*model.TOTPStatus, error)
+	EnrollTOTPService(ctx context.Context) (*model.TOTPEnrollment, error)
+	ConfirmTOTPService(ctx context.Context, req *common.VerifyOTPRequest) ([]string, error)
+	DisableTOTPService(ctx context.Context, req *common.TOTPDisableRequest) error
+	RequestEmailLoginService(ctx context.Context, req *common.SendOTPRequest) error
+	MagicLinkLoginService(ctx context.Context, plainTextToken string) (*common.AuthResult, error)
+	EmailCodeLoginService(ctx context.Context, req *common.OTPRequest) (*common.AuthResult, error)
+	PasskeyMFAStatusService(ctx context.Context) (*model.PasskeyMFAStatus, error)
+	SetPasskeyMFAService(ctx context.Context, req *common.PasskeyMFAPolicyRequest) error
+	BeginPasskeyMFAService(ctx context.Context, mfaToken string) (*protocol.CredentialAssertion, error)
+	FinishPasskeyMFAService(ctx context.Context, r *http.Request, mfaToken string) (*common.AuthResult, error)
 }
 
 type AccountService struct {
 	BaseService
-	store       store.AccountStore
-	tokenStore  store.TokenStore
-	tokens      *tokens.TokenManager
-	emailSender EmailSender
-	logger      logging.Logger
-	config      *config.Config
+	store        store.AccountStore
+	tokenStore   store.TokenStore
+	eventStore   store.SecurityEventStore
+	mfaStore     store.MFAStore
+	passkeyStore store.PasskeyStore
+	ceremonies   store.CeremonySessionStore
+	listStore    store.ListStore
+	tokens       *tokens.TokenManager
+	webauthn     *webauthn.WebAuthn
+	emailSender  EmailSender
+	logger       logging.Logger
+	config       *config.Config
 }
 
 func NewAccountService(
 	accountStore store.AccountStore,
 	tokenStore store.TokenStore,
+	eventStore store.SecurityEventStore,
+	mfaStore store.MFAStore,
+	passkeyStore store.PasskeyStore,
+	ceremonies store.CeremonySessionStore,
+	listStore store.ListStore,
 	tokenManager tokens.TokenManager,
+	webAuthn *webauthn.WebAuthn,
 	emailSender EmailSender,
 	logger logging.Logger,
 	config *config.Config,
 ) *AccountService {
 	return &AccountService{
-		store:       accountStore,
-		emailSender: emailSender,
-		tokenStore:  tokenStore,
-		tokens:      &tokenManager,
-		logger:      logger,
-		config:      config,
+		store:        accountStore,
+		emailSender:  emailSender,
+		tokenStore:   tokenStore,
+		eventStore:   eventStore,
+		mfaStore:     mfaStore,
+		passkeyStore: passkeyStore,
+		ceremonies:   ceremonies,
+		listStore:    listStore,
+		tokens:       &tokenManager,
+		webauthn:     webAuthn,
+		logger:       logger,
+		config:       config,
 	}
 }
 
@@ -118,7 +163,7 @@ func (s *AccountService) RegisterUser(ctx context.Context, req *common.RegisterR
 		Email: registerData.Email,
 	}
 
-	jwt, refreshToken, err := s.generateAndSaveTokens(ctx, user)
+	jwt, refreshToken, err := s.generateAndSaveTokens(ctx, user, nil)
 	if err != nil {
 		metaData["context"] = "generateAndSaveTokens"
 		return nil, apperror.ErrInternalServer(err, s.Logger, metaData)
@@ -160,8 +205,29 @@ func (s *AccountService) AuthService(ctx context.Context, req *common.AuthReques
 		return nil, apperror.ErrUnauthorized(errors.New("invalid credentials"), s.logger, metaData)
 	}
 
+	return s.completeLogin(ctx, user)
+}
+
+// completeLogin issues the token pair to a user who proved their first
+// factor, or starts the second step when two-factor authentication is on.
+func (s *AccountService) completeLogin(ctx context.Context, user *model.User) (*common.AuthResult, error) {
+	metaData := common.Envelop{
+		"op":      "service.completeLogin",
+		"user_id": user.ID,
+	}
+
+	// Accounts with two-factor authentication finish signing in with a code
+	// or a passkey
+	policy, err := s.mfaStore.GetMFAPolicy(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Required() {
+		return s.startMFAChallenge(ctx, user, policy)
+	}
+
 	// Generate and save tokens
-	jwt, refreshToken, err := s.generateAndSaveTokens(ctx, user)
+	jwt, refreshToken, err := s.generateAndSaveTokens(ctx, user, nil)
 	if err != nil {
 		metaData["context"] = "generateAndSaveTokens"
 		return nil, apperror.ErrInternalServer(err, s.Logger, metaData)
@@ -183,12 +249,12 @@ func (s *AccountService) LogoutService(ctx context.Context, refreshTokenPlaintex
 		"op": "service.LogoutService",
 	}
 
-	user, dbHash, err := s.getUserWithRefreshToken(ctx, refreshTokenPlaintext)
+	user, storedToken, err := s.getUserWithRefreshToken(ctx, refreshTokenPlaintext)
 	if err != nil {
 		return err
 	}
 
-	if err := s.tokenStore.DeleteRefreshToken(ctx, user.ID, dbHash[:]); err != nil {
+	if err := s.tokenStore.RevokeTokenFamily(ctx, user.ID, storedToken.Family); err != nil {
 		metaData["context"] = "REFRESH_TOKEN_DELETION"
 		return apperror.ErrDatabaseTimeout(err, s.logger, metaData)
 	}
@@ -196,27 +262,39 @@ func (s *AccountService) LogoutService(ctx context.Context, refreshTokenPlaintex
 	return nil
 }
 
+// refreshReuseGrace is how long a rotated refresh token is still accepted.
+// Tabs refreshing at the same moment all send the token only one can rotate.
+const refreshReuseGrace = 10 * time.Second
+
 // RefreshService handles refreshRequest
 func (s *AccountService) RefreshService(ctx context.Context, refreshTokenPlaintext string) (*common.AuthResult, error) {
 	metaData := common.Envelop{
 		"op": "service.RefreshService",
 	}
 	// 1. Get user and validate the incoming refresh token
-	user, dbHash, err := s.getUserWithRefreshToken(ctx, refreshTokenPlaintext)
+	user, storedToken, err := s.getUserWithRefreshToken(ctx, refreshTokenPlaintext)
 	if err != nil {
 		return nil, err
 	}
 
-	// 2. Revoke/Delete the OLD refresh token (the one just used)
-	// This is critical for "one-time-use" refresh tokens or token rotation.
-	if err := s.tokenStore.DeleteRefreshToken(ctx, user.ID, dbHash); err != nil {
-		metaData["details"] = "DELETION_FAILED_OLD_TOKEN"
-		s.Logger.Errorf(fmt.Sprintf("Failed to delete used refresh token for user %d:", user.ID), err, metaData)
-		return nil, apperror.ErrInternalServer(err, s.logger, metaData)
+	// 2. Retire the OLD refresh token (the one just used)
+	// It stays in its family so that presenting it again is detected as theft.
+	// A token retired within the grace window gets another child instead.
+	if storedToken.Retired == nil {
+		retired, err := s.tokenStore.RetireRefreshToken(ctx, storedToken.ID, time.Now())
+		if err != nil {
+			metaData["details"] = "RETIRE_FAILED_OLD_TOKEN"
+			s.Logger.Errorf(fmt.Sprintf("Failed to retire used refresh token for user %d:", user.ID), err, metaData)
+			return nil, apperror.ErrInternalServer(err, s.logger, metaData)
+		}
+		if !retired {
+			// Another request rotated this token just now, a concurrent refresh
+			s.logger.Info("Refresh token rotated concurrently", metaData)
+		}
 	}
 
-	// 3. Generate and save NEW Tokens (JWT and NEW Refresh Token)
-	jwt, refreshToken, err := s.generateAndSaveTokens(ctx, user)
+	// 3. Generate and save NEW Tokens (JWT and NEW Refresh Token), continuing the session
+	jwt, refreshToken, err := s.generateAndSaveTokens(ctx, user, storedToken)
 	if err != nil {
 		metaData["details"] = "TOKEN_PAIR_GENERATION_SAVE"
 		s.Logger.Errorf(fmt.Sprintf("Failed to generate and save new tokens for user %d:", user.ID), err, metaData)
@@ -234,6 +312,78 @@ func (s *AccountService) RefreshService(ctx context.Context, refreshTokenPlainte
 	return result, nil
 }
 
+// SessionsService lists the devices signed in to the user's account. The
+// session of refreshTokenPlaintext, when given, is flagged as the current one.
+func (s *AccountService) SessionsService(ctx context.Context, refreshTokenPlaintext string) ([]model.Session, error) {
+	metaData := common.Envelop{
+		"op": "service.SessionsService",
+	}
+
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	var currentHash []byte
+	if refreshTokenPlaintext != "" {
+		hash := sha256.Sum256([]byte(strings.TrimSpace(refreshTokenPlaintext)))
+		currentHash = hash[:]
+	}
+
+	return s.tokenStore.GetSessions(ctx, user.UserID, currentHash)
+}
+
+// RevokeSessionService signs one of the user's devices out
+func (s *AccountService) RevokeSessionService(ctx context.Context, sessionID int) error {
+	metaData := common.Envelop{
+		"op":         "service.RevokeSessionService",
+		"session_id": sessionID,
+	}
+
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	return s.tokenStore.DeleteSession(ctx, user.UserID, sessionID)
+}
+
+// RevokeOtherSessionsService signs out every device of the user except the
+// one holding refreshTokenPlaintext, and returns how many were signed out
+func (s *AccountService) RevokeOtherSessionsService(ctx context.Context, refreshTokenPlaintext string) (int64, error) {
+	metaData := common.Envelop{
+		"op": "service.RevokeOtherSessionsService",
+	}
+
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return 0, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = user.UserID
+
+	// The kept session must be the caller's own, otherwise every session would go
+	owner, current, err := s.getUserWithRefreshToken(ctx, refreshTokenPlaintext)
+	if err != nil {
+		return 0, err
+	}
+	if owner.ID != user.UserID {
+		metaData["details"] = "refresh_token_of_another_user"
+		return 0, apperror.ErrUnauthorized(nil, s.logger, metaData)
+	}
+
+	revoked, err := s.tokenStore.DeleteOtherSessions(ctx, user.UserID, current.Hash)
+	if err != nil {
+		return 0, err
+	}
+
+	metaData["revoked"] = revoked
+	s.logger.Info("Signed out all other sessions", metaData)
+	return revoked, nil
+}
+
 func (s *AccountService) AddToCollection(ctx context.Context, req *common.CollectionRequest) (*common.CollectionSuccess, error) {
 	metaData := make(common.Envelop)
 	// Sanitize Request
@@ -250,10 +400,18 @@ func (s *AccountService) AddToCollection(ctx context.Context, req *common.Collec
 		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
 	}
 
-	// Save movie to collection
-	success, err := s.store.SaveCollection(ctx, user.UserID, collectionInput.MovieID, collectionInput.Collection)
-	if err != nil {
-		return nil, err
+	// Custom lists check ownership themselves, a list of another user is not found
+	success := true
+	if collectionInput.Collection == validator.CollectionList {
+		if _, err := s.listStore.AddListItem(ctx, user.UserID, collectionInput.ListID, collectionInput.MovieID, nil); err != nil {
+			return nil, err
+		}
+	} else {
+		// Save movie to collection
+		success, err = s.store.SaveCollection(ctx, user.UserID, collectionInput.MovieID, collectionInput.Collection)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var str strings.Builder
@@ -287,10 +445,23 @@ func (s *AccountService) RemoveFromCollection(ctx context.Context, req *common.C
 		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
 	}
 
-	// Save movie to collection
-	success, err := s.store.RemoveMovieFromCollection(ctx, user.UserID, collectionInput.MovieID, collectionInput.Collection)
-	if err != nil {
-		return nil, err
+	// A watch cannot be "un-saved", its diary entries are deleted one by one
+	if collectionInput.Collection == store.RelWatched {
+		metaData["error"] = "Watched movies are removed through the diary"
+		return nil, apperror.ErrBadRequest(fmt.Errorf("remove diary entries with DELETE /api/account/diary/{id}"), s.logger, metaData)
+	}
+
+	success := true
+	if collectionInput.Collection == validator.CollectionList {
+		if err := s.listStore.RemoveListItem(ctx, user.UserID, collectionInput.ListID, collectionInput.MovieID); err != nil {
+			return nil, err
+		}
+	} else {
+		// Remove movie from collection
+		success, err = s.store.RemoveMovieFromCollection(ctx, user.UserID, collectionInput.MovieID, collectionInput.Collection)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var str strings.Builder
@@ -485,8 +656,144 @@ func (s *AccountService) AccountDetailsService(ctx context.Context, email string
 	return user, nil
 }
 
+// CollectionPageService returns one page of the authenticated user's favorites or watchlist.
+func (s *AccountService) CollectionPageService(ctx context.Context, list string, page common.PageParams) (*common.MoviePage, error) {
+	metaData := common.Envelop{
+		"op":   "service.CollectionPageService",
+		"list": list,
+	}
+
+	// GET USER FROM CONTEXT
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	// GET REQUESTED PAGE
+	moviePage, err := s.store.GetMovieListPage(ctx, list, user.UserID, page)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(moviePage.Movies) == 0 && page.Cursor == "" {
+		s.logger.Info(fmt.Sprintf("User has no item in %s collection", list), "meta", metaData)
+	}
+
+	return moviePage, nil
+}
+
+// ExportCollectionsService calls fn with each movie of the authenticated
+// user's favorites or watchlist, or of both when list is empty, streamed from
+// the database in the order they were added.
+func (s *AccountService) ExportCollectionsService(ctx context.Context, list string, fn func(list string, m *model.Movie) error) error {
+	metaData := common.Envelop{
+		"op":   "service.ExportCollectionsService",
+		"list": list,
+	}
+
+	lists := []string{store.RelFavorites, store.RelWatchlist}
+	if list != "" {
+		if !slices.Contains(lists, list) {
+			return apperror.ErrBadRequest(fmt.Errorf("list must be either 'favorites' or 'watchlist'"), s.logger, metaData)
+		}
+		lists = []string{list}
+	}
+
+	// GET USER FROM CONTEXT
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	for _, list := range lists {
+		err := s.store.StreamMovieList(ctx, list, user.UserID, func(m *model.Movie) error {
+			return fn(list, m)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LogWatchService adds a watch to the authenticated user's diary.
+func (s *AccountService) LogWatchService(ctx context.Context, req *common.DiaryRequest) (*model.DiaryEntry, error) {
+	metaData := common.Envelop{
+		"op": "service.LogWatchService",
+	}
+
+	input, err := validator.SanitizeDiaryRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	return s.store.LogWatch(ctx, user.UserID, input)
+}
+
+// DiaryPageService returns one page of the authenticated user's diary within a date range.
+func (s *AccountService) DiaryPageService(ctx context.Context, rng common.DiaryRange, page common.PageParams) (*common.DiaryPage, error) {
+	metaData := common.Envelop{
+		"op": "service.DiaryPageService",
+	}
+
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	return s.store.GetDiaryPage(ctx, user.UserID, rng, page)
+}
+
+// UpdateDiaryEntryService edits one of the authenticated user's diary entries.
+func (s *AccountService) UpdateDiaryEntryService(ctx context.Context, entryID int, req *common.DiaryRequest) (*model.DiaryEntry, error) {
+	metaData := common.Envelop{
+		"op":       "service.UpdateDiaryEntryService",
+		"entry_id": entryID,
+	}
+
+	input, err := validator.SanitizeDiaryRequest(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	return s.store.UpdateDiaryEntry(ctx, user.UserID, entryID, input)
+}
+
+// DeleteDiaryEntryService removes one of the authenticated user's diary entries.
+func (s *AccountService) DeleteDiaryEntryService(ctx context.Context, entryID int) error {
+	metaData := common.Envelop{
+		"op":       "service.DeleteDiaryEntryService",
+		"entry_id": entryID,
+	}
+
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	return s.store.DeleteDiaryEntry(ctx, user.UserID, entryID)
+}
+
 // generateAndSaveToken helper method generates access_token and refresh_token and saves refresh_token
-func (s *AccountService) generateAndSaveTokens(ctx context.Context, user *model.User) (string, *tokens.Token, error) {
+// with the requesting device. previous is the refresh token being rotated, nil for a new sign-in.
+func (s *AccountService) generateAndSaveTokens(ctx context.Context, user *model.User, previous *tokens.Token) (string, *tokens.Token, error) {
 	metaData := common.Envelop{
 		"userID": user.ID,
 	}
@@ -496,8 +803,16 @@ func (s *AccountService) generateAndSaveTokens(ctx context.Context, user *model.
 		return "", nil, apperror.ErrInternalServer(err, s.Logger, metaData)
 	}
 
+	// A rotated token continues the session started by the sign-in, in the
+	// family of the token it replaces
+	if previous != nil {
+		refreshToken.Created = previous.Created
+		refreshToken.Family = previous.Family
+		refreshToken.ParentID = previous.ID
+	}
+
 	// Save refresh token hash in store
-	refreshTokenSaveErr := s.tokenStore.SaveRefreshToken(ctx, refreshToken)
+	refreshTokenSaveErr := s.tokenStore.SaveRefreshToken(ctx, refreshToken, ctxutils.GetClientInfo(ctx))
 	if refreshTokenSaveErr != nil {
 		return "", nil, apperror.ErrInternalServer(refreshTokenSaveErr, s.Logger, metaData)
 	}
@@ -505,7 +820,8 @@ func (s *AccountService) generateAndSaveTokens(ctx context.Context, user *model.
 	return jwt, refreshToken, nil
 }
 
-func (s *AccountService) getUserWithRefreshToken(ctx context.Context, plainTextToken string) (*model.User, []byte, error) { // Accepts string
+// getUserWithRefreshToken validates a refresh token and returns it with its user
+func (s *AccountService) getUserWithRefreshToken(ctx context.Context, plainTextToken string) (*model.User, *tokens.Token, error) {
 	metaData := common.Envelop{
 		"op": "service.getUserWithRefreshToken",
 	}
@@ -532,9 +848,15 @@ func (s *AccountService) getUserWithRefreshToken(ctx context.Context, plainTextT
 		return nil, nil, apperror.ErrUnauthorized(err, s.Logger, metaData)
 	}
 
+	// A retired token was already rotated, someone kept a copy of it. Just
+	// after its rotation it may still come from a concurrent refresh.
+	if storedToken.Retired != nil && time.Since(*storedToken.Retired) > refreshReuseGrace {
+		return nil, nil, s.handleTokenReuse(ctx, storedToken)
+	}
+
 	if time.Now().After(storedToken.Expiry) {
 		s.Logger.Info("Expired refresh token found during logout attempt", metaData)
-		if err = s.tokenStore.DeleteRefreshToken(ctx, storedToken.UserID, storedToken.Hash); err != nil {
+		if err = s.tokenStore.RevokeTokenFamily(ctx, storedToken.UserID, storedToken.Family); err != nil {
 			metaData["warning"] = true
 			s.logger.Error("Failed to delete expired refresh token during logout cleanup", err, metaData)
 		}
@@ -553,7 +875,60 @@ func (s *AccountService) getUserWithRefreshToken(ctx context.Context, plainTextT
 		return nil, nil, apperror.ErrUnauthorized(nil, s.Logger, metaData)
 	}
 
-	return user, storedToken.Hash, nil
+	return user, storedToken, nil
+}
+
+// handleTokenReuse treats a reused refresh token as stolen: the whole token
+// family is revoked, the event is logged and, if enabled, the user is alerted.
+func (s *AccountService) handleTokenReuse(ctx context.Context, token *tokens.Token) error {
+	metaData := common.Envelop{
+		"op":       "service.handleTokenReuse",
+		"user_id":  token.UserID,
+		"family":   token.Family,
+		"token_id": token.ID,
+	}
+
+	if err := s.tokenStore.RevokeTokenFamily(ctx, token.UserID, token.Family); err != nil {
+		s.logger.Error("Failed to revoke the family of a reused refresh token", err, metaData)
+	}
+
+	client := ctxutils.GetClientInfo(ctx)
+	event := &model.SecurityEvent{
+		UserID: token.UserID,
+		Type:   model.SecurityEventTokenReuse,
+		Details: map[string]any{
+			"family":    token.Family,
+			"token_id":  token.ID,
+			"parent_id": token.ParentID,
+			"retired":   token.Retired,
+		},
+	}
+	if client.IPAddress != "" {
+		event.IPAddress = &client.IPAddress
+	}
+	if client.UserAgent != "" {
+		event.UserAgent = &client.UserAgent
+	}
+	if err := s.eventStore.LogSecurityEvent(ctx, event); err != nil {
+		s.logger.Error("Failed to log refresh token reuse", err, metaData)
+	}
+	s.logger.Warn("Refresh token reuse detected, token family revoked", metaData)
+
+	if s.config.Security.AlertEmails {
+		user, err := s.store.FindUserByID(ctx, token.UserID)
+		if err != nil {
+			return apperror.ErrTokenReused(nil, s.logger, metaData)
+		}
+		device := client.DeviceLabel
+		if device == "" {
+			device = "an unknown device"
+		}
+		if err := s.emailSender.SendTokenReuseAlertEmail(user.Email, user.Name, device, time.Now()); err != nil {
+			s.logger.Warn("Failed to send refresh token reuse alert", err, metaData)
+		}
+	}
+
+	return apperror.ErrTokenReused(nil, s.logger, metaData)
 }
 
 func (s *AccountService) DeleteTokenService(ctx context.Context, id int) error {
@@ -671,6 +1046,68 @@ func (s *AccountService) ConfirmPasswordReset(ctx context.Context, plainTextToke
 	return s.processAuthToken(ctx, plainTextToken, tokens.PasswordResetScope, op, resetAction)
 }
 
+// DeleteAccountService soft deletes the current user once their password is
+// confirmed, signs them out everywhere and emails a link to cancel the
+// deletion. The account is purged by a background job after the grace period.
+func (s *AccountService) DeleteAccountService(ctx context.Context, req *common.AccountDeleteRequest) error {
+	metaData := common.Envelop{
+		"op": "service.DeleteAccountService",
+	}
+
+	userCtx, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = userCtx.UserID
+
+	user, err := s.store.FindUserByID(ctx, userCtx.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Re-authenticate, a stolen access token alone must not delete the account
+	if !hashing.IsPasswordMatch(strings.TrimSpace(req.Password), user.PasswordHashed) {
+		metaData["context"] = "Reauthentication"
+		return apperror.ErrPasswordMismatch(errors.New("invalid credentials"), s.logger, metaData)
+	}
+
+	deletedAt, err := s.store.SoftDeleteUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	// Revoke every refresh and single-use token; access tokens run out on their own
+	if err := s.tokenStore.DeleteAllTokensForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	grace := s.config.Jobs.AccountDeletionGrace
+	token, err := s.generateAndSaveAuthToken(ctx, user, tokens.AccountDeletionScope, grace)
+	if err != nil {
+		s.logger.Error("Failed to generate account deletion cancel token", err, metaData)
+		return nil // The account is deleted, only the cancel link is missing
+	}
+
+	if err := s.emailSender.SendAccountDeletionEmail(user.Email, user.Name, token.Plaintext, deletedAt.Add(grace)); err != nil {
+		s.logger.Warn("Failed to send account deletion email. Token remains in DB.", err, metaData)
+	}
+
+	s.logger.Info("User account deleted, purge scheduled", metaData)
+	return nil
+}
+
+// CancelAccountDeletion restores an account deleted less than the grace period ago.
+func (s *AccountService) CancelAccountDeletion(ctx context.Context, plainTextToken string) error {
+	op := "AccountService.CancelAccountDeletion"
+
+	restoreAction := func(ctx context.Context, userID int) error {
+		return s.store.RestoreUser(ctx, userID, time.Now().Add(-s.config.Jobs.AccountDeletionGrace))
+	}
+
+	return s.processAuthToken(ctx, plainTextToken, tokens.AccountDeletionScope, op, restoreAction)
+}
+
 // processAuthToken performs the secure, single-use token lifecycle:
 // 1. Hashes the plaintext token.
 // 2. Looks up the token hash in the store (by hash and scope).
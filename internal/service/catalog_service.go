@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+
+	"multipass/internal/model"
+	"multipass/internal/store"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/ctxutils"
+	"multipass/pkg/logging"
+	"multipass/pkg/validator"
+)
+
+type AdminCatalogService interface {
+	CreateMovie(ctx context.Context, req *common.AdminMovieRequest) (*common.CatalogChangeResponse, error)
+	UpdateMovie(ctx context.Context, movieID int, req *common.AdminMovieRequest) (*common.CatalogChangeResponse, error)
+	DeleteMovie(ctx context.Context, movieID int) (*common.CatalogChangeResponse, error)
+	AttachRelations(ctx context.Context, movieID int, req *common.CatalogRelationsRequest) (*common.CatalogChangeResponse, error)
+	DetachRelations(ctx context.Context, movieID int, req *common.CatalogRelationsRequest) (*common.CatalogChangeResponse, error)
+	MovieHistory(ctx context.Context, movieID int, page common.PageParams) (*common.CatalogHistoryPage, error)
+	RevertEntry(ctx context.Context, entryID int64) (*common.CatalogChangeResponse, error)
+}
+
+type CatalogService struct {
+	store  store.CatalogStore
+	logger logging.Logger
+}
+
+func NewCatalogService(catalogStore store.CatalogStore, logger logging.Logger) *CatalogService {
+	return &CatalogService{
+		store:  catalogStore,
+		logger: logger,
+	}
+}
+
+// CreateMovie adds a movie to the catalog together with its genres, cast and keywords
+func (s *CatalogService) CreateMovie(ctx context.Context, req *common.AdminMovieRequest) (*common.CatalogChangeResponse, error) {
+	metaData := common.Envelop{
+		"op": "service.CreateMovie",
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := validator.SanitizeAdminMovieRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := validator.SanitizeCatalogRelations(&req.CatalogRelationsRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.store.CreateMovie(ctx, user.UserID, input, rel)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.changeResponse(ctx, entry, metaData)
+}
+
+// UpdateMovie edits the columns of a movie. Links are changed through attach and detach.
+func (s *CatalogService) UpdateMovie(ctx context.Context, movieID int, req *common.AdminMovieRequest) (*common.CatalogChangeResponse, error) {
+	metaData := common.Envelop{
+		"op":       "service.UpdateMovie",
+		"movie_id": movieID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := validator.SanitizeAdminMovieRequest(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.store.UpdateMovie(ctx, user.UserID, movieID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.changeResponse(ctx, entry, metaData)
+}
+
+// DeleteMovie soft deletes a movie
+func (s *CatalogService) DeleteMovie(ctx context.Context, movieID int) (*common.CatalogChangeResponse, error) {
+	metaData := common.Envelop{
+		"op":       "service.DeleteMovie",
+		"movie_id": movieID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.store.DeleteMovie(ctx, user.UserID, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.changeResponse(ctx, entry, metaData)
+}
+
+// AttachRelations links genres, actors and keywords to a movie
+func (s *CatalogService) AttachRelations(ctx context.Context, movieID int, req *common.CatalogRelationsRequest) (*common.CatalogChangeResponse, error) {
+	metaData := common.Envelop{
+		"op":       "service.AttachRelations",
+		"movie_id": movieID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := s.relations(req)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.store.AttachRelations(ctx, user.UserID, movieID, rel)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.changeResponse(ctx, entry, metaData)
+}
+
+// DetachRelations unlinks genres, actors and keywords from a movie
+func (s *CatalogService) DetachRelations(ctx context.Context, movieID int, req *common.CatalogRelationsRequest) (*common.CatalogChangeResponse, error) {
+	metaData := common.Envelop{
+		"op":       "service.DetachRelations",
+		"movie_id": movieID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := s.relations(req)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.store.DetachRelations(ctx, user.UserID, movieID, rel)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.changeResponse(ctx, entry, metaData)
+}
+
+// MovieHistory returns the recorded changes of a movie with the diff of each one
+func (s *CatalogService) MovieHistory(ctx context.Context, movieID int, page common.PageParams) (*common.CatalogHistoryPage, error) {
+	history, err := s.store.GetMovieHistory(ctx, movieID, page)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range history.Entries {
+		e := &history.Entries[i]
+		e.Changes = e.Before.Diff(e.After)
+	}
+
+	return history, nil
+}
+
+// RevertEntry undoes one recorded change of a movie
+func (s *CatalogService) RevertEntry(ctx context.Context, entryID int64) (*common.CatalogChangeResponse, error) {
+	metaData := common.Envelop{
+		"op":       "service.RevertEntry",
+		"entry_id": entryID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.store.RevertEntry(ctx, user.UserID, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.changeResponse(ctx, entry, metaData)
+}
+
+// relations validates the links of an attach or detach request, which must name at least one
+func (s *CatalogService) relations(req *common.CatalogRelationsRequest) (common.CatalogRelations, error) {
+	rel, err := validator.SanitizeCatalogRelations(req)
+	if err != nil {
+		return rel, err
+	}
+	if rel.IsEmpty() {
+		return rel, apperror.ErrMissingRequiredField("genre_ids, actor_ids, keyword_ids or keywords", nil, nil, nil)
+	}
+	return rel, nil
+}
+
+// changeResponse reports a recorded change together with the movie as it is now
+func (s *CatalogService) changeResponse(ctx context.Context, entry *model.CatalogHistoryEntry, metaData common.Envelop) (*common.CatalogChangeResponse, error) {
+	entry.Changes = entry.Before.Diff(entry.After)
+
+	movie, err := s.store.GetAdminMovie(ctx, entry.MovieID)
+	if err != nil {
+		return nil, err
+	}
+
+	metaData["movie_id"] = entry.MovieID
+	metaData["entry_id"] = entry.ID
+	metaData["action"] = entry.Action
+	s.logger.Info("Catalog movie changed", "meta", metaData)
+
+	return &common.CatalogChangeResponse{Success: true, Movie: movie, Entry: entry}, nil
+}
+
+func (s *CatalogService) currentUser(ctx context.Context, metaData common.Envelop) (*common.UserContext, error) {
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = user.UserID
+	return user, nil
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"multipass/internal/importer"
+	"multipass/internal/model"
+	"multipass/internal/store"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/ctxutils"
+	"multipass/pkg/logging"
+)
+
+const (
+	// Exports up to this many movies are matched while the upload waits
+	collectionImportSyncRows = 200
+	// Rows matched per query; progress is recorded after each batch
+	collectionImportBatchSize = 250
+	// Title candidates kept per row for the user to choose from
+	importCandidateLimit = 3
+	// A title match this similar and this far ahead of the runner-up needs no review
+	importMatchScore = 0.8
+	importScoreGap   = 0.1
+)
+
+// importCollections are the collections an export can be saved into.
+var importCollections = []string{"favorite", store.RelWatchlist, store.RelWatched}
+
+var importSources = []string{model.ImportSourceLetterboxd, model.ImportSourceIMDb, model.ImportSourceTrakt}
+
+type UserCollectionImportService interface {
+	StartImport(ctx context.Context, file io.Reader, fileName, source, collection string) (*common.CollectionImportResponse, error)
+	GetImport(ctx context.Context, importID int) (*common.CollectionImportResponse, error)
+	CommitImport(ctx context.Context, importID int, req *common.CollectionImportCommitRequest) (*common.CollectionImportCommitResponse, error)
+	DiscardImport(ctx context.Context, importID int) error
+}
+
+// BackgroundRunner runs tasks outside of the request that queued them.
+type BackgroundRunner interface {
+	Go(name string, task func(ctx context.Context) error)
+}
+
+type CollectionImportService struct {
+	store  store.CollectionImportStore
+	runner BackgroundRunner
+	logger logging.Logger
+}
+
+func NewCollectionImportService(importStore store.CollectionImportStore, runner BackgroundRunner, logger logging.Logger) *CollectionImportService {
+	return &CollectionImportService{
+		store:  importStore,
+		runner: runner,
+		logger: logger,
+	}
+}
+
+// StartImport reads an export and matches its rows to the catalog. Small
+// exports are matched before returning; larger ones in the background, the
+// returned import is then still processing.
+func (s *CollectionImportService) StartImport(ctx context.Context, file io.Reader, fileName, source, collection string) (*common.CollectionImportResponse, error) {
+	metaData := common.Envelop{
+		"op":         "service.StartImport",
+		"file_name":  fileName,
+		"source":     source,
+		"collection": collection,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	collection = strings.TrimSpace(collection)
+	if !slices.Contains(importCollections, collection) {
+		return nil, apperror.ErrBadRequest(fmt.Errorf("collection must be 'favorite', 'watchlist' or 'watched'"), s.logger, metaData)
+	}
+	source = strings.ToLower(strings.TrimSpace(source))
+	if source != "" && !slices.Contains(importSources, source) {
+		return nil, apperror.ErrBadRequest(fmt.Errorf("format must be 'letterboxd', 'imdb' or 'trakt'"), s.logger, metaData)
+	}
+
+	source, rows, err := importer.ReadExport(file, fileName, source)
+	if err != nil {
+		if errors.Is(err, importer.ErrExportTooLarge) {
+			return nil, apperror.ErrFileTooLarge(err, s.logger, metaData)
+		}
+		return nil, apperror.ErrInvalidImportFile(err, s.logger, metaData)
+	}
+
+	imp := &model.CollectionImport{
+		UserID:     user.UserID,
+		Source:     source,
+		Collection: collection,
+		FileName:   fileName,
+		Total:      len(rows),
+	}
+	if err := s.store.CreateCollectionImport(ctx, imp); err != nil {
+		return nil, err
+	}
+	metaData["import_id"] = imp.ID
+	metaData["rows"] = len(rows)
+
+	if len(rows) > collectionImportSyncRows {
+		s.runner.Go("collection_import", func(ctx context.Context) error {
+			return s.matchRows(ctx, imp.ID, rows)
+		})
+		s.logger.Info("Collection import queued", "meta", metaData)
+		return newCollectionImportResponse(imp), nil
+	}
+
+	if err := s.matchRows(ctx, imp.ID, rows); err != nil {
+		return nil, err
+	}
+	return s.loadImport(ctx, user.UserID, imp.ID)
+}
+
+// GetImport returns an import's progress, and its preview once matched
+func (s *CollectionImportService) GetImport(ctx context.Context, importID int) (*common.CollectionImportResponse, error) {
+	metaData := common.Envelop{
+		"op":        "service.GetImport",
+		"import_id": importID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.loadImport(ctx, user.UserID, importID)
+}
+
+// CommitImport saves the matched rows, plus the rows the user chose a movie
+// for, into the import's collection
+func (s *CollectionImportService) CommitImport(ctx context.Context, importID int, req *common.CollectionImportCommitRequest) (*common.CollectionImportCommitResponse, error) {
+	metaData := common.Envelop{
+		"op":        "service.CommitImport",
+		"import_id": importID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	imp, err := s.store.GetCollectionImport(ctx, user.UserID, importID)
+	if err != nil {
+		return nil, err
+	}
+	if imp.Status != model.ImportStatusReady {
+		metaData["status"] = imp.Status
+		return nil, apperror.ErrImportNotReady(nil, s.logger, metaData)
+	}
+
+	for row, movieID := range req.Choices {
+		if row < 1 || row > len(imp.Rows) || movieID <= 0 {
+			metaData["row"] = row
+			return nil, apperror.ErrBadRequest(fmt.Errorf("choices must map row numbers of the import to movie ids"), s.logger, metaData)
+		}
+	}
+
+	// Rows of the same movie are saved once, at their earliest date. Watches
+	// are kept once per day instead, rewatches are part of the diary.
+	today := time.Now().UTC()
+	seen := make(map[string]int)
+	var items []common.CollectionImportItem
+	for _, row := range imp.Rows {
+		if slices.Contains(req.Skip, row.Row) {
+			continue
+		}
+
+		movieID, chosen := req.Choices[row.Row]
+		if !chosen {
+			if row.Status != model.ImportRowMatched || row.Match == nil {
+				continue
+			}
+			movieID = row.Match.MovieID
+		}
+
+		date := today
+		if row.Date != nil {
+			date = *row.Date
+		}
+
+		key := fmt.Sprint(movieID)
+		if imp.Collection == store.RelWatched {
+			key += date.Format(time.DateOnly)
+		}
+		if i, ok := seen[key]; ok {
+			if date.Before(items[i].Date) {
+				items[i].Date = date
+			}
+			continue
+		}
+		seen[key] = len(items)
+		items = append(items, common.CollectionImportItem{MovieID: movieID, Date: date})
+	}
+
+	added, err := s.store.CommitCollectionImport(ctx, user.UserID, importID, imp.Collection, items)
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.CollectionImportCommitResponse{
+		Success:  true,
+		Selected: len(items),
+		Added:    added,
+	}, nil
+}
+
+// DiscardImport deletes an import and its preview without saving anything
+func (s *CollectionImportService) DiscardImport(ctx context.Context, importID int) error {
+	metaData := common.Envelop{
+		"op":        "service.DiscardImport",
+		"import_id": importID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return err
+	}
+
+	return s.store.DeleteCollectionImport(ctx, user.UserID, importID)
+}
+
+// matchRows matches rows batch by batch, recording progress, then stores the
+// preview. A failure is recorded on the import before it is returned.
+func (s *CollectionImportService) matchRows(ctx context.Context, importID int, rows []model.CollectionImportRow) error {
+	for start := 0; start < len(rows); start += collectionImportBatchSize {
+		batch := rows[start:min(start+collectionImportBatchSize, len(rows))]
+		err := s.matchBatch(ctx, batch)
+		if err == nil {
+			err = s.store.UpdateImportProgress(ctx, importID, start+len(batch))
+		}
+		if err != nil {
+			failure := "matching failed, please try again"
+			if ferr := s.store.FinishCollectionImport(ctx, importID, nil, &failure); ferr != nil {
+				s.logger.Error("Failed to record collection import failure", ferr, "import_id", importID)
+			}
+			return err
+		}
+	}
+
+	return s.store.FinishCollectionImport(ctx, importID, rows, nil)
+}
+
+// matchBatch matches rows by TMDB or IMDb id first, then by title and year.
+func (s *CollectionImportService) matchBatch(ctx context.Context, rows []model.CollectionImportRow) error {
+	var (
+		tmdbIDs []int
+		imdbIDs []string
+	)
+	for _, row := range rows {
+		if row.TMDB_ID != nil {
+			tmdbIDs = append(tmdbIDs, *row.TMDB_ID)
+		}
+		if row.IMDbID != nil {
+			imdbIDs = append(imdbIDs, *row.IMDbID)
+		}
+	}
+
+	byID, err := s.store.FindImportMoviesByID(ctx, tmdbIDs, imdbIDs)
+	if err != nil {
+		return err
+	}
+
+	var byTitle []model.CollectionImportRow
+	for i := range rows {
+		row := &rows[i]
+		i := slices.IndexFunc(byID, func(c model.ImportCandidate) bool {
+			return (row.TMDB_ID != nil && c.TMDB_ID == *row.TMDB_ID) ||
+				(row.IMDbID != nil && c.IMDbID != nil && *c.IMDbID == *row.IMDbID)
+		})
+		if i >= 0 {
+			row.Status = model.ImportRowMatched
+			row.Match = &byID[i]
+			continue
+		}
+		if row.Title != "" {
+			byTitle = append(byTitle, *row)
+		} else {
+			row.Status = model.ImportRowUnmatched
+		}
+	}
+
+	candidates, err := s.store.FindImportTitleCandidates(ctx, byTitle, importCandidateLimit)
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		if rows[i].Status == "" {
+			classifyImportRow(&rows[i], candidates[rows[i].Row])
+		}
+	}
+	return nil
+}
+
+// classifyImportRow matches a row to its only exact title and year match,
+// or to a clearly leading similar title. Other rows with candidates are
+// ambiguous and left for the user to decide.
+func classifyImportRow(row *model.CollectionImportRow, candidates []model.ImportCandidate) {
+	if len(candidates) == 0 {
+		row.Status = model.ImportRowUnmatched
+		return
+	}
+
+	var exact []int
+	for i, c := range candidates {
+		sameYear := row.Year == nil || (c.ReleaseYear != nil && *c.ReleaseYear == *row.Year)
+		if sameYear && strings.EqualFold(strings.TrimSpace(c.Title), row.Title) {
+			exact = append(exact, i)
+		}
+	}
+
+	top := candidates[0]
+	clearLead := len(candidates) == 1 || top.Score-candidates[1].Score >= importScoreGap
+	switch {
+	case len(exact) == 1:
+		row.Status = model.ImportRowMatched
+		row.Match = &candidates[exact[0]]
+	case len(exact) == 0 && top.Score >= importMatchScore && clearLead:
+		row.Status = model.ImportRowMatched
+		row.Match = &top
+	default:
+		row.Status = model.ImportRowAmbiguous
+		row.Candidates = candidates
+	}
+}
+
+func (s *CollectionImportService) loadImport(ctx context.Context, userID int, importID int) (*common.CollectionImportResponse, error) {
+	imp, err := s.store.GetCollectionImport(ctx, userID, importID)
+	if err != nil {
+		return nil, err
+	}
+	return newCollectionImportResponse(imp), nil
+}
+
+func newCollectionImportResponse(imp *model.CollectionImport) *common.CollectionImportResponse {
+	resp := &common.CollectionImportResponse{
+		Import:    imp,
+		Matched:   []model.CollectionImportRow{},
+		Ambiguous: []model.CollectionImportRow{},
+		Unmatched: []model.CollectionImportRow{},
+	}
+	for _, row := range imp.Rows {
+		switch row.Status {
+		case model.ImportRowMatched:
+			resp.Matched = append(resp.Matched, row)
+		case model.ImportRowAmbiguous:
+			resp.Ambiguous = append(resp.Ambiguous, row)
+		default:
+			resp.Unmatched = append(resp.Unmatched, row)
+		}
+	}
+	return resp
+}
+
+func (s *CollectionImportService) currentUser(ctx context.Context, metaData common.Envelop) (*common.UserContext, error) {
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = user.UserID
+	return user, nil
+}
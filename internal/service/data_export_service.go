@@ -0,0 +1,449 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"multipass/config"
+	"multipass/internal/auth/tokens"
+	"multipass/internal/model"
+	"multipass/internal/store"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/ctxutils"
+	"multipass/pkg/logging"
+	"multipass/pkg/utils"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// Diary entries and reviews read per query while writing an archive
+const dataExportPage = 100
+
+type UserDataExportService interface {
+	RequestDataExport(ctx context.Context) (*model.DataExport, error)
+	LatestDataExport(ctx context.Context) (*model.DataExport, error)
+	OpenDataExport(ctx context.Context, plainTextToken string) (*DataExportDownload, error)
+	CompleteDataExportDownload(ctx context.Context, download *DataExportDownload) error
+}
+
+// DataExportDownload is an archive opened through its download link. The link
+// keeps working until CompleteDataExportDownload, so an interrupted download
+// can be retried.
+type DataExportDownload struct {
+	File     *os.File
+	FileName string
+	Size     int64
+	exportID int
+}
+
+func (d *DataExportDownload) Close() error {
+	return d.File.Close()
+}
+
+type DataExportService struct {
+	store       store.DataExportStore
+	accounts    store.AccountStore
+	lists       store.ListStore
+	reviews     store.ReviewStore
+	passkeys    store.PasskeyStore
+	tokens      *tokens.TokenManager
+	emailSender EmailSender
+	runner      BackgroundRunner
+	config      *config.Config
+	logger      logging.Logger
+}
+
+func NewDataExportService(
+	exportStore store.DataExportStore,
+	accountStore store.AccountStore,
+	listStore store.ListStore,
+	reviewStore store.ReviewStore,
+	passkeyStore store.PasskeyStore,
+	tokenManager *tokens.TokenManager,
+	emailSender EmailSender,
+	runner BackgroundRunner,
+	config *config.Config,
+	logger logging.Logger,
+) *DataExportService {
+	return &DataExportService{
+		store:       exportStore,
+		accounts:    accountStore,
+		lists:       listStore,
+		reviews:     reviewStore,
+		passkeys:    passkeyStore,
+		tokens:      tokenManager,
+		emailSender: emailSender,
+		runner:      runner,
+		config:      config,
+		logger:      logger,
+	}
+}
+
+// RequestDataExport starts building an archive of the authenticated user's
+// data in the background. The download link is emailed once it is ready.
+func (s *DataExportService) RequestDataExport(ctx context.Context) (*model.DataExport, error) {
+	metaData := common.Envelop{
+		"op": "service.RequestDataExport",
+	}
+
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = user.UserID
+
+	export, err := s.store.CreateDataExport(ctx, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	metaData["export_id"] = export.ID
+
+	s.runner.Go("data_export", func(ctx context.Context) error {
+		return s.buildDataExport(ctx, export)
+	})
+
+	s.logger.Info("Data export queued", "meta", metaData)
+	return export, nil
+}
+
+// LatestDataExport returns the status of the authenticated user's last export
+func (s *DataExportService) LatestDataExport(ctx context.Context) (*model.DataExport, error) {
+	metaData := common.Envelop{
+		"op": "service.LatestDataExport",
+	}
+
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	return s.store.GetLatestDataExport(ctx, user.UserID)
+}
+
+// OpenDataExport opens the archive of a download link
+func (s *DataExportService) OpenDataExport(ctx context.Context, plainTextToken string) (*DataExportDownload, error) {
+	metaData := common.Envelop{
+		"op": "service.OpenDataExport",
+	}
+
+	plainTextToken = strings.TrimSpace(plainTextToken)
+	if !s.tokens.LooksLikeToken(plainTextToken) {
+		return nil, apperror.ErrDataExportLinkInvalid(nil, s.logger, metaData)
+	}
+	tokenHash := sha256.Sum256([]byte(plainTextToken))
+
+	export, err := s.store.FindDataExport(ctx, tokenHash[:])
+	if err != nil {
+		return nil, err
+	}
+	metaData["export_id"] = export.ID
+	metaData["user_id"] = export.UserID
+
+	if export.FilePath == nil {
+		return nil, apperror.ErrDataExportLinkInvalid(nil, s.logger, metaData)
+	}
+	file, err := os.Open(*export.FilePath)
+	if err != nil {
+		return nil, apperror.ErrDataExportLinkInvalid(err, s.logger, metaData)
+	}
+
+	download := &DataExportDownload{
+		File:     file,
+		FileName: fmt.Sprintf("movie-app-data-%s.zip", time.Now().UTC().Format(time.DateOnly)),
+		exportID: export.ID,
+	}
+	if export.Size != nil {
+		download.Size = *export.Size
+	}
+
+	s.logger.Info("Data export download started", "meta", metaData)
+	return download, nil
+}
+
+// CompleteDataExportDownload consumes the link of an archive that was sent in
+// full and deletes the archive
+func (s *DataExportService) CompleteDataExportDownload(ctx context.Context, download *DataExportDownload) error {
+	metaData := common.Envelop{
+		"op":        "service.CompleteDataExportDownload",
+		"export_id": download.exportID,
+	}
+
+	// The client may hang up right after the last byte, the link is still used
+	filePath, err := s.store.ConsumeDataExport(context.WithoutCancel(ctx), download.exportID)
+	if err != nil {
+		return err
+	}
+	if filePath == nil {
+		// Another download of the same link finished first and removed it
+		return nil
+	}
+
+	if err := os.Remove(*filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		// The cleanup job deletes downloaded archives it finds
+		s.logger.Warn("Failed to delete downloaded data export", err, "meta", metaData)
+	}
+	return nil
+}
+
+// buildDataExport writes the archive of a processing export, then emails its
+// download link. A failure is recorded on the export before it is returned.
+func (s *DataExportService) buildDataExport(ctx context.Context, export *model.DataExport) error {
+	metaData := common.Envelop{
+		"op":        "service.buildDataExport",
+		"export_id": export.ID,
+		"user_id":   export.UserID,
+	}
+
+	user, path, size, err := s.writeDataExport(ctx, export.UserID)
+	if err == nil {
+		err = s.publishDataExport(ctx, export.ID, user, path, size)
+	}
+	if err != nil {
+		if path != "" {
+			os.Remove(path) //nolint:errcheck // best effort, the archive is unusable
+		}
+		if ferr := s.store.FailDataExport(ctx, export.ID, "the archive could not be built, please request it again"); ferr != nil {
+			s.logger.Error("Failed to record data export failure", ferr, "meta", metaData)
+		}
+		return err
+	}
+
+	s.logger.Info("Data export ready", "meta", metaData)
+	return nil
+}
+
+// writeDataExport writes the user's archive into the export directory and
+// returns the user with its path and size
+func (s *DataExportService) writeDataExport(ctx context.Context, userID int) (*model.User, string, int64, error) {
+	if err := utils.EnsureDirExists(s.config.DataExport.Path); err != nil {
+		return nil, "", 0, err
+	}
+
+	user, err := s.accounts.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	f, err := os.CreateTemp(s.config.DataExport.Path, fmt.Sprintf("export-%d-*.zip", userID))
+	if err != nil {
+		return nil, "", 0, err
+	}
+	path := f.Name()
+
+	zw := zip.NewWriter(f)
+	err = s.writeArchive(ctx, zw, user)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, path, 0, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, path, 0, err
+	}
+	return user, path, info.Size(), nil
+}
+
+// publishDataExport creates the single-use download link of a written
+// archive, marks the export ready and emails the link
+func (s *DataExportService) publishDataExport(ctx context.Context, exportID int, user *model.User, path string, size int64) error {
+	metaData := common.Envelop{
+		"op":        "service.publishDataExport",
+		"export_id": exportID,
+		"user_id":   user.ID,
+	}
+
+	token, err := s.tokens.CreateRefreshToken(user.ID, s.config.DataExport.TTL, tokens.DataExportScope)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.FinishDataExport(ctx, exportID, path, size, token.Hash, token.Expiry); err != nil {
+		return err
+	}
+
+	if err := s.emailSender.SendDataExportEmail(user.Email, user.Name, token.Plaintext, token.Expiry); err != nil {
+		s.logger.Warn("Failed to send data export email. The archive expires unused.", err, "meta", metaData)
+	}
+	return nil
+}
+
+// writeArchive writes every part of the user's data as its own file
+func (s *DataExportService) writeArchive(ctx context.Context, zw *zip.Writer, user *model.User) error {
+	var err error
+	if user.Favorites, err = s.accounts.GetMovieList(ctx, store.RelFavorites, user.ID); err != nil {
+		return err
+	}
+	if user.Watchlist, err = s.accounts.GetMovieList(ctx, store.RelWatchlist, user.ID); err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "profile.json", user); err != nil {
+		return err
+	}
+
+	diary, err := s.fullDiary(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "diary.json", diary); err != nil {
+		return err
+	}
+
+	reviews, err := s.allReviews(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "reviews.json", reviews); err != nil {
+		return err
+	}
+
+	lists, err := s.lists.GetUserLists(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for i := range lists {
+		if lists[i].Items, err = s.lists.GetListItems(ctx, lists[i].ID); err != nil {
+			return err
+		}
+	}
+	if err := writeZipJSON(zw, "lists.json", lists); err != nil {
+		return err
+	}
+
+	credentials, err := s.passkeys.GetCredentials(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	passkeys := make([]model.PasskeyInfo, 0, len(credentials))
+	for _, cred := range credentials {
+		passkeys = append(passkeys, passkeyInfo(cred))
+	}
+	if err := writeZipJSON(zw, "passkeys.json", passkeys); err != nil {
+		return err
+	}
+
+	history, err := s.store.GetLoginHistory(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	sessions := []model.LoginRecord{}
+	for _, record := range history {
+		if record.Active {
+			sessions = append(sessions, record)
+		}
+	}
+	if err := writeZipJSON(zw, "sessions.json", sessions); err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "login_history.json", history); err != nil {
+		return err
+	}
+
+	return s.writeProfilePicture(zw, user)
+}
+
+// fullDiary reads every diary entry of the user, newest first
+func (s *DataExportService) fullDiary(ctx context.Context, userID int) ([]model.DiaryEntry, error) {
+	entries := []model.DiaryEntry{}
+	page := common.PageParams{Limit: dataExportPage}
+	for {
+		diary, err := s.accounts.GetDiaryPage(ctx, userID, common.DiaryRange{}, page)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, diary.Entries...)
+		if !diary.HasMore {
+			return entries, nil
+		}
+		page.Cursor = diary.NextCursor
+	}
+}
+
+// allReviews reads every review the user wrote, newest first
+func (s *DataExportService) allReviews(ctx context.Context, userID int) ([]model.Review, error) {
+	reviews := []model.Review{}
+	page := common.PageParams{Limit: dataExportPage}
+	for {
+		result, err := s.reviews.GetUserReviews(ctx, userID, page)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, result.Reviews...)
+		if !result.HasMore {
+			return reviews, nil
+		}
+		page.Cursor = result.NextCursor
+	}
+}
+
+// writeProfilePicture copies the uploaded profile picture, when there is one
+func (s *DataExportService) writeProfilePicture(zw *zip.Writer, user *model.User) error {
+	if user.ProfilePictureURL == nil || *user.ProfilePictureURL == "" {
+		return nil
+	}
+
+	name := filepath.Base(*user.ProfilePictureURL)
+	f, err := os.Open(filepath.Join(s.config.ProfilePicturePath, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			s.logger.Warn("Profile picture file missing from data export", err, "user_id", user.ID)
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	w, err := zw.Create("profile-picture" + filepath.Ext(name))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// passkeyInfo keeps the descriptive fields of a credential, not its public key
+func passkeyInfo(cred webauthn.Credential) model.PasskeyInfo {
+	info := model.PasskeyInfo{
+		CredentialID:    base64.RawURLEncoding.EncodeToString(cred.ID),
+		AAGUID:          hex.EncodeToString(cred.Authenticator.AAGUID),
+		AttestationType: cred.AttestationType,
+		Attachment:      string(cred.Authenticator.Attachment),
+		Transports:      make([]string, 0, len(cred.Transport)),
+		SignCount:       cred.Authenticator.SignCount,
+		BackupEligible:  cred.Flags.BackupEligible,
+		BackupState:     cred.Flags.BackupState,
+	}
+	for _, transport := range cred.Transport {
+		info.Transports = append(info.Transports, string(transport))
+	}
+	return info
+}
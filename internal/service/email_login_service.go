@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"time"
+
+	"multipass/internal/auth/tokens"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/validator"
+)
+
+// emailLoginResendAfter is how long a new sign-in email waits after the last one
+const emailLoginResendAfter = time.Minute
+
+// RequestEmailLoginService emails a sign-in link and a code to a registered
+// address. Unknown addresses succeed silently so accounts can't be enumerated.
+func (s *AccountService) RequestEmailLoginService(ctx context.Context, req *common.SendOTPRequest) error {
+	op := "service.RequestEmailLoginService"
+	meta := common.Envelop{"op": op}
+
+	email, err := validator.ValidateEmail(&req.Email)
+	if err != nil {
+		return err
+	}
+	meta["email"] = email
+
+	user, err := s.store.FindUserByEmail(ctx, email)
+	if err != nil {
+		s.Logger.Info("Email login requested for unknown email (silent fail)", meta)
+		return nil
+	}
+	meta["user_id"] = user.ID
+
+	ttl := s.config.EmailLogin.TTL
+
+	// Don't flood the inbox on repeated requests. Wrong guesses at a code
+	// still valid carry over to the new one, asking again doesn't reset them.
+	attempts := 0
+	if last, err := s.tokenStore.GetUserAuthToken(ctx, user.ID, tokens.LoginCodeScope); err == nil {
+		if time.Until(last.Expiry) > ttl-emailLoginResendAfter {
+			s.Logger.Info("Email login requested again too soon (silent fail)", meta)
+			return nil
+		}
+		if time.Now().Before(last.Expiry) {
+			attempts = last.Attempts
+		}
+	}
+
+	// One sign-in email is valid at a time
+	if _, err := s.tokenStore.DeleteUserTokens(ctx, user.ID, tokens.MagicLinkScope, tokens.LoginCodeScope); err != nil {
+		return err
+	}
+
+	link, err := s.generateAndSaveAuthToken(ctx, user, tokens.MagicLinkScope, ttl)
+	if err != nil {
+		s.Logger.Error("Failed to generate and save magic link token", err, meta)
+		return apperror.ErrInternalServer(err, s.Logger, meta)
+	}
+
+	code, err := s.tokens.CreateLoginCode(user.ID, ttl)
+	if err != nil {
+		return apperror.ErrInternalServer(err, s.Logger, meta)
+	}
+	code.Attempts = attempts
+	if err := s.tokenStore.SaveAuthToken(ctx, code); err != nil {
+		return err
+	}
+
+	if err := s.emailSender.SendLoginEmail(user.Email, user.Name, link.Plaintext, code.Plaintext, link.Expiry); err != nil {
+		s.Logger.Warn("Failed to send email login. Tokens remain in DB.", err, meta)
+	}
+
+	s.Logger.Info("Email login initiated and email dispatched", meta)
+	return nil
+}
+
+// MagicLinkLoginService signs the user in from the token of an emailed link
+func (s *AccountService) MagicLinkLoginService(ctx context.Context, plainTextToken string) (*common.AuthResult, error) {
+	op := "service.MagicLinkLoginService"
+
+	var result *common.AuthResult
+	loginAction := func(ctx context.Context, userID int) error {
+		user, err := s.store.FindUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		// The code sent with the link is used up too
+		if _, err := s.tokenStore.DeleteUserTokens(ctx, userID, tokens.LoginCodeScope); err != nil {
+			return err
+		}
+
+		result, err = s.completeLogin(ctx, user)
+		return err
+	}
+
+	if err := s.processAuthToken(ctx, plainTextToken, tokens.MagicLinkScope, op, loginAction); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// EmailCodeLoginService signs the user in with the code of a sign-in email,
+// typed in on any device. Wrong codes are limited per email.
+func (s *AccountService) EmailCodeLoginService(ctx context.Context, req *common.OTPRequest) (*common.AuthResult, error) {
+	meta := common.Envelop{"op": "service.EmailCodeLoginService"}
+
+	email, err := validator.ValidateEmail(&req.Email)
+	if err != nil {
+		return nil, err
+	}
+	meta["email"] = email
+
+	// Unknown addresses fail like wrong codes
+	user, err := s.store.FindUserByEmail(ctx, email)
+	if err != nil {
+		return nil, apperror.ErrInvalidOTP(nil, s.Logger, meta)
+	}
+	meta["user_id"] = user.ID
+
+	code, err := s.tokenStore.GetUserAuthToken(ctx, user.ID, tokens.LoginCodeScope)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(code.Expiry) {
+		if _, err := s.tokenStore.DeleteUserTokens(ctx, user.ID, tokens.MagicLinkScope, tokens.LoginCodeScope); err != nil {
+			s.Logger.Warn("Failed to delete expired email login", err, meta)
+		}
+		return nil, apperror.ErrTokenExpired(nil, s.Logger, meta)
+	}
+
+	// Count the attempt before checking the code, concurrent guesses included
+	attempts, allowed, err := s.tokenStore.RecordTokenAttempt(ctx, code.ID, s.config.EmailLogin.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, apperror.ErrTooManyOTPAttempts(nil, s.Logger, meta)
+	}
+
+	if !hmac.Equal(s.tokens.HashLoginCode(user.ID, req.OTP), code.Hash) {
+		meta["attempts_left"] = s.config.EmailLogin.MaxAttempts - attempts
+		return nil, apperror.ErrInvalidOTP(nil, s.Logger, meta)
+	}
+
+	// Using up the code and its link; only the request that deletes them signs in
+	deleted, err := s.tokenStore.DeleteUserTokens(ctx, user.ID, tokens.MagicLinkScope, tokens.LoginCodeScope)
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, apperror.ErrInvalidOTP(nil, s.Logger, meta)
+	}
+
+	return s.completeLogin(ctx, user)
+}
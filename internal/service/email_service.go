@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/smtp"
+	"time"
 
 	"multipass/config"
 	"multipass/pkg/apperror"
@@ -20,6 +21,10 @@ type Sender interface {
 type EmailSender interface {
 	SendVerificationEmail(toEmail, userName, tokenPlaintext string) error
 	SendPasswordResetEmail(toEmail, userName, tokenPlaintext string) error
+	SendDataExportEmail(toEmail, userName, tokenPlaintext string, expires time.Time) error
+	SendAccountDeletionEmail(toEmail, userName, tokenPlaintext string, purgeAt time.Time) error
+	SendTokenReuseAlertEmail(toEmail, userName, deviceLabel string, when time.Time) error
+	SendLoginEmail(toEmail, userName, linkToken, code string, expires time.Time) error
 }
 
 type EmailService struct {
@@ -209,3 +214,118 @@ func (e *EmailService) SendPasswordResetEmail(toEmail, userName, tokenPlaintext
 	}
 	return nil
 }
+
+// SendDataExportEmail sends the single-use link to download the user's data archive.
+func (e *EmailService) SendDataExportEmail(toEmail, userName, tokenPlaintext string, expires time.Time) error {
+	op := "email_service.SendDataExportEmail"
+	metaData := common.Envelop{"op": op, "to_email": toEmail}
+
+	downloadLink := fmt.Sprintf("%s/api/account/data-export/download?token=%s", e.FrontendURL, tokenPlaintext)
+
+	subject := "Your Movie App data is ready to download"
+
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<p>Hello %s,</p>
+			<p>The copy of your account data you requested is ready. Click the link below to download it as a ZIP archive:</p>
+			<p><a href="%s">Download Your Data</a></p>
+			<p>The link works once and expires on %s UTC, after which the archive is deleted.</p>
+			<p>If you did not request your data, please change your password.</p>
+			<p>Best regards,</p>
+			<p>The Movie App Team</p>
+		</body>
+		</html>
+	`, userName, downloadLink, expires.UTC().Format("January 2, 2006 at 15:04"))
+
+	if err := e.send(toEmail, subject, body, metaData); err != nil {
+		return apperror.ErrEmailSendFailed(err, e.logger, metaData)
+	}
+	return nil
+}
+
+// SendAccountDeletionEmail confirms a deletion and sends the link to cancel it before purgeAt.
+func (e *EmailService) SendAccountDeletionEmail(toEmail, userName, tokenPlaintext string, purgeAt time.Time) error {
+	op := "email_service.SendAccountDeletionEmail"
+	metaData := common.Envelop{"op": op, "to_email": toEmail}
+
+	cancelLink := fmt.Sprintf("%s/cancel-deletion?token=%s", e.FrontendURL, tokenPlaintext)
+
+	subject := "Your Movie App account has been deleted"
+
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<p>Hello %s,</p>
+			<p>Your account has been deleted and you have been signed out of every device.</p>
+			<p>Your data will be permanently erased on %s UTC. Until then, you can restore your account:</p>
+			<p><a href="%s">Cancel Account Deletion</a></p>
+			<p>If you did not delete your account, restore it and change your password.</p>
+			<p>Best regards,</p>
+			<p>The Movie App Team</p>
+		</body>
+		</html>
+	`, userName, purgeAt.UTC().Format("January 2, 2006 at 15:04"), cancelLink)
+
+	if err := e.send(toEmail, subject, body, metaData); err != nil {
+		return apperror.ErrEmailSendFailed(err, e.logger, metaData)
+	}
+	return nil
+}
+
+// SendTokenReuseAlertEmail warns the user that a stolen session was detected and signed out.
+func (e *EmailService) SendTokenReuseAlertEmail(toEmail, userName, deviceLabel string, when time.Time) error {
+	op := "email_service.SendTokenReuseAlertEmail"
+	metaData := common.Envelop{"op": op, "to_email": toEmail}
+
+	subject := "Security alert for your Movie App account"
+
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<p>Hello %s,</p>
+			<p>On %s UTC, an old sign-in token of your account was used again from %s. This usually means it was copied from one of your devices.</p>
+			<p>To protect your account we signed that session out. If you don't recognize this, change your password and review your signed-in devices.</p>
+			<p>Best regards,</p>
+			<p>The Movie App Team</p>
+		</body>
+		</html>
+	`, userName, when.UTC().Format("January 2, 2006 at 15:04"), deviceLabel)
+
+	if err := e.send(toEmail, subject, body, metaData); err != nil {
+		return apperror.ErrEmailSendFailed(err, e.logger, metaData)
+	}
+	return nil
+}
+
+// SendLoginEmail sends a passwordless sign-in link, and a code to type in on
+// another device.
+func (e *EmailService) SendLoginEmail(toEmail, userName, linkToken, code string, expires time.Time) error {
+	op := "email_service.SendLoginEmail"
+	metaData := common.Envelop{"op": op, "to_email": toEmail}
+
+	loginLink := fmt.Sprintf("%s/login/email?token=%s", e.FrontendURL, linkToken)
+
+	subject := "Your Movie App sign-in link"
+
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<p>Hello %s,</p>
+			<p>Click the link below to sign in to your account:</p>
+			<p><a href="%s">Sign In</a></p>
+			<p>Signing in on another device? Enter this code instead:</p>
+			<p style="font-size: 24px; letter-spacing: 4px;"><strong>%s</strong></p>
+			<p>The link and the code work once and expire on %s UTC.</p>
+			<p>If you did not try to sign in, you can ignore this email.</p>
+			<p>Best regards,</p>
+			<p>The Movie App Team</p>
+		</body>
+		</html>
+	`, userName, loginLink, code, expires.UTC().Format("January 2, 2006 at 15:04"))
+
+	if err := e.send(toEmail, subject, body, metaData); err != nil {
+		return apperror.ErrEmailSendFailed(err, e.logger, metaData)
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"multipass/internal/auth/tokens"
+	"multipass/internal/model"
+	"multipass/internal/store"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/ctxutils"
+	"multipass/pkg/logging"
+	"multipass/pkg/validator"
+)
+
+type UserListService interface {
+	UserLists(ctx context.Context) ([]model.UserList, error)
+	CreateList(ctx context.Context, req *common.ListRequest) (*model.UserList, error)
+	UpdateList(ctx context.Context, listID int, req *common.ListRequest) (*model.UserList, error)
+	DeleteList(ctx context.Context, listID int) error
+	GetList(ctx context.Context, listID int) (*model.UserList, error)
+	SharedList(ctx context.Context, token string) (*model.UserList, error)
+	AddItem(ctx context.Context, listID int, req *common.ListItemRequest) (*model.ListItem, error)
+	UpdateItem(ctx context.Context, listID int, movieID int, req *common.ListItemUpdateRequest) (*model.ListItem, error)
+	RemoveItem(ctx context.Context, listID int, movieID int) error
+	CreateShareLink(ctx context.Context, listID int) (*common.ListShareResponse, error)
+	RevokeShareLink(ctx context.Context, listID int) error
+}
+
+type ListService struct {
+	store       store.ListStore
+	tokens      *tokens.TokenManager
+	frontendURL string
+	logger      logging.Logger
+}
+
+func NewListService(listStore store.ListStore, tokenManager *tokens.TokenManager, frontendURL string, logger logging.Logger) *ListService {
+	return &ListService{
+		store:       listStore,
+		tokens:      tokenManager,
+		frontendURL: frontendURL,
+		logger:      logger,
+	}
+}
+
+// UserLists returns the authenticated user's lists without their items
+func (s *ListService) UserLists(ctx context.Context) ([]model.UserList, error) {
+	metaData := common.Envelop{
+		"op": "service.UserLists",
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.GetUserLists(ctx, user.UserID)
+}
+
+// CreateList creates an empty list for the authenticated user
+func (s *ListService) CreateList(ctx context.Context, req *common.ListRequest) (*model.UserList, error) {
+	metaData := common.Envelop{
+		"op": "service.CreateList",
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := validator.SanitizeListRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.store.CreateList(ctx, user.UserID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	metaData["list_id"] = list.ID
+	s.logger.Info("List created", "meta", metaData)
+	return list, nil
+}
+
+// UpdateList renames one of the user's lists and changes its description and visibility
+func (s *ListService) UpdateList(ctx context.Context, listID int, req *common.ListRequest) (*model.UserList, error) {
+	metaData := common.Envelop{
+		"op":      "service.UpdateList",
+		"list_id": listID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := validator.SanitizeListRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.UpdateList(ctx, user.UserID, listID, input)
+}
+
+// DeleteList removes one of the user's lists
+func (s *ListService) DeleteList(ctx context.Context, listID int) error {
+	metaData := common.Envelop{
+		"op":      "service.DeleteList",
+		"list_id": listID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.DeleteList(ctx, user.UserID, listID); err != nil {
+		return err
+	}
+
+	s.logger.Info("List deleted", "meta", metaData)
+	return nil
+}
+
+// GetList returns a list with its items. Private lists are only visible to
+// their owner; everyone else gets a not found error.
+func (s *ListService) GetList(ctx context.Context, listID int) (*model.UserList, error) {
+	metaData := common.Envelop{
+		"op":      "service.GetList",
+		"list_id": listID,
+	}
+
+	list, err := s.store.GetListByID(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !list.IsPublic {
+		user, err := ctxutils.GetUser(ctx)
+		if err != nil || user.UserID != list.UserID {
+			metaData["warning"] = "private list requested by someone else"
+			return nil, apperror.ErrListNotFound(nil, s.logger, metaData)
+		}
+	}
+
+	return s.withItems(ctx, list)
+}
+
+// SharedList returns the list a share token was issued for, public or not
+func (s *ListService) SharedList(ctx context.Context, token string) (*model.UserList, error) {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(token)))
+
+	list, err := s.store.GetListByShareHash(ctx, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withItems(ctx, list)
+}
+
+// AddItem appends a movie to one of the user's lists
+func (s *ListService) AddItem(ctx context.Context, listID int, req *common.ListItemRequest) (*model.ListItem, error) {
+	metaData := common.Envelop{
+		"op":      "service.AddListItem",
+		"list_id": listID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	movieID, note, err := validator.SanitizeListItemRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.AddListItem(ctx, user.UserID, listID, movieID, note)
+}
+
+// UpdateItem edits the note of a list item and/or moves it within the list
+func (s *ListService) UpdateItem(ctx context.Context, listID int, movieID int, req *common.ListItemUpdateRequest) (*model.ListItem, error) {
+	metaData := common.Envelop{
+		"op":       "service.UpdateListItem",
+		"list_id":  listID,
+		"movie_id": movieID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := validator.SanitizeListItemUpdate(req, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.UpdateListItem(ctx, user.UserID, listID, movieID, input)
+}
+
+// RemoveItem removes a movie from one of the user's lists
+func (s *ListService) RemoveItem(ctx context.Context, listID int, movieID int) error {
+	metaData := common.Envelop{
+		"op":       "service.RemoveListItem",
+		"list_id":  listID,
+		"movie_id": movieID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return err
+	}
+
+	return s.store.RemoveListItem(ctx, user.UserID, listID, movieID)
+}
+
+// CreateShareLink issues a read-only share token for one of the user's lists.
+// Only its hash is stored, so the link is shown once; creating a new one
+// invalidates the previous link.
+func (s *ListService) CreateShareLink(ctx context.Context, listID int) (*common.ListShareResponse, error) {
+	metaData := common.Envelop{
+		"op":      "service.CreateShareLink",
+		"list_id": listID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.tokens.CreateRefreshToken(user.UserID, 0, tokens.ListShareScope)
+	if err != nil {
+		return nil, apperror.ErrInternalServer(err, s.logger, metaData)
+	}
+
+	if err := s.store.SetListShareHash(ctx, user.UserID, listID, token.Hash); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("List share link created", "meta", metaData)
+	return &common.ListShareResponse{
+		Success:  true,
+		Token:    token.Plaintext,
+		ShareURL: fmt.Sprintf("%s/lists/shared/%s", s.frontendURL, token.Plaintext),
+	}, nil
+}
+
+// RevokeShareLink disables the share link of one of the user's lists
+func (s *ListService) RevokeShareLink(ctx context.Context, listID int) error {
+	metaData := common.Envelop{
+		"op":      "service.RevokeShareLink",
+		"list_id": listID,
+	}
+
+	user, err := s.currentUser(ctx, metaData)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.SetListShareHash(ctx, user.UserID, listID, nil); err != nil {
+		return err
+	}
+
+	s.logger.Info("List share link revoked", "meta", metaData)
+	return nil
+}
+
+func (s *ListService) withItems(ctx context.Context, list *model.UserList) (*model.UserList, error) {
+	items, err := s.store.GetListItems(ctx, list.ID)
+	if err != nil {
+		return nil, err
+	}
+	list.Items = items
+	return list, nil
+}
+
+func (s *ListService) currentUser(ctx context.Context, metaData common.Envelop) (*common.UserContext, error) {
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = user.UserID
+	return user, nil
+}
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"multipass/internal/auth/hashing"
+	"multipass/internal/auth/tokens"
+	"multipass/internal/auth/totp"
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/ctxutils"
+)
+
+// qrCodeSize is the width and height of enrollment QR codes, in pixels
+const qrCodeSize = 256
+
+// startMFAChallenge answers a correct password with a short-lived token that
+// MFALoginService or FinishPasskeyMFAService exchanges, with the second factor,
+// for the real token pair.
+func (s *AccountService) startMFAChallenge(ctx context.Context, user *model.User, policy *model.MFAPolicy) (*common.AuthResult, error) {
+	metaData := common.Envelop{
+		"op":      "service.startMFAChallenge",
+		"user_id": user.ID,
+	}
+
+	pending, err := s.tokens.CreateRefreshToken(user.ID, s.config.MFA.ChallengeTTL, tokens.OTPScope)
+	if err != nil {
+		return nil, apperror.ErrInternalServer(err, s.logger, metaData)
+	}
+
+	// Only the hash of the pending token is stored, like every other token
+	challenge := &model.OTPVerification{
+		UserID:    int64(user.ID),
+		Code:      hex.EncodeToString(pending.Hash),
+		Purpose:   model.OTPPurpose2FA,
+		ExpiresAt: pending.Expiry,
+	}
+	if err := s.mfaStore.SaveOTPVerification(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Password accepted, waiting for the second factor", metaData)
+
+	return &common.AuthResult{
+		User:       user,
+		MFAToken:   pending.Plaintext,
+		MFAMethods: policy.Methods(),
+		ExpiresAt:  pending.Expiry,
+	}, nil
+}
+
+// getMFAChallenge returns the unexpired login waiting for a second factor
+// that mfaToken was issued for
+func (s *AccountService) getMFAChallenge(ctx context.Context, mfaToken string, metaData common.Envelop) (*model.OTPVerification, error) {
+	tokenHash := sha256.Sum256([]byte(strings.TrimSpace(mfaToken)))
+	challenge, err := s.mfaStore.GetOTPVerification(ctx, hex.EncodeToString(tokenHash[:]), model.OTPPurpose2FA)
+	if err != nil {
+		return nil, err
+	}
+	metaData["user_id"] = challenge.UserID
+
+	if time.Now().After(challenge.ExpiresAt) {
+		metaData["context"] = "Expired"
+		return nil, apperror.ErrMFAChallengeInvalid(nil, s.logger, metaData)
+	}
+	return challenge, nil
+}
+
+// recordMFAAttempt counts an attempt at a second factor before it is checked,
+// concurrent guesses included
+func (s *AccountService) recordMFAAttempt(ctx context.Context, challenge *model.OTPVerification, metaData common.Envelop) error {
+	attempts, allowed, err := s.mfaStore.RecordOTPAttempt(ctx, challenge.ID, s.config.MFA.MaxAttempts)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return apperror.ErrTooManyOTPAttempts(nil, s.logger, metaData)
+	}
+	challenge.Attempts = attempts
+	return nil
+}
+
+// finishMFAChallenge uses up a login whose second factor was checked and
+// issues the token pair
+func (s *AccountService) finishMFAChallenge(ctx context.Context, challenge *model.OTPVerification, metaData common.Envelop) (*common.AuthResult, error) {
+	// A pending token completes a single login
+	consumed, err := s.mfaStore.ConsumeOTPVerification(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		metaData["context"] = "Already used"
+		return nil, apperror.ErrMFAChallengeInvalid(nil, s.logger, metaData)
+	}
+
+	user, err := s.store.FindUserByID(ctx, int(challenge.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	jwt, refreshToken, err := s.generateAndSaveTokens(ctx, user, nil)
+	if err != nil {
+		metaData["context"] = "generateAndSaveTokens"
+		return nil, apperror.ErrInternalServer(err, s.Logger, metaData)
+	}
+
+	return &common.AuthResult{
+		User:         user,
+		JWT:          jwt,
+		RefreshToken: refreshToken.Plaintext,
+		ExpiresAt:    refreshToken.Expiry,
+	}, nil
+}
+
+// MFALoginService completes a password login with an authenticator app or
+// recovery code and issues the token pair.
+func (s *AccountService) MFALoginService(ctx context.Context, req *common.MFALoginRequest) (*common.AuthResult, error) {
+	metaData := common.Envelop{
+		"op": "service.MFALoginService",
+	}
+
+	challenge, err := s.getMFAChallenge(ctx, req.MFAToken, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.recordMFAAttempt(ctx, challenge, metaData); err != nil {
+		return nil, err
+	}
+
+	valid, err := s.verifySecondFactor(ctx, int(challenge.UserID), req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		metaData["attempts_left"] = s.config.MFA.MaxAttempts - challenge.Attempts
+		return nil, apperror.ErrInvalidOTP(nil, s.logger, metaData)
+	}
+
+	return s.finishMFAChallenge(ctx, challenge, metaData)
+}
+
+// TOTPStatusService tells whether the user has two-factor authentication on
+func (s *AccountService) TOTPStatusService(ctx context.Context) (*model.TOTPStatus, error) {
+	metaData := common.Envelop{
+		"op": "service.TOTPStatusService",
+	}
+
+	userCtx, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	enabled, err := s.mfaStore.IsTOTPEnabled(ctx, userCtx.UserID)
+	if err != nil || !enabled {
+		return &model.TOTPStatus{}, err
+	}
+
+	secret, err := s.mfaStore.GetTOTP(ctx, userCtx.UserID)
+	if err != nil {
+		return nil, err
+	}
+	left, err := s.mfaStore.CountRecoveryCodes(ctx, userCtx.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPStatus{
+		Enabled:           true,
+		TimeEnabled:       secret.TimeEnabled,
+		TimeLastUsed:      secret.TimeLastUsed,
+		RecoveryCodesLeft: left,
+	}, nil
+}
+
+// EnrollTOTPService creates a pending authenticator app secret. It is enabled
+// by ConfirmTOTPService once the app shows a correct code.
+func (s *AccountService) EnrollTOTPService(ctx context.Context) (*model.TOTPEnrollment, error) {
+	metaData := common.Envelop{
+		"op": "service.EnrollTOTPService",
+	}
+
+	userCtx, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = userCtx.UserID
+
+	user, err := s.store.FindUserByID(ctx, userCtx.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, apperror.ErrInternalServer(err, s.logger, metaData)
+	}
+	if err := s.mfaStore.SaveTOTPSecret(ctx, user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	uri := totp.URI(s.config.MFA.Issuer, user.Email, secret)
+	qrCode, err := totp.QRCode(uri, qrCodeSize)
+	if err != nil {
+		return nil, apperror.ErrInternalServer(err, s.logger, metaData)
+	}
+
+	return &model.TOTPEnrollment{
+		Secret: secret,
+		URI:    uri,
+		QRCode: qrCode,
+	}, nil
+}
+
+// ConfirmTOTPService enables the pending secret once the user enters a code
+// from their app, and returns the recovery codes. They are only shown now.
+func (s *AccountService) ConfirmTOTPService(ctx context.Context, req *common.VerifyOTPRequest) ([]string, error) {
+	metaData := common.Envelop{
+		"op": "service.ConfirmTOTPService",
+	}
+
+	userCtx, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = userCtx.UserID
+
+	if err := s.recordSensitiveOTPAttempt(ctx, userCtx.UserID, metaData); err != nil {
+		return nil, err
+	}
+
+	secret, err := s.mfaStore.GetTOTP(ctx, userCtx.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if secret.TimeEnabled != nil {
+		return nil, apperror.ErrTOTPAlreadyEnabled(nil, s.logger, metaData)
+	}
+
+	step, ok := totp.Validate(secret.Secret, req.Code, time.Now())
+	if !ok {
+		return nil, apperror.ErrInvalidOTP(nil, s.logger, metaData)
+	}
+
+	codes, hashes, err := totp.GenerateRecoveryCodes(totp.RecoveryCodeCount)
+	if err != nil {
+		return nil, apperror.ErrInternalServer(err, s.logger, metaData)
+	}
+	if err := s.mfaStore.EnableTOTP(ctx, userCtx.UserID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaStore.DeleteOTPVerification(ctx, userCtx.UserID, model.OTPPurposeSensitiveAction); err != nil {
+		s.logger.Warn("Failed to reset the attempts after enabling two-factor authentication", err, metaData)
+	}
+
+	s.logger.Info("Two-factor authentication enabled", metaData)
+	return codes, nil
+}
+
+// DisableTOTPService turns two-factor authentication off after re-checking the
+// password and a current code
+func (s *AccountService) DisableTOTPService(ctx context.Context, req *common.TOTPDisableRequest) error {
+	metaData := common.Envelop{
+		"op": "service.DisableTOTPService",
+	}
+
+	userCtx, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = userCtx.UserID
+
+	if err := s.recordSensitiveOTPAttempt(ctx, userCtx.UserID, metaData); err != nil {
+		return err
+	}
+
+	user, err := s.store.FindUserByID(ctx, userCtx.UserID)
+	if err != nil {
+		return err
+	}
+
+	// A stolen access token alone must not turn the second factor off
+	if !hashing.IsPasswordMatch(strings.TrimSpace(req.Password), user.PasswordHashed) {
+		metaData["context"] = "Reauthentication"
+		return apperror.ErrPasswordMismatch(errors.New("invalid credentials"), s.logger, metaData)
+	}
+
+	valid, err := s.verifySecondFactor(ctx, user.ID, req.Code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return apperror.ErrInvalidOTP(nil, s.logger, metaData)
+	}
+
+	if err := s.mfaStore.DisableTOTP(ctx, user.ID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Two-factor authentication disabled", metaData)
+	return nil
+}
+
+// verifySecondFactor checks an authenticator app code or, failing that, uses
+// up a recovery code
+func (s *AccountService) verifySecondFactor(ctx context.Context, userID int, code string) (bool, error) {
+	secret, err := s.mfaStore.GetTOTP(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if secret.TimeEnabled == nil {
+		return false, nil
+	}
+
+	if step, ok := totp.Validate(secret.Secret, code, time.Now()); ok {
+		// Each code works once, even while it is still displayed
+		return s.mfaStore.UseTOTPStep(ctx, userID, step)
+	}
+
+	if totp.LooksLikeRecoveryCode(code) {
+		return s.mfaStore.UseRecoveryCode(ctx, userID, totp.HashRecoveryCode(code))
+	}
+
+	return false, nil
+}
+
+// recordSensitiveOTPAttempt limits the codes a signed-in user can try to
+// enable or disable two-factor authentication
+func (s *AccountService) recordSensitiveOTPAttempt(ctx context.Context, userID int, metaData common.Envelop) error {
+	attempts, err := s.mfaStore.RecordOTPWindowAttempt(ctx, userID, model.OTPPurposeSensitiveAction, s.config.MFA.ChallengeTTL)
+	if err != nil {
+		return err
+	}
+	if attempts > s.config.MFA.MaxAttempts {
+		return apperror.ErrTooManyOTPAttempts(nil, s.logger, metaData)
+	}
+	return nil
+}
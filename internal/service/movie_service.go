@@ -3,13 +3,13 @@ package service
 import (
 	"context"
 
-	"multipass/internal/model"
 	"multipass/internal/store"
+	"multipass/pkg/common"
 	"multipass/pkg/logging"
 )
 
 type MovieService interface {
-	MovieSearch(ctx context.Context, query string, order string, genre *int) ([]model.Movie, error)
+	MovieSearch(ctx context.Context, query string, order string, genre *int, page common.PageParams) (*common.MoviePage, error)
 }
 
 type movieService struct {
@@ -25,13 +25,13 @@ func NewMovieServie(ms store.MovieStore, logger logging.Logger) MovieService {
 }
 
 // SearchMovies implements the business logic for movie search.
-func (s *movieService) MovieSearch(ctx context.Context, query string, order string, genre *int) ([]model.Movie, error) {
+func (s *movieService) MovieSearch(ctx context.Context, query string, order string, genre *int, page common.PageParams) (*common.MoviePage, error) {
 	// Example: Add business logic here
 	// Maybe log search queries for analytics:
 	// s.analyticsClient.LogSearch(ctx, query) // If you add an analytics dependency
 
 	// Call the store layer for data
-	movies, err := s.store.SearchMovieByName(ctx, query, order, genre)
+	movies, err := s.store.SearchMovieByName(ctx, query, order, genre, page)
 	if err != nil {
 		// Business logic for handling store errors could go here if needed,
 		// but typically, the service passes through data errors or translates them.
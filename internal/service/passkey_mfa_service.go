@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"multipass/internal/auth/hashing"
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/ctxutils"
+
+	"github.com/go-webauthn/webauthn/protocol"
+)
+
+// passkeyMFASessionPrefix keys the ceremony of a login's passkey step by its
+// challenge, so the client only needs the mfa token to finish it
+const passkeyMFASessionPrefix = "mfa:"
+
+// PasskeyMFAStatusService tells whether password logins require a passkey
+func (s *AccountService) PasskeyMFAStatusService(ctx context.Context) (*model.PasskeyMFAStatus, error) {
+	metaData := common.Envelop{
+		"op": "service.PasskeyMFAStatusService",
+	}
+
+	userCtx, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	policy, err := s.mfaStore.GetMFAPolicy(ctx, userCtx.UserID)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := s.passkeyStore.GetCredentials(ctx, userCtx.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PasskeyMFAStatus{
+		Required: policy.Passkey,
+		Passkeys: len(credentials),
+	}, nil
+}
+
+// SetPasskeyMFAService makes password logins require a passkey, or stop
+// requiring one, after re-checking the password
+func (s *AccountService) SetPasskeyMFAService(ctx context.Context, req *common.PasskeyMFAPolicyRequest) error {
+	metaData := common.Envelop{
+		"op": "service.SetPasskeyMFAService",
+	}
+
+	userCtx, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = userCtx.UserID
+
+	if req.Required == nil {
+		return apperror.ErrMissingRequiredField("required", nil, s.logger, metaData)
+	}
+	metaData["required"] = *req.Required
+
+	user, err := s.store.FindUserByID(ctx, userCtx.UserID)
+	if err != nil {
+		return err
+	}
+
+	// A stolen access token alone must not change how the account signs in
+	if !hashing.IsPasswordMatch(strings.TrimSpace(req.Password), user.PasswordHashed) {
+		metaData["context"] = "Reauthentication"
+		return apperror.ErrPasswordMismatch(errors.New("invalid credentials"), s.logger, metaData)
+	}
+
+	// Requiring a passkey the user doesn't have would lock them out
+	if *req.Required {
+		credentials, err := s.passkeyStore.GetCredentials(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		if len(credentials) == 0 {
+			return apperror.ErrNoPasskeys(nil, s.logger, metaData)
+		}
+	}
+
+	if err := s.mfaStore.SetPasskeyMFARequired(ctx, user.ID, *req.Required); err != nil {
+		return err
+	}
+
+	s.logger.Info("Passkey second step updated", metaData)
+	return nil
+}
+
+// BeginPasskeyMFAService starts the passkey step of a password login. Only
+// the passkeys of the user who entered the password are allowed.
+func (s *AccountService) BeginPasskeyMFAService(ctx context.Context, mfaToken string) (*protocol.CredentialAssertion, error) {
+	metaData := common.Envelop{
+		"op": "service.BeginPasskeyMFAService",
+	}
+
+	challenge, err := s.getMFAChallenge(ctx, mfaToken, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.passkeyMFAUser(ctx, int(challenge.UserID), metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	// BeginLogin fills allowCredentials with the user's own passkeys
+	options, session, err := s.webauthn.BeginLogin(user)
+	if err != nil {
+		metaData["context"] = "BeginLogin"
+		return nil, apperror.ErrInternalServer(err, s.logger, metaData)
+	}
+
+	// Beginning again replaces the ceremony, the challenge's attempts still count
+	if err := s.ceremonies.Save(ctx, passkeyMFASessionPrefix+challenge.Code, *session); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// FinishPasskeyMFAService checks the passkey assertion in the body of r and
+// completes the password login it was started for.
+func (s *AccountService) FinishPasskeyMFAService(ctx context.Context, r *http.Request, mfaToken string) (*common.AuthResult, error) {
+	metaData := common.Envelop{
+		"op": "service.FinishPasskeyMFAService",
+	}
+
+	challenge, err := s.getMFAChallenge(ctx, mfaToken, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.recordMFAAttempt(ctx, challenge, metaData); err != nil {
+		return nil, err
+	}
+
+	// Each ceremony is checked once, pass or fail
+	session, ok, err := s.ceremonies.Take(ctx, passkeyMFASessionPrefix+challenge.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		metaData["context"] = "No passkey ceremony"
+		return nil, apperror.ErrPasskeyNotVerified(nil, s.logger, metaData)
+	}
+
+	user, err := s.passkeyMFAUser(ctx, int(challenge.UserID), metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.webauthn.FinishLogin(user, session, r)
+	if err != nil {
+		metaData["attempts_left"] = s.config.MFA.MaxAttempts - challenge.Attempts
+		return nil, apperror.ErrPasskeyNotVerified(err, s.logger, metaData)
+	}
+	if credential.Authenticator.CloneWarning {
+		metaData["context"] = "Clone warning"
+		return nil, apperror.ErrPasskeyNotVerified(nil, s.logger, metaData)
+	}
+
+	// A counter that didn't go up means a cloned key or a replayed assertion
+	updated, err := s.passkeyStore.UpdateCredentialUsage(ctx, int(challenge.UserID), credential)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		metaData["context"] = "Sign count did not increase"
+		return nil, apperror.ErrPasskeyNotVerified(nil, s.logger, metaData)
+	}
+
+	return s.finishMFAChallenge(ctx, challenge, metaData)
+}
+
+// passkeyMFAUser loads the user of a login waiting for its passkey step,
+// checking the account still requires one
+func (s *AccountService) passkeyMFAUser(ctx context.Context, userID int, metaData common.Envelop) (*model.PasskeyUser, error) {
+	policy, err := s.mfaStore.GetMFAPolicy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Passkey {
+		return nil, apperror.ErrPasskeyMFAUnavailable(nil, s.logger, metaData)
+	}
+
+	user, err := s.passkeyStore.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(user.Credientials) == 0 {
+		return nil, apperror.ErrPasskeyMFAUnavailable(nil, s.logger, metaData)
+	}
+	return user, nil
+}
@@ -2,17 +2,23 @@ package service
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"multipass/internal/auth/aaguid"
 	"multipass/internal/auth/tokens"
 	"multipass/internal/model"
 	"multipass/internal/store"
 	"multipass/pkg/apperror"
 	"multipass/pkg/common"
+	"multipass/pkg/ctxutils"
 	"multipass/pkg/logging"
 
+	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
 )
 
@@ -21,22 +27,30 @@ type WebAuthnService interface {
 	WebAuthnSignUpEndService(w http.ResponseWriter, r *http.Request, token string, email string) error
 	WebAuthnLoginStartService(ctx context.Context, email string) (*common.WebAuthnAuthStartResult, error)
 	WebAuthnLoginEndService(w http.ResponseWriter, r *http.Request, cookieValue string) (*common.WebAuthnAuthenticationEndResult, error)
+	WebAuthnDiscoverableLoginStartService(ctx context.Context, mediation string) (*common.WebAuthnAuthStartResult, error)
+	WebAuthnDiscoverableLoginEndService(w http.ResponseWriter, r *http.Request, cookieValue string) (*common.WebAuthnAuthenticationEndResult, error)
+	ListPasskeysService(ctx context.Context) ([]model.Passkey, error)
+	RenamePasskeyService(ctx context.Context, passkeyID int, req *common.PasskeyRenameRequest) error
+	DeletePasskeyService(ctx context.Context, passkeyID int) error
 }
 
 type PasskeyService struct {
 	store        store.PasskeyStore
+	sessions     store.CeremonySessionStore
 	webauthn     *webauthn.WebAuthn
 	tokenManager *tokens.TokenManager
 	logger       logging.Logger
 }
 
 func NewPasskeyService(store store.PasskeyStore,
+	sessions store.CeremonySessionStore,
 	webauthn *webauthn.WebAuthn,
 	tokenManager *tokens.TokenManager,
 	logger logging.Logger,
 ) *PasskeyService {
 	return &PasskeyService{
 		store:        store,
+		sessions:     sessions,
 		webauthn:     webauthn,
 		tokenManager: tokenManager,
 		logger:       logger,
@@ -56,21 +70,28 @@ func (s *PasskeyService) WebAuthnSignUpStartService(ctx context.Context, email s
 		return nil, apperror.ErrBadRequest(err, s.logger, meta)
 	}
 
-	// STEP 2: GEGIN WEBAUTHN REGISTRATION
-	options, session, err := s.webauthn.BeginRegistration(user)
+	// STEP 2: GEGIN WEBAUTHN REGISTRATION, AS A DISCOVERABLE CREDENTIAL SO
+	// THE BROWSER CAN OFFER IT WITHOUT AN EMAIL
+	options, session, err := s.webauthn.BeginRegistration(user, webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+		RequireResidentKey: protocol.ResidentKeyRequired(),
+		ResidentKey:        protocol.ResidentKeyRequirementRequired,
+		UserVerification:   protocol.VerificationPreferred,
+	}))
 	if err != nil {
 		s.logger.Error("Unable to retrieve email", err)
 		return nil, apperror.ErrInternalServer(err, s.logger, meta)
 	}
 
 	// STEP 3: GENERATE SESSION KEY & STORE SESSION DATA VALUES
-	t, err := s.store.GenSessionID()
+	t, err := store.NewCeremonySessionID()
 	if err != nil {
 		s.logger.Error("failed to generate session id: %+w", err)
 		return nil, apperror.ErrInternalServer(err, s.logger, meta)
 	}
 
-	s.store.SaveSession(t, *session)
+	if err := s.sessions.Save(ctx, t, *session); err != nil {
+		return nil, err
+	}
 
 	return &common.WebAuthnSignUpResult{
 		Options: options,
@@ -85,8 +106,14 @@ func (s *PasskeyService) WebAuthnSignUpEndService(w http.ResponseWriter, r *http
 		"email": email,
 		"op":    op,
 	}
-	// STEP 1: GET SESSION
-	session, _ := s.store.GetSession(token)
+	// STEP 1: TAKE SESSION, EACH CEREMONY FINISHES ONCE
+	session, ok, err := s.sessions.Take(ctx, token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return apperror.ErrBadRequest(errors.New("no registration ceremony for this session"), s.logger, meta)
+	}
 
 	// STEP 2: FIND (Passkey) USER BY EMAIL
 	user, err := s.store.GetUserByEmail(ctx, email)
@@ -108,11 +135,15 @@ func (s *PasskeyService) WebAuthnSignUpEndService(w http.ResponseWriter, r *http
 	}
 
 	// STEP 4: STORE CREDENTIAL OBJECT
-	user.AddCrediential(credential)
-	s.store.SaveUser(ctx, *user)
+	userID, err := strconv.Atoi(string(user.ID))
+	if err != nil {
+		return apperror.ErrBadRequest(err, s.logger, meta)
+	}
+	if err := s.store.AddCredential(ctx, userID, credential, aaguid.Name(credential.Authenticator.AAGUID)); err != nil {
+		return err
+	}
 
-	// STEP 5: DELETE SESSION DATA (IMPORTANT)
-	s.store.DeleteSession(token)
+	// STEP 5: CLEAN UP SID COOKIE, THE SESSION WAS TAKEN IN STEP 1
 	http.SetCookie(w, &http.Cookie{
 		Name:  "sid",
 		Value: "",
@@ -142,13 +173,15 @@ func (s *PasskeyService) WebAuthnLoginStartService(ctx context.Context, email st
 	}
 
 	// STEP 3: GENERATE SESSION KEY & STORE SESSION DATA VALUES
-	t, err := s.store.GenSessionID()
+	t, err := store.NewCeremonySessionID()
 	if err != nil {
 		s.logger.Error("failed to generate session id: %+w", err)
 		return nil, apperror.ErrInternalServer(err, s.logger, meta)
 	}
 
-	s.store.SaveSession(t, *session)
+	if err := s.sessions.Save(ctx, t, *session); err != nil {
+		return nil, err
+	}
 
 	return &common.WebAuthnAuthStartResult{
 		Options: options,
@@ -164,8 +197,15 @@ func (s *PasskeyService) WebAuthnLoginEndService(w http.ResponseWriter, r *http.
 		"op": op,
 	}
 
-	// STEP 1: GET SESSION DATA STORED BY WEBAUTHNLOGINSTARTSERVICE
-	session, _ := s.store.GetSession(cookieValue)
+	// STEP 1: TAKE SESSION DATA STORED BY WEBAUTHNLOGINSTARTSERVICE
+	session, ok, err := s.sessions.Take(ctx, cookieValue)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		meta["context"] = "No passkey ceremony"
+		return nil, apperror.ErrPasskeyNotVerified(nil, s.logger, meta)
+	}
 
 	// STEP 2: CONVERT USER ID TO INT FROM []byte
 	userID, err := strconv.Atoi(string(session.UserID))
@@ -196,42 +236,222 @@ func (s *PasskeyService) WebAuthnLoginEndService(w http.ResponseWriter, r *http.
 		return nil, apperror.ErrBadRequest(err, s.logger, meta)
 	}
 
-	// STEP 6: UPDATE CREDENTIALS IN CASE OF SUCCESSFUL LOGIN
-	user.UpdateCredential(credential)
-	s.store.SaveUser(ctx, *user)
+	// STEP 6: SAVE THE NEW SIGN COUNT, REJECTING COUNTERS THAT WENT BACKWARDS
+	updated, err := s.store.UpdateCredentialUsage(ctx, userID, credential)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		meta["context"] = "Sign count did not increase"
+		return nil, apperror.ErrPasskeyNotVerified(nil, s.logger, meta)
+	}
+
+	// STEP 7: ISSUE THE LOGIN
+	return s.finishPasskeyLogin(ctx, w, userID, user, meta)
+}
+
+// WebAuthnDiscoverableLoginStartService starts a login without an email: the
+// browser offers the passkeys it holds for this site. With the "conditional"
+// mediation it offers them in the autofill of the email field.
+func (s *PasskeyService) WebAuthnDiscoverableLoginStartService(ctx context.Context, mediation string) (*common.WebAuthnAuthStartResult, error) {
+	op := "PasskeyService.WebAuthnDiscoverableLoginStartService"
+	meta := common.Envelop{
+		"mediation": mediation,
+		"op":        op,
+	}
+
+	// STEP 1: BEGIN WEBAUTHN LOGIN WITHOUT A USER, NO CREDENTIALS ARE ALLOWED UPFRONT
+	mediationRequirement := protocol.MediationDefault
+	if mediation == string(protocol.MediationConditional) {
+		mediationRequirement = protocol.MediationConditional
+	}
+	options, session, err := s.webauthn.BeginDiscoverableMediatedLogin(mediationRequirement)
+	if err != nil {
+		s.logger.Error("Unable to begin discoverable login", err)
+		return nil, apperror.ErrInternalServer(err, s.logger, meta)
+	}
+
+	// STEP 2: GENERATE SESSION KEY & STORE SESSION DATA VALUES
+	t, err := store.NewCeremonySessionID()
+	if err != nil {
+		s.logger.Error("failed to generate session id: %+w", err)
+		return nil, apperror.ErrInternalServer(err, s.logger, meta)
+	}
+
+	if err := s.sessions.Save(ctx, t, *session); err != nil {
+		return nil, err
+	}
+
+	return &common.WebAuthnAuthStartResult{
+		Options: options,
+		Token:   t,
+	}, nil
+}
+
+// WebAuthnDiscoverableLoginEndService finishes a login without an email. The
+// user is the one whose ID the passkey returns as its user handle.
+func (s *PasskeyService) WebAuthnDiscoverableLoginEndService(w http.ResponseWriter, r *http.Request, cookieValue string) (*common.WebAuthnAuthenticationEndResult, error) {
+	ctx := r.Context()
+	op := "PasskeyService.WebAuthnDiscoverableLoginEndService"
+	meta := common.Envelop{
+		"op": op,
+	}
+
+	// STEP 1: TAKE SESSION DATA STORED BY WEBAUTHNDISCOVERABLELOGINSTARTSERVICE
+	session, ok, err := s.sessions.Take(ctx, cookieValue)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		meta["context"] = "No passkey ceremony"
+		return nil, apperror.ErrPasskeyNotVerified(nil, s.logger, meta)
+	}
+
+	// STEP 2: RESOLVE THE USER FROM THE USER HANDLE (PasskeyUser.ID)
+	var userID int
+	resolveUser := func(rawID, userHandle []byte) (webauthn.User, error) {
+		id, err := strconv.Atoi(string(userHandle))
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.store.GetUserByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		userID = id
+		return user, nil
+	}
+
+	// STEP 3: FINISH WEBAUTHN LOGIN, WHICH CHECKS THE PASSKEY BELONGS TO THAT USER
+	user, credential, err := s.webauthn.FinishPasskeyLogin(resolveUser, session, r)
+	if err != nil {
+		s.logger.Error("Coudln't finish discoverable login", err, "meta", meta)
+		return nil, apperror.ErrPasskeyNotVerified(err, s.logger, meta)
+	}
+	meta["user_id"] = userID
+
+	// STEP 4: CREDENTIAL AUTHENTICATOR CLOANWARNING
+	if credential.Authenticator.CloneWarning {
+		meta["context"] = "Clone warning"
+		return nil, apperror.ErrPasskeyNotVerified(nil, s.logger, meta)
+	}
+
+	// STEP 5: SAVE THE NEW SIGN COUNT, REJECTING COUNTERS THAT WENT BACKWARDS
+	updated, err := s.store.UpdateCredentialUsage(ctx, userID, credential)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		meta["context"] = "Sign count did not increase"
+		return nil, apperror.ErrPasskeyNotVerified(nil, s.logger, meta)
+	}
+
+	// STEP 6: ISSUE THE LOGIN
+	return s.finishPasskeyLogin(ctx, w, userID, user, meta)
+}
 
-	// STEP 7: DELETE SESSION DATA
-	s.store.DeleteSession(cookieValue)
+// finishPasskeyLogin issues the session and JWT of a verified passkey login,
+// whose ceremony session was already taken
+func (s *PasskeyService) finishPasskeyLogin(ctx context.Context, w http.ResponseWriter, userID int, user webauthn.User, meta common.Envelop) (*common.WebAuthnAuthenticationEndResult, error) {
+	// STEP 1: CLEAN UP SID COOKIE
 	http.SetCookie(w, &http.Cookie{
 		Name:  "sid",
 		Value: "",
 	})
 
-	// STEP 8: GENERATE NEW SESSION ID (TOKEN)
-	token, err := s.store.GenSessionID()
+	// STEP 2: GENERATE NEW SESSION ID (TOKEN)
+	token, err := store.NewCeremonySessionID()
 	if err != nil {
 		s.logger.Error("Couldn't generate session", err, "meta", meta)
 		return nil, apperror.ErrBadRequest(err, s.logger, meta)
 	}
 
-	// STEP 9: SAVE WEBAUTHN SESSION
-	s.store.SaveSession(token, webauthn.SessionData{
+	// STEP 3: SAVE WEBAUTHN SESSION
+	if err := s.sessions.Save(ctx, token, webauthn.SessionData{
 		Expires: time.Now().Add(time.Hour),
-	})
+	}); err != nil {
+		return nil, err
+	}
 
-	// STEP 10: GENERATE JWT
+	// STEP 4: GENERATE JWT
 	jwt, err := s.tokenManager.CreateJWT(&model.User{
 		ID:    userID,
-		Email: user.Name,
-		Name:  user.DisplayName,
+		Email: user.WebAuthnName(),
+		Name:  user.WebAuthnDisplayName(),
 	})
 	if err != nil {
 		return nil, apperror.ErrInternalServer(err, s.logger, meta)
 	}
 
-	// STEP 11: RETURN RESULT BACK TO HANDLER
+	// STEP 5: RETURN RESULT BACK TO HANDLER
 	return &common.WebAuthnAuthenticationEndResult{
 		Token: token,
 		JWT:   jwt,
 	}, nil
 }
+
+// maxPasskeyNicknameLength bounds the name a user gives a passkey
+const maxPasskeyNicknameLength = 64
+
+// ListPasskeysService returns the signed-in user's passkeys
+func (s *PasskeyService) ListPasskeysService(ctx context.Context) ([]model.Passkey, error) {
+	meta := common.Envelop{
+		"op": "PasskeyService.ListPasskeysService",
+	}
+
+	userCtx, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		meta["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, meta)
+	}
+
+	return s.store.ListPasskeys(ctx, userCtx.UserID)
+}
+
+// RenamePasskeyService sets the nickname the user knows a passkey by
+func (s *PasskeyService) RenamePasskeyService(ctx context.Context, passkeyID int, req *common.PasskeyRenameRequest) error {
+	meta := common.Envelop{
+		"op":         "PasskeyService.RenamePasskeyService",
+		"passkey_id": passkeyID,
+	}
+
+	userCtx, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		meta["warning"] = "User not found in context"
+		return apperror.ErrUserNotFound(err, s.logger, meta)
+	}
+	meta["user_id"] = userCtx.UserID
+
+	nickname := strings.TrimSpace(req.Nickname)
+	if nickname == "" {
+		return apperror.ErrMissingRequiredField("nickname", nil, s.logger, meta)
+	}
+	if utf8.RuneCountInString(nickname) > maxPasskeyNicknameLength {
+		return apperror.ErrBadRequest(errors.New("nickname is too long"), s.logger, meta)
+	}
+
+	return s.store.RenamePasskey(ctx, userCtx.UserID, passkeyID, nickname)
+}
+
+// DeletePasskeyService deletes one of the user's passkeys. The last one
+// stays when the account can't sign in without it.
+func (s *PasskeyService) DeletePasskeyService(ctx context.Context, passkeyID int) error {
+	meta := common.Envelop{
+		"op":         "PasskeyService.DeletePasskeyService",
+		"passkey_id": passkeyID,
+	}
+
+	userCtx, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		meta["warning"] = "User not found in context"
+		return apperror.ErrUserNotFound(err, s.logger, meta)
+	}
+	meta["user_id"] = userCtx.UserID
+
+	if err := s.store.DeletePasskey(ctx, userCtx.UserID, passkeyID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Passkey deleted", meta)
+	return nil
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"multipass/internal/model"
+	"multipass/internal/store"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/ctxutils"
+	"multipass/pkg/logging"
+)
+
+// Taste profile tuning. A favorite says more about taste than a watchlist
+// entry, and a shared keyword or actor says more than a shared genre.
+var (
+	relationWeights = map[string]float64{"favorite": 2.0, "watchlist": 1.0}
+	featureWeights  = map[string]float64{
+		model.FeatureGenre:   1.0,
+		model.FeatureKeyword: 2.0,
+		model.FeatureCast:    3.0,
+	}
+	// profileSize caps how many of the user's strongest features are used to find candidates.
+	profileSize = map[string]int{
+		model.FeatureGenre:   5,
+		model.FeatureKeyword: 25,
+		model.FeatureCast:    25,
+	}
+)
+
+const (
+	candidatesPerGroup = 200
+	popularFallbackMsg = "Popular with everyone right now"
+)
+
+type UserRecommendationService interface {
+	RecommendForUser(ctx context.Context, limit int) (*common.RecommendationsResult, error)
+}
+
+type RecommendationService struct {
+	store      store.RecommendationStore
+	movieStore store.MovieStore
+	logger     logging.Logger
+}
+
+func NewRecommendationService(recStore store.RecommendationStore, movieStore store.MovieStore, logger logging.Logger) *RecommendationService {
+	return &RecommendationService{
+		store:      recStore,
+		movieStore: movieStore,
+		logger:     logger,
+	}
+}
+
+// featureKey identifies one genre, keyword or actor.
+type featureKey struct {
+	kind string
+	id   int
+}
+
+// scoredCandidate is an unsaved movie with its taste score and the saved movie it most resembles.
+type scoredCandidate struct {
+	movieID  int
+	score    float64
+	because  int
+	overlaps float64
+}
+
+// RecommendForUser recommends movies for the authenticated user based on the
+// genres, keywords and cast of their saved movies. Users with nothing saved
+// (cold start) get popular movies instead.
+func (s *RecommendationService) RecommendForUser(ctx context.Context, limit int) (*common.RecommendationsResult, error) {
+	metaData := common.Envelop{
+		"op":    "service.RecommendForUser",
+		"limit": limit,
+	}
+
+	// STEP 1: GET USER FROM CONTEXT
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = user.UserID
+
+	// STEP 2: LOAD SAVED MOVIES
+	saved, err := s.store.GetSavedMovies(ctx, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(saved) == 0 {
+		s.logger.Info("Cold start recommendations, user has no saved movies", "meta", metaData)
+		return s.popularFallback(ctx, nil, limit, true)
+	}
+
+	savedIDs := make([]int, 0, len(saved))
+	savedByID := make(map[int]model.SavedMovie, len(saved))
+	for _, m := range saved {
+		prev, seen := savedByID[m.MovieID]
+		if !seen {
+			savedIDs = append(savedIDs, m.MovieID)
+		}
+		// a movie in both collections keeps its strongest relation
+		if !seen || relationWeights[m.Relation] > relationWeights[prev.Relation] {
+			savedByID[m.MovieID] = m
+		}
+	}
+
+	// STEP 3: BUILD TASTE PROFILE
+	savedFeatures, err := s.store.GetMovieFeatures(ctx, savedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := make(map[featureKey]float64)
+	featureOwners := make(map[featureKey][]int) // feature -> saved movies having it
+	for _, f := range savedFeatures {
+		key := featureKey{f.Kind, f.FeatureID}
+		profile[key] += relationWeights[savedByID[f.MovieID].Relation]
+		featureOwners[key] = append(featureOwners[key], f.MovieID)
+	}
+
+	genres, keywords, actors := topFeatures(profile)
+	if len(genres)+len(keywords)+len(actors) == 0 {
+		s.logger.Info("Saved movies have no genres, keywords or cast, falling back to popular", "meta", metaData)
+		return s.popularFallback(ctx, savedIDs, limit, true)
+	}
+
+	// STEP 4: SCORE CANDIDATES
+	candidateFeatures, err := s.store.GetCandidateFeatures(ctx, genres, keywords, actors, savedIDs, candidatesPerGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := scoreCandidates(candidateFeatures, profile, featureOwners)
+
+	// Over-fetch so popularity can break ties at the cut-off
+	fetch := min(len(candidates), limit*2)
+	ids := make([]int, 0, fetch)
+	for _, c := range candidates[:fetch] {
+		ids = append(ids, c.movieID)
+	}
+
+	movies, err := s.movieStore.GetMoviesByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]scoredCandidate, fetch)
+	for _, c := range candidates[:fetch] {
+		byID[c.movieID] = c
+	}
+	slices.SortStableFunc(movies, func(a, b model.Movie) int {
+		if c := cmp.Compare(byID[b.ID].score, byID[a.ID].score); c != 0 {
+			return c
+		}
+		return cmp.Compare(popularity(b), popularity(a))
+	})
+
+	// STEP 5: EXPLAIN
+	recs := make([]model.Recommendation, 0, limit)
+	for _, m := range movies[:min(len(movies), limit)] {
+		c := byID[m.ID]
+		because := c.because
+		recs = append(recs, model.Recommendation{
+			Movie:          m,
+			Score:          c.score,
+			Reason:         fmt.Sprintf("Because you saved %s", savedByID[because].Title),
+			BecauseMovieID: &because,
+		})
+	}
+
+	// Not enough taste matches yet: top up with popular titles
+	if len(recs) < limit {
+		exclude := append(slices.Clone(savedIDs), ids...)
+		fallback, err := s.popularFallback(ctx, exclude, limit-len(recs), false)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, fallback.Recommendations...)
+	}
+
+	return &common.RecommendationsResult{Recommendations: recs}, nil
+}
+
+// popularFallback recommends popular movies that are not in exclude.
+func (s *RecommendationService) popularFallback(ctx context.Context, exclude []int, limit int, coldStart bool) (*common.RecommendationsResult, error) {
+	skip := make(map[int]struct{}, len(exclude))
+	for _, id := range exclude {
+		skip[id] = struct{}{}
+	}
+
+	// Page through the popular movies until limit is left after exclusions
+	recs := make([]model.Recommendation, 0, limit)
+	page := common.PageParams{Limit: min(limit+len(exclude), common.MaxPageLimit)}
+	for len(recs) < limit {
+		top, err := s.movieStore.GetTopMovies(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range top.Movies {
+			if len(recs) == limit {
+				break
+			}
+			if _, ok := skip[m.ID]; ok {
+				continue
+			}
+			recs = append(recs, model.Recommendation{
+				Movie:  m,
+				Reason: popularFallbackMsg,
+			})
+		}
+		if !top.HasMore {
+			break
+		}
+		page.Cursor = top.NextCursor
+	}
+
+	return &common.RecommendationsResult{Recommendations: recs, ColdStart: coldStart}, nil
+}
+
+// topFeatures returns the strongest genre, keyword and actor ids of a taste profile.
+func topFeatures(profile map[featureKey]float64) (genres, keywords, actors []int) {
+	byKind := make(map[string][]featureKey)
+	for key := range profile {
+		byKind[key.kind] = append(byKind[key.kind], key)
+	}
+
+	top := func(kind string) []int {
+		keys := byKind[kind]
+		slices.SortFunc(keys, func(a, b featureKey) int {
+			if c := cmp.Compare(profile[b], profile[a]); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.id, b.id)
+		})
+		ids := make([]int, 0, profileSize[kind])
+		for _, key := range keys[:min(len(keys), profileSize[kind])] {
+			ids = append(ids, key.id)
+		}
+		return ids
+	}
+
+	return top(model.FeatureGenre), top(model.FeatureKeyword), top(model.FeatureCast)
+}
+
+// scoreCandidates sums the weighted profile strength of every feature a
+// candidate shares with the profile, and remembers which saved movie it
+// overlaps with the most. Results are sorted by score.
+func scoreCandidates(features []model.MovieFeature, profile map[featureKey]float64, owners map[featureKey][]int) []scoredCandidate {
+	scores := make(map[int]float64)
+	overlap := make(map[int]map[int]float64) // candidate -> saved movie -> shared weight
+
+	for _, f := range features {
+		key := featureKey{f.Kind, f.FeatureID}
+		weight := featureWeights[f.Kind]
+		scores[f.MovieID] += weight * profile[key]
+
+		if overlap[f.MovieID] == nil {
+			overlap[f.MovieID] = make(map[int]float64)
+		}
+		for _, savedID := range owners[key] {
+			overlap[f.MovieID][savedID] += weight
+		}
+	}
+
+	candidates := make([]scoredCandidate, 0, len(scores))
+	for movieID, score := range scores {
+		c := scoredCandidate{movieID: movieID, score: score}
+		for savedID, shared := range overlap[movieID] {
+			if shared > c.overlaps || (shared == c.overlaps && savedID < c.because) {
+				c.because, c.overlaps = savedID, shared
+			}
+		}
+		candidates = append(candidates, c)
+	}
+
+	slices.SortFunc(candidates, func(a, b scoredCandidate) int {
+		if c := cmp.Compare(b.score, a.score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.movieID, b.movieID)
+	})
+	return candidates
+}
+
+func popularity(m model.Movie) float32 {
+	if m.Popularity == nil {
+		return 0
+	}
+	return *m.Popularity
+}
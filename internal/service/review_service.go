@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+
+	"multipass/internal/model"
+	"multipass/internal/store"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/ctxutils"
+	"multipass/pkg/logging"
+	"multipass/pkg/validator"
+)
+
+type UserReviewService interface {
+	MovieReviews(ctx context.Context, movieID int, page common.PageParams) (*common.ReviewPage, *model.CommunityRating, error)
+	CreateReview(ctx context.Context, movieID int, req *common.ReviewRequest) (*model.Review, error)
+	UpdateReview(ctx context.Context, movieID int, req *common.ReviewRequest) (*model.Review, error)
+	DeleteReview(ctx context.Context, movieID int) error
+	UserReviews(ctx context.Context, page common.PageParams) (*common.ReviewPage, error)
+	ReviewHistory(ctx context.Context, reviewID int) ([]model.ReviewRevision, error)
+}
+
+type ReviewService struct {
+	store      store.ReviewStore
+	movieStore store.MovieStore
+	logger     logging.Logger
+}
+
+func NewReviewService(reviewStore store.ReviewStore, movieStore store.MovieStore, logger logging.Logger) *ReviewService {
+	return &ReviewService{
+		store:      reviewStore,
+		movieStore: movieStore,
+		logger:     logger,
+	}
+}
+
+// MovieReviews lists a movie's reviews. The first page also carries the community rating.
+func (s *ReviewService) MovieReviews(ctx context.Context, movieID int, page common.PageParams) (*common.ReviewPage, *model.CommunityRating, error) {
+	reviews, err := s.store.GetMovieReviews(ctx, movieID, page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if page.Cursor != "" {
+		return reviews, nil, nil
+	}
+
+	rating, err := s.store.GetCommunityRating(ctx, movieID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Tell an unknown movie apart from a movie nobody reviewed yet
+	if rating.Count == 0 {
+		if _, err := s.movieStore.GetMovieByID(ctx, movieID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return reviews, rating, nil
+}
+
+// CreateReview posts the authenticated user's review of a movie
+func (s *ReviewService) CreateReview(ctx context.Context, movieID int, req *common.ReviewRequest) (*model.Review, error) {
+	metaData := common.Envelop{
+		"op":       "service.CreateReview",
+		"movie_id": movieID,
+	}
+
+	user, input, err := s.reviewInput(ctx, req, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := s.store.CreateReview(ctx, user.UserID, movieID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Review created", "meta", metaData)
+	return review, nil
+}
+
+// UpdateReview edits the authenticated user's review of a movie. The replaced version is kept as history.
+func (s *ReviewService) UpdateReview(ctx context.Context, movieID int, req *common.ReviewRequest) (*model.Review, error) {
+	metaData := common.Envelop{
+		"op":       "service.UpdateReview",
+		"movie_id": movieID,
+	}
+
+	user, input, err := s.reviewInput(ctx, req, metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := s.store.UpdateReview(ctx, user.UserID, movieID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Review updated", "meta", metaData)
+	return review, nil
+}
+
+// DeleteReview removes the authenticated user's review of a movie
+func (s *ReviewService) DeleteReview(ctx context.Context, movieID int) error {
+	metaData := common.Envelop{
+		"op":       "service.DeleteReview",
+		"movie_id": movieID,
+	}
+
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = user.UserID
+
+	if err := s.store.DeleteReview(ctx, user.UserID, movieID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Review deleted", "meta", metaData)
+	return nil
+}
+
+// UserReviews lists the reviews the authenticated user wrote
+func (s *ReviewService) UserReviews(ctx context.Context, page common.PageParams) (*common.ReviewPage, error) {
+	metaData := common.Envelop{
+		"op": "service.UserReviews",
+	}
+
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	return s.store.GetUserReviews(ctx, user.UserID, page)
+}
+
+// ReviewHistory lists the previous versions of one of the authenticated user's reviews
+func (s *ReviewService) ReviewHistory(ctx context.Context, reviewID int) ([]model.ReviewRevision, error) {
+	metaData := common.Envelop{
+		"op":        "service.ReviewHistory",
+		"review_id": reviewID,
+	}
+
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+
+	return s.store.GetReviewHistory(ctx, user.UserID, reviewID)
+}
+
+// reviewInput resolves the authenticated user and validates a review request
+func (s *ReviewService) reviewInput(ctx context.Context, req *common.ReviewRequest, metaData common.Envelop) (*common.UserContext, common.ReviewInput, error) {
+	user, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		metaData["warning"] = "User not found in context"
+		return nil, common.ReviewInput{}, apperror.ErrUserNotFound(err, s.logger, metaData)
+	}
+	metaData["user_id"] = user.UserID
+
+	input, err := validator.SanitizeReviewRequest(req)
+	if err != nil {
+		return nil, common.ReviewInput{}, err
+	}
+
+	return user, input, nil
+}
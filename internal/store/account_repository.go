@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"multipass/internal/model"
 	"multipass/pkg/apperror"
 	"multipass/pkg/common"
 	"multipass/pkg/logging"
+	"multipass/pkg/utils"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	// "github.com/go-webauthn/webauthn/webauthn"
@@ -28,9 +31,19 @@ type AccountStore interface {
 	RemoveMovieFromCollection(ctx context.Context, userID int, movieID int, collection string) (bool, error)
 	UpdateUser(ctx context.Context, user *model.User) error
 	GetMovieList(ctx context.Context, list string, userID int) ([]model.Movie, error)
+	StreamMovieList(ctx context.Context, list string, userID int, fn func(m *model.Movie) error) error
+	GetMovieListPage(ctx context.Context, list string, userID int, page common.PageParams) (*common.MoviePage, error)
 	SaveProfilePictureUrl(ctx context.Context, userID int, profilePictureUrl string) error
 	MarkUserAsVerified(ctx context.Context, userID int) error
 	UpdatePassword(ctx context.Context, userID int, passwordHash string) error
+	LogWatch(ctx context.Context, userID int, input common.DiaryInput) (*model.DiaryEntry, error)
+	GetDiaryPage(ctx context.Context, userID int, rng common.DiaryRange, page common.PageParams) (*common.DiaryPage, error)
+	UpdateDiaryEntry(ctx context.Context, userID int, entryID int, input common.DiaryInput) (*model.DiaryEntry, error)
+	DeleteDiaryEntry(ctx context.Context, userID int, entryID int) error
+	IsAdmin(ctx context.Context, userID int) (bool, error)
+	SoftDeleteUser(ctx context.Context, userID int) (time.Time, error)
+	RestoreUser(ctx context.Context, userID int, deletedAfter time.Time) error
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, limit int) (int, []string, []string, error)
 }
 
 type AccountRepository struct {
@@ -193,6 +206,14 @@ func (r *AccountRepository) SaveCollection(ctx context.Context, userID int, movi
 		"collection": collection,
 		"context":    op,
 	}
+	// Watches go to the diary, where a movie can be logged more than once
+	if collection == RelWatched {
+		if _, err := r.LogWatch(ctx, userID, common.DiaryInput{MovieID: movieID, WatchedOn: time.Now().UTC()}); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
 	queryExists, err := getQuery(QueryIfMovieExists, r.logger, meta)
 	if err != nil || queryExists == "" {
 		return false, err
@@ -326,53 +347,138 @@ func (r *AccountRepository) UpdateLastLogin(ctx context.Context, userID int) err
 }
 
 func (r *AccountRepository) GetMovieList(ctx context.Context, list string, userID int) ([]model.Movie, error) {
-	op := "store.GetMovieList"
+	movieList := []model.Movie{}
+	err := r.StreamMovieList(ctx, list, userID, func(m *model.Movie) error {
+		movieList = append(movieList, *m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movieList, nil
+}
+
+// StreamMovieList calls fn with each movie of the user's favorites or
+// watchlist, oldest addition first, without holding the whole list in memory.
+// An error returned by fn stops the iteration and is returned as is.
+func (r *AccountRepository) StreamMovieList(ctx context.Context, list string, userID int, fn func(m *model.Movie) error) error {
+	op := "store.StreamMovieList"
 	meta := common.Envelop{
 		"user_id": userID,
+		"list":    list,
 		"context": op,
 	}
 	var query string
 
 	switch list {
-	case "favorites":
+	case RelFavorites:
 		query = QueryGetFavorite
-	case "watchlist":
+	case RelWatchlist:
 		query = QueryGetWatchlist
 	default:
-		return nil, fmt.Errorf("invalid list name %s: it must be either 'favorites' or 'watchlist'", list)
+		return fmt.Errorf("invalid list name %s: it must be either 'favorites' or 'watchlist'", list)
 	}
 
 	query, err := getQuery(query, r.logger, meta)
 	if err != nil || query == "" {
-		return nil, err
+		return err
 	}
 
 	rows, err := r.db.Query(ctx, query, userID)
 	if err != nil {
-		return []model.Movie{}, err
+		return handleDatabaseError(err, r.logger, op, "user_movies", meta)
 	}
 	defer rows.Close()
 
-	// getting total number or rows
-	numberOfRows, err := getRowCount(ctx, r.db, r.logger, "user_movies", "user_id", "favorites", userID)
+	for rows.Next() {
+		var m model.Movie
+		if err := scanListMovie(rows, &m); err != nil {
+			return handleDatabaseError(err, r.logger, op, "user_movies", meta)
+		}
+		if err := fn(&m); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return handleDatabaseError(err, r.logger, op, "user_movies", meta)
+	}
+
+	return nil
+}
+
+// GetMovieListPage retrieves a page of the user's favorites or watchlist,
+// most recently added first.
+func (r *AccountRepository) GetMovieListPage(ctx context.Context, list string, userID int, page common.PageParams) (*common.MoviePage, error) {
+	op := getOp(QueryGetCollectionPage)
+	meta := common.Envelop{
+		"user_id": userID,
+		"list":    list,
+		"limit":   page.Limit,
+		"context": op,
+	}
+
+	var relation string
+	switch list {
+	case RelFavorites:
+		relation = "favorite"
+	case RelWatchlist:
+		relation = "watchlist"
+	default:
+		return nil, fmt.Errorf("invalid list name %s: it must be either 'favorites' or 'watchlist'", list)
+	}
+
+	var cur *collectionCursor
+	if page.Cursor != "" {
+		cur = &collectionCursor{}
+		if err := utils.DecodeCursor(page.Cursor, cur); err != nil {
+			return nil, apperror.ErrInvalidCursor(err, r.logger, meta)
+		}
+	}
+
+	base, err := getQuery(QueryGetCollectionPage, r.logger, meta)
+	if err != nil || base == "" {
+		return nil, err
+	}
+
+	var queryBuilder strings.Builder
+	queryBuilder.WriteString(base)
+	args := []any{userID, relation}
+
+	if cur != nil {
+		queryBuilder.WriteString(" AND (um.time_added, movies.id) < ($3, $4)")
+		args = append(args, cur.TimeAdded, cur.ID)
+	}
+	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY um.time_added DESC, movies.id DESC LIMIT $%d", len(args)+1))
+	args = append(args, page.Limit+1)
+
+	rows, err := r.db.Query(ctx, queryBuilder.String(), args...)
 	if err != nil {
-		r.logger.Error("getRowCount failed to get number of rows", err)
-		numberOfRows = 0
+		return nil, handleDatabaseError(err, r.logger, op, "collection", meta)
 	}
+	defer rows.Close()
 
-	// scan Rows
-	movieList, err := scanRowsToSlice(
-		rows,
-		scanMovie,
-		r.logger,
-		op,
-		meta,
-		numberOfRows)
+	movies, err := scanRowsToSlice(rows, scanCollectionMovie, r.logger, op, meta, page.Limit+1)
 	if err != nil {
 		return nil, err
 	}
 
-	return movieList, nil
+	result := &common.MoviePage{Movies: movies}
+	if len(movies) > page.Limit {
+		result.Movies = movies[:page.Limit]
+		result.HasMore = true
+
+		last := result.Movies[page.Limit-1]
+		next := collectionCursor{ID: last.ID}
+		if last.TimeAdded != nil {
+			next.TimeAdded = *last.TimeAdded
+		}
+		if result.NextCursor, err = utils.EncodeCursor(next); err != nil {
+			return nil, apperror.ErrInternalServer(err, r.logger, meta)
+		}
+	}
+
+	return result, nil
 }
 
 func (r *AccountRepository) getMovieList(ctx context.Context, queryKey string, userID int) ([]model.Movie, error) {
@@ -403,7 +509,7 @@ func (r *AccountRepository) getMovieList(ctx context.Context, queryKey string, u
 	// scan Rows
 	movieList, err := scanRowsToSlice(
 		rows,
-		scanMovie,
+		scanListMovie,
 		r.logger,
 		op,
 		meta,
@@ -493,6 +599,160 @@ func (r *AccountRepository) MarkUserAsVerified(ctx context.Context, userID int)
 	return nil
 }
 
+// IsAdmin reports whether the user may manage the catalog. Unknown and deleted users are not admins.
+func (r *AccountRepository) IsAdmin(ctx context.Context, userID int) (bool, error) {
+	op := getOp(QueryIsAdmin)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryIsAdmin, r.logger, meta)
+	if err != nil || query == "" {
+		return false, err
+	}
+
+	var isAdmin bool
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&isAdmin); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, handleDatabaseError(err, r.logger, op, "is_admin", meta)
+	}
+
+	return isAdmin, nil
+}
+
+// SoftDeleteUser marks the user deleted, hiding them from every user query,
+// and returns the time of deletion
+func (r *AccountRepository) SoftDeleteUser(ctx context.Context, userID int) (time.Time, error) {
+	op := getOp(QuerySoftDeleteUser)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QuerySoftDeleteUser, r.logger, meta)
+	if err != nil || query == "" {
+		return time.Time{}, err
+	}
+
+	var deletedAt time.Time
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&deletedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, apperror.ErrUserNotFound(err, r.logger, meta)
+		}
+		return time.Time{}, handleDatabaseError(err, r.logger, op, "users", meta)
+	}
+
+	r.logger.Info("User account soft deleted", "meta", meta)
+	return deletedAt, nil
+}
+
+// RestoreUser undoes a soft delete made after deletedAfter
+func (r *AccountRepository) RestoreUser(ctx context.Context, userID int, deletedAfter time.Time) error {
+	op := getOp(QueryRestoreUser)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryRestoreUser, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, query, userID, deletedAfter)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "users", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrAccountNotRestorable(nil, r.logger, meta)
+	}
+
+	r.logger.Info("User account restored", "meta", meta)
+	return nil
+}
+
+// PurgeDeletedUsers permanently deletes up to limit users soft deleted before
+// deletedBefore, with everything they own. It returns how many were purged,
+// their profile picture URLs and their data export archive paths, whose files
+// are left to the caller.
+func (r *AccountRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, limit int) (int, []string, []string, error) {
+	op := getOp(QueryPurgeUsers)
+	meta := common.Envelop{"deleted_before": deletedBefore, "context": op}
+
+	lockQuery, err := getQuery(QueryLockPurgeableUsers, r.logger, meta)
+	if err != nil || lockQuery == "" {
+		return 0, nil, nil, err
+	}
+	exportsQuery, err := getQuery(QueryPurgeUserExports, r.logger, meta)
+	if err != nil || exportsQuery == "" {
+		return 0, nil, nil, err
+	}
+	passkeysQuery, err := getQuery(QueryPurgeUserPasskeys, r.logger, meta)
+	if err != nil || passkeysQuery == "" {
+		return 0, nil, nil, err
+	}
+	moviesQuery, err := getQuery(QueryPurgeUserMovies, r.logger, meta)
+	if err != nil || moviesQuery == "" {
+		return 0, nil, nil, err
+	}
+	usersQuery, err := getQuery(QueryPurgeUsers, r.logger, meta)
+	if err != nil || usersQuery == "" {
+		return 0, nil, nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, nil, nil, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	rows, err := tx.Query(ctx, lockQuery, deletedBefore, limit)
+	if err != nil {
+		return 0, nil, nil, handleDatabaseError(err, r.logger, op, "users", meta)
+	}
+
+	var userIDs []int
+	var pictures []string
+	for rows.Next() {
+		var id int
+		var picture *string
+		if err := rows.Scan(&id, &picture); err != nil {
+			rows.Close()
+			return 0, nil, nil, handleDatabaseError(err, r.logger, op, "users", meta)
+		}
+		userIDs = append(userIDs, id)
+		if picture != nil && *picture != "" {
+			pictures = append(pictures, *picture)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, nil, handleDatabaseError(err, r.logger, op, "users", meta)
+	}
+
+	if len(userIDs) == 0 {
+		return 0, nil, nil, nil
+	}
+
+	// The archives must be read before the rows cascade away with the users
+	rows, err = tx.Query(ctx, exportsQuery, userIDs)
+	if err != nil {
+		return 0, nil, nil, handleDatabaseError(err, r.logger, op, "data_exports", meta)
+	}
+	exports, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, nil, nil, handleDatabaseError(err, r.logger, op, "data_exports", meta)
+	}
+
+	for _, q := range []string{passkeysQuery, moviesQuery, usersQuery} {
+		if _, err := tx.Exec(ctx, q, userIDs); err != nil {
+			return 0, nil, nil, handleDatabaseError(err, r.logger, op, "users", meta)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, nil, nil, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+
+	meta["purged"] = len(userIDs)
+	r.logger.Info("Deleted user accounts purged", "meta", meta)
+	return len(userIDs), pictures, exports, nil
+}
+
 func (r *AccountRepository) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
 	op := "store.UpdatePassword"
 	meta := common.Envelop{"user_id": userID, "context": op}
@@ -1 +1,176 @@
 package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// minSharedMovies is how many movies two actors must share to count as frequent collaborators.
+	minSharedMovies    = 2
+	maxCollaborators   = 10
+	defaultFilmography = "year"
+)
+
+/* ActorStore Interface */
+type ActorStore interface {
+	GetActorByID(ctx context.Context, id int) (*model.Actor, error)
+	GetActorProfile(ctx context.Context, id int, order string) (*model.ActorProfile, error)
+	GetFilmography(ctx context.Context, actorID int, order string) ([]model.Movie, error)
+	GetFrequentCollaborators(ctx context.Context, actorID int, limit int) ([]model.Collaborator, error)
+	SearchActors(ctx context.Context, name string, limit int) ([]model.Actor, error)
+}
+
+type ActorRepository struct {
+	db     *pgxpool.Pool
+	logger logging.Logger
+}
+
+func NewActorRepository(db *pgxpool.Pool, logger logging.Logger) *ActorRepository {
+	return &ActorRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetActorByID retrieves an actor by its ID
+func (r *ActorRepository) GetActorByID(ctx context.Context, id int) (*model.Actor, error) {
+	op := getOp(QueryGetActorByID)
+	meta := common.Envelop{
+		"actor_id": id,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryGetActorByID, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	var a model.Actor
+	err = r.db.QueryRow(ctx, query, id).Scan(&a.ID, &a.FirstName, &a.LastName, &a.ImageURL)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperror.ErrInvalidActorID(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "actor", meta)
+	}
+
+	return &a, nil
+}
+
+// GetActorProfile retrieves an actor together with their filmography and frequent collaborators
+func (r *ActorRepository) GetActorProfile(ctx context.Context, id int, order string) (*model.ActorProfile, error) {
+	actor, err := r.GetActorByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &model.ActorProfile{Actor: *actor}
+
+	var g errgroup.Group
+	g.Go(func() error {
+		movies, err := r.GetFilmography(ctx, id, order)
+		profile.Filmography = movies
+		return err
+	})
+	g.Go(func() error {
+		collaborators, err := r.GetFrequentCollaborators(ctx, id, maxCollaborators)
+		profile.Collaborators = collaborators
+		return err
+	})
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+// GetFilmography retrieves every movie the actor was cast in, sorted by
+// release year ("year") or popularity ("popularity").
+func (r *ActorRepository) GetFilmography(ctx context.Context, actorID int, order string) ([]model.Movie, error) {
+	validOrders := map[string]string{
+		"year":       "movies.release_year DESC NULLS LAST, movies.popularity DESC NULLS LAST",
+		"popularity": "movies.popularity DESC NULLS LAST, movies.release_year DESC NULLS LAST",
+	}
+	orderBy, ok := validOrders[order]
+	if !ok {
+		orderBy = validOrders[defaultFilmography]
+	}
+
+	op := getOp(QueryGetActorFilmography)
+	meta := common.Envelop{
+		"actor_id": actorID,
+		"order_by": order,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryGetActorFilmography, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, fmt.Sprintf("%s ORDER BY %s, movies.id", query, orderBy), actorID)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "filmography", meta)
+	}
+	defer rows.Close()
+
+	return scanRowsToSlice(rows, scanMovie, r.logger, op, meta, 0)
+}
+
+// GetFrequentCollaborators retrieves the actors who most often appear in the same movies as actorID
+func (r *ActorRepository) GetFrequentCollaborators(ctx context.Context, actorID int, limit int) ([]model.Collaborator, error) {
+	op := getOp(QueryGetActorCollaborators)
+	meta := common.Envelop{
+		"actor_id": actorID,
+		"limit":    limit,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryGetActorCollaborators, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, actorID, minSharedMovies, limit)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "collaborators", meta)
+	}
+	defer rows.Close()
+
+	return scanRowsToSlice(rows, scanCollaborator, r.logger, op, meta, limit)
+}
+
+// SearchActors retrieves actors whose full name contains name, most prolific first
+func (r *ActorRepository) SearchActors(ctx context.Context, name string, limit int) ([]model.Actor, error) {
+	op := getOp(QuerySearchActors)
+	meta := common.Envelop{
+		"search_query": name,
+		"limit":        limit,
+		"context":      op,
+	}
+
+	query, err := getQuery(QuerySearchActors, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, containsPattern(name), limit)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "actors", meta)
+	}
+	defer rows.Close()
+
+	return scanRowsToSlice(rows, scanActor, r.logger, op, meta, limit)
+}
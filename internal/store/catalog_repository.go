@@ -0,0 +1,598 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+	"multipass/pkg/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// catalogRelation names the join table of one kind of movie link, its id
+// column and the table the ids point to. Only these names are ever formatted
+// into the relation queries.
+type catalogRelation struct {
+	joinTable string
+	column    string
+	table     string
+}
+
+var catalogRelations = map[string]catalogRelation{
+	model.FeatureGenre:   {joinTable: "movie_genres", column: "genre_id", table: "genres"},
+	model.FeatureCast:    {joinTable: "movie_cast", column: "actor_id", table: "actors"},
+	model.FeatureKeyword: {joinTable: "movie_keywords", column: "keyword_id", table: "keywords"},
+}
+
+/* CatalogStore Interface */
+type CatalogStore interface {
+	CreateMovie(ctx context.Context, userID int, input common.MovieInput, rel common.CatalogRelations) (*model.CatalogHistoryEntry, error)
+	UpdateMovie(ctx context.Context, userID int, movieID int, input common.MovieInput) (*model.CatalogHistoryEntry, error)
+	DeleteMovie(ctx context.Context, userID int, movieID int) (*model.CatalogHistoryEntry, error)
+	AttachRelations(ctx context.Context, userID int, movieID int, rel common.CatalogRelations) (*model.CatalogHistoryEntry, error)
+	DetachRelations(ctx context.Context, userID int, movieID int, rel common.CatalogRelations) (*model.CatalogHistoryEntry, error)
+	GetMovieHistory(ctx context.Context, movieID int, page common.PageParams) (*common.CatalogHistoryPage, error)
+	RevertEntry(ctx context.Context, userID int, entryID int64) (*model.CatalogHistoryEntry, error)
+	GetAdminMovie(ctx context.Context, movieID int) (*model.Movie, error)
+}
+
+type CatalogRepository struct {
+	db     *pgxpool.Pool
+	logger logging.Logger
+}
+
+func NewCatalogRepository(db *pgxpool.Pool, logger logging.Logger) *CatalogRepository {
+	return &CatalogRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateMovie inserts a movie with its links and records the creation
+func (r *CatalogRepository) CreateMovie(ctx context.Context, userID int, input common.MovieInput, rel common.CatalogRelations) (*model.CatalogHistoryEntry, error) {
+	op := getOp(QueryCreateMovie)
+	meta := common.Envelop{
+		"user_id": userID,
+		"tmdb_id": input.TMDB_ID,
+		"context": op,
+	}
+
+	query, err := getQuery(QueryCreateMovie, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	var movieID int
+	err = tx.QueryRow(ctx, query,
+		input.TMDB_ID, input.Title, input.Tagline, input.ReleaseYear, input.Overview,
+		input.Score, input.Popularity, input.Language, input.PosterURL, input.TrailerURL,
+	).Scan(&movieID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // tmdb_id is unique
+			return nil, apperror.ErrMovieAlreadyExists(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "movies", meta)
+	}
+	meta["movie_id"] = movieID
+
+	if err := r.attach(ctx, tx, movieID, rel, meta); err != nil {
+		return nil, err
+	}
+
+	after, err := r.snapshot(ctx, tx, movieID, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &model.CatalogHistoryEntry{MovieID: movieID, UserID: &userID, Action: model.CatalogCreate, After: after}
+	if err := r.addHistory(ctx, tx, entry, meta); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+
+	r.logger.Info("catalog movie successfully created", "meta", meta)
+	return entry, nil
+}
+
+// UpdateMovie changes the columns of a movie that input sets
+func (r *CatalogRepository) UpdateMovie(ctx context.Context, userID int, movieID int, input common.MovieInput) (*model.CatalogHistoryEntry, error) {
+	op := getOp(QueryUpdateMovie)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"movie_id": movieID,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryUpdateMovie, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	return r.change(ctx, userID, movieID, model.CatalogUpdate, nil, op, meta, func(tx pgx.Tx, _ *model.MovieSnapshot) error {
+		_, err := tx.Exec(ctx, query, movieID,
+			input.TMDB_ID, input.Title, input.Tagline, input.ReleaseYear, input.Overview,
+			input.Score, input.Popularity, input.Language, input.PosterURL, input.TrailerURL,
+		)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return apperror.ErrMovieAlreadyExists(err, r.logger, meta)
+			}
+			return handleDatabaseError(err, r.logger, op, "movies", meta)
+		}
+		return nil
+	})
+}
+
+// DeleteMovie soft deletes a movie. It disappears from every listing but
+// keeps its links, collections and history, so the deletion can be reverted.
+func (r *CatalogRepository) DeleteMovie(ctx context.Context, userID int, movieID int) (*model.CatalogHistoryEntry, error) {
+	op := getOp(QuerySoftDeleteMovie)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"movie_id": movieID,
+		"context":  op,
+	}
+
+	query, err := getQuery(QuerySoftDeleteMovie, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	return r.change(ctx, userID, movieID, model.CatalogDelete, nil, op, meta, func(tx pgx.Tx, _ *model.MovieSnapshot) error {
+		tag, err := tx.Exec(ctx, query, movieID)
+		if err != nil {
+			return handleDatabaseError(err, r.logger, op, "movies", meta)
+		}
+		if tag.RowsAffected() == 0 { // already deleted
+			return apperror.ErrMovieNotFound(pgx.ErrNoRows, r.logger, meta)
+		}
+		return nil
+	})
+}
+
+// AttachRelations links genres, actors and keywords to a movie. Links that
+// already exist are skipped, unknown ids fail the whole change.
+func (r *CatalogRepository) AttachRelations(ctx context.Context, userID int, movieID int, rel common.CatalogRelations) (*model.CatalogHistoryEntry, error) {
+	op := getOp(QueryAttachRelation)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"movie_id": movieID,
+		"context":  op,
+	}
+
+	return r.change(ctx, userID, movieID, model.CatalogAttach, nil, op, meta, func(tx pgx.Tx, _ *model.MovieSnapshot) error {
+		return r.attach(ctx, tx, movieID, rel, meta)
+	})
+}
+
+// DetachRelations unlinks genres, actors and keywords from a movie. Keywords
+// given as words are matched case-insensitively and never created.
+func (r *CatalogRepository) DetachRelations(ctx context.Context, userID int, movieID int, rel common.CatalogRelations) (*model.CatalogHistoryEntry, error) {
+	op := getOp(QueryDetachRelation)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"movie_id": movieID,
+		"context":  op,
+	}
+
+	tpl, err := getQuery(QueryDetachRelation, r.logger, meta)
+	if err != nil || tpl == "" {
+		return nil, err
+	}
+	findQuery, err := getQuery(QueryFindKeyword, r.logger, meta)
+	if err != nil || findQuery == "" {
+		return nil, err
+	}
+
+	return r.change(ctx, userID, movieID, model.CatalogDetach, nil, op, meta, func(tx pgx.Tx, _ *model.MovieSnapshot) error {
+		keywordIDs := rel.KeywordIDs
+		for _, word := range rel.Keywords {
+			var id int
+			if err := tx.QueryRow(ctx, findQuery, word).Scan(&id); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					continue
+				}
+				return handleDatabaseError(err, r.logger, op, "keywords", meta)
+			}
+			keywordIDs = append(keywordIDs, id)
+		}
+
+		for kind, ids := range relationIDs(rel.GenreIDs, rel.ActorIDs, keywordIDs) {
+			jr := catalogRelations[kind]
+			if _, err := tx.Exec(ctx, fmt.Sprintf(tpl, jr.joinTable, jr.column), movieID, ids); err != nil {
+				return handleDatabaseError(err, r.logger, op, jr.joinTable, meta)
+			}
+		}
+		return nil
+	})
+}
+
+// GetMovieHistory retrieves the recorded changes of a movie, newest first
+func (r *CatalogRepository) GetMovieHistory(ctx context.Context, movieID int, page common.PageParams) (*common.CatalogHistoryPage, error) {
+	op := getOp(QueryGetCatalogHistory)
+	meta := common.Envelop{
+		"movie_id": movieID,
+		"limit":    page.Limit,
+		"context":  op,
+	}
+
+	var cur *catalogHistoryCursor
+	if page.Cursor != "" {
+		cur = &catalogHistoryCursor{}
+		if err := utils.DecodeCursor(page.Cursor, cur); err != nil {
+			return nil, apperror.ErrInvalidCursor(err, r.logger, meta)
+		}
+	}
+
+	base, err := getQuery(QueryGetCatalogHistory, r.logger, meta)
+	if err != nil || base == "" {
+		return nil, err
+	}
+
+	var queryBuilder strings.Builder
+	queryBuilder.WriteString(base)
+	args := []any{movieID}
+
+	if cur != nil {
+		queryBuilder.WriteString(" AND h.id < $2")
+		args = append(args, cur.ID)
+	}
+	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY h.id DESC LIMIT $%d", len(args)+1))
+	args = append(args, page.Limit+1)
+
+	rows, err := r.db.Query(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "catalog_history", meta)
+	}
+	defer rows.Close()
+
+	entries, err := scanRowsToSlice(rows, scanCatalogEntry, r.logger, op, meta, page.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &common.CatalogHistoryPage{Entries: entries}
+	if len(entries) > page.Limit {
+		result.Entries = entries[:page.Limit]
+		result.HasMore = true
+
+		next := catalogHistoryCursor{ID: result.Entries[page.Limit-1].ID}
+		if result.NextCursor, err = utils.EncodeCursor(next); err != nil {
+			return nil, apperror.ErrInternalServer(err, r.logger, meta)
+		}
+	}
+
+	return result, nil
+}
+
+// RevertEntry undoes one recorded change on top of the movie's current state
+// and records the revert as a change of its own. Fields and links the entry
+// did not touch keep their current values.
+func (r *CatalogRepository) RevertEntry(ctx context.Context, userID int, entryID int64) (*model.CatalogHistoryEntry, error) {
+	op := getOp(QueryGetCatalogEntry)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"entry_id": entryID,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryGetCatalogEntry, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, entryID)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "catalog_history", meta)
+	}
+	entries, err := scanRowsToSlice(rows, scanCatalogEntry, r.logger, op, meta, 1)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, apperror.ErrCatalogEntryNotFound(pgx.ErrNoRows, r.logger, meta)
+	}
+	target := entries[0]
+	meta["movie_id"] = target.MovieID
+
+	return r.change(ctx, userID, target.MovieID, model.CatalogRevert, &target.ID, op, meta, func(tx pgx.Tx, current *model.MovieSnapshot) error {
+		return r.restore(ctx, tx, target.MovieID, current.Revert(target.Before, target.After), meta)
+	})
+}
+
+// GetAdminMovie retrieves the columns of a movie, including soft-deleted ones
+func (r *CatalogRepository) GetAdminMovie(ctx context.Context, movieID int) (*model.Movie, error) {
+	op := getOp(QueryGetAdminMovie)
+	meta := common.Envelop{
+		"movie_id": movieID,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryGetAdminMovie, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, movieID)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "movies", meta)
+	}
+	defer rows.Close()
+
+	movies, err := scanRowsToSlice(rows, scanMovie, r.logger, op, meta, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(movies) == 0 {
+		return nil, apperror.ErrMovieNotFound(pgx.ErrNoRows, r.logger, meta)
+	}
+
+	return &movies[0], nil
+}
+
+// change locks a movie, applies mutate and records the before and after
+// snapshots in the catalog history, all in one transaction.
+func (r *CatalogRepository) change(
+	ctx context.Context,
+	userID int,
+	movieID int,
+	action string,
+	revertedID *int64,
+	op string,
+	meta common.Envelop,
+	mutate func(tx pgx.Tx, current *model.MovieSnapshot) error,
+) (*model.CatalogHistoryEntry, error) {
+	lockQuery, err := getQuery(QueryLockMovie, r.logger, meta)
+	if err != nil || lockQuery == "" {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	var id int
+	if err := tx.QueryRow(ctx, lockQuery, movieID).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperror.ErrMovieNotFound(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "movies", meta)
+	}
+
+	before, err := r.snapshot(ctx, tx, movieID, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mutate(tx, before); err != nil {
+		return nil, err
+	}
+
+	after, err := r.snapshot(ctx, tx, movieID, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &model.CatalogHistoryEntry{
+		MovieID:         movieID,
+		UserID:          &userID,
+		Action:          action,
+		Before:          before,
+		After:           after,
+		RevertedEntryID: revertedID,
+	}
+	if err := r.addHistory(ctx, tx, entry, meta); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+
+	r.logger.Info("catalog movie successfully changed", "action", action, "meta", meta)
+	return entry, nil
+}
+
+// attach links the ids of rel to a movie, creating keywords given as words when missing
+func (r *CatalogRepository) attach(ctx context.Context, tx pgx.Tx, movieID int, rel common.CatalogRelations, meta common.Envelop) error {
+	op := getOp(QueryAttachRelation)
+
+	tpl, err := getQuery(QueryAttachRelation, r.logger, meta)
+	if err != nil || tpl == "" {
+		return err
+	}
+	missingTpl, err := getQuery(QueryFindMissingIDs, r.logger, meta)
+	if err != nil || missingTpl == "" {
+		return err
+	}
+
+	keywordIDs := rel.KeywordIDs
+	for _, word := range rel.Keywords {
+		id, err := r.findOrCreateKeyword(ctx, tx, word, meta)
+		if err != nil {
+			return err
+		}
+		keywordIDs = append(keywordIDs, id)
+	}
+
+	links := relationIDs(rel.GenreIDs, rel.ActorIDs, keywordIDs)
+
+	unknown := false
+	for kind, ids := range links {
+		jr := catalogRelations[kind]
+		var missing []int
+		if err := tx.QueryRow(ctx, fmt.Sprintf(missingTpl, jr.table), ids).Scan(&missing); err != nil {
+			return handleDatabaseError(err, r.logger, op, jr.table, meta)
+		}
+		if len(missing) > 0 {
+			meta["unknown_"+jr.column+"s"] = missing
+			unknown = true
+		}
+	}
+	if unknown {
+		return apperror.ErrUnknownCatalogIDs(nil, r.logger, meta)
+	}
+
+	for kind, ids := range links {
+		jr := catalogRelations[kind]
+		if _, err := tx.Exec(ctx, fmt.Sprintf(tpl, jr.joinTable, jr.column), movieID, ids); err != nil {
+			return handleDatabaseError(err, r.logger, op, jr.joinTable, meta)
+		}
+	}
+	return nil
+}
+
+// restore writes a snapshot back to a movie, replacing its links
+func (r *CatalogRepository) restore(ctx context.Context, tx pgx.Tx, movieID int, s model.MovieSnapshot, meta common.Envelop) error {
+	op := getOp(QueryRestoreMovieSnapshot)
+
+	query, err := getQuery(QueryRestoreMovieSnapshot, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+	retainTpl, err := getQuery(QueryRetainRelation, r.logger, meta)
+	if err != nil || retainTpl == "" {
+		return err
+	}
+	attachTpl, err := getQuery(QueryAttachRelation, r.logger, meta)
+	if err != nil || attachTpl == "" {
+		return err
+	}
+
+	var releaseYear *int
+	if s.ReleaseYear != 0 {
+		releaseYear = &s.ReleaseYear
+	}
+
+	_, err = tx.Exec(ctx, query, movieID,
+		s.TMDB_ID, s.Title, s.Tagline, releaseYear, s.Overview,
+		s.Score, s.Popularity, s.Language, s.PosterURL, s.TrailerURL, s.Deleted,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // the old tmdb_id was taken since
+			return apperror.ErrMovieAlreadyExists(err, r.logger, meta)
+		}
+		return handleDatabaseError(err, r.logger, op, "movies", meta)
+	}
+
+	for kind, ids := range map[string][]int{
+		model.FeatureGenre:   s.GenreIDs,
+		model.FeatureCast:    s.ActorIDs,
+		model.FeatureKeyword: s.KeywordIDs,
+	} {
+		jr := catalogRelations[kind]
+		if ids == nil {
+			ids = []int{}
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf(retainTpl, jr.joinTable, jr.column), movieID, ids); err != nil {
+			return handleDatabaseError(err, r.logger, op, jr.joinTable, meta)
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf(attachTpl, jr.joinTable, jr.column), movieID, ids); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" { // a linked row was deleted since
+				meta["relation"] = jr.table
+				return apperror.ErrUnknownCatalogIDs(err, r.logger, meta)
+			}
+			return handleDatabaseError(err, r.logger, op, jr.joinTable, meta)
+		}
+	}
+	return nil
+}
+
+func (r *CatalogRepository) findOrCreateKeyword(ctx context.Context, tx pgx.Tx, word string, meta common.Envelop) (int, error) {
+	op := getOp(QueryCreateKeyword)
+
+	findQuery, err := getQuery(QueryFindKeyword, r.logger, meta)
+	if err != nil || findQuery == "" {
+		return 0, err
+	}
+	createQuery, err := getQuery(QueryCreateKeyword, r.logger, meta)
+	if err != nil || createQuery == "" {
+		return 0, err
+	}
+
+	var id int
+	err = tx.QueryRow(ctx, findQuery, word).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, handleDatabaseError(err, r.logger, op, "keywords", meta)
+	}
+
+	if err := tx.QueryRow(ctx, createQuery, word).Scan(&id); err != nil {
+		return 0, handleDatabaseError(err, r.logger, op, "keywords", meta)
+	}
+	return id, nil
+}
+
+func (r *CatalogRepository) snapshot(ctx context.Context, tx pgx.Tx, movieID int, meta common.Envelop) (*model.MovieSnapshot, error) {
+	op := getOp(QueryGetMovieSnapshot)
+
+	query, err := getQuery(QueryGetMovieSnapshot, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	var s model.MovieSnapshot
+	if err := tx.QueryRow(ctx, query, movieID).Scan(&s); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "movies", meta)
+	}
+	return &s, nil
+}
+
+// addHistory inserts entry and fills in its id and creation time
+func (r *CatalogRepository) addHistory(ctx context.Context, tx pgx.Tx, entry *model.CatalogHistoryEntry, meta common.Envelop) error {
+	op := getOp(QueryAddCatalogHistory)
+
+	query, err := getQuery(QueryAddCatalogHistory, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, query,
+		entry.MovieID, entry.UserID, entry.Action, entry.Before, entry.After, entry.RevertedEntryID,
+	).Scan(&entry.ID, &entry.TimeCreated)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "catalog_history", meta)
+	}
+	return nil
+}
+
+// relationIDs groups the non-empty id lists by relation kind
+func relationIDs(genreIDs, actorIDs, keywordIDs []int) map[string][]int {
+	links := make(map[string][]int, len(catalogRelations))
+	for kind, ids := range map[string][]int{
+		model.FeatureGenre:   genreIDs,
+		model.FeatureCast:    actorIDs,
+		model.FeatureKeyword: keywordIDs,
+	} {
+		if len(ids) > 0 {
+			links[kind] = ids
+		}
+	}
+	return links
+}
@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/redis/go-redis/v9"
+)
+
+/* CeremonySessionStore Interface */
+// CeremonySessionStore keeps the state of a WebAuthn ceremony between its
+// begin and finish requests. Sessions expire at their Expires time or, when
+// it isn't set, after the store's TTL.
+type CeremonySessionStore interface {
+	Save(ctx context.Context, key string, data webauthn.SessionData) error
+	// Take returns a session and deletes it in one step, so each ceremony
+	// is finished at most once
+	Take(ctx context.Context, key string) (webauthn.SessionData, bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// ceremonyKeyPrefix namespaces ceremony sessions among the other Redis keys
+const ceremonyKeyPrefix = "webauthn:session:"
+
+// defaultCeremonyTimeout is go-webauthn's timeout when none is configured
+const defaultCeremonyTimeout = 5 * time.Minute
+
+// NewCeremonySessionID generates a random key for a ceremony session
+func NewCeremonySessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+// CeremonyTimeout returns the longest a login or registration ceremony may
+// take under cfg, which is how long its session has to be kept
+func CeremonyTimeout(cfg *webauthn.Config) time.Duration {
+	if cfg == nil {
+		return defaultCeremonyTimeout
+	}
+	timeout := max(
+		cfg.Timeouts.Login.Timeout,
+		cfg.Timeouts.Login.TimeoutUVD,
+		cfg.Timeouts.Registration.Timeout,
+		cfg.Timeouts.Registration.TimeoutUVD,
+	)
+	if timeout <= 0 {
+		return defaultCeremonyTimeout
+	}
+	return timeout
+}
+
+// ceremonyTTL is how long to keep a session: until it expires, or ttl
+func ceremonyTTL(data webauthn.SessionData, ttl time.Duration) time.Duration {
+	if !data.Expires.IsZero() {
+		return time.Until(data.Expires)
+	}
+	return ttl
+}
+
+// -----------------------------------------------------------
+// REDIS
+// -----------------------------------------------------------
+
+// RedisCeremonySessionStore shares ceremony sessions between instances, so a
+// ceremony can finish on another node than it began on
+type RedisCeremonySessionStore struct {
+	client *redis.Client
+	ttl    time.Duration
+	logger logging.Logger
+}
+
+func NewRedisCeremonySessionStore(client *redis.Client, ttl time.Duration, logger logging.Logger) *RedisCeremonySessionStore {
+	return &RedisCeremonySessionStore{
+		client: client,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// Save stores a session as JSON with the TTL of its ceremony
+func (s *RedisCeremonySessionStore) Save(ctx context.Context, key string, data webauthn.SessionData) error {
+	meta := common.Envelop{"op": "RedisCeremonySessionStore.Save"}
+
+	ttl := ceremonyTTL(data, s.ttl)
+	if ttl <= 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return apperror.ErrInternalServer(err, s.logger, meta)
+	}
+	if err := s.client.Set(ctx, ceremonyKeyPrefix+key, payload, ttl).Err(); err != nil {
+		return apperror.ErrInternalServer(err, s.logger, meta)
+	}
+	return nil
+}
+
+// Take gets and deletes a session with GETDEL, so two requests can't both
+// finish the same ceremony
+func (s *RedisCeremonySessionStore) Take(ctx context.Context, key string) (webauthn.SessionData, bool, error) {
+	meta := common.Envelop{"op": "RedisCeremonySessionStore.Take"}
+
+	payload, err := s.client.GetDel(ctx, ceremonyKeyPrefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return webauthn.SessionData{}, false, nil
+		}
+		return webauthn.SessionData{}, false, apperror.ErrInternalServer(err, s.logger, meta)
+	}
+
+	var data webauthn.SessionData
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return webauthn.SessionData{}, false, apperror.ErrInternalServer(err, s.logger, meta)
+	}
+	return data, true, nil
+}
+
+// Delete removes a session, if it is still there
+func (s *RedisCeremonySessionStore) Delete(ctx context.Context, key string) error {
+	meta := common.Envelop{"op": "RedisCeremonySessionStore.Delete"}
+
+	if err := s.client.Del(ctx, ceremonyKeyPrefix+key).Err(); err != nil {
+		return apperror.ErrInternalServer(err, s.logger, meta)
+	}
+	return nil
+}
+
+// -----------------------------------------------------------
+// IN-MEMORY
+// -----------------------------------------------------------
+
+type memoryCeremonySession struct {
+	data    webauthn.SessionData
+	expires time.Time
+}
+
+// MemoryCeremonySessionStore keeps ceremony sessions in this process. It
+// suits tests and a single node; behind a load balancer use Redis.
+type MemoryCeremonySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]memoryCeremonySession
+	ttl      time.Duration
+}
+
+func NewMemoryCeremonySessionStore(ttl time.Duration) *MemoryCeremonySessionStore {
+	return &MemoryCeremonySessionStore{
+		sessions: make(map[string]memoryCeremonySession),
+		ttl:      ttl,
+	}
+}
+
+// Save stores a session until its ceremony times out. Expired sessions are
+// evicted on the way, so abandoned ceremonies don't pile up.
+func (s *MemoryCeremonySessionStore) Save(_ context.Context, key string, data webauthn.SessionData) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, session := range s.sessions {
+		if now.After(session.expires) {
+			delete(s.sessions, k)
+		}
+	}
+
+	s.sessions[key] = memoryCeremonySession{
+		data:    data,
+		expires: now.Add(ceremonyTTL(data, s.ttl)),
+	}
+	return nil
+}
+
+// Take returns and deletes a session that hasn't expired
+func (s *MemoryCeremonySessionStore) Take(_ context.Context, key string) (webauthn.SessionData, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[key]
+	if !ok {
+		return webauthn.SessionData{}, false, nil
+	}
+	delete(s.sessions, key)
+
+	if time.Now().After(session.expires) {
+		return webauthn.SessionData{}, false, nil
+	}
+	return session.data, true, nil
+}
+
+// Delete removes a session, if it is still there
+func (s *MemoryCeremonySessionStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, key)
+	return nil
+}
@@ -0,0 +1,427 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+/* CollectionImportStore Interface */
+type CollectionImportStore interface {
+	CreateCollectionImport(ctx context.Context, imp *model.CollectionImport) error
+	UpdateImportProgress(ctx context.Context, importID int, processed int) error
+	FinishCollectionImport(ctx context.Context, importID int, rows []model.CollectionImportRow, failure *string) error
+	GetCollectionImport(ctx context.Context, userID int, importID int) (*model.CollectionImport, error)
+	CommitCollectionImport(ctx context.Context, userID int, importID int, collection string, items []common.CollectionImportItem) (int, error)
+	DeleteCollectionImport(ctx context.Context, userID int, importID int) error
+	FindImportMoviesByID(ctx context.Context, tmdbIDs []int, imdbIDs []string) ([]model.ImportCandidate, error)
+	FindImportTitleCandidates(ctx context.Context, rows []model.CollectionImportRow, limit int) (map[int][]model.ImportCandidate, error)
+	FailStaleCollectionImports(ctx context.Context, silentSince time.Time, reason string) (int64, error)
+	PurgeCollectionImports(ctx context.Context, updatedBefore time.Time) (int64, error)
+}
+
+type CollectionImportRepository struct {
+	db     *pgxpool.Pool
+	logger logging.Logger
+}
+
+func NewCollectionImportRepository(db *pgxpool.Pool, logger logging.Logger) *CollectionImportRepository {
+	return &CollectionImportRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateCollectionImport records a new import in the processing state
+func (r *CollectionImportRepository) CreateCollectionImport(ctx context.Context, imp *model.CollectionImport) error {
+	op := getOp(QueryCreateCollectionImport)
+	meta := common.Envelop{
+		"user_id": imp.UserID,
+		"source":  imp.Source,
+		"rows":    imp.Total,
+		"context": op,
+	}
+
+	query, err := getQuery(QueryCreateCollectionImport, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	err = r.db.QueryRow(ctx, query, imp.UserID, imp.Source, imp.Collection, imp.FileName, imp.Total).
+		Scan(&imp.ID, &imp.Status, &imp.TimeCreated)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "collection_imports", meta)
+	}
+	return nil
+}
+
+// UpdateImportProgress records how many rows of a processing import are matched
+func (r *CollectionImportRepository) UpdateImportProgress(ctx context.Context, importID int, processed int) error {
+	op := getOp(QueryUpdateImportProgress)
+	meta := common.Envelop{"import_id": importID, "processed": processed, "context": op}
+
+	query, err := getQuery(QueryUpdateImportProgress, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	if _, err := r.db.Exec(ctx, query, importID, processed); err != nil {
+		return handleDatabaseError(err, r.logger, op, "collection_imports", meta)
+	}
+	return nil
+}
+
+// FinishCollectionImport stores the matched rows of an import, or marks it
+// failed when failure is set. An import discarded meanwhile is left alone.
+func (r *CollectionImportRepository) FinishCollectionImport(ctx context.Context, importID int, rows []model.CollectionImportRow, failure *string) error {
+	op := getOp(QueryFinishCollectionImport)
+	meta := common.Envelop{"import_id": importID, "context": op}
+
+	query, err := getQuery(QueryFinishCollectionImport, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	status := model.ImportStatusReady
+	if failure != nil {
+		status = model.ImportStatusFailed
+	}
+	if _, err := r.db.Exec(ctx, query, importID, status, rows, failure); err != nil {
+		return handleDatabaseError(err, r.logger, op, "collection_imports", meta)
+	}
+	return nil
+}
+
+// GetCollectionImport retrieves one of the user's imports with its preview
+func (r *CollectionImportRepository) GetCollectionImport(ctx context.Context, userID int, importID int) (*model.CollectionImport, error) {
+	op := getOp(QueryGetCollectionImport)
+	meta := common.Envelop{"user_id": userID, "import_id": importID, "context": op}
+
+	query, err := getQuery(QueryGetCollectionImport, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	var imp model.CollectionImport
+	err = r.db.QueryRow(ctx, query, importID, userID).Scan(
+		&imp.ID,
+		&imp.UserID,
+		&imp.Source,
+		&imp.Collection,
+		&imp.FileName,
+		&imp.Status,
+		&imp.Total,
+		&imp.Processed,
+		&imp.Rows,
+		&imp.Error,
+		&imp.TimeCreated,
+		&imp.TimeCommitted,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperror.ErrImportNotFound(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "collection_imports", meta)
+	}
+	return &imp, nil
+}
+
+// CommitCollectionImport saves the items of a ready import into collection
+// in one transaction and marks the import committed. Favorites and watchlist
+// items go to user_movies, watches to the diary. It returns how many rows
+// were added; movies already saved are not counted.
+func (r *CollectionImportRepository) CommitCollectionImport(
+	ctx context.Context,
+	userID int,
+	importID int,
+	collection string,
+	items []common.CollectionImportItem,
+) (int, error) {
+	op := getOp(QueryMarkImportCommitted)
+	meta := common.Envelop{
+		"user_id":    userID,
+		"import_id":  importID,
+		"collection": collection,
+		"items":      len(items),
+		"context":    op,
+	}
+
+	lockQuery, err := getQuery(QueryLockCollectionImport, r.logger, meta)
+	if err != nil || lockQuery == "" {
+		return 0, err
+	}
+	commitQuery, err := getQuery(QueryMarkImportCommitted, r.logger, meta)
+	if err != nil || commitQuery == "" {
+		return 0, err
+	}
+
+	movieIDs := make([]int, 0, len(items))
+	dates := make([]time.Time, 0, len(items))
+	for _, item := range items {
+		movieIDs = append(movieIDs, item.MovieID)
+		dates = append(dates, item.Date)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	var status string
+	if err := tx.QueryRow(ctx, lockQuery, importID, userID).Scan(&status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, apperror.ErrImportNotFound(err, r.logger, meta)
+		}
+		return 0, handleDatabaseError(err, r.logger, op, "collection_imports", meta)
+	}
+	if status != model.ImportStatusReady {
+		meta["status"] = status
+		return 0, apperror.ErrImportNotReady(nil, r.logger, meta)
+	}
+
+	var added int64
+	if len(items) > 0 {
+		var tag pgconn.CommandTag
+		if collection == RelWatched {
+			tag, err = r.logWatches(ctx, tx, userID, movieIDs, dates, meta)
+		} else {
+			tag, err = r.addToCollection(ctx, tx, userID, collection, movieIDs, dates, meta)
+		}
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				return 0, apperror.ErrMovieNotFound(err, r.logger, meta)
+			}
+			return 0, handleDatabaseError(err, r.logger, op, collection, meta)
+		}
+		added = tag.RowsAffected()
+	}
+
+	if _, err := tx.Exec(ctx, commitQuery, importID); err != nil {
+		return 0, handleDatabaseError(err, r.logger, op, "collection_imports", meta)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+
+	meta["added"] = added
+	r.logger.Info("collection import committed", "meta", meta)
+	return int(added), nil
+}
+
+func (r *CollectionImportRepository) addToCollection(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID int,
+	collection string,
+	movieIDs []int,
+	dates []time.Time,
+	meta common.Envelop,
+) (pgconn.CommandTag, error) {
+	query, err := getQuery(QueryImportAddToCollection, r.logger, meta)
+	if err != nil || query == "" {
+		return pgconn.CommandTag{}, err
+	}
+	return tx.Exec(ctx, query, userID, collection, movieIDs, dates)
+}
+
+// logWatches logs the watches in the diary and, like LogWatch, takes the
+// movies watched for the first time off the watchlist. Existing entries dated
+// after an imported watch become rewatches.
+func (r *CollectionImportRepository) logWatches(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID int,
+	movieIDs []int,
+	dates []time.Time,
+	meta common.Envelop,
+) (pgconn.CommandTag, error) {
+	lockQuery, err := getQuery(QueryLockWatches, r.logger, meta)
+	if err != nil || lockQuery == "" {
+		return pgconn.CommandTag{}, err
+	}
+	watchlistQuery, err := getQuery(QueryImportClearWatchlist, r.logger, meta)
+	if err != nil || watchlistQuery == "" {
+		return pgconn.CommandTag{}, err
+	}
+	query, err := getQuery(QueryImportLogWatches, r.logger, meta)
+	if err != nil || query == "" {
+		return pgconn.CommandTag{}, err
+	}
+	rewatchQuery, err := getQuery(QueryImportMarkRewatches, r.logger, meta)
+	if err != nil || rewatchQuery == "" {
+		return pgconn.CommandTag{}, err
+	}
+
+	// Takes the lock of LogWatch on every movie, in id order so two imports
+	// of the same user cannot deadlock
+	locked := slices.Clone(movieIDs)
+	slices.Sort(locked)
+	batch := &pgx.Batch{}
+	for _, movieID := range slices.Compact(locked) {
+		batch.Queue(lockQuery, userID, movieID)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	if _, err := tx.Exec(ctx, watchlistQuery, userID, movieIDs); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	tag, err := tx.Exec(ctx, query, userID, movieIDs, dates)
+	if err != nil {
+		return tag, err
+	}
+	if _, err := tx.Exec(ctx, rewatchQuery, userID, movieIDs, dates); err != nil {
+		return tag, err
+	}
+	return tag, nil
+}
+
+// DeleteCollectionImport discards one of the user's imports and its preview
+func (r *CollectionImportRepository) DeleteCollectionImport(ctx context.Context, userID int, importID int) error {
+	op := getOp(QueryDeleteCollectionImport)
+	meta := common.Envelop{"user_id": userID, "import_id": importID, "context": op}
+
+	query, err := getQuery(QueryDeleteCollectionImport, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, query, importID, userID)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "collection_imports", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrImportNotFound(nil, r.logger, meta)
+	}
+	return nil
+}
+
+// FindImportMoviesByID retrieves the catalog movies having one of the given TMDB or IMDb ids
+func (r *CollectionImportRepository) FindImportMoviesByID(ctx context.Context, tmdbIDs []int, imdbIDs []string) ([]model.ImportCandidate, error) {
+	op := getOp(QueryImportCandidatesByID)
+	meta := common.Envelop{"tmdb_ids": len(tmdbIDs), "imdb_ids": len(imdbIDs), "context": op}
+
+	if len(tmdbIDs)+len(imdbIDs) == 0 {
+		return nil, nil
+	}
+
+	query, err := getQuery(QueryImportCandidatesByID, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, tmdbIDs, imdbIDs)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "movies", meta)
+	}
+	defer rows.Close()
+
+	var movies []model.ImportCandidate
+	for rows.Next() {
+		c := model.ImportCandidate{Score: 1}
+		if err := rows.Scan(&c.MovieID, &c.TMDB_ID, &c.IMDbID, &c.Title, &c.ReleaseYear, &c.PosterURL); err != nil {
+			return nil, handleDatabaseError(err, r.logger, op, "movies", meta)
+		}
+		movies = append(movies, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "movies", meta)
+	}
+	return movies, nil
+}
+
+// FindImportTitleCandidates looks up the movies whose title resembles each
+// row's, within a year of it when the row has one. Candidates are keyed by
+// row number, best first.
+func (r *CollectionImportRepository) FindImportTitleCandidates(ctx context.Context, rows []model.CollectionImportRow, limit int) (map[int][]model.ImportCandidate, error) {
+	op := getOp(QueryImportCandidatesByTitle)
+	meta := common.Envelop{"rows": len(rows), "context": op}
+
+	candidates := make(map[int][]model.ImportCandidate)
+	if len(rows) == 0 {
+		return candidates, nil
+	}
+
+	query, err := getQuery(QueryImportCandidatesByTitle, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	idx := make([]int, 0, len(rows))
+	titles := make([]string, 0, len(rows))
+	years := make([]*int, 0, len(rows))
+	for _, row := range rows {
+		idx = append(idx, row.Row)
+		titles = append(titles, row.Title)
+		years = append(years, row.Year)
+	}
+
+	result, err := r.db.Query(ctx, query, idx, titles, years, limit)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "movies", meta)
+	}
+	defer result.Close()
+
+	for result.Next() {
+		var (
+			row int
+			c   model.ImportCandidate
+		)
+		if err := result.Scan(&row, &c.MovieID, &c.TMDB_ID, &c.IMDbID, &c.Title, &c.ReleaseYear, &c.PosterURL, &c.Score); err != nil {
+			return nil, handleDatabaseError(err, r.logger, op, "movies", meta)
+		}
+		candidates[row] = append(candidates[row], c)
+	}
+	if err := result.Err(); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "movies", meta)
+	}
+	return candidates, nil
+}
+
+// FailStaleCollectionImports fails processing imports without progress since silentSince
+func (r *CollectionImportRepository) FailStaleCollectionImports(ctx context.Context, silentSince time.Time, reason string) (int64, error) {
+	op := getOp(QueryFailStaleCollectionImports)
+	meta := common.Envelop{"silent_since": silentSince, "context": op}
+
+	query, err := getQuery(QueryFailStaleCollectionImports, r.logger, meta)
+	if err != nil || query == "" {
+		return 0, err
+	}
+
+	tag, err := r.db.Exec(ctx, query, silentSince, reason)
+	if err != nil {
+		return 0, handleDatabaseError(err, r.logger, op, "collection_imports", meta)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PurgeCollectionImports deletes finished imports last updated before updatedBefore
+func (r *CollectionImportRepository) PurgeCollectionImports(ctx context.Context, updatedBefore time.Time) (int64, error) {
+	op := getOp(QueryPurgeCollectionImports)
+	meta := common.Envelop{"updated_before": updatedBefore, "context": op}
+
+	query, err := getQuery(QueryPurgeCollectionImports, r.logger, meta)
+	if err != nil || query == "" {
+		return 0, err
+	}
+
+	tag, err := r.db.Exec(ctx, query, updatedBefore)
+	if err != nil {
+		return 0, handleDatabaseError(err, r.logger, op, "collection_imports", meta)
+	}
+	return tag.RowsAffected(), nil
+}
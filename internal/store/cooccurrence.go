@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+)
+
+// RebuildCooccurrence replaces movie_cooccurrence with fresh scores computed
+// from user_movies. Pairs saved together by fewer than minSupport distinct
+// users are dropped so a single user's list can never be inferred from them.
+// It returns the number of stored (directed) pairs.
+func (r *MovieRepository) RebuildCooccurrence(ctx context.Context, minSupport int) (int64, error) {
+	op := getOp(QueryRebuildCooccurrence)
+	meta := common.Envelop{
+		"min_support": minSupport,
+		"context":     op,
+	}
+
+	clearQuery, err := getQuery(QueryClearCooccurrence, r.logger, meta)
+	if err != nil || clearQuery == "" {
+		return 0, err
+	}
+	query, err := getQuery(QueryRebuildCooccurrence, r.logger, meta)
+	if err != nil || query == "" {
+		return 0, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	if _, err := tx.Exec(ctx, clearQuery); err != nil {
+		return 0, handleDatabaseError(err, r.logger, op, "movie_cooccurrence", meta)
+	}
+
+	tag, err := tx.Exec(ctx, query, minSupport)
+	if err != nil {
+		return 0, handleDatabaseError(err, r.logger, op, "movie_cooccurrence", meta)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// GetAlsoSavedMovies retrieves the movies most often saved by users who also saved movieID
+func (r *MovieRepository) GetAlsoSavedMovies(ctx context.Context, movieID int, limit int) ([]model.Movie, error) {
+	op := getOp(QueryGetAlsoSavedMovies)
+	meta := common.Envelop{
+		"movie_id": movieID,
+		"limit":    limit,
+		"context":  op,
+	}
+
+	rows, err := r.queryRowsWithErrorHandling(ctx, QueryGetAlsoSavedMovies, op, meta, movieID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		return []model.Movie{}, nil
+	}
+	defer rows.Close()
+
+	return scanRowsToSlice(rows, scanMovie, r.logger, op, meta, limit)
+}
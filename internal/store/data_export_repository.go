@@ -0,0 +1,244 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+/* DataExportStore Interface */
+type DataExportStore interface {
+	CreateDataExport(ctx context.Context, userID int) (*model.DataExport, error)
+	FinishDataExport(ctx context.Context, exportID int, filePath string, size int64, tokenHash []byte, expires time.Time) error
+	FailDataExport(ctx context.Context, exportID int, reason string) error
+	GetLatestDataExport(ctx context.Context, userID int) (*model.DataExport, error)
+	FindDataExport(ctx context.Context, tokenHash []byte) (*model.DataExport, error)
+	ConsumeDataExport(ctx context.Context, exportID int) (*string, error)
+	ExpireDataExports(ctx context.Context, now time.Time, staleBefore time.Time, reason string) ([]string, error)
+	GetLoginHistory(ctx context.Context, userID int) ([]model.LoginRecord, error)
+}
+
+type DataExportRepository struct {
+	db     *pgxpool.Pool
+	logger logging.Logger
+}
+
+func NewDataExportRepository(db *pgxpool.Pool, logger logging.Logger) *DataExportRepository {
+	return &DataExportRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateDataExport records a new export in the processing state. Users can
+// only have one export processing at a time.
+func (r *DataExportRepository) CreateDataExport(ctx context.Context, userID int) (*model.DataExport, error) {
+	op := getOp(QueryCreateDataExport)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryCreateDataExport, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	export := &model.DataExport{UserID: userID}
+	err = r.db.QueryRow(ctx, query, userID).Scan(&export.ID, &export.Status, &export.TimeCreated)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, apperror.ErrDataExportInProgress(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "data_exports", meta)
+	}
+	return export, nil
+}
+
+// FinishDataExport marks a processing export ready to be downloaded until expires
+func (r *DataExportRepository) FinishDataExport(ctx context.Context, exportID int, filePath string, size int64, tokenHash []byte, expires time.Time) error {
+	op := getOp(QueryFinishDataExport)
+	meta := common.Envelop{"export_id": exportID, "size": size, "context": op}
+
+	query, err := getQuery(QueryFinishDataExport, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	if _, err := r.db.Exec(ctx, query, exportID, filePath, size, tokenHash, expires); err != nil {
+		return handleDatabaseError(err, r.logger, op, "data_exports", meta)
+	}
+	return nil
+}
+
+// FailDataExport marks a processing export failed
+func (r *DataExportRepository) FailDataExport(ctx context.Context, exportID int, reason string) error {
+	op := getOp(QueryFailDataExport)
+	meta := common.Envelop{"export_id": exportID, "context": op}
+
+	query, err := getQuery(QueryFailDataExport, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	if _, err := r.db.Exec(ctx, query, exportID, reason); err != nil {
+		return handleDatabaseError(err, r.logger, op, "data_exports", meta)
+	}
+	return nil
+}
+
+// GetLatestDataExport returns the user's most recent export
+func (r *DataExportRepository) GetLatestDataExport(ctx context.Context, userID int) (*model.DataExport, error) {
+	op := getOp(QueryGetLatestDataExport)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryGetLatestDataExport, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	var export model.DataExport
+	err = r.db.QueryRow(ctx, query, userID).Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Status,
+		&export.Size,
+		&export.Error,
+		&export.TimeCreated,
+		&export.TimeReady,
+		&export.TimeExpires,
+		&export.TimeDownloaded,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperror.ErrDataExportNotFound(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "data_exports", meta)
+	}
+	return &export, nil
+}
+
+// FindDataExport returns the ready, unexpired export a download link points
+// to, with its file. The link stays usable until ConsumeDataExport.
+func (r *DataExportRepository) FindDataExport(ctx context.Context, tokenHash []byte) (*model.DataExport, error) {
+	op := getOp(QueryFindDataExport)
+	meta := common.Envelop{"context": op}
+
+	query, err := getQuery(QueryFindDataExport, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	export := &model.DataExport{Status: model.DataExportReady}
+	err = r.db.QueryRow(ctx, query, tokenHash).Scan(&export.ID, &export.UserID, &export.FilePath, &export.Size)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperror.ErrDataExportLinkInvalid(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "data_exports", meta)
+	}
+	return export, nil
+}
+
+// ConsumeDataExport uses up the download link of a ready export and returns
+// its file. Exports no longer ready, e.g. downloaded concurrently, return nil.
+func (r *DataExportRepository) ConsumeDataExport(ctx context.Context, exportID int) (*string, error) {
+	op := getOp(QueryConsumeDataExport)
+	meta := common.Envelop{"export_id": exportID, "context": op}
+
+	query, err := getQuery(QueryConsumeDataExport, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	var filePath *string
+	err = r.db.QueryRow(ctx, query, exportID).Scan(&filePath)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "data_exports", meta)
+	}
+	return filePath, nil
+}
+
+// ExpireDataExports expires the archives past their expiry or already
+// downloaded, and fails exports still processing since staleBefore with reason.
+// It returns the archive files to delete.
+func (r *DataExportRepository) ExpireDataExports(ctx context.Context, now time.Time, staleBefore time.Time, reason string) ([]string, error) {
+	op := getOp(QueryExpireDataExports)
+	meta := common.Envelop{"stale_before": staleBefore, "context": op}
+
+	query, err := getQuery(QueryExpireDataExports, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, now, staleBefore, reason)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "data_exports", meta)
+	}
+	defer rows.Close()
+
+	var files []string
+	for rows.Next() {
+		var path *string
+		if err := rows.Scan(&path); err != nil {
+			return nil, handleDatabaseError(err, r.logger, op, "data_exports", meta)
+		}
+		if path != nil {
+			files = append(files, *path)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "data_exports", meta)
+	}
+	return files, nil
+}
+
+// GetLoginHistory returns the refresh tokens issued to the user, newest first
+func (r *DataExportRepository) GetLoginHistory(ctx context.Context, userID int) ([]model.LoginRecord, error) {
+	op := getOp(QueryGetLoginHistory)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryGetLoginHistory, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "tokens", meta)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	history := []model.LoginRecord{}
+	for rows.Next() {
+		var record model.LoginRecord
+		if err := rows.Scan(
+			&record.ID,
+			&record.DeviceLabel,
+			&record.UserAgent,
+			&record.IPAddress,
+			&record.TimeSignedIn,
+			&record.TimeLastUsed,
+			&record.TimeExpires,
+		); err != nil {
+			return nil, handleDatabaseError(err, r.logger, op, "tokens", meta)
+		}
+		record.Active = record.TimeExpires.After(now)
+		history = append(history, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "tokens", meta)
+	}
+	return history, nil
+}
@@ -26,8 +26,18 @@ const (
 	KeyMovieID      = "movie_id"
 	RelFavorites    = "favorites"
 	RelWatchlist    = "watchlist"
+	RelWatched      = "watched" // logged in watch_diary instead of user_movies
 )
 
+// likeEscaper escapes the LIKE wildcards of user input, for patterns written
+// with ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// containsPattern returns a LIKE pattern matching s anywhere in a value
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
 func getOp(q string) string {
 	return fmt.Sprintf("store.%s", strings.TrimPrefix(q, "Query"))
 }
@@ -186,6 +196,43 @@ func scanMovie(rows pgx.Rows, m *model.Movie) error {
 	)
 }
 
+// scanCollectionMovie scans a movie row followed by the time it was added to a user collection
+func scanCollectionMovie(rows pgx.Rows, m *model.Movie) error {
+	return rows.Scan(
+		&m.ID,
+		&m.TMDB_ID,
+		&m.Title,
+		&m.Tagline,
+		&m.ReleaseYear,
+		&m.Overview,
+		&m.Score,
+		&m.Popularity,
+		&m.Language,
+		&m.PosterURL,
+		&m.TrailerURL,
+		&m.TimeAdded,
+	)
+}
+
+// scanListMovie scans a collection movie with its IMDb id and the time it was added
+func scanListMovie(rows pgx.Rows, m *model.Movie) error {
+	return rows.Scan(
+		&m.ID,
+		&m.TMDB_ID,
+		&m.Title,
+		&m.Tagline,
+		&m.ReleaseYear,
+		&m.Overview,
+		&m.Score,
+		&m.Popularity,
+		&m.Language,
+		&m.PosterURL,
+		&m.TrailerURL,
+		&m.IMDbID,
+		&m.TimeAdded,
+	)
+}
+
 // scanActor function (UPDATED: now scans 6 fields including Character)
 func scanActor(rows pgx.Rows, a *model.Actor) error {
 	return rows.Scan(
@@ -196,6 +243,141 @@ func scanActor(rows pgx.Rows, a *model.Actor) error {
 	)
 }
 
+// scanCollaborator scans an actor row followed by the number of shared movies
+func scanCollaborator(rows pgx.Rows, c *model.Collaborator) error {
+	return rows.Scan(
+		&c.ID,
+		&c.FirstName,
+		&c.LastName,
+		&c.ImageURL,
+		&c.SharedMovies,
+	)
+}
+
+// scanMovieFeature scans a (movie_id, kind, feature_id) row
+func scanMovieFeature(rows pgx.Rows, f *model.MovieFeature) error {
+	return rows.Scan(
+		&f.MovieID,
+		&f.Kind,
+		&f.FeatureID,
+	)
+}
+
+// scanReview scans a review row joined with the reviewer's name
+func scanReview(rows pgx.Rows, rv *model.Review) error {
+	return rows.Scan(
+		&rv.ID,
+		&rv.MovieID,
+		&rv.UserID,
+		&rv.UserName,
+		&rv.Rating,
+		&rv.Body,
+		&rv.ContainsSpoilers,
+		&rv.TimeCreated,
+		&rv.TimeUpdated,
+	)
+}
+
+// scanUserReview scans a review row followed by a summary of the reviewed movie
+func scanUserReview(rows pgx.Rows, rv *model.Review) error {
+	rv.Movie = &model.Movie{}
+	if err := rows.Scan(
+		&rv.ID,
+		&rv.MovieID,
+		&rv.UserID,
+		&rv.UserName,
+		&rv.Rating,
+		&rv.Body,
+		&rv.ContainsSpoilers,
+		&rv.TimeCreated,
+		&rv.TimeUpdated,
+		&rv.Movie.TMDB_ID,
+		&rv.Movie.Title,
+		&rv.Movie.ReleaseYear,
+		&rv.Movie.PosterURL,
+	); err != nil {
+		return err
+	}
+	rv.Movie.ID = rv.MovieID
+	return nil
+}
+
+// scanDiaryEntry scans a diary row followed by a summary of the watched movie
+func scanDiaryEntry(rows pgx.Rows, d *model.DiaryEntry) error {
+	d.Movie = &model.Movie{}
+	if err := rows.Scan(
+		&d.ID,
+		&d.MovieID,
+		&d.WatchedOn,
+		&d.Rewatch,
+		&d.Note,
+		&d.TimeAdded,
+		&d.Movie.TMDB_ID,
+		&d.Movie.Title,
+		&d.Movie.ReleaseYear,
+		&d.Movie.PosterURL,
+	); err != nil {
+		return err
+	}
+	d.Movie.ID = d.MovieID
+	return nil
+}
+
+// scanUserList scans a list row joined with its owner's name and item count
+func scanUserList(rows pgx.Rows, l *model.UserList) error {
+	return rows.Scan(
+		&l.ID,
+		&l.UserID,
+		&l.OwnerName,
+		&l.Name,
+		&l.Description,
+		&l.IsPublic,
+		&l.HasShareLink,
+		&l.ItemCount,
+		&l.TimeCreated,
+		&l.TimeUpdated,
+	)
+}
+
+// scanListItem scans a list item row with a summary of its movie
+func scanListItem(rows pgx.Rows, i *model.ListItem) error {
+	return rows.Scan(
+		&i.Movie.ID,
+		&i.Movie.TMDB_ID,
+		&i.Movie.Title,
+		&i.Movie.ReleaseYear,
+		&i.Movie.PosterURL,
+		&i.Note,
+		&i.Position,
+		&i.TimeAdded,
+	)
+}
+
+// scanCatalogEntry scans a catalog history entry with the name of the admin who made it
+func scanCatalogEntry(rows pgx.Rows, e *model.CatalogHistoryEntry) error {
+	return rows.Scan(
+		&e.ID,
+		&e.MovieID,
+		&e.UserID,
+		&e.UserName,
+		&e.Action,
+		&e.Before,
+		&e.After,
+		&e.RevertedEntryID,
+		&e.TimeCreated,
+	)
+}
+
+func scanReviewRevision(rows pgx.Rows, rr *model.ReviewRevision) error {
+	return rows.Scan(
+		&rr.Rating,
+		&rr.Body,
+		&rr.ContainsSpoilers,
+		&rr.TimeWritten,
+		&rr.TimeReplaced,
+	)
+}
+
 func handleDatabaseError(err error, log logging.Logger, op string, key string, meta common.Envelop) error {
 	metaData := common.Envelop{
 		"op": op,
@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// LogWatch adds a watch of a movie to the user's diary. When input.Rewatch is
+// nil it is inferred from earlier entries. The first entry of a movie, whatever
+// input.Rewatch says, also removes it from the user's watchlist.
+func (r *AccountRepository) LogWatch(ctx context.Context, userID int, input common.DiaryInput) (*model.DiaryEntry, error) {
+	op := getOp(QueryAddDiaryEntry)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"movie_id": input.MovieID,
+		"context":  op,
+	}
+
+	lockQuery, err := getQuery(QueryLockWatches, r.logger, meta)
+	if err != nil || lockQuery == "" {
+		return nil, err
+	}
+	countQuery, err := getQuery(QueryCountWatches, r.logger, meta)
+	if err != nil || countQuery == "" {
+		return nil, err
+	}
+	insertQuery, err := getQuery(QueryAddDiaryEntry, r.logger, meta)
+	if err != nil || insertQuery == "" {
+		return nil, err
+	}
+	watchlistQuery, err := getQuery(QueryRemoveFromWatchlist, r.logger, meta)
+	if err != nil || watchlistQuery == "" {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	// Serializes watches of the same movie, so only one of them is the first
+	if _, err := tx.Exec(ctx, lockQuery, userID, input.MovieID); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "watch_diary", meta)
+	}
+
+	var watches int
+	if err := tx.QueryRow(ctx, countQuery, userID, input.MovieID).Scan(&watches); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "watch_diary", meta)
+	}
+	firstWatch := watches == 0
+
+	rewatch := !firstWatch
+	if input.Rewatch != nil {
+		rewatch = *input.Rewatch
+	}
+
+	entry := &model.DiaryEntry{
+		MovieID:   input.MovieID,
+		WatchedOn: input.WatchedOn,
+		Rewatch:   rewatch,
+		Note:      input.Note,
+	}
+	err = tx.QueryRow(ctx, insertQuery, userID, input.MovieID, input.WatchedOn, rewatch, input.Note).Scan(&entry.ID, &entry.TimeAdded)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, apperror.ErrMovieNotFound(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "watch_diary", meta)
+	}
+
+	if firstWatch {
+		if _, err := tx.Exec(ctx, watchlistQuery, userID, input.MovieID); err != nil {
+			return nil, handleDatabaseError(err, r.logger, op, "user_movies", meta)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+
+	r.logger.Info("watch successfully logged to user diary", "meta", meta)
+	return entry, nil
+}
+
+// GetDiaryPage retrieves a page of the user's diary within rng, most recent watch first
+func (r *AccountRepository) GetDiaryPage(ctx context.Context, userID int, rng common.DiaryRange, page common.PageParams) (*common.DiaryPage, error) {
+	op := getOp(QueryGetDiaryPage)
+	meta := common.Envelop{
+		"user_id": userID,
+		"from":    rng.From,
+		"to":      rng.To,
+		"limit":   page.Limit,
+		"context": op,
+	}
+
+	var cur *diaryCursor
+	if page.Cursor != "" {
+		cur = &diaryCursor{}
+		if err := utils.DecodeCursor(page.Cursor, cur); err != nil {
+			return nil, apperror.ErrInvalidCursor(err, r.logger, meta)
+		}
+	}
+
+	base, err := getQuery(QueryGetDiaryPage, r.logger, meta)
+	if err != nil || base == "" {
+		return nil, err
+	}
+
+	var queryBuilder strings.Builder
+	queryBuilder.WriteString(base)
+	args := []any{userID, rng.From, rng.To}
+
+	if cur != nil {
+		queryBuilder.WriteString(" AND (d.watched_on, d.id) < ($4, $5)")
+		args = append(args, cur.WatchedOn, cur.ID)
+	}
+	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY d.watched_on DESC, d.id DESC LIMIT $%d", len(args)+1))
+	args = append(args, page.Limit+1)
+
+	rows, err := r.db.Query(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "watch_diary", meta)
+	}
+	defer rows.Close()
+
+	entries, err := scanRowsToSlice(rows, scanDiaryEntry, r.logger, op, meta, page.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &common.DiaryPage{Entries: entries}
+	if len(entries) > page.Limit {
+		result.Entries = entries[:page.Limit]
+		result.HasMore = true
+
+		last := result.Entries[page.Limit-1]
+		next := diaryCursor{WatchedOn: last.WatchedOn, ID: last.ID}
+		if result.NextCursor, err = utils.EncodeCursor(next); err != nil {
+			return nil, apperror.ErrInternalServer(err, r.logger, meta)
+		}
+	}
+
+	return result, nil
+}
+
+// UpdateDiaryEntry changes the date, rewatch flag and note of one of the user's diary entries
+func (r *AccountRepository) UpdateDiaryEntry(ctx context.Context, userID int, entryID int, input common.DiaryInput) (*model.DiaryEntry, error) {
+	op := getOp(QueryUpdateDiaryEntry)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"entry_id": entryID,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryUpdateDiaryEntry, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	var entry model.DiaryEntry
+	err = r.db.QueryRow(ctx, query, entryID, userID, input.WatchedOn, input.Rewatch, input.Note).Scan(
+		&entry.ID,
+		&entry.MovieID,
+		&entry.WatchedOn,
+		&entry.Rewatch,
+		&entry.Note,
+		&entry.TimeAdded,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperror.ErrDiaryEntryNotFound(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "watch_diary", meta)
+	}
+
+	return &entry, nil
+}
+
+// DeleteDiaryEntry removes one of the user's diary entries
+func (r *AccountRepository) DeleteDiaryEntry(ctx context.Context, userID int, entryID int) error {
+	op := getOp(QueryDeleteDiaryEntry)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"entry_id": entryID,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryDeleteDiaryEntry, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, query, entryID, userID)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "watch_diary", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrDiaryEntryNotFound(pgx.ErrNoRows, r.logger, meta)
+	}
+
+	return nil
+}
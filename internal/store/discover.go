@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// maxLanguageFacets caps the language facet, which has a long tail.
+const maxLanguageFacets = 20
+
+// buildDiscoverConditions translates a DiscoverFilter into a WHERE clause over
+// the "movies" table. Soft-deleted movies are always filtered out.
+func buildDiscoverConditions(f *common.DiscoverFilter) (string, []any) {
+	var (
+		conds = []string{"movies.time_deleted IS NULL"}
+		args  []any
+	)
+	next := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(f.GenreIDs) > 0 {
+		if f.MatchAllGenres {
+			genres := next(f.GenreIDs)
+			conds = append(conds, fmt.Sprintf(`(
+				SELECT COUNT(DISTINCT genre_id) FROM movie_genres
+				WHERE movie_id = movies.id AND genre_id = ANY(%s)
+			) = %s`, genres, next(len(f.GenreIDs))))
+		} else {
+			conds = append(conds, fmt.Sprintf(`EXISTS (
+				SELECT 1 FROM movie_genres
+				WHERE movie_id = movies.id AND genre_id = ANY(%s)
+			)`, next(f.GenreIDs)))
+		}
+	}
+	if f.YearFrom != nil {
+		conds = append(conds, "movies.release_year >= "+next(*f.YearFrom))
+	}
+	if f.YearTo != nil {
+		conds = append(conds, "movies.release_year <= "+next(*f.YearTo))
+	}
+	if f.ScoreMin != nil {
+		conds = append(conds, "movies.score >= "+next(*f.ScoreMin))
+	}
+	if f.ScoreMax != nil {
+		conds = append(conds, "movies.score <= "+next(*f.ScoreMax))
+	}
+	if f.Language != "" {
+		conds = append(conds, "lower(movies.language) = lower("+next(f.Language)+")")
+	}
+	if f.ActorID != nil {
+		conds = append(conds, fmt.Sprintf(`EXISTS (
+				SELECT 1 FROM movie_cast
+				WHERE movie_id = movies.id AND actor_id = %s
+			)`, next(*f.ActorID)))
+	}
+	if f.Keyword != "" {
+		conds = append(conds, fmt.Sprintf(`EXISTS (
+				SELECT 1 FROM movie_keywords mk
+				JOIN keywords k ON k.id = mk.keyword_id
+				WHERE mk.movie_id = movies.id AND lower(k.word) = lower(%s)
+			)`, next(f.Keyword)))
+	}
+
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// DiscoverMovies browses the catalog with any combination of filters and no
+// text query. The first page also carries genre, decade and language facet
+// counts over the whole filtered set.
+func (r *MovieRepository) DiscoverMovies(ctx context.Context, filter *common.DiscoverFilter, order string, page common.PageParams) (*common.DiscoverResult, error) {
+	if _, ok := movieSorts[order]; !ok {
+		order = defaultMovieOrder
+	}
+
+	op := getOp(QueryDiscoverMovies)
+	meta := common.Envelop{
+		"filter":   filter,
+		"order_by": order,
+		"limit":    page.Limit,
+		"context":  op,
+	}
+
+	base, err := getQuery(QueryDiscoverMovies, r.logger, meta)
+	if err != nil || base == "" {
+		return nil, err
+	}
+
+	where, args := buildDiscoverConditions(filter)
+	facetArgs, facetMeta := slices.Clone(args), maps.Clone(meta)
+	result := &common.DiscoverResult{}
+
+	var g errgroup.Group
+	g.Go(func() error {
+		moviePage, err := r.queryMoviePage(ctx, op, base+where, true, args, order, page, meta)
+		if err != nil {
+			return err
+		}
+		result.Page = moviePage
+		return nil
+	})
+
+	if page.Cursor == "" {
+		g.Go(func() error {
+			facets, err := r.discoverFacets(ctx, where, facetArgs, facetMeta)
+			if err != nil {
+				return err
+			}
+			result.Facets = facets
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// discoverFacets counts the filtered movies per genre, decade and language in one round trip.
+func (r *MovieRepository) discoverFacets(ctx context.Context, where string, args []any, meta common.Envelop) (*common.DiscoverFacets, error) {
+	op := getOp(QueryDiscoverFacets)
+
+	tpl, err := getQuery(QueryDiscoverFacets, r.logger, meta)
+	if err != nil || tpl == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, fmt.Sprintf(tpl, where), args...)
+	if err != nil {
+		return nil, apperror.ErrDatabaseOpFailed(
+			apperror.CodeDatabaseError,
+			apperror.ErrQueryFailedMsg,
+			op,
+			err,
+			r.logger,
+			common.Envelop{"db_error_type": "query"},
+		)
+	}
+	defer rows.Close()
+
+	facets := &common.DiscoverFacets{
+		Genres:    []common.FacetCount{},
+		Decades:   []common.FacetCount{},
+		Languages: []common.FacetCount{},
+	}
+	for rows.Next() {
+		var (
+			facet string
+			fc    common.FacetCount
+		)
+		if err := rows.Scan(&facet, &fc.Key, &fc.Label, &fc.Count); err != nil {
+			return nil, apperror.ErrDatabaseOpFailed(
+				apperror.CodeDataException,
+				apperror.ErrQueryFailedMsg,
+				op,
+				err,
+				r.logger,
+				common.Envelop{"db_error_type": "query_scan"},
+			)
+		}
+
+		switch facet {
+		case "genre":
+			facets.Genres = append(facets.Genres, fc)
+		case "decade":
+			facets.Decades = append(facets.Decades, fc)
+		case "language":
+			if len(facets.Languages) < maxLanguageFacets {
+				facets.Languages = append(facets.Languages, fc)
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.ErrDatabaseOpFailed(
+			apperror.CodeDatabaseError,
+			apperror.ErrQueryFailedMsg,
+			op,
+			err,
+			r.logger,
+			common.Envelop{"db_error_type": "query_iteration"},
+		)
+	}
+
+	return facets, nil
+}
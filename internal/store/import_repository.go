@@ -0,0 +1,456 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ImportPlan is what one batch of an offline import writes: the movies to
+// insert or update and the genres, actors and keywords that do not exist yet.
+type ImportPlan struct {
+	Inserts      []model.ImportMovie
+	Updates      []model.ImportMovie
+	NewGenres    []string
+	NewActors    []model.ImportActor
+	NewKeywords  []string
+	GenreLinks   int
+	CastLinks    int
+	KeywordLinks int
+}
+
+/* ImportStore Interface */
+type ImportStore interface {
+	LoadCatalogNames(ctx context.Context) error
+	PlanBatch(inserts []model.ImportMovie, updates []model.ImportMovie) *ImportPlan
+	ApplyBatch(ctx context.Context, plan *ImportPlan) error
+	GetCatalogTitles(ctx context.Context) ([]model.CatalogTitle, error)
+	ApplyIMDbBatch(ctx context.Context, batch []model.IMDbEnrichment) error
+}
+
+// ImportRepository writes import batches with COPY. It caches the ids of
+// every genre, actor and keyword by lowercase name for the length of one
+// import run, so it must not be shared between concurrent imports.
+type ImportRepository struct {
+	db     *pgxpool.Pool
+	logger logging.Logger
+	// ids holds the id of every known name, per relation kind
+	ids map[string]map[string]int
+	// planned holds names planned for creation but not written yet, per relation kind
+	planned map[string]map[string]struct{}
+}
+
+func NewImportRepository(db *pgxpool.Pool, logger logging.Logger) *ImportRepository {
+	return &ImportRepository{
+		db:     db,
+		logger: logger,
+		ids: map[string]map[string]int{
+			model.FeatureGenre:   {},
+			model.FeatureCast:    {},
+			model.FeatureKeyword: {},
+		},
+		planned: map[string]map[string]struct{}{
+			model.FeatureGenre:   {},
+			model.FeatureCast:    {},
+			model.FeatureKeyword: {},
+		},
+	}
+}
+
+// LoadCatalogNames fills the name caches with the genres, actors and keywords already in the catalog
+func (r *ImportRepository) LoadCatalogNames(ctx context.Context) error {
+	for kind, key := range map[string]string{
+		model.FeatureGenre:   QueryImportLoadGenres,
+		model.FeatureCast:    QueryImportLoadActors,
+		model.FeatureKeyword: QueryImportLoadKeywords,
+	} {
+		if err := r.loadNames(ctx, r.db, key, nil, r.ids[kind]); err != nil {
+			return err
+		}
+	}
+
+	r.logger.Info("catalog names loaded for import", "meta", common.Envelop{
+		"genres":   len(r.ids[model.FeatureGenre]),
+		"actors":   len(r.ids[model.FeatureCast]),
+		"keywords": len(r.ids[model.FeatureKeyword]),
+	})
+	return nil
+}
+
+// PlanBatch works out which genres, actors and keywords of a batch must be
+// created. Names planned by an earlier batch are not planned again, so dry
+// runs report every name once.
+func (r *ImportRepository) PlanBatch(inserts []model.ImportMovie, updates []model.ImportMovie) *ImportPlan {
+	plan := &ImportPlan{Inserts: inserts, Updates: updates}
+
+	isNew := func(kind string, key string) bool {
+		if _, ok := r.ids[kind][key]; ok {
+			return false
+		}
+		if _, ok := r.planned[kind][key]; ok {
+			return false
+		}
+		r.planned[kind][key] = struct{}{}
+		return true
+	}
+
+	for _, movies := range [][]model.ImportMovie{inserts, updates} {
+		for _, m := range movies {
+			for _, name := range m.Genres {
+				if isNew(model.FeatureGenre, strings.ToLower(name)) {
+					plan.NewGenres = append(plan.NewGenres, name)
+				}
+			}
+			for _, a := range m.Cast {
+				if isNew(model.FeatureCast, a.Key()) {
+					plan.NewActors = append(plan.NewActors, a)
+				}
+			}
+			for _, word := range m.Keywords {
+				if isNew(model.FeatureKeyword, strings.ToLower(word)) {
+					plan.NewKeywords = append(plan.NewKeywords, word)
+				}
+			}
+			plan.GenreLinks += len(m.Genres)
+			plan.CastLinks += len(m.Cast)
+			plan.KeywordLinks += len(m.Keywords)
+		}
+	}
+
+	return plan
+}
+
+// ApplyBatch writes a planned batch in one transaction. New names and movies
+// are copied in bulk, existing movies are updated and get their links replaced.
+func (r *ImportRepository) ApplyBatch(ctx context.Context, plan *ImportPlan) error {
+	op := getOp(QueryImportMovieIDs)
+	meta := common.Envelop{
+		"inserts": len(plan.Inserts),
+		"updates": len(plan.Updates),
+		"context": op,
+	}
+
+	idsQuery, err := getQuery(QueryImportMovieIDs, r.logger, meta)
+	if err != nil || idsQuery == "" {
+		return err
+	}
+	updateQuery, err := getQuery(QueryImportUpdateMovie, r.logger, meta)
+	if err != nil || updateQuery == "" {
+		return err
+	}
+	clearTpl, err := getQuery(QueryImportClearLinks, r.logger, meta)
+	if err != nil || clearTpl == "" {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	// STEP 1: CREATE MISSING NAMES
+	// ids are collected apart from the caches, which only learn them after commit
+	created := map[string]map[string]int{
+		model.FeatureGenre:   {},
+		model.FeatureCast:    {},
+		model.FeatureKeyword: {},
+	}
+
+	genreRows := make([][]any, 0, len(plan.NewGenres))
+	genreKeys := make([]string, 0, len(plan.NewGenres))
+	for _, name := range plan.NewGenres {
+		genreRows = append(genreRows, []any{name})
+		genreKeys = append(genreKeys, strings.ToLower(name))
+	}
+	if err := r.copyNames(ctx, tx, "genres", []string{"name"}, genreRows, QueryImportGenreIDs, genreKeys, created[model.FeatureGenre], meta); err != nil {
+		return err
+	}
+
+	actorRows := make([][]any, 0, len(plan.NewActors))
+	actorKeys := make([]string, 0, len(plan.NewActors))
+	for _, a := range plan.NewActors {
+		actorRows = append(actorRows, []any{a.FirstName, a.LastName, a.ImageURL})
+		actorKeys = append(actorKeys, a.Key())
+	}
+	if err := r.copyNames(ctx, tx, "actors", []string{"first_name", "last_name", "image_url"}, actorRows, QueryImportActorIDs, actorKeys, created[model.FeatureCast], meta); err != nil {
+		return err
+	}
+
+	keywordRows := make([][]any, 0, len(plan.NewKeywords))
+	keywordKeys := make([]string, 0, len(plan.NewKeywords))
+	for _, word := range plan.NewKeywords {
+		keywordRows = append(keywordRows, []any{word})
+		keywordKeys = append(keywordKeys, strings.ToLower(word))
+	}
+	if err := r.copyNames(ctx, tx, "keywords", []string{"word"}, keywordRows, QueryImportKeywordIDs, keywordKeys, created[model.FeatureKeyword], meta); err != nil {
+		return err
+	}
+
+	// STEP 2: WRITE MOVIES
+	if len(plan.Inserts) > 0 {
+		rows := make([][]any, 0, len(plan.Inserts))
+		for _, m := range plan.Inserts {
+			rows = append(rows, []any{
+				m.TMDB_ID, m.Title, m.Tagline, m.ReleaseYear, m.Overview,
+				m.Score, m.Popularity, m.Language, m.PosterURL, m.TrailerURL,
+			})
+		}
+		columns := []string{"tmdb_id", "title", "tagline", "release_year", "overview", "score", "popularity", "language", "poster_url", "trailer_url"}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"movies"}, columns, pgx.CopyFromRows(rows)); err != nil {
+			return handleDatabaseError(err, r.logger, op, "movies", meta)
+		}
+	}
+
+	if len(plan.Updates) > 0 {
+		batch := &pgx.Batch{}
+		for _, m := range plan.Updates {
+			batch.Queue(updateQuery,
+				m.TMDB_ID, m.Title, m.Tagline, m.ReleaseYear, m.Overview,
+				m.Score, m.Popularity, m.Language, m.PosterURL, m.TrailerURL,
+			)
+		}
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return handleDatabaseError(err, r.logger, op, "movies", meta)
+		}
+	}
+
+	// STEP 3: RESOLVE MOVIE IDS
+	tmdbIDs := make([]int, 0, len(plan.Inserts)+len(plan.Updates))
+	for _, movies := range [][]model.ImportMovie{plan.Inserts, plan.Updates} {
+		for _, m := range movies {
+			tmdbIDs = append(tmdbIDs, m.TMDB_ID)
+		}
+	}
+	movieIDs := make(map[int]int, len(tmdbIDs))
+	rows, err := tx.Query(ctx, idsQuery, tmdbIDs)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "movies", meta)
+	}
+	for rows.Next() {
+		var id, tmdbID int
+		if err := rows.Scan(&id, &tmdbID); err != nil {
+			rows.Close()
+			return handleDatabaseError(err, r.logger, op, "movies", meta)
+		}
+		movieIDs[tmdbID] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return handleDatabaseError(err, r.logger, op, "movies", meta)
+	}
+
+	// STEP 4: REPLACE LINKS
+	// Updated movies lose their old links first, the import is the source of truth
+	if len(plan.Updates) > 0 {
+		updated := make([]int, 0, len(plan.Updates))
+		for _, m := range plan.Updates {
+			updated = append(updated, movieIDs[m.TMDB_ID])
+		}
+		for _, rel := range catalogRelations {
+			if _, err := tx.Exec(ctx, fmt.Sprintf(clearTpl, rel.joinTable), updated); err != nil {
+				return handleDatabaseError(err, r.logger, op, rel.joinTable, meta)
+			}
+		}
+	}
+
+	idOf := func(kind string, key string) int {
+		if id, ok := created[kind][key]; ok {
+			return id
+		}
+		return r.ids[kind][key]
+	}
+
+	links := map[string][][]any{}
+	for _, movies := range [][]model.ImportMovie{plan.Inserts, plan.Updates} {
+		for _, m := range movies {
+			movieID := movieIDs[m.TMDB_ID]
+			for _, name := range m.Genres {
+				links[model.FeatureGenre] = append(links[model.FeatureGenre], []any{movieID, idOf(model.FeatureGenre, strings.ToLower(name))})
+			}
+			for _, a := range m.Cast {
+				links[model.FeatureCast] = append(links[model.FeatureCast], []any{movieID, idOf(model.FeatureCast, a.Key())})
+			}
+			for _, word := range m.Keywords {
+				links[model.FeatureKeyword] = append(links[model.FeatureKeyword], []any{movieID, idOf(model.FeatureKeyword, strings.ToLower(word))})
+			}
+		}
+	}
+	for kind, rows := range links {
+		rel := catalogRelations[kind]
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{rel.joinTable}, []string{"movie_id", rel.column}, pgx.CopyFromRows(rows)); err != nil {
+			return handleDatabaseError(err, r.logger, op, rel.joinTable, meta)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+
+	for kind, ids := range created {
+		for key, id := range ids {
+			r.ids[kind][key] = id
+			delete(r.planned[kind], key)
+		}
+	}
+
+	return nil
+}
+
+// GetCatalogTitles lists every catalog movie with what an IMDb enrichment matches it on
+func (r *ImportRepository) GetCatalogTitles(ctx context.Context) ([]model.CatalogTitle, error) {
+	op := getOp(QueryImportTitles)
+	meta := common.Envelop{"context": op}
+
+	query, err := getQuery(QueryImportTitles, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, QueryImportTitles, meta)
+	}
+	defer rows.Close()
+
+	var titles []model.CatalogTitle
+	for rows.Next() {
+		var t model.CatalogTitle
+		if err := rows.Scan(&t.ID, &t.Title, &t.ReleaseYear, &t.IMDbID); err != nil {
+			return nil, handleDatabaseError(err, r.logger, op, QueryImportTitles, meta)
+		}
+		titles = append(titles, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, QueryImportTitles, meta)
+	}
+	return titles, nil
+}
+
+// ApplyIMDbBatch writes the IMDb id, runtime and rating of a batch of movies
+// in one transaction and replaces their directors and writers.
+func (r *ImportRepository) ApplyIMDbBatch(ctx context.Context, batch []model.IMDbEnrichment) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	op := getOp(QueryImportSetIMDb)
+	meta := common.Envelop{
+		"movies":  len(batch),
+		"context": op,
+	}
+
+	setQuery, err := getQuery(QueryImportSetIMDb, r.logger, meta)
+	if err != nil || setQuery == "" {
+		return err
+	}
+	clearQuery, err := getQuery(QueryImportClearCrew, r.logger, meta)
+	if err != nil || clearQuery == "" {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	movieIDs := make([]int, 0, len(batch))
+	updates := &pgx.Batch{}
+	var crew [][]any
+	for _, e := range batch {
+		movieIDs = append(movieIDs, e.MovieID)
+		updates.Queue(setQuery, e.MovieID, e.IMDbID, e.Runtime, e.Rating, e.Votes)
+		for _, c := range e.Crew {
+			crew = append(crew, []any{e.MovieID, c.IMDbID, c.Name, c.Role, c.Ordering})
+		}
+	}
+
+	if err := tx.SendBatch(ctx, updates).Close(); err != nil {
+		return handleDatabaseError(err, r.logger, op, "movies", meta)
+	}
+	if _, err := tx.Exec(ctx, clearQuery, movieIDs); err != nil {
+		return handleDatabaseError(err, r.logger, op, "movie_crew", meta)
+	}
+	if len(crew) > 0 {
+		columns := []string{"movie_id", "imdb_person_id", "name", "role", "ordering"}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"movie_crew"}, columns, pgx.CopyFromRows(crew)); err != nil {
+			return handleDatabaseError(err, r.logger, op, "movie_crew", meta)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	return nil
+}
+
+// copyNames copies new name rows into table and reads their ids back into ids by lowercase key
+func (r *ImportRepository) copyNames(
+	ctx context.Context,
+	tx pgx.Tx,
+	table string,
+	columns []string,
+	rows [][]any,
+	idsKey string,
+	keys []string,
+	ids map[string]int,
+	meta common.Envelop,
+) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows)); err != nil {
+		return handleDatabaseError(err, r.logger, getOp(idsKey), table, meta)
+	}
+	return r.loadNames(ctx, tx, idsKey, keys, ids)
+}
+
+// loadNames runs a query returning (id, lowercase name) rows into ids. keys, when set, is passed as $1.
+func (r *ImportRepository) loadNames(ctx context.Context, q interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}, key string, keys []string, ids map[string]int,
+) error {
+	op := getOp(key)
+	meta := common.Envelop{"context": op}
+
+	query, err := getQuery(key, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	var args []any
+	if keys != nil {
+		args = append(args, keys)
+	}
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, key, meta)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   int
+			name string
+		)
+		if err := rows.Scan(&id, &name); err != nil {
+			return handleDatabaseError(err, r.logger, op, key, meta)
+		}
+		ids[name] = id
+	}
+	if err := rows.Err(); err != nil {
+		return handleDatabaseError(err, r.logger, op, key, meta)
+	}
+	return nil
+}
@@ -0,0 +1,567 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// List ordering. Items have fractional positions: an appended item goes
+// listPositionGap after the last one and a moved item is placed halfway
+// between its new neighbours, so a drag only rewrites the dragged row. When
+// neighbours get closer than minListPositionGap the list is renumbered.
+const (
+	listPositionGap    = 1024.0
+	minListPositionGap = 1e-6
+	MaxListItems       = 1000
+)
+
+/* ListStore Interface */
+type ListStore interface {
+	CreateList(ctx context.Context, userID int, input common.ListInput) (*model.UserList, error)
+	UpdateList(ctx context.Context, userID int, listID int, input common.ListInput) (*model.UserList, error)
+	DeleteList(ctx context.Context, userID int, listID int) error
+	GetUserLists(ctx context.Context, userID int) ([]model.UserList, error)
+	GetListByID(ctx context.Context, listID int) (*model.UserList, error)
+	GetListByShareHash(ctx context.Context, hash []byte) (*model.UserList, error)
+	GetListItems(ctx context.Context, listID int) ([]model.ListItem, error)
+	SetListShareHash(ctx context.Context, userID int, listID int, hash []byte) error
+	AddListItem(ctx context.Context, userID int, listID int, movieID int, note *string) (*model.ListItem, error)
+	UpdateListItem(ctx context.Context, userID int, listID int, movieID int, input common.ListItemInput) (*model.ListItem, error)
+	RemoveListItem(ctx context.Context, userID int, listID int, movieID int) error
+}
+
+type ListRepository struct {
+	db     *pgxpool.Pool
+	logger logging.Logger
+}
+
+func NewListRepository(db *pgxpool.Pool, logger logging.Logger) *ListRepository {
+	return &ListRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateList creates an empty list for the user
+func (r *ListRepository) CreateList(ctx context.Context, userID int, input common.ListInput) (*model.UserList, error) {
+	op := getOp(QueryCreateList)
+	meta := common.Envelop{
+		"user_id": userID,
+		"name":    input.Name,
+		"context": op,
+	}
+
+	query, err := getQuery(QueryCreateList, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, userID, input.Name, input.Description, input.IsPublic)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "user_lists", meta)
+	}
+
+	return r.scanSingleList(rows, op, meta)
+}
+
+// UpdateList renames a list and changes its description and visibility
+func (r *ListRepository) UpdateList(ctx context.Context, userID int, listID int, input common.ListInput) (*model.UserList, error) {
+	op := getOp(QueryUpdateList)
+	meta := common.Envelop{
+		"user_id": userID,
+		"list_id": listID,
+		"context": op,
+	}
+
+	query, err := getQuery(QueryUpdateList, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, listID, userID, input.Name, input.Description, input.IsPublic)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "user_lists", meta)
+	}
+
+	return r.scanSingleList(rows, op, meta)
+}
+
+// DeleteList removes a list together with its items
+func (r *ListRepository) DeleteList(ctx context.Context, userID int, listID int) error {
+	op := getOp(QueryDeleteList)
+	meta := common.Envelop{
+		"user_id": userID,
+		"list_id": listID,
+		"context": op,
+	}
+
+	query, err := getQuery(QueryDeleteList, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, query, listID, userID)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "user_lists", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrListNotFound(pgx.ErrNoRows, r.logger, meta)
+	}
+
+	return nil
+}
+
+// GetUserLists retrieves all lists of a user, most recently changed first
+func (r *ListRepository) GetUserLists(ctx context.Context, userID int) ([]model.UserList, error) {
+	op := getOp(QueryGetUserLists)
+	meta := common.Envelop{
+		"user_id": userID,
+		"context": op,
+	}
+
+	query, err := getQuery(QueryGetUserLists, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "user_lists", meta)
+	}
+	defer rows.Close()
+
+	return scanRowsToSlice(rows, scanUserList, r.logger, op, meta, 0)
+}
+
+// GetListByID retrieves a list without its items. Visibility is checked by the caller.
+func (r *ListRepository) GetListByID(ctx context.Context, listID int) (*model.UserList, error) {
+	op := getOp(QueryGetListByID)
+	meta := common.Envelop{
+		"list_id": listID,
+		"context": op,
+	}
+
+	query, err := getQuery(QueryGetListByID, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, listID)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "user_lists", meta)
+	}
+
+	return r.scanSingleList(rows, op, meta)
+}
+
+// GetListByShareHash retrieves the list a share token was issued for
+func (r *ListRepository) GetListByShareHash(ctx context.Context, hash []byte) (*model.UserList, error) {
+	op := getOp(QueryGetListByShareHash)
+	meta := common.Envelop{
+		"context": op,
+	}
+
+	query, err := getQuery(QueryGetListByShareHash, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, hash)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "user_lists", meta)
+	}
+
+	return r.scanSingleList(rows, op, meta)
+}
+
+// GetListItems retrieves every item of a list in list order
+func (r *ListRepository) GetListItems(ctx context.Context, listID int) ([]model.ListItem, error) {
+	op := getOp(QueryGetListItems)
+	meta := common.Envelop{
+		"list_id": listID,
+		"context": op,
+	}
+
+	query, err := getQuery(QueryGetListItems, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, listID)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "user_list_items", meta)
+	}
+	defer rows.Close()
+
+	return scanRowsToSlice(rows, scanListItem, r.logger, op, meta, 0)
+}
+
+// SetListShareHash stores the hash of a new share token, replacing the
+// previous one. A nil hash revokes the share link.
+func (r *ListRepository) SetListShareHash(ctx context.Context, userID int, listID int, hash []byte) error {
+	op := getOp(QuerySetListShareHash)
+	meta := common.Envelop{
+		"user_id": userID,
+		"list_id": listID,
+		"revoke":  hash == nil,
+		"context": op,
+	}
+
+	query, err := getQuery(QuerySetListShareHash, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, query, listID, userID, hash)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "user_lists", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrListNotFound(pgx.ErrNoRows, r.logger, meta)
+	}
+
+	return nil
+}
+
+// AddListItem appends a movie to the end of one of the user's lists
+func (r *ListRepository) AddListItem(ctx context.Context, userID int, listID int, movieID int, note *string) (*model.ListItem, error) {
+	op := getOp(QueryAddListItem)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"list_id":  listID,
+		"movie_id": movieID,
+		"context":  op,
+	}
+
+	countQuery, err := getQuery(QueryCountListItems, r.logger, meta)
+	if err != nil || countQuery == "" {
+		return nil, err
+	}
+	lastQuery, err := getQuery(QueryGetLastListPosition, r.logger, meta)
+	if err != nil || lastQuery == "" {
+		return nil, err
+	}
+	insertQuery, err := getQuery(QueryAddListItem, r.logger, meta)
+	if err != nil || insertQuery == "" {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	if err := r.lockList(ctx, tx, userID, listID, meta); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := tx.QueryRow(ctx, countQuery, listID).Scan(&count); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "user_list_items", meta)
+	}
+	if count >= MaxListItems {
+		meta["max_items"] = MaxListItems
+		return nil, apperror.ErrListFull(nil, r.logger, meta)
+	}
+
+	var last *float64
+	if err := tx.QueryRow(ctx, lastQuery, listID).Scan(&last); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "user_list_items", meta)
+	}
+	position := listPositionGap
+	if last != nil {
+		position = *last + listPositionGap
+	}
+
+	if _, err := tx.Exec(ctx, insertQuery, listID, movieID, position, note); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505": // primary key (list_id, movie_id)
+				return nil, apperror.ErrListItemExists(err, r.logger, meta)
+			case "23503": // the movie does not exist
+				return nil, apperror.ErrMovieNotFound(err, r.logger, meta)
+			}
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "user_list_items", meta)
+	}
+
+	item, err := r.finishItemChange(ctx, tx, listID, movieID, op, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.Info("movie successfully added to user list", "meta", meta)
+	return item, nil
+}
+
+// UpdateListItem changes the note of a list item and/or moves it right after
+// input.AfterMovieID, or to the top when that is nil.
+func (r *ListRepository) UpdateListItem(ctx context.Context, userID int, listID int, movieID int, input common.ListItemInput) (*model.ListItem, error) {
+	op := getOp(QueryMoveListItem)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"list_id":  listID,
+		"movie_id": movieID,
+		"context":  op,
+	}
+
+	positionQuery, err := getQuery(QueryGetListItemPosition, r.logger, meta)
+	if err != nil || positionQuery == "" {
+		return nil, err
+	}
+	noteQuery, err := getQuery(QueryUpdateListItemNote, r.logger, meta)
+	if err != nil || noteQuery == "" {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	if err := r.lockList(ctx, tx, userID, listID, meta); err != nil {
+		return nil, err
+	}
+
+	var current float64
+	if err := tx.QueryRow(ctx, positionQuery, listID, movieID).Scan(&current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperror.ErrListItemNotFound(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "user_list_items", meta)
+	}
+
+	if input.UpdateNote {
+		if _, err := tx.Exec(ctx, noteQuery, listID, movieID, input.Note); err != nil {
+			return nil, handleDatabaseError(err, r.logger, op, "user_list_items", meta)
+		}
+	}
+
+	if input.Move {
+		meta["after_movie_id"] = input.AfterMovieID
+		if err := r.moveListItem(ctx, tx, listID, movieID, input.AfterMovieID, meta); err != nil {
+			return nil, err
+		}
+	}
+
+	item, err := r.finishItemChange(ctx, tx, listID, movieID, op, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.Info("user list item successfully updated", "meta", meta)
+	return item, nil
+}
+
+// RemoveListItem removes a movie from one of the user's lists
+func (r *ListRepository) RemoveListItem(ctx context.Context, userID int, listID int, movieID int) error {
+	op := getOp(QueryRemoveListItem)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"list_id":  listID,
+		"movie_id": movieID,
+		"context":  op,
+	}
+
+	removeQuery, err := getQuery(QueryRemoveListItem, r.logger, meta)
+	if err != nil || removeQuery == "" {
+		return err
+	}
+	touchQuery, err := getQuery(QueryTouchList, r.logger, meta)
+	if err != nil || touchQuery == "" {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	if err := r.lockList(ctx, tx, userID, listID, meta); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, removeQuery, listID, movieID)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "user_list_items", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrListItemNotFound(pgx.ErrNoRows, r.logger, meta)
+	}
+
+	if _, err := tx.Exec(ctx, touchQuery, listID); err != nil {
+		return handleDatabaseError(err, r.logger, op, "user_lists", meta)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+
+	return nil
+}
+
+// moveListItem gives a list item a position between its new neighbours. The
+// list must be locked by the caller.
+func (r *ListRepository) moveListItem(ctx context.Context, tx pgx.Tx, listID int, movieID int, afterMovieID *int, meta common.Envelop) error {
+	op := getOp(QueryMoveListItem)
+
+	positionQuery, err := getQuery(QueryGetListItemPosition, r.logger, meta)
+	if err != nil || positionQuery == "" {
+		return err
+	}
+	nextQuery, err := getQuery(QueryGetNextListPosition, r.logger, meta)
+	if err != nil || nextQuery == "" {
+		return err
+	}
+	moveQuery, err := getQuery(QueryMoveListItem, r.logger, meta)
+	if err != nil || moveQuery == "" {
+		return err
+	}
+	renumberQuery, err := getQuery(QueryRenumberListItems, r.logger, meta)
+	if err != nil || renumberQuery == "" {
+		return err
+	}
+
+	neighbours := func() (prev, next *float64, err error) {
+		if afterMovieID != nil {
+			var p float64
+			if err := tx.QueryRow(ctx, positionQuery, listID, *afterMovieID).Scan(&p); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return nil, nil, apperror.ErrListItemNotFound(err, r.logger, meta)
+				}
+				return nil, nil, handleDatabaseError(err, r.logger, op, "user_list_items", meta)
+			}
+			prev = &p
+		}
+		if err := tx.QueryRow(ctx, nextQuery, listID, movieID, prev).Scan(&next); err != nil {
+			return nil, nil, handleDatabaseError(err, r.logger, op, "user_list_items", meta)
+		}
+		return prev, next, nil
+	}
+
+	prev, next, err := neighbours()
+	if err != nil {
+		return err
+	}
+
+	// Halving has run out of room between these two neighbours
+	if prev != nil && next != nil && *next-*prev < minListPositionGap {
+		r.logger.Info("renumbering user list positions", "meta", meta)
+		if _, err := tx.Exec(ctx, renumberQuery, listID, listPositionGap); err != nil {
+			return handleDatabaseError(err, r.logger, op, "user_list_items", meta)
+		}
+		if prev, next, err = neighbours(); err != nil {
+			return err
+		}
+	}
+
+	var position float64
+	switch {
+	case prev != nil && next != nil:
+		position = *prev + (*next-*prev)/2
+	case prev != nil:
+		position = *prev + listPositionGap
+	case next != nil:
+		position = *next - listPositionGap
+	default: // the item is alone in the list
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, moveQuery, listID, movieID, position); err != nil {
+		return handleDatabaseError(err, r.logger, op, "user_list_items", meta)
+	}
+	return nil
+}
+
+// lockList locks one of the user's lists for the rest of tx. Lists of other
+// users are reported as not found, so their existence is not revealed.
+func (r *ListRepository) lockList(ctx context.Context, tx pgx.Tx, userID int, listID int, meta common.Envelop) error {
+	op := getOp(QueryLockList)
+
+	query, err := getQuery(QueryLockList, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	var id int
+	if err := tx.QueryRow(ctx, query, listID, userID).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apperror.ErrListNotFound(err, r.logger, meta)
+		}
+		return handleDatabaseError(err, r.logger, op, "user_lists", meta)
+	}
+	return nil
+}
+
+// finishItemChange marks the list as updated, commits tx and returns the changed item.
+func (r *ListRepository) finishItemChange(ctx context.Context, tx pgx.Tx, listID int, movieID int, op string, meta common.Envelop) (*model.ListItem, error) {
+	touchQuery, err := getQuery(QueryTouchList, r.logger, meta)
+	if err != nil || touchQuery == "" {
+		return nil, err
+	}
+	itemQuery, err := getQuery(QueryGetListItem, r.logger, meta)
+	if err != nil || itemQuery == "" {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, touchQuery, listID); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "user_lists", meta)
+	}
+
+	rows, err := tx.Query(ctx, itemQuery, listID, movieID)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "user_list_items", meta)
+	}
+	items, err := scanRowsToSlice(rows, scanListItem, r.logger, op, meta, 1)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, apperror.ErrListItemNotFound(pgx.ErrNoRows, r.logger, meta)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+
+	return &items[0], nil
+}
+
+// scanSingleList reads the one list returned by a lookup, insert or update.
+// pgx reports statement errors while iterating, so constraint violations are
+// mapped here. No row means the list does not exist or is not the user's.
+func (r *ListRepository) scanSingleList(rows pgx.Rows, op string, meta common.Envelop) (*model.UserList, error) {
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" { // uq_user_lists_user_name
+				return nil, apperror.ErrListNameTaken(err, r.logger, meta)
+			}
+			return nil, handleDatabaseError(err, r.logger, op, "user_lists", meta)
+		}
+		return nil, apperror.ErrListNotFound(pgx.ErrNoRows, r.logger, meta)
+	}
+
+	var list model.UserList
+	if err := scanUserList(rows, &list); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "user_lists", meta)
+	}
+
+	return &list, nil
+}
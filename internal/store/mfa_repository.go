@@ -0,0 +1,406 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+/* MFAStore Interface */
+type MFAStore interface {
+	IsTOTPEnabled(ctx context.Context, userID int) (bool, error)
+	GetTOTP(ctx context.Context, userID int) (*model.TOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID int, secret string) error
+	EnableTOTP(ctx context.Context, userID int, step int64, recoveryHashes [][]byte) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+	DisableTOTP(ctx context.Context, userID int) error
+	CountRecoveryCodes(ctx context.Context, userID int) (int, error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash []byte) (bool, error)
+	SaveOTPVerification(ctx context.Context, v *model.OTPVerification) error
+	GetOTPVerification(ctx context.Context, code, purpose string) (*model.OTPVerification, error)
+	RecordOTPAttempt(ctx context.Context, id int64, maxAttempts int) (int, bool, error)
+	RecordOTPWindowAttempt(ctx context.Context, userID int, purpose string, window time.Duration) (int, error)
+	ConsumeOTPVerification(ctx context.Context, id int64) (bool, error)
+	DeleteOTPVerification(ctx context.Context, userID int, purpose string) error
+	GetMFAPolicy(ctx context.Context, userID int) (*model.MFAPolicy, error)
+	SetPasskeyMFARequired(ctx context.Context, userID int, required bool) error
+}
+
+type MFARepository struct {
+	db     *pgxpool.Pool
+	logger logging.Logger
+}
+
+func NewMFARepository(db *pgxpool.Pool, logger logging.Logger) *MFARepository {
+	return &MFARepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// IsTOTPEnabled tells whether the user confirmed an authenticator app
+func (r *MFARepository) IsTOTPEnabled(ctx context.Context, userID int) (bool, error) {
+	op := getOp(QueryIsTOTPEnabled)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryIsTOTPEnabled, r.logger, meta)
+	if err != nil || query == "" {
+		return false, err
+	}
+
+	var enabled bool
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&enabled); err != nil {
+		return false, handleDatabaseError(err, r.logger, op, "user_totp", meta)
+	}
+	return enabled, nil
+}
+
+// GetTOTP returns the user's authenticator app secret, pending or enabled
+func (r *MFARepository) GetTOTP(ctx context.Context, userID int) (*model.TOTP, error) {
+	op := getOp(QueryGetTOTP)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryGetTOTP, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	var t model.TOTP
+	err = r.db.QueryRow(ctx, query, userID).Scan(
+		&t.UserID,
+		&t.Secret,
+		&t.LastStep,
+		&t.TimeCreated,
+		&t.TimeEnabled,
+		&t.TimeLastUsed,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperror.ErrTOTPNotEnrolled(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "user_totp", meta)
+	}
+	return &t, nil
+}
+
+// SaveTOTPSecret stores a pending secret, replacing any previous pending one
+func (r *MFARepository) SaveTOTPSecret(ctx context.Context, userID int, secret string) error {
+	op := getOp(QuerySaveTOTPSecret)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QuerySaveTOTPSecret, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, query, userID, secret, time.Now())
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "user_totp", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrTOTPAlreadyEnabled(nil, r.logger, meta)
+	}
+	return nil
+}
+
+// EnableTOTP enables the pending secret, marking step as used, and replaces
+// the user's recovery codes
+func (r *MFARepository) EnableTOTP(ctx context.Context, userID int, step int64, recoveryHashes [][]byte) error {
+	op := getOp(QueryEnableTOTP)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	enableQuery, err := getQuery(QueryEnableTOTP, r.logger, meta)
+	if err != nil || enableQuery == "" {
+		return err
+	}
+	deleteCodesQuery, err := getQuery(QueryDeleteRecoveryCodes, r.logger, meta)
+	if err != nil || deleteCodesQuery == "" {
+		return err
+	}
+	saveCodesQuery, err := getQuery(QuerySaveRecoveryCodes, r.logger, meta)
+	if err != nil || saveCodesQuery == "" {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	now := time.Now()
+	tag, err := tx.Exec(ctx, enableQuery, userID, now, step)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "user_totp", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrTOTPAlreadyEnabled(nil, r.logger, meta)
+	}
+
+	if _, err := tx.Exec(ctx, deleteCodesQuery, userID); err != nil {
+		return handleDatabaseError(err, r.logger, op, "recovery_codes", meta)
+	}
+	if _, err := tx.Exec(ctx, saveCodesQuery, userID, recoveryHashes, now); err != nil {
+		return handleDatabaseError(err, r.logger, op, "recovery_codes", meta)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	return nil
+}
+
+// UseTOTPStep records step as the last one used. It returns false when a code
+// of that step or a later one was already used.
+func (r *MFARepository) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	op := getOp(QueryUseTOTPStep)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryUseTOTPStep, r.logger, meta)
+	if err != nil || query == "" {
+		return false, err
+	}
+
+	tag, err := r.db.Exec(ctx, query, userID, step, time.Now())
+	if err != nil {
+		return false, handleDatabaseError(err, r.logger, op, "user_totp", meta)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// DisableTOTP deletes the user's secret, recovery codes and pending checks
+func (r *MFARepository) DisableTOTP(ctx context.Context, userID int) error {
+	op := getOp(QueryDeleteTOTP)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	queries := make([]string, 0, 3)
+	for _, key := range []string{QueryDeleteTOTP, QueryDeleteRecoveryCodes, QueryDeleteOTPVerifications} {
+		query, err := getQuery(key, r.logger, meta)
+		if err != nil || query == "" {
+			return err
+		}
+		queries = append(queries, query)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	for _, q := range queries {
+		if _, err := tx.Exec(ctx, q, userID); err != nil {
+			return handleDatabaseError(err, r.logger, op, "user_totp", meta)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	return nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left
+func (r *MFARepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	op := getOp(QueryCountRecoveryCodes)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryCountRecoveryCodes, r.logger, meta)
+	if err != nil || query == "" {
+		return 0, err
+	}
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, handleDatabaseError(err, r.logger, op, "recovery_codes", meta)
+	}
+	return count, nil
+}
+
+// UseRecoveryCode uses up a recovery code. It returns false when the code is
+// unknown or was already used.
+func (r *MFARepository) UseRecoveryCode(ctx context.Context, userID int, codeHash []byte) (bool, error) {
+	op := getOp(QueryUseRecoveryCode)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryUseRecoveryCode, r.logger, meta)
+	if err != nil || query == "" {
+		return false, err
+	}
+
+	tag, err := r.db.Exec(ctx, query, userID, codeHash, time.Now())
+	if err != nil {
+		return false, handleDatabaseError(err, r.logger, op, "recovery_codes", meta)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// SaveOTPVerification starts a check identified by v.Code, replacing the
+// user's previous check of the same purpose. It sets v.ID and v.Attempts.
+func (r *MFARepository) SaveOTPVerification(ctx context.Context, v *model.OTPVerification) error {
+	op := getOp(QuerySaveOTPVerification)
+	meta := common.Envelop{"user_id": v.UserID, "purpose": v.Purpose, "context": op}
+
+	query, err := getQuery(QuerySaveOTPVerification, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	v.CreatedAt = time.Now()
+	err = r.db.QueryRow(ctx, query, v.UserID, v.Code, v.Purpose, v.ExpiresAt, v.CreatedAt).Scan(&v.ID, &v.Attempts)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "otp_verifications", meta)
+	}
+	return nil
+}
+
+// GetOTPVerification returns the unverified check identified by code
+func (r *MFARepository) GetOTPVerification(ctx context.Context, code, purpose string) (*model.OTPVerification, error) {
+	op := getOp(QueryGetOTPVerification)
+	meta := common.Envelop{"purpose": purpose, "context": op}
+
+	query, err := getQuery(QueryGetOTPVerification, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	v := model.OTPVerification{Code: code}
+	err = r.db.QueryRow(ctx, query, code, purpose).Scan(
+		&v.ID,
+		&v.UserID,
+		&v.Purpose,
+		&v.ExpiresAt,
+		&v.Verified,
+		&v.Attempts,
+		&v.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperror.ErrMFAChallengeInvalid(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "otp_verifications", meta)
+	}
+	return &v, nil
+}
+
+// RecordOTPAttempt counts an attempt at a check and returns the attempts made.
+// It returns false, counting nothing, once maxAttempts were made.
+func (r *MFARepository) RecordOTPAttempt(ctx context.Context, id int64, maxAttempts int) (int, bool, error) {
+	op := getOp(QueryRecordOTPAttempt)
+	meta := common.Envelop{"otp_id": id, "context": op}
+
+	query, err := getQuery(QueryRecordOTPAttempt, r.logger, meta)
+	if err != nil || query == "" {
+		return 0, false, err
+	}
+
+	var attempts int
+	if err := r.db.QueryRow(ctx, query, id, maxAttempts).Scan(&attempts); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return maxAttempts, false, nil
+		}
+		return 0, false, handleDatabaseError(err, r.logger, op, "otp_verifications", meta)
+	}
+	return attempts, true, nil
+}
+
+// RecordOTPWindowAttempt counts an attempt at a check that has no token and
+// returns the attempts made in the current window
+func (r *MFARepository) RecordOTPWindowAttempt(ctx context.Context, userID int, purpose string, window time.Duration) (int, error) {
+	op := getOp(QueryRecordOTPWindowAttempt)
+	meta := common.Envelop{"user_id": userID, "purpose": purpose, "context": op}
+
+	query, err := getQuery(QueryRecordOTPWindowAttempt, r.logger, meta)
+	if err != nil || query == "" {
+		return 0, err
+	}
+
+	now := time.Now()
+	var attempts int
+	if err := r.db.QueryRow(ctx, query, userID, purpose, now.Add(window), now).Scan(&attempts); err != nil {
+		return 0, handleDatabaseError(err, r.logger, op, "otp_verifications", meta)
+	}
+	return attempts, nil
+}
+
+// ConsumeOTPVerification marks a check verified. It returns false when it
+// already was, so a check only succeeds once.
+func (r *MFARepository) ConsumeOTPVerification(ctx context.Context, id int64) (bool, error) {
+	op := getOp(QueryConsumeOTPVerification)
+	meta := common.Envelop{"otp_id": id, "context": op}
+
+	query, err := getQuery(QueryConsumeOTPVerification, r.logger, meta)
+	if err != nil || query == "" {
+		return false, err
+	}
+
+	tag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, handleDatabaseError(err, r.logger, op, "otp_verifications", meta)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// DeleteOTPVerification ends the user's check of a purpose, resetting its attempts
+func (r *MFARepository) DeleteOTPVerification(ctx context.Context, userID int, purpose string) error {
+	op := getOp(QueryDeleteOTPVerification)
+	meta := common.Envelop{"user_id": userID, "purpose": purpose, "context": op}
+
+	query, err := getQuery(QueryDeleteOTPVerification, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	if _, err := r.db.Exec(ctx, query, userID, purpose); err != nil {
+		return handleDatabaseError(err, r.logger, op, "otp_verifications", meta)
+	}
+	return nil
+}
+
+// GetMFAPolicy returns the second factors the user's password logins require
+func (r *MFARepository) GetMFAPolicy(ctx context.Context, userID int) (*model.MFAPolicy, error) {
+	op := getOp(QueryGetMFAPolicy)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryGetMFAPolicy, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	var p model.MFAPolicy
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&p.TOTP, &p.Passkey); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperror.ErrUserNotFound(err, r.logger, meta)
+		}
+		return nil, handleDatabaseError(err, r.logger, op, "users", meta)
+	}
+	return &p, nil
+}
+
+// SetPasskeyMFARequired turns the passkey second step of password logins on or off
+func (r *MFARepository) SetPasskeyMFARequired(ctx context.Context, userID int, required bool) error {
+	op := getOp(QuerySetPasskeyMFARequired)
+	meta := common.Envelop{"user_id": userID, "required": required, "context": op}
+
+	query, err := getQuery(QuerySetPasskeyMFARequired, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, query, userID, required)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "users", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrUserNotFound(nil, r.logger, meta)
+	}
+	return nil
+}
@@ -14,15 +14,22 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 	"golang.org/x/sync/errgroup"
 )
 
 /* MovieStore Interface */
 type MovieStore interface {
-	GetTopMovies(ctx context.Context) ([]model.Movie, error)
+	GetTopMovies(ctx context.Context, page common.PageParams) (*common.MoviePage, error)
 	GetRandomMovies(ctx context.Context) ([]model.Movie, error)
 	GetMovieByID(ctx context.Context, id int) (model.Movie, error)
-	SearchMovieByName(ctx context.Context, name string, order string, genre *int) ([]model.Movie, error)
+	SearchMovieByName(ctx context.Context, name string, order string, genre *int, page common.PageParams) (*common.MoviePage, error)
+	GetMoviesByGenre(ctx context.Context, genreID int, order string, page common.PageParams) (*common.MoviePage, error)
+	DiscoverMovies(ctx context.Context, filter *common.DiscoverFilter, order string, page common.PageParams) (*common.DiscoverResult, error)
+	GetSimilarMovies(ctx context.Context, movieID int, limit int) ([]model.Movie, error)
+	GetMoviesByIDs(ctx context.Context, ids []int) ([]model.Movie, error)
+	GetAlsoSavedMovies(ctx context.Context, movieID int, limit int) ([]model.Movie, error)
+	RebuildCooccurrence(ctx context.Context, minSupport int) (int64, error)
 	GetAllGenres(ctx context.Context) ([]model.Genre, error)
 	DoesMovieExist(ctx context.Context, tmdbID int) (bool, error)
 	SearchMovies(ctx context.Context, query string) ([]int, error)
@@ -31,12 +38,14 @@ type MovieStore interface {
 
 type MovieRepository struct {
 	db     *pgxpool.Pool
+	redis  *redis.Client // optional, nil disables caching
 	logger logging.Logger
 }
 
-func NewMovieRepository(db *pgxpool.Pool, logger logging.Logger) *MovieRepository {
+func NewMovieRepository(db *pgxpool.Pool, redisClient *redis.Client, logger logging.Logger) *MovieRepository {
 	return &MovieRepository{
 		db:     db,
+		redis:  redisClient,
 		logger: logger,
 	}
 }
@@ -62,38 +71,34 @@ func (r *MovieRepository) DoesMovieExist(ctx context.Context, tmdbID int) (bool,
 	return exists, nil
 }
 
-// SearchMovies performs a full-text search on movies based on a query string.
+// SearchMovies performs a ranked full-text and trigram search on movies based on a query string.
 // It searches across title, tagline, overview, and keywords.
 // Returns a slice of TMDB IDs of matching movies, ordered by relevance.
 func (r *MovieRepository) SearchMovies(ctx context.Context, query string) ([]int, error) {
-	searchQuery := `SELECT tmdb_id FROM movies WHERE search_vector @@ plainto_tsquery('english', $1) ORDER BY ts_rank(search_vector, plainto_tsquery('english', $1)) DESC LIMIT 50;`
-
-	rows, err := r.db.Query(ctx, searchQuery, query)
+	page, err := r.SearchMovieByName(ctx, query, relevanceOrder, nil, common.PageParams{Limit: 50})
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform movie search: %w", err)
-	}
-	defer rows.Close()
-
-	var tmdbIDs []int
-	for rows.Next() {
-		var tmdbID int
-		if err := rows.Scan(&tmdbID); err != nil {
-			r.logger.Errorf("Failed to scan TMDB ID during search results: %v", err)
-			continue
-		}
-		tmdbIDs = append(tmdbIDs, tmdbID)
+		return nil, err
 	}
 
-	if rows.Err() != nil {
-		return nil, fmt.Errorf("error iterating search results: %w", rows.Err())
+	tmdbIDs := make([]int, 0, len(page.Movies))
+	for _, m := range page.Movies {
+		tmdbIDs = append(tmdbIDs, m.TMDB_ID)
 	}
 
 	return tmdbIDs, nil
 }
 
-// GetTopMovies retrieves top 10 movies
-func (r *MovieRepository) GetTopMovies(ctx context.Context) ([]model.Movie, error) {
-	return r.getMovies(ctx, QueryGetTopMovies, 10)
+// GetTopMovies retrieves a page of movies ordered by popularity
+func (r *MovieRepository) GetTopMovies(ctx context.Context, page common.PageParams) (*common.MoviePage, error) {
+	op := getOp(QueryGetTopMovies)
+	meta := common.Envelop{"limit": page.Limit, "context": op, "query_key": QueryGetTopMovies}
+
+	query, err := getQuery(QueryGetTopMovies, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	return r.queryMoviePage(ctx, op, query, true, nil, defaultMovieOrder, page, meta)
 }
 
 // GetRandomMovies retrieves 10 random movies
@@ -127,6 +132,10 @@ func (r *MovieRepository) GetMovieByID(ctx context.Context, id int) (model.Movie
 		&m.Language,
 		&m.PosterURL,
 		&m.TrailerURL,
+		&m.IMDbID,
+		&m.Runtime,
+		&m.IMDbRating,
+		&m.IMDbVotes,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -148,35 +157,77 @@ func (r *MovieRepository) GetMovieByID(ctx context.Context, id int) (model.Movie
 		return model.Movie{}, err
 	}
 
+	rating, err := getCommunityRating(ctx, r.db, r.logger, id)
+	if err != nil {
+		return model.Movie{}, err
+	}
+	m.CommunityRating = rating
+
+	if m.IMDbID != nil {
+		if err := r.fetchMovieCrew(ctx, &m); err != nil {
+			return model.Movie{}, err
+		}
+	}
+
 	return m, nil
 }
 
-// SearchMovieByName retrieves a movie by its name
-func (r *MovieRepository) SearchMovieByName(ctx context.Context, name string, order string, genre *int) ([]model.Movie, error) {
-	const defaultOrderBy = "popularity DESC"
-	const limit int = 10
+// fetchMovieCrew loads the directors and writers an IMDb enrichment recorded
+func (r *MovieRepository) fetchMovieCrew(ctx context.Context, m *model.Movie) error {
+	op := getOp(QueryGetMovieCrew)
+	meta := common.Envelop{"movie_id": m.ID, "context": op}
 
-	validOrders := map[string]string{
-		"score":      "score DESC",
-		"name":       "title",
-		"date":       "release_year DESC",
-		"popularity": defaultOrderBy,
+	rows, err := r.queryRowsWithErrorHandling(ctx, QueryGetMovieCrew, op, meta, m.ID)
+	if err != nil || rows == nil {
+		return err
 	}
-	orderBy, ok := validOrders[order]
-	if !ok {
-		orderBy = defaultOrderBy
-		r.logger.Info("Invalid order by parameter, defaulting to popularity", common.Envelop{"provided_order": order})
+	defer rows.Close()
+
+	for rows.Next() {
+		var c model.CrewMember
+		if err := rows.Scan(&c.IMDbID, &c.Name, &c.Role, &c.Ordering); err != nil {
+			return handleDatabaseError(err, r.logger, op, QueryGetMovieCrew, meta)
+		}
+		switch c.Role {
+		case model.CrewDirector:
+			m.Directors = append(m.Directors, c)
+		case model.CrewWriter:
+			m.Writers = append(m.Writers, c)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return handleDatabaseError(err, r.logger, op, QueryGetMovieCrew, meta)
+	}
+	return nil
+}
+
+// SearchMovieByName retrieves a page of movies matching name, ranked by
+// relevance unless another order is requested. When the first page has no
+// hits, "did you mean" suggestions are returned instead.
+func (r *MovieRepository) SearchMovieByName(ctx context.Context, name string, order string, genre *int, page common.PageParams) (*common.MoviePage, error) {
+	sort := relevanceSort
+	if order != "" && order != relevanceOrder {
+		var ok bool
+		if sort, ok = resolveMovieSort(order); !ok {
+			r.logger.Info("Invalid order by parameter, defaulting to relevance", common.Envelop{"provided_order": order})
+			sort = relevanceSort
+		}
 	}
 
 	op := "store.SearchMovieByName"
 	meta := common.Envelop{
 		"search_query": name,
-		"order_by":     orderBy,
+		"order_by":     sort.name,
 		"genre":        genre,
-		"limit":        limit,
+		"limit":        page.Limit,
 		"context":      op,
 	}
 
+	cur, err := decodeMovieCursor(page.Cursor, sort)
+	if err != nil {
+		return nil, apperror.ErrInvalidCursor(err, r.logger, meta)
+	}
+
 	// Get the base query string using the key
 	baseQuery, err := getQuery(QuerySearchMovieByName, r.logger, meta)
 	if baseQuery == "" {
@@ -186,27 +237,23 @@ func (r *MovieRepository) SearchMovieByName(ctx context.Context, name string, or
 	var queryBuilder strings.Builder
 	queryBuilder.WriteString(baseQuery)
 
-	args := make([]any, 0, 3)
-	args = append(args, "%"+name+"%") // $1
-	argPos := 2
+	args := make([]any, 0, 6)
+	args = append(args, buildSearchTSQuery(name), strings.TrimSpace(name)) // $1, $2
+	hasWhere := false
 
 	if genre != nil {
-		queryBuilder.WriteString(fmt.Sprintf(` AND EXISTS (
+		queryBuilder.WriteString(fmt.Sprintf(` WHERE EXISTS (
 					SELECT 1 FROM movie_genres
 					WHERE movie_id = movies.id AND genre_id = $%d
-				)`, argPos))
+				)`, len(args)+1))
 		args = append(args, *genre)
-		argPos++
+		hasWhere = true
 	}
 
-	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY %s LIMIT $%d", orderBy, argPos)) // Added space before ORDER BY
-	args = append(args, limit)
+	query, args := buildMoviePageQuery(queryBuilder.String(), hasWhere, args, sort, cur, page.Limit)
 
-	rows, err := r.db.Query(ctx, queryBuilder.String(), args...)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return []model.Movie{}, nil
-		}
 		meta["db_error_type"] = "query"
 		r.logger.Errorf("Failed to query movies by name", err, meta)
 		return nil, apperror.ErrDatabaseOpFailed(
@@ -218,14 +265,15 @@ func (r *MovieRepository) SearchMovieByName(ctx context.Context, name string, or
 			meta,
 		)
 	}
-
 	defer rows.Close()
 
-	movies := make([]model.Movie, 0, limit)
+	movies := make([]model.Movie, 0, page.Limit+1)
+	ranks := make([]float64, 0, page.Limit+1)
 	for rows.Next() {
 		var m model.Movie
+		var rank float64
 		err := rows.Scan(
-			&m.ID, &m.TMDB_ID, &m.Title, &m.Tagline, &m.ReleaseYear, &m.Overview, &m.Score, &m.Popularity, &m.Language, &m.PosterURL, &m.TrailerURL, // FIXED: TMDB_ID
+			&m.ID, &m.TMDB_ID, &m.Title, &m.Tagline, &m.ReleaseYear, &m.Overview, &m.Score, &m.Popularity, &m.Language, &m.PosterURL, &m.TrailerURL, &rank,
 		)
 		if err != nil {
 			meta["db_error_type"] = "query_scan"
@@ -241,6 +289,7 @@ func (r *MovieRepository) SearchMovieByName(ctx context.Context, name string, or
 		}
 
 		movies = append(movies, m)
+		ranks = append(ranks, rank)
 	}
 
 	// Check for any error during iteration
@@ -256,6 +305,115 @@ func (r *MovieRepository) SearchMovieByName(ctx context.Context, name string, or
 			meta,
 		)
 	}
+
+	result, err := newMoviePage(movies, page.Limit, sort, ranks)
+	if err != nil {
+		return nil, apperror.ErrInternalServer(err, r.logger, meta)
+	}
+
+	if len(result.Movies) == 0 && cur == nil {
+		result.Suggestions = r.searchSuggestions(ctx, name, meta)
+	}
+
+	return result, nil
+}
+
+// searchSuggestions returns titles and keywords that look like name. It is
+// best effort: failures are logged and yield no suggestions.
+func (r *MovieRepository) searchSuggestions(ctx context.Context, name string, meta common.Envelop) []string {
+	const (
+		minSimilarity  = 0.3
+		maxSuggestions = 5
+	)
+
+	query, err := getQuery(QuerySearchSuggestions, r.logger, meta)
+	if err != nil || query == "" {
+		return nil
+	}
+
+	// Over-fetch since a title may also appear as a keyword
+	rows, err := r.db.Query(ctx, query, strings.TrimSpace(name), minSimilarity, maxSuggestions*2)
+	if err != nil {
+		r.logger.Error("Failed to query search suggestions", err, meta)
+		return nil
+	}
+	defer rows.Close()
+
+	seen := make(map[string]struct{}, maxSuggestions)
+	suggestions := make([]string, 0, maxSuggestions)
+	for rows.Next() && len(suggestions) < maxSuggestions {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			r.logger.Error("Failed to scan search suggestion", err, meta)
+			return suggestions
+		}
+		key := strings.ToLower(s)
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions
+}
+
+// GetMoviesByGenre retrieves a page of movies tagged with the given genre
+func (r *MovieRepository) GetMoviesByGenre(ctx context.Context, genreID int, order string, page common.PageParams) (*common.MoviePage, error) {
+	if _, ok := movieSorts[order]; !ok {
+		order = defaultMovieOrder
+	}
+
+	op := getOp(QueryGetMoviesByGenre)
+	meta := common.Envelop{
+		"genre_id": genreID,
+		"order_by": order,
+		"limit":    page.Limit,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryGetMoviesByGenre, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	return r.queryMoviePage(ctx, op, query, true, []any{genreID}, order, page, meta)
+}
+
+// GetMoviesByIDs retrieves the given movies in the order of ids; unknown ids are skipped
+func (r *MovieRepository) GetMoviesByIDs(ctx context.Context, ids []int) ([]model.Movie, error) {
+	if len(ids) == 0 {
+		return []model.Movie{}, nil
+	}
+
+	op := getOp(QueryGetMoviesByIDs)
+	meta := common.Envelop{"movie_count": len(ids), "context": op}
+
+	rows, err := r.queryRowsWithErrorHandling(ctx, QueryGetMoviesByIDs, op, meta, ids)
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		return []model.Movie{}, nil
+	}
+	defer rows.Close()
+
+	found, err := scanRowsToSlice(rows, scanMovie, r.logger, op, meta, len(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]model.Movie, len(found))
+	for _, m := range found {
+		byID[m.ID] = m
+	}
+
+	movies := make([]model.Movie, 0, len(found))
+	for _, id := range ids {
+		if m, ok := byID[id]; ok {
+			movies = append(movies, m)
+		}
+	}
 	return movies, nil
 }
 
@@ -415,6 +573,49 @@ func (r *MovieRepository) getMovies(ctx context.Context, queryKey string, limit
 	return movies, nil
 }
 
+// queryMoviePage runs a keyset-paginated movie listing built on top of base
+// and returns the requested page together with the cursor of the next one.
+func (r *MovieRepository) queryMoviePage(
+	ctx context.Context,
+	op string,
+	base string,
+	hasWhere bool,
+	args []any,
+	order string,
+	page common.PageParams,
+	meta common.Envelop,
+) (*common.MoviePage, error) {
+	sort, _ := resolveMovieSort(order)
+
+	cur, err := decodeMovieCursor(page.Cursor, sort)
+	if err != nil {
+		return nil, apperror.ErrInvalidCursor(err, r.logger, meta)
+	}
+
+	query, args := buildMoviePageQuery(base, hasWhere, args, sort, cur, page.Limit)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		meta["db_error_type"] = "query"
+		return nil, apperror.ErrDatabaseOpFailed(
+			apperror.CodeDatabaseError,
+			apperror.ErrQueryFailedMsg,
+			op,
+			err,
+			r.logger,
+			meta,
+		)
+	}
+	defer rows.Close()
+
+	movies, err := scanRowsToSlice(rows, scanMovie, r.logger, op, meta, page.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMoviePage(movies, page.Limit, sort, nil)
+}
+
 // queryRowsWithErrorHandling retrieves multiple rows from the database, handling query lookup and initial query errors.
 func (r *MovieRepository) queryRowsWithErrorHandling(
 	ctx context.Context,
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"multipass/internal/model"
+	"multipass/pkg/common"
+	"multipass/pkg/utils"
+)
+
+// movieCursor is the decoded form of the opaque cursor handed to clients.
+// It records the sort key of the last row of a page so the next page can
+// resume with a keyset predicate instead of an OFFSET.
+type movieCursor struct {
+	Order string  `json:"o"`
+	Num   float64 `json:"n,omitempty"`
+	Text  string  `json:"t,omitempty"`
+	ID    int     `json:"id"`
+}
+
+// collectionCursor resumes a user collection listing, which is always
+// ordered by the time a movie was added.
+type collectionCursor struct {
+	TimeAdded time.Time `json:"t"`
+	ID        int       `json:"id"`
+}
+
+// reviewCursor resumes a review listing, which is always newest first.
+type reviewCursor struct {
+	TimeCreated time.Time `json:"t"`
+	ID          int       `json:"id"`
+}
+
+// diaryCursor resumes a diary listing, which is ordered by watch date, newest first.
+type diaryCursor struct {
+	WatchedOn time.Time `json:"w"`
+	ID        int       `json:"id"`
+}
+
+// catalogHistoryCursor resumes a catalog history listing, which is ordered by entry id, newest first.
+type catalogHistoryCursor struct {
+	ID int64 `json:"id"`
+}
+
+// movieSort describes how a movie listing is ordered. Every ordering is
+// made total by using movies.id as the tie breaker.
+type movieSort struct {
+	name string
+	expr string // SQL expression the rows are ordered by
+	desc bool
+	text bool // expr yields text instead of a number
+}
+
+const (
+	defaultMovieOrder = "popularity"
+	relevanceOrder    = "relevance"
+)
+
+// relevanceSort orders search results by the search_rank column computed in QuerySearchMovieByName.
+var relevanceSort = movieSort{name: relevanceOrder, expr: "movies.search_rank", desc: true}
+
+var movieSorts = map[string]movieSort{
+	"popularity": {name: "popularity", expr: "COALESCE(movies.popularity, 0)::float8", desc: true},
+	"score":      {name: "score", expr: "COALESCE(movies.score, 0)::float8", desc: true},
+	"date":       {name: "date", expr: "COALESCE(movies.release_year, 0)::float8", desc: true},
+	"name":       {name: "name", expr: "movies.title", text: true},
+}
+
+// resolveMovieSort returns the sort for order, falling back to popularity.
+func resolveMovieSort(order string) (movieSort, bool) {
+	sort, ok := movieSorts[order]
+	if !ok {
+		return movieSorts[defaultMovieOrder], false
+	}
+	return sort, true
+}
+
+// decodeMovieCursor decodes a client cursor and checks that it was issued for the same ordering.
+func decodeMovieCursor(raw string, sort movieSort) (*movieCursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var cur movieCursor
+	if err := utils.DecodeCursor(raw, &cur); err != nil {
+		return nil, err
+	}
+	if cur.Order != sort.name {
+		return nil, fmt.Errorf("cursor was issued for order %q, not %q", cur.Order, sort.name)
+	}
+	if cur.ID < 1 {
+		return nil, errors.New("cursor is missing the last movie id")
+	}
+	return &cur, nil
+}
+
+// buildMoviePageQuery appends the keyset predicate, ORDER BY and LIMIT of a
+// cursor-paginated listing to base. hasWhere reports whether base already
+// contains a WHERE clause. One row more than limit is requested so the
+// caller can tell whether another page exists.
+func buildMoviePageQuery(base string, hasWhere bool, args []any, sort movieSort, cur *movieCursor, limit int) (string, []any) {
+	var qb strings.Builder
+	qb.WriteString(base)
+
+	direction, cmp := "ASC", ">"
+	if sort.desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if cur != nil {
+		if hasWhere {
+			qb.WriteString(" AND ")
+		} else {
+			qb.WriteString(" WHERE ")
+		}
+		qb.WriteString(fmt.Sprintf("(%s, movies.id) %s ($%d, $%d)", sort.expr, cmp, len(args)+1, len(args)+2))
+		if sort.text {
+			args = append(args, cur.Text, cur.ID)
+		} else {
+			args = append(args, cur.Num, cur.ID)
+		}
+	}
+
+	qb.WriteString(fmt.Sprintf(" ORDER BY %s %s, movies.id %s LIMIT $%d", sort.expr, direction, direction, len(args)+1))
+	args = append(args, limit+1)
+
+	return qb.String(), args
+}
+
+// newMoviePage trims the look-ahead row off movies and builds the cursor of the next page.
+// ranks holds the search_rank of every row and is only needed for relevanceSort.
+func newMoviePage(movies []model.Movie, limit int, sort movieSort, ranks []float64) (*common.MoviePage, error) {
+	page := &common.MoviePage{Movies: movies}
+	if len(movies) <= limit {
+		return page, nil
+	}
+
+	page.Movies = movies[:limit]
+	page.HasMore = true
+
+	last := page.Movies[limit-1]
+	cur := movieCursor{Order: sort.name, ID: last.ID}
+	switch sort.name {
+	case relevanceOrder:
+		cur.Num = ranks[limit-1]
+	case "name":
+		cur.Text = last.Title
+	case "score":
+		cur.Num = float64(derefFloat32(last.Score))
+	case "date":
+		cur.Num = float64(last.ReleaseYear)
+	default:
+		cur.Num = float64(derefFloat32(last.Popularity))
+	}
+
+	next, err := utils.EncodeCursor(cur)
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = next
+	return page, nil
+}
+
+func derefFloat32(v *float32) float32 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
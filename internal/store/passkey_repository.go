@@ -2,11 +2,12 @@ package store
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"time"
 
 	"multipass/internal/model"
 	"multipass/pkg/apperror"
@@ -14,67 +15,34 @@ import (
 	"multipass/pkg/logging"
 
 	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PasskeyStore interface {
 	GetUserByEmail(ctx context.Context, email string) (*model.PasskeyUser, error)
 	GetUserByID(ctx context.Context, ID int) (*model.PasskeyUser, error)
-	SaveUser(ctx context.Context, user model.PasskeyUser) error
-
-	GenSessionID() (string, error)
-	GetSession(token string) (webauthn.SessionData, bool)
-	SaveSession(token string, data webauthn.SessionData)
-	DeleteSession(token string)
+	GetCredentials(ctx context.Context, userID int) ([]webauthn.Credential, error)
+	AddCredential(ctx context.Context, userID int, credential *webauthn.Credential, authenticator string) error
+	UpdateCredentialUsage(ctx context.Context, userID int, credential *webauthn.Credential) (bool, error)
+	ListPasskeys(ctx context.Context, userID int) ([]model.Passkey, error)
+	RenamePasskey(ctx context.Context, userID, passkeyID int, nickname string) error
+	DeletePasskey(ctx context.Context, userID, passkeyID int) error
 }
 
 type PasskeyRepository struct {
-	db       *pgxpool.Pool
-	logger   logging.Logger
-	sessions map[string]webauthn.SessionData
+	db     *pgxpool.Pool
+	logger logging.Logger
 }
 
 // NewPasskeyRepository initializes a new PasskeyRepository with a database connection.and logger
 func NewPasskeyRepository(db *pgxpool.Pool, logger logging.Logger) *PasskeyRepository {
 	return &PasskeyRepository{
-		db:       db,
-		logger:   logger,
-		sessions: make(map[string]webauthn.SessionData),
-	}
-}
-
-// GetSession retrieves session data from the in-memory map.
-func (r *PasskeyRepository) GenSessionID() (string, error) {
-	b := make([]byte, 32)
-	_, err := rand.Read(b)
-	if err != nil {
-		return "", err
+		db:     db,
+		logger: logger,
 	}
-
-	return base64.URLEncoding.EncodeToString(b), nil
-}
-
-// GetSession retrieves session data from the in-memory map.
-func (r *PasskeyRepository) GetSession(token string) (webauthn.SessionData, bool) {
-	r.logger.Info(fmt.Sprintf("GetSession: %v", r.sessions[token]))
-	val, ok := r.sessions[token]
-	return val, ok
-}
-
-// SaveSession stores session data in the in-memory map.
-func (r *PasskeyRepository) SaveSession(token string, data webauthn.SessionData) {
-	r.logger.Info(fmt.Sprintf("SaveSession: %s - %v", token, data))
-	r.sessions[token] = data
-}
-
-// DeleteSession removes session data from the in-memory map.
-func (r *PasskeyRepository) DeleteSession(token string) {
-	r.logger.Info("DeleteSession: %v", token)
-	delete(r.sessions, token)
 }
 
-// GenSessionID generates a random session ID.
-
 // GetUserByEmail retrieves user by email.
 func (r *PasskeyRepository) GetUserByEmail(ctx context.Context, email string) (*model.PasskeyUser, error) {
 	op := "PasskeyRepository.GetUserByEmail"
@@ -86,7 +54,7 @@ func (r *PasskeyRepository) GetUserByEmail(ctx context.Context, email string) (*
 
 	// STEP 1: CHECK IF USER EXISTS BY EMAIL
 	var userID int
-	err := r.db.QueryRow(ctx, "SELECT id FROM users WHERE email = $1", email).Scan(&userID)
+	err := r.db.QueryRow(ctx, "SELECT id FROM users WHERE email = $1 AND time_deleted IS NULL", email).Scan(&userID)
 	if err != nil {
 		return nil, handleDatabaseError(err, r.logger, op, "user", meta)
 	}
@@ -139,7 +107,7 @@ func (r *PasskeyRepository) GetUserByID(ctx context.Context, ID int) (*model.Pas
 	// STEP 1: CHECK IF USER EXISTS BY ID
 	var userID int
 	var email string
-	err := r.db.QueryRow(ctx, "SELECT id, email FROM users WHERE user_id = $1", ID).Scan(&userID, &email)
+	err := r.db.QueryRow(ctx, "SELECT id, email FROM users WHERE id = $1 AND time_deleted IS NULL", ID).Scan(&userID, &email)
 	if err != nil {
 		return nil, handleDatabaseError(err, r.logger, op, "user", meta)
 	}
@@ -182,47 +150,226 @@ func (r *PasskeyRepository) GetUserByID(ctx context.Context, ID int) (*model.Pas
 	return &user, nil
 }
 
-func (r *PasskeyRepository) SaveUser(ctx context.Context, user model.PasskeyUser) error {
-	op := "PasskeyRepository.SaveUser"
+// GetCredentials returns the passkeys registered by a user.
+func (r *PasskeyRepository) GetCredentials(ctx context.Context, userID int) ([]webauthn.Credential, error) {
+	op := "PasskeyRepository.GetCredentials"
 	meta := common.Envelop{
-		"op": op,
+		"op":      op,
+		"user_id": userID,
 	}
 
-	// STEP 1: CONVERT user ID from byte slice to integer
-	userID, err := strconv.Atoi(string(user.ID))
+	rows, err := r.db.Query(ctx, "SELECT keys FROM passkeys WHERE user_id = $1", userID)
 	if err != nil {
-		r.logger.Error("invalid user ID", err)
-		return fmt.Errorf("invalid user ID: %+w", err)
+		return nil, handleDatabaseError(err, r.logger, op, "user_id", meta)
 	}
+	defer rows.Close()
 
-	// STEP 2: INSERT NEW CREDENTIALS
-	for _, cred := range user.Credientials {
-		keys, err := serializeCredential(cred)
-		if err != nil {
-			r.logger.Error("failed to serialize credentials", err)
-			continue
+	credentials := []webauthn.Credential{}
+	for rows.Next() {
+		var keys string
+		if err := rows.Scan(&keys); err != nil {
+			return nil, handleDatabaseError(err, r.logger, op, "passkey", meta)
 		}
 
-		// CHECK IF KEY ALREADY EXISTS IN DATABASE
-		var exists bool
-		err = r.db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM passkeys WHERE user_id = $1 AND keys = $2)", userID, keys).Scan(&exists)
+		cred, err := deserializeCredential(keys)
 		if err != nil {
-			r.logger.Error("failed to check if passkey exit", err)
-			continue
+			r.logger.Error("failed to deserialize credential", err)
+			continue // SKIPPING INVALID CREDENTIAL
+		}
+		credentials = append(credentials, cred)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "passkey", meta)
+	}
+
+	return credentials, nil
+}
+
+// AddCredential stores a newly registered passkey
+func (r *PasskeyRepository) AddCredential(ctx context.Context, userID int, credential *webauthn.Credential, authenticator string) error {
+	op := getOp(QueryAddPasskey)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryAddPasskey, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	keys, err := serializeCredential(*credential)
+	if err != nil {
+		return apperror.ErrInternalServer(err, r.logger, meta)
+	}
+
+	tag, err := r.db.Exec(ctx, query,
+		userID,
+		keys,
+		credential.ID,
+		credential.Authenticator.AAGUID,
+		authenticator,
+		int64(credential.Authenticator.SignCount),
+		credential.Flags.BackupEligible,
+		credential.Flags.BackupState,
+		time.Now(),
+	)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "passkeys", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrUniqueViolation(nil, op, r.logger, meta)
+	}
+	return nil
+}
+
+// UpdateCredentialUsage saves the sign count and backup state of a passkey
+// that was just used. It returns false, saving nothing, when the sign count
+// did not go up.
+func (r *PasskeyRepository) UpdateCredentialUsage(ctx context.Context, userID int, credential *webauthn.Credential) (bool, error) {
+	op := getOp(QueryUpdatePasskeyUsage)
+	meta := common.Envelop{"user_id": userID, "sign_count": credential.Authenticator.SignCount, "context": op}
+
+	query, err := getQuery(QueryUpdatePasskeyUsage, r.logger, meta)
+	if err != nil || query == "" {
+		return false, err
+	}
+
+	keys, err := serializeCredential(*credential)
+	if err != nil {
+		return false, apperror.ErrInternalServer(err, r.logger, meta)
+	}
+
+	tag, err := r.db.Exec(ctx, query,
+		userID,
+		credential.ID,
+		keys,
+		int64(credential.Authenticator.SignCount),
+		credential.Flags.BackupState,
+		time.Now(),
+	)
+	if err != nil {
+		return false, handleDatabaseError(err, r.logger, op, "passkeys", meta)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ListPasskeys returns the user's passkeys, oldest first
+func (r *PasskeyRepository) ListPasskeys(ctx context.Context, userID int) ([]model.Passkey, error) {
+	op := getOp(QueryListPasskeys)
+	meta := common.Envelop{"user_id": userID, "context": op}
+
+	query, err := getQuery(QueryListPasskeys, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "passkeys", meta)
+	}
+	defer rows.Close()
+
+	passkeys := []model.Passkey{}
+	for rows.Next() {
+		var p model.Passkey
+		if err := rows.Scan(
+			&p.ID,
+			&p.Nickname,
+			&p.Authenticator,
+			&p.SignCount,
+			&p.BackupEligible,
+			&p.BackupState,
+			&p.TimeCreated,
+			&p.TimeLastUsed,
+		); err != nil {
+			return nil, handleDatabaseError(err, r.logger, op, "passkeys", meta)
 		}
+		passkeys = append(passkeys, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "passkeys", meta)
+	}
+	return passkeys, nil
+}
+
+// RenamePasskey sets the nickname of one of the user's passkeys
+func (r *PasskeyRepository) RenamePasskey(ctx context.Context, userID, passkeyID int, nickname string) error {
+	op := getOp(QueryRenamePasskey)
+	meta := common.Envelop{"user_id": userID, "passkey_id": passkeyID, "context": op}
 
-		if exists {
-			r.logger.Info(fmt.Sprintf("Passkey already exists for user_id: %d", userID))
-			return apperror.ErrUniqueViolation(err, op, r.logger, meta)
+	query, err := getQuery(QueryRenamePasskey, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, query, userID, passkeyID, nickname)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "passkeys", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrPasskeyNotFound(nil, r.logger, meta)
+	}
+	return nil
+}
+
+// DeletePasskey deletes one of the user's passkeys, unless it is the last one
+// and the account can't sign in without it: it has no password, or its
+// password logins require a passkey.
+func (r *PasskeyRepository) DeletePasskey(ctx context.Context, userID, passkeyID int) error {
+	op := getOp(QueryDeletePasskey)
+	meta := common.Envelop{"user_id": userID, "passkey_id": passkeyID, "context": op}
+
+	queries := make([]string, 0, 3)
+	for _, key := range []string{QueryLockPasskeyOwner, QueryLockPasskeys, QueryDeletePasskey} {
+		query, err := getQuery(key, r.logger, meta)
+		if err != nil || query == "" {
+			return err
 		}
+		queries = append(queries, query)
+	}
 
-		// FINALLY INSERT KEY INTO DB
-		_, err = r.db.Exec(ctx, "INSERT INTO passkeys (user_id, keys) VALUES ($1, $2)", userID, keys)
-		if err != nil {
-			return handleDatabaseError(err, r.logger, op, "passkey", meta)
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	// Locking the user and their passkeys stops two deletes from each
+	// leaving the other passkey behind
+	var hasPassword, passkeyRequired bool
+	if err := tx.QueryRow(ctx, queries[0], userID).Scan(&hasPassword, &passkeyRequired); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apperror.ErrUserNotFound(err, r.logger, meta)
+		}
+		return handleDatabaseError(err, r.logger, op, "users", meta)
+	}
+
+	rows, err := tx.Query(ctx, queries[1], userID)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "passkeys", meta)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "passkeys", meta)
+	}
+
+	if !slices.Contains(ids, passkeyID) {
+		return apperror.ErrPasskeyNotFound(nil, r.logger, meta)
+	}
+	if len(ids) == 1 {
+		if !hasPassword {
+			return apperror.ErrLastPasskey(nil, r.logger, meta)
+		}
+		if passkeyRequired {
+			return apperror.ErrPasskeyRequired(nil, r.logger, meta)
 		}
 	}
 
+	if _, err := tx.Exec(ctx, queries[2], userID, passkeyID); err != nil {
+		return handleDatabaseError(err, r.logger, op, "passkeys", meta)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return apperror.ErrDatabaseOpFailed(apperror.CodeTransactionFailed, apperror.ErrQueryFailedMsg, op, err, r.logger, meta)
+	}
 	return nil
 }
 
@@ -17,19 +17,159 @@ const (
 	QueryGetRandomMovies        = "RandomMovies"
 	QueryGetMovieByID           = "GetMovieByID"
 	QuerySearchMovieByName      = "SearchMovieByName"
+	QuerySearchSuggestions      = "SearchSuggestions"
+	QueryGetMoviesByGenre       = "GetMoviesByGenre"
+	QueryDiscoverMovies         = "DiscoverMovies"
+	QueryGetSimilarMovies       = "GetSimilarMovies"
+	QueryCatalogMovieExists     = "CatalogMovieExists"
+	QueryClearCooccurrence      = "ClearCooccurrence"
+	QueryRebuildCooccurrence    = "RebuildCooccurrence"
+	QueryGetAlsoSavedMovies     = "GetAlsoSavedMovies"
+	QueryDiscoverFacets         = "DiscoverFacets"
 	QueryGetGenreByMovieID      = "GetGenreByMovieID"
 	QueryGetActorByMovieID      = "GetActorByMovieID"
 	QueryGetKeywordByMovieID    = "GetKeywordByMovieID"
 	QueryGetGenres              = "GetGenres"
 	QueryGetActors              = "GetActors"
 	QueryGetKeywords            = "GetKeywords"
+	QueryGetMovieCrew           = "GetMovieCrew"
 	QueryGetFavorite            = "GetFavorite"
 	QueryGetWatchlist           = "GetWatchlist"
+	QueryGetCollectionPage      = "GetCollectionPage"
 	QueryIfMovieExists          = "IfMovieExists"
 	QueryAddToCollection        = "AddToCollection"
 	QueryRemoveFromToCollection = "RemoveFromCollection"
 )
 
+// ACTORS
+const (
+	QueryGetActorByID          = "GetActorByID"
+	QueryGetActorFilmography   = "GetActorFilmography"
+	QueryGetActorCollaborators = "GetActorCollaborators"
+	QuerySearchActors          = "SearchActors"
+)
+
+// RECOMMENDATIONS
+const (
+	QueryGetSavedMovies       = "GetSavedMovies"
+	QueryGetMovieFeatures     = "GetMovieFeatures"
+	QueryGetCandidateFeatures = "GetCandidateFeatures"
+	QueryGetMoviesByIDs       = "GetMoviesByIDs"
+)
+
+// DIARY
+const (
+	QueryLockWatches         = "LockWatches"
+	QueryCountWatches        = "CountWatches"
+	QueryAddDiaryEntry       = "AddDiaryEntry"
+	QueryGetDiaryPage        = "GetDiaryPage"
+	QueryUpdateDiaryEntry    = "UpdateDiaryEntry"
+	QueryDeleteDiaryEntry    = "DeleteDiaryEntry"
+	QueryRemoveFromWatchlist = "RemoveFromWatchlist"
+)
+
+// REVIEWS
+const (
+	QueryCreateReview       = "CreateReview"
+	QueryUpdateReview       = "UpdateReview"
+	QueryDeleteReview       = "DeleteReview"
+	QueryGetMovieReviews    = "GetMovieReviews"
+	QueryGetUserReviews     = "GetUserReviews"
+	QueryGetReviewHistory   = "GetReviewHistory"
+	QueryGetCommunityRating = "GetCommunityRating"
+)
+
+// LISTS
+const (
+	QueryCreateList          = "CreateList"
+	QueryUpdateList          = "UpdateList"
+	QueryDeleteList          = "DeleteList"
+	QueryGetUserLists        = "GetUserLists"
+	QueryGetListByID         = "GetListByID"
+	QueryGetListByShareHash  = "GetListByShareHash"
+	QuerySetListShareHash    = "SetListShareHash"
+	QueryLockList            = "LockList"
+	QueryTouchList           = "TouchList"
+	QueryCountListItems      = "CountListItems"
+	QueryGetListItems        = "GetListItems"
+	QueryGetListItem         = "GetListItem"
+	QueryGetListItemPosition = "GetListItemPosition"
+	QueryGetLastListPosition = "GetLastListPosition"
+	QueryGetNextListPosition = "GetNextListPosition"
+	QueryAddListItem         = "AddListItem"
+	QueryUpdateListItemNote  = "UpdateListItemNote"
+	QueryMoveListItem        = "MoveListItem"
+	QueryRenumberListItems   = "RenumberListItems"
+	QueryRemoveListItem      = "RemoveListItem"
+)
+
+// CATALOG
+const (
+	QueryLockMovie            = "LockMovie"
+	QueryGetMovieSnapshot     = "GetMovieSnapshot"
+	QueryCreateMovie          = "CreateMovie"
+	QueryUpdateMovie          = "UpdateMovie"
+	QuerySoftDeleteMovie      = "SoftDeleteMovie"
+	QueryRestoreMovieSnapshot = "RestoreMovieSnapshot"
+	QueryGetAdminMovie        = "GetAdminMovie"
+	QueryFindMissingIDs       = "FindMissingIDs"
+	QueryFindKeyword          = "FindKeyword"
+	QueryCreateKeyword        = "CreateKeyword"
+	QueryAttachRelation       = "AttachRelation"
+	QueryDetachRelation       = "DetachRelation"
+	QueryRetainRelation       = "RetainRelation"
+	QueryAddCatalogHistory    = "AddCatalogHistory"
+	QueryGetCatalogHistory    = "GetCatalogHistory"
+	QueryGetCatalogEntry      = "GetCatalogEntry"
+)
+
+// IMPORT
+const (
+	QueryImportLoadGenres   = "ImportLoadGenres"
+	QueryImportLoadActors   = "ImportLoadActors"
+	QueryImportLoadKeywords = "ImportLoadKeywords"
+	QueryImportGenreIDs     = "ImportGenreIDs"
+	QueryImportActorIDs     = "ImportActorIDs"
+	QueryImportKeywordIDs   = "ImportKeywordIDs"
+	QueryImportMovieIDs     = "ImportMovieIDs"
+	QueryImportUpdateMovie  = "ImportUpdateMovie"
+	QueryImportClearLinks   = "ImportClearLinks"
+	QueryImportTitles       = "ImportTitles"
+	QueryImportSetIMDb      = "ImportSetIMDb"
+	QueryImportClearCrew    = "ImportClearCrew"
+)
+
+// COLLECTION IMPORTS
+const (
+	QueryCreateCollectionImport     = "CreateCollectionImport"
+	QueryUpdateImportProgress       = "UpdateImportProgress"
+	QueryFinishCollectionImport     = "FinishCollectionImport"
+	QueryGetCollectionImport        = "GetCollectionImport"
+	QueryLockCollectionImport       = "LockCollectionImport"
+	QueryMarkImportCommitted        = "MarkImportCommitted"
+	QueryDeleteCollectionImport     = "DeleteCollectionImport"
+	QueryImportCandidatesByID       = "ImportCandidatesByID"
+	QueryImportCandidatesByTitle    = "ImportCandidatesByTitle"
+	QueryImportAddToCollection      = "ImportAddToCollection"
+	QueryImportLogWatches           = "ImportLogWatches"
+	QueryImportClearWatchlist       = "ImportClearWatchlist"
+	QueryImportMarkRewatches        = "ImportMarkRewatches"
+	QueryFailStaleCollectionImports = "FailStaleCollectionImports"
+	QueryPurgeCollectionImports     = "PurgeCollectionImports"
+)
+
+// DATA EXPORTS
+const (
+	QueryCreateDataExport    = "CreateDataExport"
+	QueryFinishDataExport    = "FinishDataExport"
+	QueryFailDataExport      = "FailDataExport"
+	QueryGetLatestDataExport = "GetLatestDataExport"
+	QueryFindDataExport      = "FindDataExport"
+	QueryConsumeDataExport   = "ConsumeDataExport"
+	QueryExpireDataExports   = "ExpireDataExports"
+	QueryGetLoginHistory     = "GetLoginHistory"
+)
+
 // USERS
 const (
 	QueryCreateUser           = "CreateUser"
@@ -40,6 +180,14 @@ const (
 	QueryGetUserDetails       = "GetUserDetails"
 	QueryGetUserFromTokenHash = "GetUserFromTokenHash"
 	QueryUpdateUserDetails    = "UpdateUserDetails"
+	QueryIsAdmin              = "IsAdmin"
+	QuerySoftDeleteUser       = "SoftDeleteUser"
+	QueryRestoreUser          = "RestoreUser"
+	QueryLockPurgeableUsers   = "LockPurgeableUsers"
+	QueryPurgeUserExports     = "PurgeUserExports"
+	QueryPurgeUserPasskeys    = "PurgeUserPasskeys"
+	QueryPurgeUserMovies      = "PurgeUserMovies"
+	QueryPurgeUsers           = "PurgeUsers"
 )
 
 // TOKENS
@@ -48,27 +196,204 @@ const (
 	QueryGetRefreshTokenHash    = "GetRefreshTokenHash"
 	QueryDeleteRefreshToken     = "DeleteRefreshToken"
 	QueryDeleteAllTokensForUser = "DeleteAllTokensForUser"
+	QueryGetTokenDetailsByHash  = "GetTokenDetailsByHash"
+	QueryGetSessions            = "GetSessions"
+	QueryDeleteSession          = "DeleteSession"
+	QueryDeleteOtherSessions    = "DeleteOtherSessions"
+	QueryRetireRefreshToken     = "RetireRefreshToken"
+	QueryRevokeTokenFamily      = "RevokeTokenFamily"
+	QueryPurgeRetiredTokens     = "PurgeRetiredTokens"
+	QueryGetUserAuthToken       = "GetUserAuthToken"
+	QueryRecordTokenAttempt     = "RecordTokenAttempt"
+	QueryDeleteUserTokens       = "DeleteUserTokens"
+)
+
+// SECURITY EVENTS
+const (
+	QueryLogSecurityEvent = "LogSecurityEvent"
+)
+
+// TWO-FACTOR AUTHENTICATION
+const (
+	QueryIsTOTPEnabled          = "IsTOTPEnabled"
+	QueryGetTOTP                = "GetTOTP"
+	QuerySaveTOTPSecret         = "SaveTOTPSecret"
+	QueryEnableTOTP             = "EnableTOTP"
+	QueryUseTOTPStep            = "UseTOTPStep"
+	QueryDeleteTOTP             = "DeleteTOTP"
+	QueryCountRecoveryCodes     = "CountRecoveryCodes"
+	QuerySaveRecoveryCodes      = "SaveRecoveryCodes"
+	QueryUseRecoveryCode        = "UseRecoveryCode"
+	QueryDeleteRecoveryCodes    = "DeleteRecoveryCodes"
+	QuerySaveOTPVerification    = "SaveOTPVerification"
+	QueryGetOTPVerification     = "GetOTPVerification"
+	QueryRecordOTPAttempt       = "RecordOTPAttempt"
+	QueryRecordOTPWindowAttempt = "RecordOTPWindowAttempt"
+	QueryConsumeOTPVerification = "ConsumeOTPVerification"
+	QueryDeleteOTPVerification  = "DeleteOTPVerification"
+	QueryDeleteOTPVerifications = "DeleteOTPVerifications"
+	QueryGetMFAPolicy           = "GetMFAPolicy"
+	QuerySetPasskeyMFARequired  = "SetPasskeyMFARequired"
+)
+
+// PASSKEYS
+const (
+	QueryListPasskeys       = "ListPasskeys"
+	QueryAddPasskey         = "AddPasskey"
+	QueryRenamePasskey      = "RenamePasskey"
+	QueryUpdatePasskeyUsage = "UpdatePasskeyUsage"
+	QueryLockPasskeyOwner   = "LockPasskeyOwner"
+	QueryLockPasskeys       = "LockPasskeys"
+	QueryDeletePasskey      = "DeletePasskey"
 )
 
 var Queries = map[string]string{
 	// MOVIES
+	// Ordering and LIMIT are appended by the keyset pagination helpers.
 	QueryGetTopMovies: `SELECT id, tmdb_id, title, tagline, release_year, overview, score, popularity, language, poster_url, trailer_url
 	FROM movies
-	ORDER BY popularity DESC
-	LIMIT $1`,
+	WHERE time_deleted IS NULL`,
 
 	QueryGetRandomMovies: `SELECT id, tmdb_id, title, tagline, release_year, overview, score, popularity, language, poster_url, trailer_url
 	FROM movies
+	WHERE time_deleted IS NULL
 	ORDER BY random()
 	LIMIT $1`,
 
-	QueryGetMovieByID: `SELECT id, tmdb_id, title, tagline, release_year, overview, score, popularity, language, poster_url, trailer_url
+	QueryGetMovieByID: `SELECT id, tmdb_id, title, tagline, release_year, overview, score, popularity, language, poster_url, trailer_url,
+		imdb_id, runtime_minutes, imdb_rating, imdb_votes
 	FROM movies
-	WHERE id = $1`,
+	WHERE id = $1 AND time_deleted IS NULL`,
 
-	QuerySearchMovieByName: `SELECT id, tmdb_id, title, tagline, release_year, overview, score, popularity, language, poster_url, trailer_url
+	// $1 is the tsquery built by buildSearchTSQuery, $2 the raw search text.
+	// search_rank blends weighted full-text relevance, trigram similarity of
+	// the title (typo tolerance) and a logarithmic popularity boost.
+	QuerySearchMovieByName: `SELECT id, tmdb_id, title, tagline, release_year, overview, score, popularity, language, poster_url, trailer_url, search_rank
+	FROM (
+		SELECT m.*,
+			ts_rank_cd(m.search_vector, to_tsquery('english', $1), 32) * 2.0
+			+ GREATEST(similarity(m.title, $2), word_similarity($2, m.title))
+			+ ln(1 + COALESCE(m.popularity, 0)) * 0.1 AS search_rank
+		FROM movies m
+		WHERE (m.search_vector @@ to_tsquery('english', $1)
+			OR m.title % $2
+			OR $2 <% m.title)
+			AND m.time_deleted IS NULL
+	) AS movies`,
+
+	QuerySearchSuggestions: `SELECT suggestion FROM (
+		SELECT title AS suggestion, word_similarity($1, title) AS sim, COALESCE(popularity, 0) AS pop
+		FROM movies
+		WHERE word_similarity($1, title) > $2 AND time_deleted IS NULL
+		UNION ALL
+		SELECT word, word_similarity($1, word), 0
+		FROM keywords
+		WHERE word_similarity($1, word) > $2
+	) s
+	ORDER BY sim DESC, pop DESC
+	LIMIT $3`,
+
+	QueryGetMoviesByGenre: `SELECT id, tmdb_id, title, tagline, release_year, overview, score, popularity, language, poster_url, trailer_url
 	FROM movies
-	WHERE (title ILIKE $1 OR overview ILIKE $1)`,
+	WHERE movies.time_deleted IS NULL AND EXISTS (
+		SELECT 1 FROM movie_genres
+		WHERE movie_id = movies.id AND genre_id = $1
+	)`,
+
+	// Filters from buildDiscoverConditions, ordering and LIMIT are appended in code.
+	QueryDiscoverMovies: `SELECT id, tmdb_id, title, tagline, release_year, overview, score, popularity, language, poster_url, trailer_url
+	FROM movies`,
+
+	// %s is replaced by the WHERE clause from buildDiscoverConditions (placeholders only, never user input).
+	QueryDiscoverFacets: `WITH filtered AS (
+		SELECT movies.id, movies.release_year, movies.language
+		FROM movies%s
+	)
+	SELECT 'genre' AS facet, g.id::text AS key, g.name AS label, COUNT(*)::int AS count
+	FROM filtered f
+	JOIN movie_genres mg ON mg.movie_id = f.id
+	JOIN genres g ON g.id = mg.genre_id
+	GROUP BY g.id, g.name
+	UNION ALL
+	SELECT 'decade', (f.release_year / 10 * 10)::text, (f.release_year / 10 * 10)::text || 's', COUNT(*)::int
+	FROM filtered f
+	WHERE f.release_year IS NOT NULL
+	GROUP BY f.release_year / 10 * 10
+	UNION ALL
+	SELECT 'language', f.language, f.language, COUNT(*)::int
+	FROM filtered f
+	WHERE f.language IS NOT NULL
+	GROUP BY f.language
+	ORDER BY facet, count DESC, key`,
+
+	// Deleted movies are gone from the catalog
+	QueryCatalogMovieExists: `SELECT EXISTS(SELECT 1 FROM movies WHERE id = $1 AND time_deleted IS NULL)`,
+
+	// $1 movie id, $2-$4 genre/keyword/cast weights, $5 limit
+	QueryGetSimilarMovies: `WITH overlap AS (
+		SELECT mg.movie_id, COUNT(*) * $2::float8 AS score
+		FROM movie_genres mg
+		WHERE mg.genre_id IN (SELECT genre_id FROM movie_genres WHERE movie_id = $1)
+		GROUP BY mg.movie_id
+		UNION ALL
+		SELECT mk.movie_id, COUNT(*) * $3::float8
+		FROM movie_keywords mk
+		WHERE mk.keyword_id IN (SELECT keyword_id FROM movie_keywords WHERE movie_id = $1)
+		GROUP BY mk.movie_id
+		UNION ALL
+		SELECT mc.movie_id, COUNT(*) * $4::float8
+		FROM movie_cast mc
+		WHERE mc.actor_id IN (SELECT actor_id FROM movie_cast WHERE movie_id = $1)
+		GROUP BY mc.movie_id
+	), scored AS (
+		SELECT movie_id, SUM(score) AS similarity
+		FROM overlap
+		WHERE movie_id <> $1
+		GROUP BY movie_id
+	)
+	SELECT movies.id, movies.tmdb_id, movies.title, movies.tagline, movies.release_year, movies.overview,
+	movies.score, movies.popularity, movies.language, movies.poster_url, movies.trailer_url
+	FROM scored
+	JOIN movies ON movies.id = scored.movie_id
+	WHERE movies.time_deleted IS NULL
+	ORDER BY scored.similarity DESC, movies.popularity DESC NULLS LAST, movies.id
+	LIMIT $5`,
+
+	QueryClearCooccurrence: `DELETE FROM movie_cooccurrence`,
+
+	// Cosine similarity between the sets of users who saved each movie. Deleted
+	// accounts are ignored and pairs below the $1 support threshold are dropped.
+	QueryRebuildCooccurrence: `INSERT INTO movie_cooccurrence (movie_id, other_movie_id, support, score, computed_at)
+	WITH saves AS (
+		SELECT DISTINCT um.user_id, um.movie_id
+		FROM user_movies um
+		JOIN users u ON u.id = um.user_id
+		WHERE u.time_deleted IS NULL
+	), savers AS (
+		SELECT movie_id, COUNT(*) AS n
+		FROM saves
+		GROUP BY movie_id
+	), pairs AS (
+		SELECT a.movie_id, b.movie_id AS other_movie_id, COUNT(*) AS support
+		FROM saves a
+		JOIN saves b ON b.user_id = a.user_id AND b.movie_id <> a.movie_id
+		GROUP BY a.movie_id, b.movie_id
+		HAVING COUNT(*) >= $1
+	)
+	SELECT p.movie_id, p.other_movie_id, p.support,
+		p.support / sqrt(sa.n::float8 * sb.n::float8),
+		CURRENT_TIMESTAMP
+	FROM pairs p
+	JOIN savers sa ON sa.movie_id = p.movie_id
+	JOIN savers sb ON sb.movie_id = p.other_movie_id`,
+
+	QueryGetAlsoSavedMovies: `SELECT m.id, m.tmdb_id, m.title, m.tagline, m.release_year, m.overview,
+	m.score, m.popularity, m.language, m.poster_url, m.trailer_url
+	FROM movie_cooccurrence mc
+	JOIN movies m ON m.id = mc.other_movie_id
+	WHERE mc.movie_id = $1 AND m.time_deleted IS NULL
+	ORDER BY mc.score DESC, mc.support DESC, m.popularity DESC NULLS LAST
+	LIMIT $2`,
 
 	QueryGetGenres: `SELECT g.id, g.name
 	FROM genres g
@@ -85,6 +410,11 @@ var Queries = map[string]string{
 	JOIN movie_keywords mk ON k.id = mk.keyword_id
 	WHERE mk.movie_id = $1`,
 
+	QueryGetMovieCrew: `SELECT imdb_person_id, name, role, ordering
+	FROM movie_crew
+	WHERE movie_id = $1
+	ORDER BY role, ordering`,
+
 	// QueryGetGenreByMovieID: `SELECT g.id, g.name
 	// FROM genres g
 	// JOIN movie_genres mg ON g.id = mg.genre_id
@@ -101,17 +431,27 @@ var Queries = map[string]string{
 	// WHERE mk.movie_id = $1`,
 
 	QueryGetFavorite: `SELECT m.id, m.tmdb_id, m.title, m.tagline, m.release_year,
-	m.overview, m.score, m.popularity, m.language, m.poster_url, m.trailer_url
+	m.overview, m.score, m.popularity, m.language, m.poster_url, m.trailer_url,
+	m.imdb_id, um.time_added
 	FROM movies m
 	JOIN user_movies um ON m.id = um.movie_id
-	WHERE um.user_id = $1 AND um.relation_type = 'favorite'`,
+	WHERE um.user_id = $1 AND um.relation_type = 'favorite'
+	ORDER BY um.time_added, m.id`,
 
 	QueryGetWatchlist: `SELECT m.id, m.tmdb_id, m.title, m.tagline, m.release_year,
 	m.overview, m.score, m.popularity, m.language,
-	m.poster_url, m.trailer_url
+	m.poster_url, m.trailer_url, m.imdb_id, um.time_added
 	FROM movies m
 	JOIN user_movies um ON m.id = um.movie_id
-	WHERE um.user_id = $1 AND um.relation_type = 'watchlist'`,
+	WHERE um.user_id = $1 AND um.relation_type = 'watchlist'
+	ORDER BY um.time_added, m.id`,
+
+	QueryGetCollectionPage: `SELECT movies.id, movies.tmdb_id, movies.title, movies.tagline, movies.release_year,
+	movies.overview, movies.score, movies.popularity, movies.language,
+	movies.poster_url, movies.trailer_url, um.time_added
+	FROM movies
+	JOIN user_movies um ON movies.id = um.movie_id
+	WHERE um.user_id = $1 AND um.relation_type = $2`,
 
 	QueryIfMovieExists: `SELECT EXISTS(
 		SELECT 1
@@ -127,6 +467,540 @@ var Queries = map[string]string{
 	QueryRemoveFromToCollection: `DELETE FROM user_movies
 	WHERE user_id = $1 AND movie_id = $2 AND relation_type = $3`,
 
+	// ACTORS
+	QueryGetActorByID: `SELECT id, first_name, last_name, image_url
+	FROM actors
+	WHERE id = $1`,
+
+	// ORDER BY is appended by ActorRepository.GetFilmography
+	QueryGetActorFilmography: `SELECT movies.id, movies.tmdb_id, movies.title, movies.tagline, movies.release_year,
+	movies.overview, movies.score, movies.popularity, movies.language, movies.poster_url, movies.trailer_url
+	FROM movies
+	JOIN movie_cast mc ON mc.movie_id = movies.id
+	WHERE mc.actor_id = $1 AND movies.time_deleted IS NULL`,
+
+	QueryGetActorCollaborators: `SELECT a.id, a.first_name, a.last_name, a.image_url, COUNT(DISTINCT other.movie_id)::int AS shared
+	FROM movie_cast mc
+	JOIN movies m ON m.id = mc.movie_id
+	JOIN movie_cast other ON other.movie_id = mc.movie_id AND other.actor_id <> mc.actor_id
+	JOIN actors a ON a.id = other.actor_id
+	WHERE mc.actor_id = $1 AND m.time_deleted IS NULL
+	GROUP BY a.id, a.first_name, a.last_name, a.image_url
+	HAVING COUNT(DISTINCT other.movie_id) >= $2
+	ORDER BY shared DESC, a.id
+	LIMIT $3`,
+
+	QuerySearchActors: `SELECT a.id, a.first_name, a.last_name, a.image_url
+	FROM actors a
+	WHERE (a.first_name || ' ' || a.last_name) ILIKE $1 ESCAPE '\'
+	ORDER BY (SELECT COUNT(*) FROM movie_cast mc WHERE mc.actor_id = a.id) DESC, a.last_name, a.first_name
+	LIMIT $2`,
+
+	// RECOMMENDATIONS
+	QueryGetSavedMovies: `SELECT m.id, m.title, um.relation_type
+	FROM user_movies um
+	JOIN movies m ON m.id = um.movie_id
+	WHERE um.user_id = $1
+	ORDER BY um.time_added DESC`,
+
+	QueryGetMovieFeatures: `SELECT movie_id, 'genre', genre_id FROM movie_genres WHERE movie_id = ANY($1)
+	UNION ALL
+	SELECT movie_id, 'keyword', keyword_id FROM movie_keywords WHERE movie_id = ANY($1)
+	UNION ALL
+	SELECT movie_id, 'cast', actor_id FROM movie_cast WHERE movie_id = ANY($1)`,
+
+	// $1 genre ids, $2 keyword ids, $3 actor ids, $4 excluded movie ids, $5 per-group candidate cap
+	QueryGetCandidateFeatures: `WITH candidates AS (
+		(SELECT m.id FROM movies m
+		WHERE m.id <> ALL($4) AND m.time_deleted IS NULL
+			AND (EXISTS (SELECT 1 FROM movie_keywords mk WHERE mk.movie_id = m.id AND mk.keyword_id = ANY($2))
+				OR EXISTS (SELECT 1 FROM movie_cast mc WHERE mc.movie_id = m.id AND mc.actor_id = ANY($3)))
+		ORDER BY m.popularity DESC NULLS LAST
+		LIMIT $5)
+		UNION
+		(SELECT m.id FROM movies m
+		WHERE m.id <> ALL($4) AND m.time_deleted IS NULL
+			AND EXISTS (SELECT 1 FROM movie_genres mg WHERE mg.movie_id = m.id AND mg.genre_id = ANY($1))
+		ORDER BY m.popularity DESC NULLS LAST
+		LIMIT $5)
+	)
+	SELECT mg.movie_id, 'genre', mg.genre_id FROM movie_genres mg JOIN candidates c ON c.id = mg.movie_id WHERE mg.genre_id = ANY($1)
+	UNION ALL
+	SELECT mk.movie_id, 'keyword', mk.keyword_id FROM movie_keywords mk JOIN candidates c ON c.id = mk.movie_id WHERE mk.keyword_id = ANY($2)
+	UNION ALL
+	SELECT mc.movie_id, 'cast', mc.actor_id FROM movie_cast mc JOIN candidates c ON c.id = mc.movie_id WHERE mc.actor_id = ANY($3)`,
+
+	QueryGetMoviesByIDs: `SELECT id, tmdb_id, title, tagline, release_year, overview, score, popularity, language, poster_url, trailer_url
+	FROM movies
+	WHERE id = ANY($1) AND time_deleted IS NULL`,
+
+	// DIARY
+	// A first watch has no row to lock yet, the lock on ($1 user, $2 movie)
+	// ends with the transaction
+	QueryLockWatches: `SELECT pg_advisory_xact_lock($1, $2)`,
+
+	QueryCountWatches: `SELECT COUNT(*) FROM watch_diary WHERE user_id = $1 AND movie_id = $2`,
+
+	QueryAddDiaryEntry: `INSERT INTO watch_diary (user_id, movie_id, watched_on, rewatch, note)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, time_added`,
+
+	// Date bounds are optional. Ordering and LIMIT are appended by GetDiaryPage.
+	QueryGetDiaryPage: `SELECT d.id, d.movie_id, d.watched_on, d.rewatch, d.note, d.time_added,
+	m.tmdb_id, m.title, m.release_year, m.poster_url
+	FROM watch_diary d
+	JOIN movies m ON m.id = d.movie_id
+	WHERE d.user_id = $1
+	AND ($2::date IS NULL OR d.watched_on >= $2::date)
+	AND ($3::date IS NULL OR d.watched_on <= $3::date)`,
+
+	QueryUpdateDiaryEntry: `UPDATE watch_diary
+	SET watched_on = $3, rewatch = COALESCE($4, rewatch), note = $5
+	WHERE id = $1 AND user_id = $2
+	RETURNING id, movie_id, watched_on, rewatch, note, time_added`,
+
+	QueryDeleteDiaryEntry: `DELETE FROM watch_diary WHERE id = $1 AND user_id = $2`,
+
+	QueryRemoveFromWatchlist: `DELETE FROM user_movies
+	WHERE user_id = $1 AND movie_id = $2 AND relation_type = 'watchlist'`,
+
+	// REVIEWS
+	QueryCreateReview: `WITH inserted AS (
+		INSERT INTO reviews (user_id, movie_id, rating, body, contains_spoilers)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, movie_id, user_id, rating, body, contains_spoilers, time_created, time_updated
+	)
+	SELECT i.id, i.movie_id, i.user_id, u.name, i.rating, i.body, i.contains_spoilers, i.time_created, i.time_updated
+	FROM inserted i
+	JOIN users u ON u.id = i.user_id`,
+
+	// The replaced version is copied to review_revisions in the same statement.
+	QueryUpdateReview: `WITH previous AS (
+		SELECT id, rating, body, contains_spoilers, COALESCE(time_updated, time_created) AS time_written
+		FROM reviews
+		WHERE user_id = $1 AND movie_id = $2
+		FOR UPDATE
+	), revision AS (
+		INSERT INTO review_revisions (review_id, rating, body, contains_spoilers, time_written)
+		SELECT id, rating, body, contains_spoilers, time_written FROM previous
+	), updated AS (
+		UPDATE reviews r
+		SET rating = $3, body = $4, contains_spoilers = $5, time_updated = CURRENT_TIMESTAMP
+		FROM previous p
+		WHERE r.id = p.id
+		RETURNING r.id, r.movie_id, r.user_id, r.rating, r.body, r.contains_spoilers, r.time_created, r.time_updated
+	)
+	SELECT up.id, up.movie_id, up.user_id, u.name, up.rating, up.body, up.contains_spoilers, up.time_created, up.time_updated
+	FROM updated up
+	JOIN users u ON u.id = up.user_id`,
+
+	QueryDeleteReview: `DELETE FROM reviews WHERE user_id = $1 AND movie_id = $2`,
+
+	// Ordering and LIMIT are appended by buildReviewPageQuery.
+	QueryGetMovieReviews: `SELECT r.id, r.movie_id, r.user_id, u.name, r.rating, r.body, r.contains_spoilers, r.time_created, r.time_updated
+	FROM reviews r
+	JOIN users u ON u.id = r.user_id
+	WHERE r.movie_id = $1 AND u.time_deleted IS NULL`,
+
+	QueryGetUserReviews: `SELECT r.id, r.movie_id, r.user_id, u.name, r.rating, r.body, r.contains_spoilers, r.time_created, r.time_updated,
+	m.tmdb_id, m.title, m.release_year, m.poster_url
+	FROM reviews r
+	JOIN users u ON u.id = r.user_id
+	JOIN movies m ON m.id = r.movie_id
+	WHERE r.user_id = $1`,
+
+	QueryGetReviewHistory: `SELECT rr.rating, rr.body, rr.contains_spoilers, rr.time_written, rr.time_replaced
+	FROM review_revisions rr
+	JOIN reviews r ON r.id = rr.review_id
+	WHERE rr.review_id = $1 AND r.user_id = $2
+	ORDER BY rr.time_replaced DESC, rr.id DESC`,
+
+	// Bayesian average: (prior * site mean + sum of ratings) / (prior + number of ratings).
+	// $2 is the prior weight, i.e. how many "average" ratings every movie starts with.
+	// Reviews of deleted accounts are left out, as in the review listings.
+	QueryGetCommunityRating: `WITH site AS (
+		SELECT AVG(r.rating)::float8 AS mean
+		FROM reviews r
+		JOIN users u ON u.id = r.user_id
+		WHERE u.time_deleted IS NULL
+	), movie AS (
+		SELECT COUNT(*) AS votes, COALESCE(SUM(r.rating), 0)::float8 AS total
+		FROM reviews r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.movie_id = $1 AND u.time_deleted IS NULL
+	)
+	SELECT movie.votes,
+		CASE WHEN movie.votes = 0 THEN NULL
+		ELSE ($2 * site.mean + movie.total) / ($2 + movie.votes) END
+	FROM movie, site`,
+
+	// LISTS
+	QueryCreateList: `WITH inserted AS (
+		INSERT INTO user_lists (user_id, name, description, is_public)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, user_id, name, description, is_public, share_token_hash, time_created, time_updated
+	)
+	SELECT i.id, i.user_id, u.name, i.name, i.description, i.is_public, i.share_token_hash IS NOT NULL, 0, i.time_created, i.time_updated
+	FROM inserted i
+	JOIN users u ON u.id = i.user_id`,
+
+	QueryUpdateList: `WITH updated AS (
+		UPDATE user_lists
+		SET name = $3, description = $4, is_public = $5, time_updated = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2
+		RETURNING id, user_id, name, description, is_public, share_token_hash, time_created, time_updated
+	)
+	SELECT up.id, up.user_id, u.name, up.name, up.description, up.is_public, up.share_token_hash IS NOT NULL,
+	(SELECT COUNT(*) FROM user_list_items WHERE list_id = up.id), up.time_created, up.time_updated
+	FROM updated up
+	JOIN users u ON u.id = up.user_id`,
+
+	QueryDeleteList: `DELETE FROM user_lists WHERE id = $1 AND user_id = $2`,
+
+	QueryGetUserLists: `SELECT l.id, l.user_id, u.name, l.name, l.description, l.is_public, l.share_token_hash IS NOT NULL,
+	(SELECT COUNT(*) FROM user_list_items WHERE list_id = l.id), l.time_created, l.time_updated
+	FROM user_lists l
+	JOIN users u ON u.id = l.user_id
+	WHERE l.user_id = $1
+	ORDER BY COALESCE(l.time_updated, l.time_created) DESC, l.id DESC`,
+
+	QueryGetListByID: `SELECT l.id, l.user_id, u.name, l.name, l.description, l.is_public, l.share_token_hash IS NOT NULL,
+	(SELECT COUNT(*) FROM user_list_items WHERE list_id = l.id), l.time_created, l.time_updated
+	FROM user_lists l
+	JOIN users u ON u.id = l.user_id
+	WHERE l.id = $1 AND u.time_deleted IS NULL`,
+
+	QueryGetListByShareHash: `SELECT l.id, l.user_id, u.name, l.name, l.description, l.is_public, l.share_token_hash IS NOT NULL,
+	(SELECT COUNT(*) FROM user_list_items WHERE list_id = l.id), l.time_created, l.time_updated
+	FROM user_lists l
+	JOIN users u ON u.id = l.user_id
+	WHERE l.share_token_hash = $1 AND u.time_deleted IS NULL`,
+
+	// A NULL hash revokes the share link.
+	QuerySetListShareHash: `UPDATE user_lists SET share_token_hash = $3 WHERE id = $1 AND user_id = $2`,
+
+	// Locks the list for the rest of the transaction and checks that the user owns it.
+	QueryLockList: `SELECT id FROM user_lists WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+
+	QueryTouchList: `UPDATE user_lists SET time_updated = CURRENT_TIMESTAMP WHERE id = $1`,
+
+	QueryCountListItems: `SELECT COUNT(*) FROM user_list_items WHERE list_id = $1`,
+
+	QueryGetListItems: `SELECT i.movie_id, m.tmdb_id, m.title, m.release_year, m.poster_url, i.note, i.position, i.time_added
+	FROM user_list_items i
+	JOIN movies m ON m.id = i.movie_id
+	WHERE i.list_id = $1
+	ORDER BY i.position, i.time_added`,
+
+	QueryGetListItem: `SELECT i.movie_id, m.tmdb_id, m.title, m.release_year, m.poster_url, i.note, i.position, i.time_added
+	FROM user_list_items i
+	JOIN movies m ON m.id = i.movie_id
+	WHERE i.list_id = $1 AND i.movie_id = $2`,
+
+	QueryGetListItemPosition: `SELECT position FROM user_list_items WHERE list_id = $1 AND movie_id = $2`,
+
+	QueryGetLastListPosition: `SELECT MAX(position) FROM user_list_items WHERE list_id = $1`,
+
+	// First position after $3 (or the very first when $3 is NULL), ignoring the item being moved.
+	QueryGetNextListPosition: `SELECT MIN(position) FROM user_list_items
+	WHERE list_id = $1 AND movie_id <> $2 AND ($3::float8 IS NULL OR position > $3::float8)`,
+
+	QueryAddListItem: `INSERT INTO user_list_items (list_id, movie_id, position, note)
+	VALUES ($1, $2, $3, $4)`,
+
+	QueryUpdateListItemNote: `UPDATE user_list_items SET note = $3 WHERE list_id = $1 AND movie_id = $2`,
+
+	QueryMoveListItem: `UPDATE user_list_items SET position = $3 WHERE list_id = $1 AND movie_id = $2`,
+
+	// Spreads the positions of a list evenly again, $2 apart, keeping the current order.
+	QueryRenumberListItems: `UPDATE user_list_items i
+	SET position = o.rn * $2
+	FROM (
+		SELECT movie_id, ROW_NUMBER() OVER (ORDER BY position, time_added) AS rn
+		FROM user_list_items
+		WHERE list_id = $1
+	) o
+	WHERE i.list_id = $1 AND i.movie_id = o.movie_id`,
+
+	QueryRemoveListItem: `DELETE FROM user_list_items WHERE list_id = $1 AND movie_id = $2`,
+
+	// CATALOG
+	// Soft-deleted movies are included, admins can still edit and revert them.
+	QueryLockMovie: `SELECT id FROM movies WHERE id = $1 FOR UPDATE`,
+
+	QueryGetMovieSnapshot: `SELECT jsonb_build_object(
+		'tmdb_id', m.tmdb_id,
+		'title', m.title,
+		'tagline', COALESCE(m.tagline, ''),
+		'release_year', COALESCE(m.release_year, 0),
+		'overview', m.overview,
+		'score', m.score,
+		'popularity', m.popularity,
+		'language', m.language,
+		'poster_url', m.poster_url,
+		'trailer_url', m.trailer_url,
+		'deleted', m.time_deleted IS NOT NULL,
+		'genre_ids', COALESCE((SELECT jsonb_agg(genre_id ORDER BY genre_id) FROM movie_genres WHERE movie_id = m.id), '[]'),
+		'actor_ids', COALESCE((SELECT jsonb_agg(actor_id ORDER BY actor_id) FROM movie_cast WHERE movie_id = m.id), '[]'),
+		'keyword_ids', COALESCE((SELECT jsonb_agg(keyword_id ORDER BY keyword_id) FROM movie_keywords WHERE movie_id = m.id), '[]')
+	)
+	FROM movies m
+	WHERE m.id = $1`,
+
+	QueryCreateMovie: `INSERT INTO movies (tmdb_id, title, tagline, release_year, overview, score, popularity, language, poster_url, trailer_url)
+	VALUES ($1, $2, COALESCE($3, ''), $4, NULLIF($5, ''), $6, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''))
+	RETURNING id`,
+
+	// NULL leaves a column alone, an empty string clears optional text.
+	QueryUpdateMovie: `UPDATE movies SET
+		tmdb_id = COALESCE($2, tmdb_id),
+		title = COALESCE($3, title),
+		tagline = COALESCE($4, tagline),
+		release_year = COALESCE($5, release_year),
+		overview = CASE WHEN $6::text IS NULL THEN overview ELSE NULLIF($6, '') END,
+		score = COALESCE($7, score),
+		popularity = COALESCE($8, popularity),
+		language = CASE WHEN $9::text IS NULL THEN language ELSE NULLIF($9, '') END,
+		poster_url = CASE WHEN $10::text IS NULL THEN poster_url ELSE NULLIF($10, '') END,
+		trailer_url = CASE WHEN $11::text IS NULL THEN trailer_url ELSE NULLIF($11, '') END
+	WHERE id = $1`,
+
+	QuerySoftDeleteMovie: `UPDATE movies SET time_deleted = CURRENT_TIMESTAMP WHERE id = $1 AND time_deleted IS NULL`,
+
+	// Writes every column of a snapshot back, used by reverts.
+	QueryRestoreMovieSnapshot: `UPDATE movies SET
+		tmdb_id = $2, title = $3, tagline = $4, release_year = $5, overview = $6,
+		score = $7, popularity = $8, language = $9, poster_url = $10, trailer_url = $11,
+		time_deleted = CASE WHEN $12 THEN COALESCE(time_deleted, CURRENT_TIMESTAMP) ELSE NULL END
+	WHERE id = $1`,
+
+	QueryGetAdminMovie: `SELECT id, tmdb_id, title, tagline, release_year, overview, score, popularity, language, poster_url, trailer_url
+	FROM movies
+	WHERE id = $1`,
+
+	// %s is a table from catalogRelations. Returns the ids of $1 that do not exist.
+	QueryFindMissingIDs: `SELECT COALESCE(array_agg(x), '{}') FROM unnest($1::int[]) AS x
+	WHERE NOT EXISTS (SELECT 1 FROM %s WHERE id = x)`,
+
+	QueryFindKeyword: `SELECT id FROM keywords WHERE lower(word) = lower($1) ORDER BY id LIMIT 1`,
+
+	QueryCreateKeyword: `INSERT INTO keywords (word) VALUES ($1) RETURNING id`,
+
+	// %[1]s is a join table and %[2]s its id column, both from catalogRelations.
+	QueryAttachRelation: `INSERT INTO %[1]s (movie_id, %[2]s)
+	SELECT $1, x FROM unnest($2::int[]) AS x
+	WHERE NOT EXISTS (SELECT 1 FROM %[1]s WHERE movie_id = $1 AND %[2]s = x)`,
+
+	QueryDetachRelation: `DELETE FROM %[1]s WHERE movie_id = $1 AND %[2]s = ANY($2::int[])`,
+
+	// Removes every link that is not in $2.
+	QueryRetainRelation: `DELETE FROM %[1]s WHERE movie_id = $1 AND %[2]s <> ALL($2::int[])`,
+
+	QueryAddCatalogHistory: `INSERT INTO catalog_history (movie_id, user_id, action, before, after, reverted_entry_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, time_created`,
+
+	// Ordering and LIMIT are appended by GetMovieHistory.
+	QueryGetCatalogHistory: `SELECT h.id, h.movie_id, h.user_id, u.name, h.action, h.before, h.after, h.reverted_entry_id, h.time_created
+	FROM catalog_history h
+	LEFT JOIN users u ON u.id = h.user_id
+	WHERE h.movie_id = $1`,
+
+	QueryGetCatalogEntry: `SELECT h.id, h.movie_id, h.user_id, u.name, h.action, h.before, h.after, h.reverted_entry_id, h.time_created
+	FROM catalog_history h
+	LEFT JOIN users u ON u.id = h.user_id
+	WHERE h.id = $1`,
+
+	// IMPORT
+	// Names are matched case-insensitively. Duplicate rows resolve to the oldest id.
+	QueryImportLoadGenres: `SELECT MIN(id), lower(name) FROM genres GROUP BY lower(name)`,
+
+	QueryImportLoadActors: `SELECT MIN(id), lower(trim(first_name || ' ' || last_name)) FROM actors
+	GROUP BY lower(trim(first_name || ' ' || last_name))`,
+
+	QueryImportLoadKeywords: `SELECT MIN(id), lower(word) FROM keywords GROUP BY lower(word)`,
+
+	QueryImportGenreIDs: `SELECT MIN(id), lower(name) FROM genres WHERE lower(name) = ANY($1) GROUP BY lower(name)`,
+
+	QueryImportActorIDs: `SELECT MIN(id), lower(trim(first_name || ' ' || last_name)) FROM actors
+	WHERE lower(trim(first_name || ' ' || last_name)) = ANY($1)
+	GROUP BY lower(trim(first_name || ' ' || last_name))`,
+
+	QueryImportKeywordIDs: `SELECT MIN(id), lower(word) FROM keywords WHERE lower(word) = ANY($1) GROUP BY lower(word)`,
+
+	QueryImportMovieIDs: `SELECT id, tmdb_id FROM movies WHERE tmdb_id = ANY($1)`,
+
+	// Soft-deleted movies stay deleted, an import never restores them.
+	QueryImportUpdateMovie: `UPDATE movies SET
+		title = $2, tagline = $3, release_year = $4, overview = $5, score = $6,
+		popularity = $7, language = $8, poster_url = $9, trailer_url = $10
+	WHERE tmdb_id = $1`,
+
+	// %s is a join table from catalogRelations.
+	QueryImportClearLinks: `DELETE FROM %s WHERE movie_id = ANY($1)`,
+
+	QueryImportTitles: `SELECT id, title, release_year, imdb_id FROM movies WHERE time_deleted IS NULL`,
+
+	// Values missing from the datasets keep what an earlier enrichment wrote.
+	QueryImportSetIMDb: `UPDATE movies SET
+		imdb_id = $2,
+		runtime_minutes = COALESCE($3, runtime_minutes),
+		imdb_rating = COALESCE($4, imdb_rating),
+		imdb_votes = COALESCE($5, imdb_votes)
+	WHERE id = $1`,
+
+	QueryImportClearCrew: `DELETE FROM movie_crew WHERE movie_id = ANY($1)`,
+
+	// COLLECTION IMPORTS
+	QueryCreateCollectionImport: `INSERT INTO collection_imports (user_id, source, collection, file_name, total_rows)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, status, time_created`,
+
+	QueryUpdateImportProgress: `UPDATE collection_imports
+	SET processed_rows = $2, time_updated = CURRENT_TIMESTAMP
+	WHERE id = $1 AND status = 'processing'`,
+
+	QueryFinishCollectionImport: `UPDATE collection_imports
+	SET status = $2, preview = $3, error = $4, time_updated = CURRENT_TIMESTAMP
+	WHERE id = $1 AND status = 'processing'`,
+
+	QueryGetCollectionImport: `SELECT id, user_id, source, collection, file_name, status, total_rows, processed_rows,
+		preview, error, time_created, time_committed
+	FROM collection_imports
+	WHERE id = $1 AND user_id = $2`,
+
+	QueryLockCollectionImport: `SELECT status FROM collection_imports WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+
+	QueryMarkImportCommitted: `UPDATE collection_imports
+	SET status = 'committed', time_committed = CURRENT_TIMESTAMP, time_updated = CURRENT_TIMESTAMP
+	WHERE id = $1`,
+
+	QueryDeleteCollectionImport: `DELETE FROM collection_imports WHERE id = $1 AND user_id = $2`,
+
+	QueryImportCandidatesByID: `SELECT id, tmdb_id, imdb_id, title, release_year, poster_url
+	FROM movies
+	WHERE time_deleted IS NULL AND (tmdb_id = ANY($1) OR imdb_id = ANY($2))`,
+
+	// $1..$3 are the row numbers, titles and (nullable) years of the export rows,
+	// $4 the candidates kept per row. title % uses the pg_trgm similarity threshold.
+	QueryImportCandidatesByTitle: `SELECT r.idx, m.id, m.tmdb_id, m.imdb_id, m.title, m.release_year, m.poster_url, m.sim
+	FROM unnest($1::int[], $2::text[], $3::int[]) AS r(idx, title, year)
+	CROSS JOIN LATERAL (
+		SELECT mv.id, mv.tmdb_id, mv.imdb_id, mv.title, mv.release_year, mv.poster_url,
+			similarity(mv.title, r.title) AS sim
+		FROM movies mv
+		WHERE mv.time_deleted IS NULL
+			AND mv.title % r.title
+			AND (r.year IS NULL OR mv.release_year BETWEEN r.year - 1 AND r.year + 1)
+		ORDER BY sim DESC, (mv.release_year = r.year) DESC NULLS LAST, mv.popularity DESC NULLS LAST
+		LIMIT $4
+	) m
+	ORDER BY r.idx, m.sim DESC`,
+
+	// Movies already in the collection keep their original time_added.
+	QueryImportAddToCollection: `INSERT INTO user_movies (user_id, movie_id, relation_type, time_added)
+	SELECT $1, t.movie_id, $2, t.time_added
+	FROM unnest($3::int[], $4::timestamptz[]) AS t(movie_id, time_added)
+	WHERE NOT EXISTS (
+		SELECT 1 FROM user_movies um
+		WHERE um.user_id = $1 AND um.movie_id = t.movie_id AND um.relation_type = $2
+	)`,
+
+	// A watch already logged on the same day is not logged again. Earlier
+	// watches, in the diary or in the import, make a rewatch.
+	QueryImportLogWatches: `INSERT INTO watch_diary (user_id, movie_id, watched_on, rewatch)
+	SELECT $1, t.movie_id, t.watched_on,
+		row_number() OVER (PARTITION BY t.movie_id ORDER BY t.watched_on) > 1
+		OR EXISTS (
+			SELECT 1 FROM watch_diary d
+			WHERE d.user_id = $1 AND d.movie_id = t.movie_id AND d.watched_on < t.watched_on
+		)
+	FROM unnest($2::int[], $3::date[]) AS t(movie_id, watched_on)
+	WHERE NOT EXISTS (
+		SELECT 1 FROM watch_diary d
+		WHERE d.user_id = $1 AND d.movie_id = t.movie_id AND d.watched_on = t.watched_on
+	)`,
+
+	// Runs before the watches are logged: only movies without a diary entry
+	// yet leave the watchlist, like the first entry of LogWatch.
+	QueryImportClearWatchlist: `DELETE FROM user_movies um
+	WHERE um.user_id = $1 AND um.movie_id = ANY($2) AND um.relation_type = 'watchlist'
+	AND NOT EXISTS (
+		SELECT 1 FROM watch_diary d
+		WHERE d.user_id = $1 AND d.movie_id = um.movie_id
+	)`,
+
+	// Entries logged after an imported watch are no longer first watches
+	QueryImportMarkRewatches: `UPDATE watch_diary d SET rewatch = TRUE
+	WHERE d.user_id = $1 AND NOT d.rewatch
+	AND EXISTS (
+		SELECT 1 FROM unnest($2::int[], $3::date[]) AS t(movie_id, watched_on)
+		WHERE t.movie_id = d.movie_id AND t.watched_on < d.watched_on
+	)`,
+
+	// Matching updates time_updated after every batch, a silent import lost its worker.
+	QueryFailStaleCollectionImports: `UPDATE collection_imports
+	SET status = 'failed', error = $2, time_updated = CURRENT_TIMESTAMP
+	WHERE status = 'processing' AND time_updated < $1`,
+
+	QueryPurgeCollectionImports: `DELETE FROM collection_imports
+	WHERE status <> 'processing' AND time_updated < $1`,
+
+	// DATA EXPORTS
+	QueryCreateDataExport: `INSERT INTO data_exports (user_id)
+	VALUES ($1)
+	RETURNING id, status, time_created`,
+
+	QueryFinishDataExport: `UPDATE data_exports
+	SET status = 'ready', file_path = $2, size_bytes = $3, token_hash = $4,
+		time_ready = CURRENT_TIMESTAMP, time_expires = $5
+	WHERE id = $1 AND status = 'processing'`,
+
+	QueryFailDataExport: `UPDATE data_exports
+	SET status = 'failed', error = $2
+	WHERE id = $1 AND status = 'processing'`,
+
+	QueryGetLatestDataExport: `SELECT id, user_id, status, size_bytes, error,
+	time_created, time_ready, time_expires, time_downloaded
+	FROM data_exports
+	WHERE user_id = $1
+	ORDER BY id DESC
+	LIMIT 1`,
+
+	QueryFindDataExport: `SELECT id, user_id, file_path, size_bytes
+	FROM data_exports
+	WHERE token_hash = $1 AND status = 'ready' AND time_expires > CURRENT_TIMESTAMP`,
+
+	// A complete download consumes the link, a second request finds no ready archive
+	QueryConsumeDataExport: `UPDATE data_exports
+	SET status = 'downloaded', token_hash = NULL, time_downloaded = CURRENT_TIMESTAMP
+	WHERE id = $1 AND status = 'ready'
+	RETURNING file_path`,
+
+	// $1 now, $2 processing exports started before this are failed with
+	// the reason $3. Returns the files to delete.
+	QueryExpireDataExports: `WITH stale AS (
+		SELECT id, file_path
+		FROM data_exports
+		WHERE (status IN ('ready', 'downloaded') AND file_path IS NOT NULL
+			AND (status = 'downloaded' OR time_expires < $1))
+			OR (status = 'processing' AND time_created < $2)
+		FOR UPDATE
+	)
+	UPDATE data_exports d
+	SET status = CASE d.status WHEN 'processing' THEN 'failed' WHEN 'ready' THEN 'expired' ELSE d.status END,
+		error = CASE WHEN d.status = 'processing' THEN $3 ELSE d.error END,
+		file_path = NULL,
+		token_hash = NULL
+	FROM stale
+	WHERE d.id = stale.id
+	RETURNING stale.file_path`,
+
+	QueryGetLoginHistory: `SELECT id, device_label, user_agent, ip_address, time_created, time_last_used, expiry
+	FROM tokens
+	WHERE user_id = $1 AND scope = 'refresh' AND time_retired IS NULL
+	ORDER BY expiry DESC`,
+
 	// USERS
 	QueryCreateUser: `INSERT INTO users (name, email, password_hashed, time_created)
 	VALUES ($1, $2, $3, $4)
@@ -155,13 +1029,48 @@ var Queries = map[string]string{
 	SET last_login = $1
 	WHERE id = $2`,
 
+	QueryIsAdmin: `SELECT is_admin FROM users WHERE id = $1 AND time_deleted IS NULL`,
+
 	QueryGetUserDetails: `SELECT id, name, email
 	FROM users
 	WHERE email = $1 AND time_deleted IS NULL`,
 
+	QuerySoftDeleteUser: `UPDATE users
+	SET time_deleted = CURRENT_TIMESTAMP
+	WHERE id = $1 AND time_deleted IS NULL
+	RETURNING time_deleted`,
+
+	// $2 accounts deleted before this are past their grace period
+	QueryRestoreUser: `UPDATE users
+	SET time_deleted = NULL, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND time_deleted > $2`,
+
+	QueryLockPurgeableUsers: `SELECT id, profile_picture_url
+	FROM users
+	WHERE time_deleted < $1
+	ORDER BY id
+	LIMIT $2
+	FOR UPDATE SKIP LOCKED`,
+
+	// data_exports rows cascade with the user, their archives are deleted by the caller
+	QueryPurgeUserExports: `SELECT file_path FROM data_exports
+	WHERE user_id = ANY($1) AND file_path IS NOT NULL`,
+
+	// passkeys and user_movies predate the migrations and don't cascade,
+	// the other user tables are removed by ON DELETE CASCADE
+	QueryPurgeUserPasskeys: `DELETE FROM passkeys WHERE user_id = ANY($1)`,
+
+	QueryPurgeUserMovies: `DELETE FROM user_movies WHERE user_id = ANY($1)`,
+
+	QueryPurgeUsers: `DELETE FROM users WHERE id = ANY($1)`,
+
 	// TOKENS
-	QuerySaveRefreshToken: `INSERT INTO tokens (hash, user_id, expiry, scope)
-	VALUES ($1, $2, $3, $4)`,
+	// A token without family ($10 = 0) starts a new one
+	QuerySaveRefreshToken: `INSERT INTO tokens (hash, user_id, expiry, scope, user_agent, ip_address, device_label,
+		time_created, time_last_used, family_id, parent_id)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $9,
+		COALESCE(NULLIF($10::BIGINT, 0), nextval('token_families_seq')), NULLIF($11::INT, 0))
+	RETURNING id, family_id`,
 
 	QueryGetRefreshTokenHash: `SELECT hash FROM tokens
 	WHERE hash = $1 AND scope = 'refresh' AND expiry > now()
@@ -171,7 +1080,183 @@ var Queries = map[string]string{
 	WHERE user_id = $1 AND hash = $2 AND scope = 'refresh'`,
 
 	QueryDeleteAllTokensForUser: `DELETE FROM tokens
-	WHERE scope=$1 AND user_id=$2`,
+	WHERE user_id = $1`,
+
+	QueryGetTokenDetailsByHash: `SELECT id, user_id, hash, expiry, scope, time_created,
+		COALESCE(family_id, 0), COALESCE(parent_id, 0), time_retired
+	FROM tokens
+	WHERE hash = $1 AND scope = 'refresh'`,
+
+	// $2 is the hash of the refresh token of the request, flagged as current
+	QueryGetSessions: `SELECT id, device_label, user_agent, ip_address, time_created, time_last_used, expiry,
+		COALESCE(hash = $2, false) AS current
+	FROM tokens
+	WHERE user_id = $1 AND scope = 'refresh' AND time_retired IS NULL AND expiry > $3
+	ORDER BY COALESCE(time_last_used, time_created) DESC, id DESC`,
+
+	// Signing a session out revokes its whole token family
+	QueryDeleteSession: `DELETE FROM tokens
+	WHERE user_id = $2 AND scope = 'refresh' AND family_id = (
+		SELECT family_id FROM tokens
+		WHERE id = $1 AND user_id = $2 AND scope = 'refresh' AND time_retired IS NULL
+	)`,
+
+	// Returns the number of sessions signed out, retired tokens aside
+	QueryDeleteOtherSessions: `WITH deleted AS (
+		DELETE FROM tokens
+		WHERE user_id = $1 AND scope = 'refresh' AND family_id IS DISTINCT FROM (
+			SELECT family_id FROM tokens WHERE hash = $2 AND scope = 'refresh'
+		)
+		RETURNING time_retired
+	)
+	SELECT count(*) FROM deleted WHERE time_retired IS NULL`,
+
+	// Only one request can retire a token, a second one is a reuse
+	QueryRetireRefreshToken: `UPDATE tokens
+	SET time_retired = $2
+	WHERE id = $1 AND scope = 'refresh' AND time_retired IS NULL`,
+
+	QueryRevokeTokenFamily: `DELETE FROM tokens
+	WHERE user_id = $1 AND family_id = $2 AND scope = 'refresh'`,
+
+	// Retired tokens are kept to detect reuse, which an expired token can't be
+	QueryPurgeRetiredTokens: `DELETE FROM tokens
+	WHERE scope = 'refresh' AND time_retired IS NOT NULL AND expiry < $1`,
+
+	QueryGetUserAuthToken: `SELECT id, user_id, hash, expiry, scope, attempts
+	FROM tokens
+	WHERE user_id = $1 AND scope = $2
+	ORDER BY id DESC
+	LIMIT 1`,
+
+	QueryRecordTokenAttempt: `UPDATE tokens
+	SET attempts = attempts + 1
+	WHERE id = $1 AND attempts < $2
+	RETURNING attempts`,
+
+	QueryDeleteUserTokens: `DELETE FROM tokens
+	WHERE user_id = $1 AND scope = ANY($2)`,
+
+	// SECURITY EVENTS
+	QueryLogSecurityEvent: `INSERT INTO security_events (user_id, event_type, ip_address, user_agent, details)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, time_created`,
+
+	// TWO-FACTOR AUTHENTICATION
+	QueryIsTOTPEnabled: `SELECT EXISTS (SELECT 1 FROM user_totp WHERE user_id = $1 AND time_enabled IS NOT NULL)`,
+
+	QueryGetTOTP: `SELECT user_id, secret, last_step, time_created, time_enabled, time_last_used
+	FROM user_totp
+	WHERE user_id = $1`,
+
+	// Replaces a pending secret, never an enabled one.
+	QuerySaveTOTPSecret: `INSERT INTO user_totp (user_id, secret, time_created)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (user_id) DO UPDATE
+	SET secret = EXCLUDED.secret, time_created = EXCLUDED.time_created
+	WHERE user_totp.time_enabled IS NULL`,
+
+	QueryEnableTOTP: `UPDATE user_totp
+	SET time_enabled = $2, last_step = $3, time_last_used = $2
+	WHERE user_id = $1 AND time_enabled IS NULL`,
+
+	// Only a later step than the last one used is accepted, a code works once.
+	QueryUseTOTPStep: `UPDATE user_totp
+	SET last_step = $2, time_last_used = $3
+	WHERE user_id = $1 AND time_enabled IS NOT NULL AND (last_step IS NULL OR last_step < $2)`,
+
+	QueryDeleteTOTP: `DELETE FROM user_totp WHERE user_id = $1`,
+
+	QueryCountRecoveryCodes: `SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND time_used IS NULL`,
+
+	QuerySaveRecoveryCodes: `INSERT INTO recovery_codes (user_id, code_hash, time_created)
+	SELECT $1, code_hash, $3 FROM unnest($2::BYTEA[]) AS code_hash`,
+
+	QueryUseRecoveryCode: `UPDATE recovery_codes
+	SET time_used = $3
+	WHERE user_id = $1 AND code_hash = $2 AND time_used IS NULL`,
+
+	QueryDeleteRecoveryCodes: `DELETE FROM recovery_codes WHERE user_id = $1`,
+
+	// A new check replaces the previous one of the same purpose. Its failed
+	// attempts carry over while the previous check is still running, so
+	// starting over does not grant more guesses.
+	QuerySaveOTPVerification: `INSERT INTO otp_verifications (user_id, code, purpose, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (user_id, purpose) DO UPDATE
+	SET code = EXCLUDED.code,
+		expires_at = EXCLUDED.expires_at,
+		created_at = EXCLUDED.created_at,
+		verified = FALSE,
+		attempts = CASE
+			WHEN otp_verifications.verified OR otp_verifications.expires_at <= $5 THEN 0
+			ELSE otp_verifications.attempts
+		END
+	RETURNING id, attempts`,
+
+	QueryGetOTPVerification: `SELECT id, user_id, purpose, expires_at, verified, attempts, created_at
+	FROM otp_verifications
+	WHERE code = $1 AND purpose = $2 AND NOT verified`,
+
+	QueryRecordOTPAttempt: `UPDATE otp_verifications
+	SET attempts = attempts + 1
+	WHERE id = $1 AND attempts < $2
+	RETURNING attempts`,
+
+	// Counts attempts in a window of a check without a token, opening a new
+	// window once the previous one ended.
+	QueryRecordOTPWindowAttempt: `INSERT INTO otp_verifications (user_id, purpose, expires_at, attempts, created_at)
+	VALUES ($1, $2, $3, 1, $4)
+	ON CONFLICT (user_id, purpose) DO UPDATE
+	SET attempts = CASE WHEN otp_verifications.expires_at <= $4 THEN 1 ELSE otp_verifications.attempts + 1 END,
+		expires_at = CASE WHEN otp_verifications.expires_at <= $4 THEN EXCLUDED.expires_at ELSE otp_verifications.expires_at END,
+		created_at = CASE WHEN otp_verifications.expires_at <= $4 THEN EXCLUDED.created_at ELSE otp_verifications.created_at END
+	RETURNING attempts`,
+
+	QueryConsumeOTPVerification: `UPDATE otp_verifications
+	SET verified = TRUE
+	WHERE id = $1 AND NOT verified`,
+
+	QueryDeleteOTPVerification: `DELETE FROM otp_verifications WHERE user_id = $1 AND purpose = $2`,
+
+	QueryDeleteOTPVerifications: `DELETE FROM otp_verifications WHERE user_id = $1`,
+
+	QueryGetMFAPolicy: `SELECT EXISTS (SELECT 1 FROM user_totp t WHERE t.user_id = u.id AND t.time_enabled IS NOT NULL),
+		u.passkey_mfa_required
+	FROM users u
+	WHERE u.id = $1 AND u.time_deleted IS NULL`,
+
+	QuerySetPasskeyMFARequired: `UPDATE users SET passkey_mfa_required = $2 WHERE id = $1 AND time_deleted IS NULL`,
+
+	// PASSKEYS
+	QueryListPasskeys: `SELECT id, nickname, authenticator, sign_count, backup_eligible, backup_state, time_created, time_last_used
+	FROM passkeys
+	WHERE user_id = $1
+	ORDER BY time_created, id`,
+
+	QueryAddPasskey: `INSERT INTO passkeys (user_id, keys, credential_id, aaguid, authenticator, sign_count, backup_eligible, backup_state, time_created)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (credential_id) DO NOTHING`,
+
+	QueryRenamePasskey: `UPDATE passkeys SET nickname = $3 WHERE id = $2 AND user_id = $1`,
+
+	// The counter must go up, unless the authenticator doesn't keep one (always 0).
+	// A lower or equal counter means the key was cloned or the assertion replayed.
+	QueryUpdatePasskeyUsage: `UPDATE passkeys
+	SET keys = $3, sign_count = $4, backup_state = $5, time_last_used = $6
+	WHERE user_id = $1 AND credential_id = $2
+	  AND (sign_count < $4 OR (sign_count = 0 AND $4 = 0))`,
+
+	// Whether the account can sign in without passkeys, locked so the answer
+	// holds until the passkey is deleted
+	QueryLockPasskeyOwner: `SELECT COALESCE(password_hashed, '') <> '', passkey_mfa_required
+	FROM users
+	WHERE id = $1
+	FOR UPDATE`,
+
+	QueryLockPasskeys: `SELECT id FROM passkeys WHERE user_id = $1 FOR UPDATE`,
+
+	QueryDeletePasskey: `DELETE FROM passkeys WHERE id = $2 AND user_id = $1`,
 
 	QueryGetUserFromTokenHash: `SELECT u.id, u.name, u.email, u.time_created, u.time_confirmed
 	FROM users u
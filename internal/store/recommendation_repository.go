@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+
+	"multipass/internal/model"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+/* RecommendationStore Interface */
+type RecommendationStore interface {
+	GetSavedMovies(ctx context.Context, userID int) ([]model.SavedMovie, error)
+	GetMovieFeatures(ctx context.Context, movieIDs []int) ([]model.MovieFeature, error)
+	GetCandidateFeatures(ctx context.Context, genreIDs, keywordIDs, actorIDs, excludeIDs []int, limit int) ([]model.MovieFeature, error)
+}
+
+type RecommendationRepository struct {
+	db     *pgxpool.Pool
+	logger logging.Logger
+}
+
+func NewRecommendationRepository(db *pgxpool.Pool, logger logging.Logger) *RecommendationRepository {
+	return &RecommendationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetSavedMovies retrieves every movie in the user's favorites and watchlist
+func (r *RecommendationRepository) GetSavedMovies(ctx context.Context, userID int) ([]model.SavedMovie, error) {
+	op := getOp(QueryGetSavedMovies)
+	meta := common.Envelop{
+		"user_id": userID,
+		"context": op,
+	}
+
+	query, err := getQuery(QueryGetSavedMovies, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "saved_movies", meta)
+	}
+	defer rows.Close()
+
+	return scanRowsToSlice(rows, func(rows pgx.Rows, m *model.SavedMovie) error {
+		return rows.Scan(&m.MovieID, &m.Title, &m.Relation)
+	}, r.logger, op, meta, 0)
+}
+
+// GetMovieFeatures retrieves the genres, keywords and cast of the given movies
+func (r *RecommendationRepository) GetMovieFeatures(ctx context.Context, movieIDs []int) ([]model.MovieFeature, error) {
+	return r.queryFeatures(ctx, QueryGetMovieFeatures, common.Envelop{"movie_count": len(movieIDs)}, movieIDs)
+}
+
+// GetCandidateFeatures retrieves the matching features of movies that share
+// at least one of the given genres, keywords or actors. Movies sharing a
+// keyword or actor and movies sharing only a genre are capped separately
+// (limit each, by popularity) so broad genres cannot crowd out closer matches.
+func (r *RecommendationRepository) GetCandidateFeatures(ctx context.Context, genreIDs, keywordIDs, actorIDs, excludeIDs []int, limit int) ([]model.MovieFeature, error) {
+	if excludeIDs == nil {
+		excludeIDs = []int{} // a NULL array would make "id <> ALL($4)" exclude every movie
+	}
+
+	meta := common.Envelop{
+		"genres":   len(genreIDs),
+		"keywords": len(keywordIDs),
+		"actors":   len(actorIDs),
+		"limit":    limit,
+	}
+	return r.queryFeatures(ctx, QueryGetCandidateFeatures, meta, genreIDs, keywordIDs, actorIDs, excludeIDs, limit)
+}
+
+func (r *RecommendationRepository) queryFeatures(ctx context.Context, queryKey string, meta common.Envelop, args ...any) ([]model.MovieFeature, error) {
+	op := getOp(queryKey)
+	meta["context"] = op
+
+	query, err := getQuery(queryKey, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "movie_features", meta)
+	}
+	defer rows.Close()
+
+	return scanRowsToSlice(rows, scanMovieFeature, r.logger, op, meta, 0)
+}
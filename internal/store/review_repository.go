@@ -0,0 +1,279 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+	"multipass/pkg/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// communityRatingPrior is how many site-average ratings every movie starts
+// with, so a single 10/10 does not put a movie on top of the charts.
+const communityRatingPrior = 10
+
+/* ReviewStore Interface */
+type ReviewStore interface {
+	CreateReview(ctx context.Context, userID int, movieID int, input common.ReviewInput) (*model.Review, error)
+	UpdateReview(ctx context.Context, userID int, movieID int, input common.ReviewInput) (*model.Review, error)
+	DeleteReview(ctx context.Context, userID int, movieID int) error
+	GetMovieReviews(ctx context.Context, movieID int, page common.PageParams) (*common.ReviewPage, error)
+	GetUserReviews(ctx context.Context, userID int, page common.PageParams) (*common.ReviewPage, error)
+	GetReviewHistory(ctx context.Context, userID int, reviewID int) ([]model.ReviewRevision, error)
+	GetCommunityRating(ctx context.Context, movieID int) (*model.CommunityRating, error)
+}
+
+type ReviewRepository struct {
+	db     *pgxpool.Pool
+	logger logging.Logger
+}
+
+func NewReviewRepository(db *pgxpool.Pool, logger logging.Logger) *ReviewRepository {
+	return &ReviewRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateReview saves the first review of a user for a movie
+func (r *ReviewRepository) CreateReview(ctx context.Context, userID int, movieID int, input common.ReviewInput) (*model.Review, error) {
+	op := getOp(QueryCreateReview)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"movie_id": movieID,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryCreateReview, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, userID, movieID, input.Rating, input.Body, input.ContainsSpoilers)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "review", meta)
+	}
+
+	return r.scanSingleReview(rows, op, meta)
+}
+
+// UpdateReview replaces the user's review of a movie and keeps the previous version as a revision
+func (r *ReviewRepository) UpdateReview(ctx context.Context, userID int, movieID int, input common.ReviewInput) (*model.Review, error) {
+	op := getOp(QueryUpdateReview)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"movie_id": movieID,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryUpdateReview, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, userID, movieID, input.Rating, input.Body, input.ContainsSpoilers)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "review", meta)
+	}
+
+	return r.scanSingleReview(rows, op, meta)
+}
+
+// DeleteReview removes the user's review of a movie together with its edit history
+func (r *ReviewRepository) DeleteReview(ctx context.Context, userID int, movieID int) error {
+	op := getOp(QueryDeleteReview)
+	meta := common.Envelop{
+		"user_id":  userID,
+		"movie_id": movieID,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryDeleteReview, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, query, userID, movieID)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "review", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrReviewNotFound(pgx.ErrNoRows, r.logger, meta)
+	}
+
+	return nil
+}
+
+// GetMovieReviews retrieves a page of a movie's reviews, newest first
+func (r *ReviewRepository) GetMovieReviews(ctx context.Context, movieID int, page common.PageParams) (*common.ReviewPage, error) {
+	op := getOp(QueryGetMovieReviews)
+	meta := common.Envelop{
+		"movie_id": movieID,
+		"limit":    page.Limit,
+		"context":  op,
+	}
+
+	return r.queryReviewPage(ctx, QueryGetMovieReviews, scanReview, movieID, page, meta)
+}
+
+// GetUserReviews retrieves a page of the reviews a user wrote, newest first
+func (r *ReviewRepository) GetUserReviews(ctx context.Context, userID int, page common.PageParams) (*common.ReviewPage, error) {
+	op := getOp(QueryGetUserReviews)
+	meta := common.Envelop{
+		"user_id": userID,
+		"limit":   page.Limit,
+		"context": op,
+	}
+
+	return r.queryReviewPage(ctx, QueryGetUserReviews, scanUserReview, userID, page, meta)
+}
+
+// GetReviewHistory retrieves the previous versions of one of the user's reviews, latest edit first
+func (r *ReviewRepository) GetReviewHistory(ctx context.Context, userID int, reviewID int) ([]model.ReviewRevision, error) {
+	op := getOp(QueryGetReviewHistory)
+	meta := common.Envelop{
+		"user_id":   userID,
+		"review_id": reviewID,
+		"context":   op,
+	}
+
+	query, err := getQuery(QueryGetReviewHistory, r.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, reviewID, userID)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "review_revisions", meta)
+	}
+	defer rows.Close()
+
+	return scanRowsToSlice(rows, scanReviewRevision, r.logger, op, meta, 0)
+}
+
+// GetCommunityRating computes the Bayesian average of the ratings users gave a movie
+func (r *ReviewRepository) GetCommunityRating(ctx context.Context, movieID int) (*model.CommunityRating, error) {
+	return getCommunityRating(ctx, r.db, r.logger, movieID)
+}
+
+// getCommunityRating is shared with MovieRepository, which attaches the rating to movie details.
+func getCommunityRating(ctx context.Context, db *pgxpool.Pool, logger logging.Logger, movieID int) (*model.CommunityRating, error) {
+	op := getOp(QueryGetCommunityRating)
+	meta := common.Envelop{
+		"movie_id": movieID,
+		"context":  op,
+	}
+
+	query, err := getQuery(QueryGetCommunityRating, logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	var rating model.CommunityRating
+	if err := db.QueryRow(ctx, query, movieID, communityRatingPrior).Scan(&rating.Count, &rating.Average); err != nil {
+		return nil, handleDatabaseError(err, logger, op, "community_rating", meta)
+	}
+
+	return &rating, nil
+}
+
+// queryReviewPage runs a keyset paginated review listing. base must end with
+// a WHERE clause filtering on $1.
+func (r *ReviewRepository) queryReviewPage(
+	ctx context.Context,
+	queryKey string,
+	scanFn func(pgx.Rows, *model.Review) error,
+	ownerID int,
+	page common.PageParams,
+	meta common.Envelop,
+) (*common.ReviewPage, error) {
+	op := getOp(queryKey)
+
+	var cur *reviewCursor
+	if page.Cursor != "" {
+		cur = &reviewCursor{}
+		if err := utils.DecodeCursor(page.Cursor, cur); err != nil {
+			return nil, apperror.ErrInvalidCursor(err, r.logger, meta)
+		}
+	}
+
+	base, err := getQuery(queryKey, r.logger, meta)
+	if err != nil || base == "" {
+		return nil, err
+	}
+
+	var queryBuilder strings.Builder
+	queryBuilder.WriteString(base)
+	args := []any{ownerID}
+
+	if cur != nil {
+		queryBuilder.WriteString(" AND (r.time_created, r.id) < ($2, $3)")
+		args = append(args, cur.TimeCreated, cur.ID)
+	}
+	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY r.time_created DESC, r.id DESC LIMIT $%d", len(args)+1))
+	args = append(args, page.Limit+1)
+
+	rows, err := r.db.Query(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "reviews", meta)
+	}
+	defer rows.Close()
+
+	reviews, err := scanRowsToSlice(rows, scanFn, r.logger, op, meta, page.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &common.ReviewPage{Reviews: reviews}
+	if len(reviews) > page.Limit {
+		result.Reviews = reviews[:page.Limit]
+		result.HasMore = true
+
+		last := result.Reviews[page.Limit-1]
+		next := reviewCursor{TimeCreated: last.TimeCreated, ID: last.ID}
+		if result.NextCursor, err = utils.EncodeCursor(next); err != nil {
+			return nil, apperror.ErrInternalServer(err, r.logger, meta)
+		}
+	}
+
+	return result, nil
+}
+
+// scanSingleReview reads the one review returned by an insert or update.
+// pgx reports statement errors while iterating, so constraint violations are
+// mapped here. No row means the user has no review for the movie.
+func (r *ReviewRepository) scanSingleReview(rows pgx.Rows, op string, meta common.Envelop) (*model.Review, error) {
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				switch pgErr.Code {
+				case "23505": // uq_reviews_user_movie
+					return nil, apperror.ErrReviewAlreadyExists(err, r.logger, meta)
+				case "23503": // the movie does not exist
+					return nil, apperror.ErrMovieNotFound(err, r.logger, meta)
+				}
+			}
+			return nil, handleDatabaseError(err, r.logger, op, "review", meta)
+		}
+		return nil, apperror.ErrReviewNotFound(pgx.ErrNoRows, r.logger, meta)
+	}
+
+	var review model.Review
+	if err := scanReview(rows, &review); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "review", meta)
+	}
+
+	return &review, nil
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxSearchTerms caps how many words of the user's input end up in the tsquery.
+const maxSearchTerms = 8
+
+// romanNumerals lets "godfather 2" match "The Godfather Part II" and vice versa.
+var romanNumerals = []string{"", "i", "ii", "iii", "iv", "v", "vi", "vii", "viii", "ix", "x"}
+
+// buildSearchTSQuery turns free text into a to_tsquery expression. Input is
+// reduced to letters and digits so it can never inject tsquery operators;
+// words are prefix-matched and sequel numbers also match their roman form.
+func buildSearchTSQuery(input string) string {
+	terms := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if alt, ok := sequelAlternative(term); ok {
+			parts = append(parts, fmt.Sprintf("(%s | %s)", term, alt))
+			continue
+		}
+		parts = append(parts, term+":*")
+	}
+
+	return strings.Join(parts, " & ")
+}
+
+// sequelAlternative maps an arabic number to its roman numeral and back.
+func sequelAlternative(term string) (string, bool) {
+	if n, err := strconv.Atoi(term); err == nil {
+		if n > 0 && n < len(romanNumerals) {
+			return romanNumerals[n], true
+		}
+		return "", false
+	}
+
+	// single letters are too ambiguous ("i", "v", "x") to treat as numerals
+	if len(term) < 2 {
+		return "", false
+	}
+	for n, numeral := range romanNumerals {
+		if n > 0 && term == numeral {
+			return strconv.Itoa(n), true
+		}
+	}
+	return "", false
+}
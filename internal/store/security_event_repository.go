@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+
+	"multipass/internal/model"
+	"multipass/pkg/common"
+	"multipass/pkg/logging"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+/* SecurityEventStore Interface */
+type SecurityEventStore interface {
+	LogSecurityEvent(ctx context.Context, event *model.SecurityEvent) error
+}
+
+type SecurityEventRepository struct {
+	db     *pgxpool.Pool
+	logger logging.Logger
+}
+
+func NewSecurityEventRepository(db *pgxpool.Pool, logger logging.Logger) *SecurityEventRepository {
+	return &SecurityEventRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// LogSecurityEvent records an event of the user's account and sets its ID
+// and creation time
+func (r *SecurityEventRepository) LogSecurityEvent(ctx context.Context, event *model.SecurityEvent) error {
+	op := getOp(QueryLogSecurityEvent)
+	meta := common.Envelop{"user_id": event.UserID, "event_type": event.Type, "context": op}
+
+	query, err := getQuery(QueryLogSecurityEvent, r.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	details := event.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+
+	err = r.db.QueryRow(ctx, query, event.UserID, event.Type, event.IPAddress, event.UserAgent, details).
+		Scan(&event.ID, &event.TimeCreated)
+	if err != nil {
+		return handleDatabaseError(err, r.logger, op, "security_events", meta)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"multipass/internal/cache"
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
+	"multipass/pkg/common"
+)
+
+// Weights of a shared attribute when scoring similar movies. Keywords and
+// cast are far more specific than genres, so they count for more.
+const (
+	similarGenreWeight   = 1.0
+	similarKeywordWeight = 2.0
+	similarCastWeight    = 3.0
+
+	similarMoviesCacheTTL = 6 * time.Hour
+)
+
+// GetSimilarMovies retrieves up to limit movies sharing the most genres,
+// keywords and cast with movieID, tie-broken by popularity. Results are
+// cached in Redis when a client is configured.
+func (r *MovieRepository) GetSimilarMovies(ctx context.Context, movieID int, limit int) ([]model.Movie, error) {
+	op := getOp(QueryGetSimilarMovies)
+	meta := common.Envelop{
+		"movie_id": movieID,
+		"limit":    limit,
+		"context":  op,
+	}
+
+	// Checked before the cache too, so a movie deleted since is not served
+	existsQuery, err := getQuery(QueryCatalogMovieExists, r.logger, meta)
+	if err != nil || existsQuery == "" {
+		return nil, err
+	}
+	var exists bool
+	if err := r.db.QueryRow(ctx, existsQuery, movieID).Scan(&exists); err != nil {
+		return nil, handleDatabaseError(err, r.logger, op, "movie", meta)
+	}
+	if !exists {
+		return nil, apperror.ErrMovieNotFound(fmt.Errorf("movie %d does not exist", movieID), r.logger, meta)
+	}
+
+	cacheKey := fmt.Sprintf("movies:similar:%d:%d", movieID, limit)
+	if r.redis != nil {
+		var cached []model.Movie
+		if cache.GetCache(r.redis, cacheKey, &cached) {
+			return cached, nil
+		}
+	}
+
+	rows, err := r.queryRowsWithErrorHandling(ctx, QueryGetSimilarMovies, op, meta,
+		movieID, similarGenreWeight, similarKeywordWeight, similarCastWeight, limit)
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		return []model.Movie{}, nil
+	}
+	defer rows.Close()
+
+	movies, err := scanRowsToSlice(rows, scanMovie, r.logger, op, meta, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.redis != nil {
+		if err := cache.SetCache(r.redis, cacheKey, movies, similarMoviesCacheTTL); err != nil {
+			r.logger.Error("Failed to cache similar movies", err, meta)
+		}
+	}
+
+	return movies, nil
+}
@@ -2,22 +2,37 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"multipass/internal/auth/tokens"
+	"multipass/internal/model"
+	"multipass/pkg/apperror"
 	"multipass/pkg/common"
 	"multipass/pkg/logging"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type TokenStore interface {
-	SaveRefreshToken(ctx context.Context, token *tokens.Token) error
+	SaveRefreshToken(ctx context.Context, token *tokens.Token, client common.ClientInfo) error
 	GetRefreshTokenHash(ctx context.Context, hash []byte) ([]byte, error)
 	GetTokenDetailsByHash(ctx context.Context, tokenHash []byte) (*tokens.Token, error)
 	DeleteRefreshToken(ctx context.Context, userID int, token []byte) error
 	DeleteAllTokensForUser(ctx context.Context, userID int) error
 
+	// Signed-in devices, one per refresh token
+	GetSessions(ctx context.Context, userID int, currentHash []byte) ([]model.Session, error)
+	DeleteSession(ctx context.Context, userID int, sessionID int) error
+	DeleteOtherSessions(ctx context.Context, userID int, keepHash []byte) (int64, error)
+
+	// Refresh token rotation
+	RetireRefreshToken(ctx context.Context, tokenID int, retiredAt time.Time) (bool, error)
+	RevokeTokenFamily(ctx context.Context, userID int, family int64) error
+	PurgeRetiredTokens(ctx context.Context, expiredBefore time.Time) (int64, error)
+
 	// Save verification/reset token
 	SaveAuthToken(ctx context.Context, token *tokens.Token) error
 
@@ -26,6 +41,11 @@ type TokenStore interface {
 
 	// Delete a token by hash (used after successful consumption)
 	DeleteAuthTokenByHash(ctx context.Context, tokenHash []byte) error
+
+	// Short codes, looked up by user and limited in attempts
+	GetUserAuthToken(ctx context.Context, userID int, scope string) (*tokens.Token, error)
+	RecordTokenAttempt(ctx context.Context, tokenID int, maxAttempts int) (int, bool, error)
+	DeleteUserTokens(ctx context.Context, userID int, scopes ...string) (int64, error)
 }
 
 type TokenRepository struct {
@@ -40,8 +60,10 @@ func NewTokenStore(db *pgxpool.Pool, logger logging.Logger) TokenStore {
 	}
 }
 
-// SaveRefreshToken stores a new hashed refresh token in the database.
-func (t *TokenRepository) SaveRefreshToken(ctx context.Context, refreshToken *tokens.Token) error {
+// SaveRefreshToken stores a new hashed refresh token in the database, along
+// with the device it was issued to. A token without family starts a new one;
+// the token's ID and family are set from the stored row.
+func (t *TokenRepository) SaveRefreshToken(ctx context.Context, refreshToken *tokens.Token, client common.ClientInfo) error {
 	op := getOp(QuerySaveRefreshToken)
 	meta := common.Envelop{
 		"context": op,
@@ -51,12 +73,19 @@ func (t *TokenRepository) SaveRefreshToken(ctx context.Context, refreshToken *to
 	if err != nil || query == "" {
 		return err
 	}
-	_, err = t.db.Exec(ctx, query,
+	err = t.db.QueryRow(ctx, query,
 		refreshToken.Hash,
 		refreshToken.UserID,
 		refreshToken.Expiry,
 		refreshToken.Scope,
-	)
+		client.UserAgent,
+		client.IPAddress,
+		client.DeviceLabel,
+		refreshToken.Created,
+		time.Now(),
+		refreshToken.Family,
+		refreshToken.ParentID,
+	).Scan(&refreshToken.ID, &refreshToken.Family)
 	if err != nil {
 		meta["user_id"] = refreshToken.UserID
 		return handleDatabaseError(err, t.logger, op, "user", meta)
@@ -137,28 +166,191 @@ func (t *TokenRepository) DeleteAllTokensForUser(ctx context.Context, userID int
 
 // GetTokenDetailsByHash retrieves a refresh token's details from the database by its hash.
 func (t *TokenRepository) GetTokenDetailsByHash(ctx context.Context, tokenHash []byte) (*tokens.Token, error) {
-	const op = "tokenStore.GetTokenDetailsByHash"
-	query := `
-		SELECT user_id, token_hash, expiry, scope
-		FROM refresh_tokens
-		WHERE token_hash = $1`
+	op := getOp(QueryGetTokenDetailsByHash)
+	meta := common.Envelop{
+		"context":    op,
+		"token_hash": fmt.Sprintf("%x", tokenHash),
+	}
+
+	query, err := getQuery(QueryGetTokenDetailsByHash, t.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
 
 	token := &tokens.Token{}
-	var storedHash []byte // To scan the BYTEA from DB
+	err = t.db.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Hash,
+		&token.Expiry,
+		&token.Scope,
+		&token.Created,
+		&token.Family,
+		&token.ParentID,
+		&token.Retired,
+	)
+	if err != nil {
+		return nil, handleDatabaseError(err, t.logger, op, "token_lookup", meta)
+	}
 
-	row := t.db.QueryRow(ctx, query, tokenHash)
-	err := row.Scan(&token.UserID, &storedHash, &token.Expiry, &token.Scope)
+	return token, nil
+}
+
+// GetSessions lists the user's signed-in devices, most recently used first.
+// The session whose refresh token hashes to currentHash is flagged current.
+func (t *TokenRepository) GetSessions(ctx context.Context, userID int, currentHash []byte) ([]model.Session, error) {
+	op := getOp(QueryGetSessions)
+	meta := common.Envelop{
+		"context": op,
+		"user_id": userID,
+	}
+
+	query, err := getQuery(QueryGetSessions, t.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	rows, err := t.db.Query(ctx, query, userID, currentHash, time.Now())
 	if err != nil {
-		return nil, handleDatabaseError(err, t.logger, "token_lookup", op, common.Envelop{
-			"op":         op,
-			"token_hash": fmt.Sprintf("%x", tokenHash),
-		})
+		return nil, handleDatabaseError(err, t.logger, op, "sessions", meta)
+	}
+	defer rows.Close()
+
+	sessions := []model.Session{}
+	for rows.Next() {
+		var session model.Session
+		if err := rows.Scan(
+			&session.ID,
+			&session.DeviceLabel,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.TimeCreated,
+			&session.TimeLastUsed,
+			&session.TimeExpires,
+			&session.Current,
+		); err != nil {
+			return nil, handleDatabaseError(err, t.logger, op, "sessions", meta)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, handleDatabaseError(err, t.logger, op, "sessions", meta)
 	}
 
-	// Assign retrieved hash to the token struct
-	token.Hash = storedHash
+	return sessions, nil
+}
 
-	return token, nil
+// DeleteSession signs one of the user's devices out by deleting its token family
+func (t *TokenRepository) DeleteSession(ctx context.Context, userID int, sessionID int) error {
+	op := getOp(QueryDeleteSession)
+	meta := common.Envelop{
+		"context":    op,
+		"user_id":    userID,
+		"session_id": sessionID,
+	}
+
+	query, err := getQuery(QueryDeleteSession, t.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	tag, err := t.db.Exec(ctx, query, sessionID, userID)
+	if err != nil {
+		return handleDatabaseError(err, t.logger, op, "sessions", meta)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.ErrSessionNotFound(nil, t.logger, meta)
+	}
+
+	return nil
+}
+
+// DeleteOtherSessions signs out every device of the user except the one
+// holding the refresh token that hashes to keepHash
+func (t *TokenRepository) DeleteOtherSessions(ctx context.Context, userID int, keepHash []byte) (int64, error) {
+	op := getOp(QueryDeleteOtherSessions)
+	meta := common.Envelop{
+		"context": op,
+		"user_id": userID,
+	}
+
+	query, err := getQuery(QueryDeleteOtherSessions, t.logger, meta)
+	if err != nil || query == "" {
+		return 0, err
+	}
+
+	var revoked int64
+	if err := t.db.QueryRow(ctx, query, userID, keepHash).Scan(&revoked); err != nil {
+		return 0, handleDatabaseError(err, t.logger, op, "sessions", meta)
+	}
+
+	return revoked, nil
+}
+
+// RetireRefreshToken marks a refresh token rotated. It reports false when the
+// token was already retired, i.e. it is being reused.
+func (t *TokenRepository) RetireRefreshToken(ctx context.Context, tokenID int, retiredAt time.Time) (bool, error) {
+	op := getOp(QueryRetireRefreshToken)
+	meta := common.Envelop{
+		"context":  op,
+		"token_id": tokenID,
+	}
+
+	query, err := getQuery(QueryRetireRefreshToken, t.logger, meta)
+	if err != nil || query == "" {
+		return false, err
+	}
+
+	tag, err := t.db.Exec(ctx, query, tokenID, retiredAt)
+	if err != nil {
+		return false, handleDatabaseError(err, t.logger, op, "tokens", meta)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// RevokeTokenFamily deletes every refresh token of a family, signing its
+// session out
+func (t *TokenRepository) RevokeTokenFamily(ctx context.Context, userID int, family int64) error {
+	op := getOp(QueryRevokeTokenFamily)
+	meta := common.Envelop{
+		"context": op,
+		"user_id": userID,
+		"family":  family,
+	}
+
+	query, err := getQuery(QueryRevokeTokenFamily, t.logger, meta)
+	if err != nil || query == "" {
+		return err
+	}
+
+	if _, err := t.db.Exec(ctx, query, userID, family); err != nil {
+		return handleDatabaseError(err, t.logger, op, "tokens", meta)
+	}
+
+	return nil
+}
+
+// PurgeRetiredTokens deletes the rotated refresh tokens that expired before
+// expiredBefore and returns how many were deleted
+func (t *TokenRepository) PurgeRetiredTokens(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	op := getOp(QueryPurgeRetiredTokens)
+	meta := common.Envelop{
+		"context":        op,
+		"expired_before": expiredBefore,
+	}
+
+	query, err := getQuery(QueryPurgeRetiredTokens, t.logger, meta)
+	if err != nil || query == "" {
+		return 0, err
+	}
+
+	tag, err := t.db.Exec(ctx, query, expiredBefore)
+	if err != nil {
+		return 0, handleDatabaseError(err, t.logger, op, "tokens", meta)
+	}
+
+	return tag.RowsAffected(), nil
 }
 
 func (t *TokenRepository) SaveAuthToken(ctx context.Context, token *tokens.Token) error {
@@ -167,13 +359,14 @@ func (t *TokenRepository) SaveAuthToken(ctx context.Context, token *tokens.Token
 		"context": op,
 	}
 
-	query := `INSERT INTO tokens (hash, user_id, expiry, scope)
-	VALUES ($1, $2, $3, $4)`
+	query := `INSERT INTO tokens (hash, user_id, expiry, scope, attempts)
+	VALUES ($1, $2, $3, $4, $5)`
 	_, err := t.db.Exec(ctx, query,
 		token.Hash,
 		token.UserID,
 		token.Expiry,
 		token.Scope,
+		token.Attempts,
 	)
 	if err != nil {
 		meta["user_id"] = token.UserID
@@ -220,3 +413,84 @@ func (t *TokenRepository) DeleteAuthTokenByHash(ctx context.Context, tokenHash [
 
 	return nil
 }
+
+// GetUserAuthToken returns the user's latest token of a scope
+func (t *TokenRepository) GetUserAuthToken(ctx context.Context, userID int, scope string) (*tokens.Token, error) {
+	op := getOp(QueryGetUserAuthToken)
+	meta := common.Envelop{
+		"context": op,
+		"user_id": userID,
+		"scope":   scope,
+	}
+
+	query, err := getQuery(QueryGetUserAuthToken, t.logger, meta)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	token := &tokens.Token{}
+	err = t.db.QueryRow(ctx, query, userID, scope).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Hash,
+		&token.Expiry,
+		&token.Scope,
+		&token.Attempts,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperror.ErrInvalidOTP(err, t.logger, meta)
+		}
+		return nil, handleDatabaseError(err, t.logger, op, "tokens", meta)
+	}
+
+	return token, nil
+}
+
+// RecordTokenAttempt counts a guess at a token and returns the guesses made.
+// It returns false, counting nothing, once maxAttempts were made.
+func (t *TokenRepository) RecordTokenAttempt(ctx context.Context, tokenID int, maxAttempts int) (int, bool, error) {
+	op := getOp(QueryRecordTokenAttempt)
+	meta := common.Envelop{
+		"context":  op,
+		"token_id": tokenID,
+	}
+
+	query, err := getQuery(QueryRecordTokenAttempt, t.logger, meta)
+	if err != nil || query == "" {
+		return 0, false, err
+	}
+
+	var attempts int
+	if err := t.db.QueryRow(ctx, query, tokenID, maxAttempts).Scan(&attempts); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return maxAttempts, false, nil
+		}
+		return 0, false, handleDatabaseError(err, t.logger, op, "tokens", meta)
+	}
+
+	return attempts, true, nil
+}
+
+// DeleteUserTokens deletes the user's tokens of the given scopes and returns
+// how many there were
+func (t *TokenRepository) DeleteUserTokens(ctx context.Context, userID int, scopes ...string) (int64, error) {
+	op := getOp(QueryDeleteUserTokens)
+	meta := common.Envelop{
+		"context": op,
+		"user_id": userID,
+		"scopes":  scopes,
+	}
+
+	query, err := getQuery(QueryDeleteUserTokens, t.logger, meta)
+	if err != nil || query == "" {
+		return 0, err
+	}
+
+	tag, err := t.db.Exec(ctx, query, userID, scopes)
+	if err != nil {
+		return 0, handleDatabaseError(err, t.logger, op, "tokens", meta)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"net/http"
 	"os"
@@ -30,6 +31,13 @@ func main() {
 	defer app.DB.Close()
 	defer app.Logger.Close()
 
+	//￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
+	//           # BACKGROUND JOBS
+	//	__________________________________________
+	app.Jobs.Start(context.Background())
+	defer app.Jobs.Stop()
+	defer app.Tasks.Stop()
+
 	//￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣￣
 	//           # SERVER PORT SETUP
 	//____________________________________________
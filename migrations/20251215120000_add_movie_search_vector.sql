@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE movies
+    ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+-- Weighted document: title (A), tagline and keywords (B), overview (C).
+-- Keywords live in movie_keywords, so the vector is maintained by triggers
+-- instead of a generated column.
+CREATE OR REPLACE FUNCTION movies_search_vector_refresh() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('english', coalesce(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(NEW.tagline, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce((
+            SELECT string_agg(k.word, ' ')
+            FROM movie_keywords mk
+            JOIN keywords k ON k.id = mk.keyword_id
+            WHERE mk.movie_id = NEW.id
+        ), '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(NEW.overview, '')), 'C');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_movies_search_vector
+    BEFORE INSERT OR UPDATE OF title, tagline, overview, search_vector ON movies
+    FOR EACH ROW EXECUTE FUNCTION movies_search_vector_refresh();
+
+-- Touching search_vector re-runs the movies trigger above.
+CREATE OR REPLACE FUNCTION movie_keywords_search_vector_refresh() RETURNS trigger AS $$
+BEGIN
+    UPDATE movies SET search_vector = NULL
+    WHERE id = COALESCE(NEW.movie_id, OLD.movie_id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_movie_keywords_search_vector
+    AFTER INSERT OR UPDATE OR DELETE ON movie_keywords
+    FOR EACH ROW EXECUTE FUNCTION movie_keywords_search_vector_refresh();
+
+-- Backfill existing rows
+UPDATE movies SET search_vector = NULL;
+
+CREATE INDEX IF NOT EXISTS idx_movies_search_vector ON movies USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_movies_title_trgm ON movies USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_keywords_word_trgm ON keywords USING GIN (word gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_keywords_word_trgm;
+DROP INDEX IF EXISTS idx_movies_title_trgm;
+DROP INDEX IF EXISTS idx_movies_search_vector;
+DROP TRIGGER IF EXISTS trg_movie_keywords_search_vector ON movie_keywords;
+DROP FUNCTION IF EXISTS movie_keywords_search_vector_refresh();
+DROP TRIGGER IF EXISTS trg_movies_search_vector ON movies;
+DROP FUNCTION IF EXISTS movies_search_vector_refresh();
+ALTER TABLE movies DROP COLUMN IF EXISTS search_vector;
+-- +goose StatementEnd
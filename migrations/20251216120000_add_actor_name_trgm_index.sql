@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_actors_full_name_trgm ON actors USING GIN ((first_name || ' ' || last_name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_movie_cast_actor_id ON movie_cast (actor_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_movie_cast_actor_id;
+DROP INDEX IF EXISTS idx_actors_full_name_trgm;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE movie_cooccurrence (
+    movie_id INT NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    other_movie_id INT NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    support INT NOT NULL,            -- distinct users who saved both movies
+    score DOUBLE PRECISION NOT NULL, -- cosine similarity of the two movies' savers
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (movie_id, other_movie_id)
+);
+CREATE INDEX idx_movie_cooccurrence_score ON movie_cooccurrence (movie_id, score DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS movie_cooccurrence;
+-- +goose StatementEnd
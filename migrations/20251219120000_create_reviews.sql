@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE reviews (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    movie_id INT NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 10),
+    body TEXT,
+    contains_spoilers BOOLEAN NOT NULL DEFAULT FALSE,
+    time_created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    time_updated TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT uq_reviews_user_movie UNIQUE (user_id, movie_id) -- one review per user per movie
+);
+CREATE INDEX idx_reviews_movie_created ON reviews (movie_id, time_created DESC, id DESC);
+CREATE INDEX idx_reviews_user_created ON reviews (user_id, time_created DESC, id DESC);
+
+-- Every edit stores the version it replaces.
+CREATE TABLE review_revisions (
+    id SERIAL PRIMARY KEY,
+    review_id INT NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL,
+    body TEXT,
+    contains_spoilers BOOLEAN NOT NULL,
+    time_written TIMESTAMP WITH TIME ZONE NOT NULL,  -- when this version was posted
+    time_replaced TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_review_revisions_review ON review_revisions (review_id, time_replaced DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS review_revisions;
+DROP TABLE IF EXISTS reviews;
+-- +goose StatementEnd
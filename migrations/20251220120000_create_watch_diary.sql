@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Unlike user_movies, a movie can be logged any number of times.
+CREATE TABLE watch_diary (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    movie_id INT NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    watched_on DATE NOT NULL,
+    rewatch BOOLEAN NOT NULL DEFAULT FALSE,
+    note TEXT,
+    time_added TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_watch_diary_user_watched ON watch_diary (user_id, watched_on DESC, id DESC);
+CREATE INDEX idx_watch_diary_user_movie ON watch_diary (user_id, movie_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS watch_diary;
+-- +goose StatementEnd
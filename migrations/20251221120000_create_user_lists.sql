@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE user_lists (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    is_public BOOLEAN NOT NULL DEFAULT FALSE,
+    share_token_hash BYTEA UNIQUE, -- sha256 of the read-only share token, NULL when not shared
+    time_created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    time_updated TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT uq_user_lists_user_name UNIQUE (user_id, name)
+);
+
+-- position is fractional: moving an item only rewrites that item, it is
+-- placed halfway between its new neighbours.
+CREATE TABLE user_list_items (
+    list_id INT NOT NULL REFERENCES user_lists(id) ON DELETE CASCADE,
+    movie_id INT NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    position DOUBLE PRECISION NOT NULL,
+    note TEXT,
+    time_added TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (list_id, movie_id)
+);
+CREATE INDEX idx_user_list_items_position ON user_list_items (list_id, position);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_list_items;
+DROP TABLE IF EXISTS user_lists;
+-- +goose StatementEnd
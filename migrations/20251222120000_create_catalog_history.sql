@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Soft-deleted movies disappear from the catalog but stay in user collections, diaries and reviews.
+ALTER TABLE movies
+    ADD COLUMN IF NOT EXISTS time_deleted TIMESTAMP WITH TIME ZONE;
+
+-- Every admin change stores a full snapshot of the movie (columns plus
+-- genre, actor and keyword ids) before and after, so it can be diffed and reverted.
+CREATE TABLE catalog_history (
+    id BIGSERIAL PRIMARY KEY,
+    movie_id INT NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(16) NOT NULL,
+    before JSONB, -- NULL for the creation of a movie
+    after JSONB NOT NULL,
+    reverted_entry_id BIGINT REFERENCES catalog_history(id) ON DELETE SET NULL,
+    time_created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_catalog_history_movie ON catalog_history (movie_id, id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS catalog_history;
+ALTER TABLE movies DROP COLUMN IF EXISTS time_deleted;
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Filled from the IMDb non-commercial datasets by the catalog importer (-mode imdb).
+ALTER TABLE movies
+    ADD COLUMN IF NOT EXISTS imdb_id VARCHAR(16) UNIQUE,
+    ADD COLUMN IF NOT EXISTS runtime_minutes INT,
+    ADD COLUMN IF NOT EXISTS imdb_rating REAL,
+    ADD COLUMN IF NOT EXISTS imdb_votes INT;
+
+-- IMDb people have no row in actors, they are kept by their nconst. name is
+-- only known when name.basics was part of the import.
+CREATE TABLE movie_crew (
+    movie_id INT NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    imdb_person_id VARCHAR(16) NOT NULL,
+    name VARCHAR(255),
+    role VARCHAR(16) NOT NULL CHECK (role IN ('director', 'writer')),
+    ordering INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (movie_id, role, imdb_person_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS movie_crew;
+ALTER TABLE movies
+    DROP COLUMN IF EXISTS imdb_votes,
+    DROP COLUMN IF EXISTS imdb_rating,
+    DROP COLUMN IF EXISTS runtime_minutes,
+    DROP COLUMN IF EXISTS imdb_id;
+-- +goose StatementEnd
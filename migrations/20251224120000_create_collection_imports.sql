@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- An upload of another service's export. preview holds the matched rows until
+-- the user commits it into user_movies (or watch_diary for watches).
+CREATE TABLE collection_imports (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source VARCHAR(16) NOT NULL CHECK (source IN ('letterboxd', 'imdb', 'trakt')),
+    collection VARCHAR(16) NOT NULL CHECK (collection IN ('favorite', 'watchlist', 'watched')),
+    file_name VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'ready', 'committed', 'failed')),
+    total_rows INT NOT NULL DEFAULT 0,
+    processed_rows INT NOT NULL DEFAULT 0,
+    preview JSONB,
+    error TEXT,
+    time_created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    time_updated TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    time_committed TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX idx_collection_imports_user ON collection_imports (user_id, id DESC);
+CREATE INDEX idx_collection_imports_updated ON collection_imports (time_updated);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS collection_imports;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A "download my data" archive. token_hash is the hash of the single-use
+-- download link; it is cleared once the link is used or the archive expires.
+CREATE TABLE data_exports (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'ready', 'downloaded', 'expired', 'failed')),
+    file_path TEXT,
+    size_bytes BIGINT,
+    token_hash BYTEA UNIQUE,
+    error TEXT,
+    time_created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    time_ready TIMESTAMP WITH TIME ZONE,
+    time_expires TIMESTAMP WITH TIME ZONE,
+    time_downloaded TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX idx_data_exports_user ON data_exports (user_id, id DESC);
+-- One archive is built at a time per user
+CREATE UNIQUE INDEX ux_data_exports_processing ON data_exports (user_id) WHERE status = 'processing';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS data_exports;
+-- +goose StatementEnd
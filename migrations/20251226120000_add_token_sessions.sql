@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Describe the device behind each refresh token. A rotated token keeps the
+-- time_created of the sign-in it continues, so a row is one signed-in session.
+ALTER TABLE tokens
+    ADD COLUMN user_agent TEXT,
+    ADD COLUMN ip_address TEXT,
+    ADD COLUMN device_label TEXT,
+    ADD COLUMN time_created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN time_last_used TIMESTAMP;
+CREATE INDEX idx_tokens_user_scope ON tokens (user_id, scope);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_tokens_user_scope;
+ALTER TABLE tokens
+    DROP COLUMN IF EXISTS time_last_used,
+    DROP COLUMN IF EXISTS time_created,
+    DROP COLUMN IF EXISTS device_label,
+    DROP COLUMN IF EXISTS ip_address,
+    DROP COLUMN IF EXISTS user_agent;
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Refresh tokens are rotated into families: every rotation retires the
+-- presented token and links its replacement to it. A retired token that is
+-- presented again was stolen, and its whole family is revoked.
+CREATE SEQUENCE token_families_seq;
+ALTER TABLE tokens
+    ADD COLUMN family_id BIGINT,
+    ADD COLUMN parent_id INT REFERENCES tokens(id) ON DELETE SET NULL,
+    ADD COLUMN time_retired TIMESTAMP;
+UPDATE tokens SET family_id = nextval('token_families_seq') WHERE scope = 'refresh';
+CREATE INDEX idx_tokens_family ON tokens (family_id) WHERE family_id IS NOT NULL;
+
+-- Security relevant events of an account, such as a reused refresh token
+CREATE TABLE security_events (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_type VARCHAR(64) NOT NULL,
+    ip_address TEXT,
+    user_agent TEXT,
+    details JSONB NOT NULL DEFAULT '{}',
+    time_created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_security_events_user ON security_events (user_id, id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS security_events;
+DROP INDEX IF EXISTS idx_tokens_family;
+ALTER TABLE tokens
+    DROP COLUMN IF EXISTS time_retired,
+    DROP COLUMN IF EXISTS parent_id,
+    DROP COLUMN IF EXISTS family_id;
+DROP SEQUENCE IF EXISTS token_families_seq;
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Authenticator app (TOTP) secrets. A secret is pending until the user
+-- confirms it with a first code; last_step stops a code from being used twice.
+CREATE TABLE user_totp (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    last_step BIGINT,
+    time_created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    time_enabled TIMESTAMP,
+    time_last_used TIMESTAMP
+);
+
+-- One-time recovery codes, stored as SHA-256 hashes
+CREATE TABLE recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash BYTEA NOT NULL,
+    time_created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    time_used TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+-- Pending one-time password checks and their failed attempts. A user has at
+-- most one per purpose; code holds the hex SHA-256 of the token identifying
+-- the check, when it has one.
+CREATE TABLE otp_verifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code TEXT,
+    purpose VARCHAR(32) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    attempts INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, purpose)
+);
+CREATE INDEX idx_otp_verifications_code ON otp_verifications (code) WHERE code IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS otp_verifications;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS user_totp;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Wrong guesses at short codes such as the emailed sign-in code
+ALTER TABLE tokens ADD COLUMN attempts INT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tokens DROP COLUMN IF EXISTS attempts;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Password logins finish with a passkey assertion when set
+ALTER TABLE users ADD COLUMN passkey_mfa_required BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS passkey_mfa_required;
+-- +goose StatementEnd
@@ -0,0 +1,59 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Registered passkeys. keys holds the whole go-webauthn credential as JSON;
+-- the other columns describe it for the passkey list and track its use.
+CREATE TABLE IF NOT EXISTS passkeys (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    keys TEXT NOT NULL
+);
+
+ALTER TABLE passkeys
+    ADD COLUMN IF NOT EXISTS id BIGSERIAL,
+    ADD COLUMN IF NOT EXISTS credential_id BYTEA,
+    ADD COLUMN IF NOT EXISTS nickname TEXT,
+    ADD COLUMN IF NOT EXISTS aaguid BYTEA,
+    ADD COLUMN IF NOT EXISTS authenticator TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS sign_count BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS backup_state BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS time_created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS time_last_used TIMESTAMP;
+
+-- Passkeys so far only had their JSON; go-webauthn encodes bytes as base64
+UPDATE passkeys SET
+    credential_id = decode(keys::jsonb->>'id', 'base64'),
+    aaguid = decode(keys::jsonb->'authenticator'->>'AAGUID', 'base64'),
+    sign_count = COALESCE((keys::jsonb->'authenticator'->>'signCount')::BIGINT, 0),
+    backup_eligible = COALESCE((keys::jsonb->'flags'->>'backupEligible')::BOOLEAN, FALSE),
+    backup_state = COALESCE((keys::jsonb->'flags'->>'backupState')::BOOLEAN, FALSE)
+WHERE credential_id IS NULL;
+
+-- Logins used to save the updated credential as another row; keep the latest
+DELETE FROM passkeys p
+USING passkeys q
+WHERE p.credential_id = q.credential_id
+  AND (p.sign_count, p.id) < (q.sign_count, q.id);
+
+ALTER TABLE passkeys ALTER COLUMN credential_id SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_passkeys_id ON passkeys (id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_passkeys_credential_id ON passkeys (credential_id);
+CREATE INDEX IF NOT EXISTS idx_passkeys_user_id ON passkeys (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_passkeys_user_id;
+DROP INDEX IF EXISTS idx_passkeys_credential_id;
+DROP INDEX IF EXISTS idx_passkeys_id;
+ALTER TABLE passkeys
+    DROP COLUMN IF EXISTS time_last_used,
+    DROP COLUMN IF EXISTS time_created,
+    DROP COLUMN IF EXISTS backup_state,
+    DROP COLUMN IF EXISTS backup_eligible,
+    DROP COLUMN IF EXISTS sign_count,
+    DROP COLUMN IF EXISTS authenticator,
+    DROP COLUMN IF EXISTS aaguid,
+    DROP COLUMN IF EXISTS nickname,
+    DROP COLUMN IF EXISTS credential_id,
+    DROP COLUMN IF EXISTS id;
+-- +goose StatementEnd
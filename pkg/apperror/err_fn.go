@@ -17,6 +17,11 @@ func ErrForbidden(err error, logger logging.Logger, metadata common.Envelop) *Ap
 	return NewAppError(CodeForbidden, ErrForbiddenMsg, "authorization_failed", err, logger, metadata)
 }
 
+// ErrAdminOnly creates an error when a non-admin user calls an admin endpoint.
+func ErrAdminOnly(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeAdminOnlyResource, ErrAdminOnlyMsg, "admin_only", err, logger, metadata)
+}
+
 // ErrInvalidAuth creates an error for malformed or incorrect authentication details.
 func ErrInvalidAuth(err error, logger logging.Logger, metadata common.Envelop) *AppError {
 	return NewAppError(CodeUnauthorized, ErrInvalidAuthMsg, "auth_header_or_token_invalid", err, logger, metadata)
@@ -354,6 +359,16 @@ func ErrInvalidIDParameter(err error, logger logging.Logger, metadata common.Env
 	return NewAppError(CodeBadRequest, ErrInvalidIDParameterMsg, "id_parameter_validation", err, logger, metadata)
 }
 
+// ErrInvalidCursor creates an error for a pagination cursor that cannot be decoded.
+func ErrInvalidCursor(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeBadRequest, ErrInvalidCursorMsg, "cursor_parameter_validation", err, logger, metadata)
+}
+
+// ErrInvalidPageLimit creates an error for a non-numeric or non-positive page limit.
+func ErrInvalidPageLimit(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeBadRequest, ErrInvalidPageLimitMsg, "limit_parameter_validation", err, logger, metadata)
+}
+
 // ErrInvalidContentType creates an error for an unsupported content type.
 func ErrInvalidContentType(err error, logger logging.Logger, metadata common.Envelop) *AppError {
 	return NewAppError(CodeUnsupportedMediaType, ErrInvalidContentTypeMsg, "content_type_validation", err, logger, metadata)
@@ -460,6 +475,176 @@ func ErrMovieReviewFailed(err error, logger logging.Logger, metadata common.Enve
 	return NewAppError(CodeInternal, ErrMovieReviewFailedMsg, "movie_review_submission_failed", err, logger, metadata)
 }
 
+// ErrReviewNotFound creates an error when the user has no review for a movie.
+func ErrReviewNotFound(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeNotFound, ErrReviewNotFoundMsg, "review_lookup", err, logger, metadata)
+}
+
+// ErrReviewAlreadyExists creates an error when the user reviews the same movie twice.
+func ErrReviewAlreadyExists(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeConflict, ErrReviewAlreadyExistsMsg, "review_already_exists", err, logger, metadata)
+}
+
+// ErrInvalidReviewRating creates an error for a review rating outside 1-10.
+func ErrInvalidReviewRating(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeBadRequest, ErrInvalidReviewRatingMsg, "review_rating_validation", err, logger, metadata)
+}
+
+// ErrReviewTooLong creates an error for a review body over the length limit.
+func ErrReviewTooLong(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeBadRequest, ErrReviewTooLongMsg, "review_body_validation", err, logger, metadata)
+}
+
+// ErrDiaryEntryNotFound creates an error when a diary entry does not exist or belongs to another user.
+func ErrDiaryEntryNotFound(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeNotFound, ErrDiaryEntryNotFoundMsg, "diary_entry_lookup", err, logger, metadata)
+}
+
+// ErrInvalidWatchDate creates an error for a malformed or future watch date.
+func ErrInvalidWatchDate(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeBadRequest, ErrInvalidWatchDateMsg, "watch_date_validation", err, logger, metadata)
+}
+
+// ErrListNotFound creates an error for a list that does not exist or that the user may not see.
+func ErrListNotFound(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeNotFound, ErrListNotFoundMsg, "list_lookup", err, logger, metadata)
+}
+
+// ErrListNameTaken creates an error when a user already owns a list with the same name.
+func ErrListNameTaken(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeConflict, ErrListNameTakenMsg, "list_name_taken", err, logger, metadata)
+}
+
+// ErrListItemNotFound creates an error for a movie that is not part of a list.
+func ErrListItemNotFound(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeNotFound, ErrListItemNotFoundMsg, "list_item_lookup", err, logger, metadata)
+}
+
+// ErrListItemExists creates an error when a movie is added to a list twice.
+func ErrListItemExists(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeConflict, ErrListItemExistsMsg, "list_item_exists", err, logger, metadata)
+}
+
+// ErrCatalogEntryNotFound creates an error for a catalog history entry that does not exist.
+func ErrCatalogEntryNotFound(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeNotFound, ErrCatalogEntryNotFoundMsg, "catalog_history_lookup", err, logger, metadata)
+}
+
+// ErrUnknownCatalogIDs creates an error when genre, actor or keyword ids to attach do not exist.
+func ErrUnknownCatalogIDs(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeUnprocessable, ErrUnknownCatalogIDsMsg, "catalog_ids_validation", err, logger, metadata)
+}
+
+// ErrImportNotFound creates an error for a collection import that does not exist or belongs to another user.
+func ErrImportNotFound(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeNotFound, ErrImportNotFoundMsg, "collection_import_lookup", err, logger, metadata)
+}
+
+// ErrImportNotReady creates an error when an import is committed before its matching finished, or twice.
+func ErrImportNotReady(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeConflict, ErrImportNotReadyMsg, "collection_import_status", err, logger, metadata)
+}
+
+// ErrInvalidImportFile creates an error for an upload that is not a supported export.
+func ErrInvalidImportFile(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeUnprocessable, ErrInvalidImportFileMsg, "collection_import_file", err, logger, metadata)
+}
+
+// ErrDataExportNotFound creates an error when a user never requested a data export.
+func ErrDataExportNotFound(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeNotFound, ErrDataExportNotFoundMsg, "data_export_lookup", err, logger, metadata)
+}
+
+// ErrDataExportInProgress creates an error when a data export is requested while one is being built.
+func ErrDataExportInProgress(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeConflict, ErrDataExportInProgressMsg, "data_export_in_progress", err, logger, metadata)
+}
+
+// ErrDataExportLinkInvalid creates an error for an unknown, expired or already used download link.
+func ErrDataExportLinkInvalid(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeNotFound, ErrDataExportLinkInvalidMsg, "data_export_link", err, logger, metadata)
+}
+
+// ErrTokenReused creates an error when a rotated refresh token is presented again.
+func ErrTokenReused(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeUnauthorized, ErrTokenReusedMsg, "refresh_token_reuse", err, logger, metadata)
+}
+
+// ErrSessionNotFound creates an error for a session that does not exist or belongs to another user.
+func ErrSessionNotFound(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeNotFound, ErrSessionNotFoundMsg, "session_lookup", err, logger, metadata)
+}
+
+// ErrPasswordMismatch creates an error when re-entering the password before a sensitive action fails.
+func ErrPasswordMismatch(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeForbidden, ErrPasswordMismatchMsg, "reauthentication_failed", err, logger, metadata)
+}
+
+// ErrAccountNotRestorable creates an error when a deleted account is past its grace period or was never deleted.
+func ErrAccountNotRestorable(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeNotFound, ErrAccountNotRestorableMsg, "account_restore", err, logger, metadata)
+}
+
+// ErrInvalidOTP creates an error for a wrong, replayed or used one-time code.
+func ErrInvalidOTP(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeUnauthorized, ErrInvalidOTPMsg, "otp_verification", err, logger, metadata)
+}
+
+// ErrTooManyOTPAttempts creates an error when a one-time code check ran out of attempts.
+func ErrTooManyOTPAttempts(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeRateLimitExceeded, ErrTooManyOTPAttemptsMsg, "otp_attempts_exceeded", err, logger, metadata)
+}
+
+// ErrMFAChallengeInvalid creates an error for an unknown, expired or used two-factor login token.
+func ErrMFAChallengeInvalid(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeUnauthorized, ErrMFAChallengeInvalidMsg, "mfa_challenge", err, logger, metadata)
+}
+
+// ErrTOTPAlreadyEnabled creates an error when enrolling an account that already uses an authenticator app.
+func ErrTOTPAlreadyEnabled(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeConflict, ErrTOTPAlreadyEnabledMsg, "totp_enrollment", err, logger, metadata)
+}
+
+// ErrTOTPNotEnrolled creates an error when no authenticator app secret is set up.
+func ErrTOTPNotEnrolled(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeNotFound, ErrTOTPNotEnrolledMsg, "totp_enrollment", err, logger, metadata)
+}
+
+// ErrPasskeyMFAUnavailable creates an error when a login is finished with a passkey on an account that doesn't require one.
+func ErrPasskeyMFAUnavailable(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeBadRequest, ErrPasskeyMFAUnavailableMsg, "passkey_mfa", err, logger, metadata)
+}
+
+// ErrPasskeyNotVerified creates an error for a passkey assertion that failed verification.
+func ErrPasskeyNotVerified(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeUnauthorized, ErrPasskeyNotVerifiedMsg, "passkey_assertion", err, logger, metadata)
+}
+
+// ErrNoPasskeys creates an error when requiring a passkey on an account without one.
+func ErrNoPasskeys(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeConflict, ErrNoPasskeysMsg, "passkey_mfa", err, logger, metadata)
+}
+
+// ErrPasskeyNotFound creates an error for a passkey the user doesn't have.
+func ErrPasskeyNotFound(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeNotFound, ErrPasskeyNotFoundMsg, "passkey_not_found", err, logger, metadata)
+}
+
+// ErrLastPasskey creates an error when deleting the only passkey of an account without a password.
+func ErrLastPasskey(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeConflict, ErrLastPasskeyMsg, "last_passkey", err, logger, metadata)
+}
+
+// ErrPasskeyRequired creates an error when deleting the only passkey of an account whose logins require one.
+func ErrPasskeyRequired(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeConflict, ErrPasskeyRequiredMsg, "last_passkey", err, logger, metadata)
+}
+
+// ErrListFull creates an error when a list reached its item limit.
+func ErrListFull(err error, logger logging.Logger, metadata common.Envelop) *AppError {
+	return NewAppError(CodeConflict, ErrListFullMsg, "list_full", err, logger, metadata)
+}
+
 // ErrInvalidActorID creates an error for an invalid actor ID.
 func ErrInvalidActorID(err error, logger logging.Logger, metadata common.Envelop) *AppError {
 	return NewAppError(CodeNotFound, ErrInvalidActorIDMsg, "actor_id_validation", err, logger, metadata)
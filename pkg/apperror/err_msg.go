@@ -25,6 +25,7 @@ const (
 // Clear, actionable messages for identity and access issues.
 // =====================================
 const (
+	ErrAdminOnlyMsg             = "This area is restricted to administrators."
 	ErrCSRFTokenMissingMsg      = "A security token (CSRF) is missing. Please refresh the page and try again."
 	ErrForbiddenMsg             = "You don't have permission to access this resource or perform this action."
 	ErrInvalidAPIKeyMsg         = "The API key provided is invalid or has expired."
@@ -87,6 +88,8 @@ const (
 	ErrFieldRequiredMsg         = "A required field is missing. Please ensure all necessary fields are provided." // General "field required"
 	ErrInvalidContentTypeMsg    = "The 'Content-Type' header is missing or unsupported. Please use 'application/json'."
 	ErrInvalidIDParameterMsg    = "The ID you provided is invalid. Please ensure it's in the correct format."
+	ErrInvalidCursorMsg         = "The pagination cursor is invalid or has expired. Please start again from the first page."
+	ErrInvalidPageLimitMsg      = "The page limit must be a positive number."
 	ErrInvalidRequestPayloadMsg = "The request body is malformed or invalid."                           // Covers general malformed body, invalid JSON structure, bad data.
 	ErrJSONDecodeFailedMsg      = "Failed to process the request's JSON data. Please check its format." // Important for logging, but also somewhat client-friendly.
 	ErrJSONEncodeFailedMsg      = "Failed to prepare the response data. This is an internal issue."     // More for logging, but a client *might* see it if response writing fails.
@@ -190,6 +193,40 @@ const (
 	ErrMovieNotFoundMsg          = "The movie you are looking for could not be found."
 	ErrMovieRatingOutOfBoundsMsg = "Movie ratings must be between 0 and 10."
 	ErrMovieReviewFailedMsg      = "We couldn't post your movie review. Please try again."
+	ErrReviewNotFoundMsg         = "We couldn't find a review for this movie."
+	ErrReviewAlreadyExistsMsg    = "You have already reviewed this movie. Edit your existing review instead."
+	ErrInvalidReviewRatingMsg    = "Review ratings must be whole numbers from 1 to 10."
+	ErrReviewTooLongMsg          = "Reviews can be at most 10000 characters long."
+	ErrDiaryEntryNotFoundMsg     = "We couldn't find that diary entry."
+	ErrInvalidWatchDateMsg       = "The watch date must be a YYYY-MM-DD date that is not in the future."
+	ErrListNotFoundMsg           = "We couldn't find that list."
+	ErrListNameTakenMsg          = "You already have a list with this name."
+	ErrListItemNotFoundMsg       = "That movie is not in the list."
+	ErrListItemExistsMsg         = "That movie is already in the list."
+	ErrListFullMsg               = "This list has reached the maximum number of movies."
+	ErrCatalogEntryNotFoundMsg   = "We couldn't find that catalog history entry."
+	ErrUnknownCatalogIDsMsg      = "Some of the genres, actors or keywords do not exist."
+	ErrImportNotFoundMsg         = "We couldn't find that import."
+	ErrImportNotReadyMsg         = "This import is still being matched or was already saved."
+	ErrInvalidImportFileMsg      = "We couldn't read this file. Upload a Letterboxd CSV, IMDb CSV or Trakt JSON export."
+	ErrDataExportNotFoundMsg     = "You haven't requested a copy of your data yet."
+	ErrDataExportInProgressMsg   = "Your data archive is still being prepared. We'll email you when it's ready."
+	ErrDataExportLinkInvalidMsg  = "This download link is invalid, expired or was already used. Please request your data again."
+	ErrTokenReusedMsg            = "This session was signed out for your security. Please log in again."
+	ErrSessionNotFoundMsg        = "We couldn't find that session. It may have already been signed out."
+	ErrPasswordMismatchMsg       = "The password you entered is incorrect."
+	ErrAccountNotRestorableMsg   = "This account is not scheduled for deletion or can no longer be restored."
+	ErrInvalidOTPMsg             = "The code you entered is incorrect or has already been used."
+	ErrTooManyOTPAttemptsMsg     = "Too many incorrect codes. Please wait a few minutes and try again."
+	ErrMFAChallengeInvalidMsg    = "Your sign-in attempt has expired. Please log in again."
+	ErrTOTPAlreadyEnabledMsg     = "Two-factor authentication is already enabled."
+	ErrTOTPNotEnrolledMsg        = "Two-factor authentication is not set up. Please start the setup again."
+	ErrPasskeyMFAUnavailableMsg  = "Passkeys are not set up as a second step for this account."
+	ErrPasskeyNotVerifiedMsg     = "We couldn't verify your passkey. Please try again."
+	ErrNoPasskeysMsg             = "Register a passkey before requiring it at sign-in."
+	ErrPasskeyNotFoundMsg        = "We couldn't find that passkey."
+	ErrLastPasskeyMsg            = "This passkey is the only way to sign in to your account. Add another passkey or set a password first."
+	ErrPasskeyRequiredMsg        = "Signing in with your password requires a passkey. Add another passkey or stop requiring one first."
 	ErrMovieUpdateFailedMsg      = "We couldn't update the movie. It might have been deleted or changed by someone else."
 )
 
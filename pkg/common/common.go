@@ -34,6 +34,13 @@ type UserContext struct {
 	Name   string
 }
 
+// ClientInfo describes the device signing in, stored with its session
+type ClientInfo struct {
+	UserAgent   string
+	IPAddress   string
+	DeviceLabel string
+}
+
 type WebAuthnSignUpResult struct {
 	Options *protocol.CredentialCreation
 	Token   string
@@ -54,6 +61,52 @@ type PasswordResetRequest struct {
 	NewPassword string `json:"new_password"`
 }
 
+// AccountDeleteRequest re-authenticates the user before their account is deleted
+type AccountDeleteRequest struct {
+	Password string `json:"password"`
+}
+
+// AccountRestoreRequest carries the token of an emailed account deletion cancel link
+type AccountRestoreRequest struct {
+	Token string `json:"token"`
+}
+
+// MFALoginRequest completes a password login with an authenticator app or
+// recovery code
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// TOTPDisableRequest re-authenticates the user before two-factor
+// authentication is turned off
+type TOTPDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// PasskeyMFABeginRequest starts finishing a password login with a passkey
+type PasskeyMFABeginRequest struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+// PasskeyMFAPolicyRequest re-authenticates the user before password logins
+// start or stop requiring a passkey
+type PasskeyMFAPolicyRequest struct {
+	Required *bool  `json:"required"`
+	Password string `json:"password"`
+}
+
+// PasskeyRenameRequest sets the nickname of a passkey
+type PasskeyRenameRequest struct {
+	Nickname string `json:"nickname"`
+}
+
+// MagicLinkLoginRequest carries the token of an emailed sign-in link
+type MagicLinkLoginRequest struct {
+	Token string `json:"token"`
+}
+
 type SendOTPRequest struct {
 	Email   string `json:"email"`
 	Purpose string `json:"purpose"`
@@ -64,7 +117,29 @@ type OTPRequest struct {
 	OTP   string `json:"otp"`
 }
 
+// PageParams carries the cursor pagination input of list endpoints.
+type PageParams struct {
+	Cursor string
+	Limit  int
+}
+
+// DiscoverFilter holds the optional filters of the discover endpoint.
+// Nil/empty fields are not applied.
+type DiscoverFilter struct {
+	GenreIDs       []int
+	MatchAllGenres bool // true: movie must have every genre in GenreIDs, false: any of them
+	YearFrom       *int
+	YearTo         *int
+	ScoreMin       *float64
+	ScoreMax       *float64
+	Language       string
+	ActorID        *int
+	Keyword        string
+}
+
 const (
 	AllowedExtensions string = "jpg|jpeg|png"
 	MaxFileSize       int64  = 10 * 1024 * 1024 // 10 MB
+	DefaultPageLimit  int    = 20
+	MaxPageLimit      int    = 100
 )
@@ -47,10 +47,14 @@ type RegisterResult struct {
 	ExpiresAt    time.Time   `json:"expires_at"`
 }
 
+// AuthResult holds the issued tokens, or only MFAToken when the login still
+// needs a second factor
 type AuthResult struct {
 	User         *model.User `json:"-"`
 	JWT          string      `json:"jwt,omitempty"`
 	RefreshToken string      `json:"refresh_token,omitempty"`
+	MFAToken     string      `json:"mfa_token,omitempty"`
+	MFAMethods   []string    `json:"mfa_methods,omitempty"`
 	ExpiresAt    time.Time   `json:"expires_at"`
 }
 
@@ -61,6 +65,24 @@ type AuthResponse struct {
 	User    *model.User `json:"user"`
 }
 
+// MFARequiredResponse answers a correct password on an account with
+// two-factor authentication
+type MFARequiredResponse struct {
+	Success     bool      `json:"success"`
+	Message     string    `json:"message"`
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	MFAMethods  []string  `json:"mfa_methods"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// RecoveryCodesResponse shows the recovery codes once, when they are generated
+type RecoveryCodesResponse struct {
+	Success       bool     `json:"success"`
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
 type UserUpdateRequest struct {
 	Name              *string `json:"name,omitempty"`
 	Email             *string `json:"email,omitempty"`
@@ -113,11 +135,13 @@ type ProfilePictureUploadResponse struct {
 type CollectionRequest struct {
 	MovieID    *int    `json:"movie_id"`
 	Collection *string `json:"collection"`
+	ListID     *int    `json:"list_id"` // required when Collection is "list"
 }
 
 type CollectionInput struct {
 	MovieID    int    `json:"movie_id"`
 	Collection string `json:"collection"`
+	ListID     int    `json:"list_id,omitempty"`
 }
 
 type CollectionSuccess struct {
@@ -126,7 +150,261 @@ type CollectionSuccess struct {
 }
 
 type MoviesResponse struct {
-	Success bool          `json:"success,omitempty"`
-	Movies  []model.Movie `json:"movies,omitempty"`
-	Count   int           `json:"count,omitempty"`
+	Success     bool          `json:"success,omitempty"`
+	Movies      []model.Movie `json:"movies,omitempty"`
+	Count       int           `json:"count,omitempty"`
+	NextCursor  string        `json:"next_cursor,omitempty"`
+	HasMore     bool          `json:"has_more"`
+	Suggestions []string      `json:"suggestions,omitempty"`
+}
+
+// MoviePage is a single page of movies returned by the store layer.
+type MoviePage struct {
+	Movies     []model.Movie
+	NextCursor string
+	HasMore    bool
+	// Suggestions holds "did you mean" alternatives when a search has no hits.
+	Suggestions []string
+}
+
+// FacetCount is the number of matching movies for one facet value.
+type FacetCount struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// DiscoverFacets groups facet counts of a discover query.
+type DiscoverFacets struct {
+	Genres    []FacetCount `json:"genres"`
+	Decades   []FacetCount `json:"decades"`
+	Languages []FacetCount `json:"languages"`
+}
+
+// DiscoverResult is a page of discovered movies plus facet counts.
+// Facets are only computed for the first page.
+type DiscoverResult struct {
+	Page   *MoviePage
+	Facets *DiscoverFacets
+}
+
+type DiscoverResponse struct {
+	MoviesResponse
+	Facets *DiscoverFacets `json:"facets,omitempty"`
+}
+
+// RecommendationsResult is what the recommendation service produces for a user.
+type RecommendationsResult struct {
+	Recommendations []model.Recommendation
+	// ColdStart is true when the user has no saved movies and got popular titles instead.
+	ColdStart bool
+}
+
+type RecommendationsResponse struct {
+	Success         bool                   `json:"success,omitempty"`
+	Recommendations []model.Recommendation `json:"recommendations"`
+	Count           int                    `json:"count"`
+	ColdStart       bool                   `json:"cold_start"`
+}
+
+type ReviewRequest struct {
+	Rating           *int    `json:"rating"`
+	Body             *string `json:"body"`
+	ContainsSpoilers *bool   `json:"contains_spoilers"`
+}
+
+type ReviewInput struct {
+	Rating           int
+	Body             *string
+	ContainsSpoilers bool
+}
+
+// ReviewPage is a single page of reviews returned by the store layer.
+type ReviewPage struct {
+	Reviews    []model.Review
+	NextCursor string
+	HasMore    bool
+}
+
+type ReviewsResponse struct {
+	Success         bool                   `json:"success,omitempty"`
+	Reviews         []model.Review         `json:"reviews"`
+	Count           int                    `json:"count"`
+	NextCursor      string                 `json:"next_cursor,omitempty"`
+	HasMore         bool                   `json:"has_more"`
+	CommunityRating *model.CommunityRating `json:"community_rating,omitempty"`
+}
+
+type DiaryRequest struct {
+	MovieID   *int    `json:"movie_id"`
+	WatchedOn *string `json:"watched_on"` // YYYY-MM-DD, defaults to today
+	Rewatch   *bool   `json:"rewatch"`    // inferred from earlier entries when omitted
+	Note      *string `json:"note"`
+}
+
+type DiaryInput struct {
+	MovieID   int
+	WatchedOn time.Time
+	Rewatch   *bool
+	Note      *string
+}
+
+// DiaryRange limits a diary listing to entries watched between From and To, both inclusive.
+type DiaryRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// DiaryPage is a single page of diary entries returned by the store layer.
+type DiaryPage struct {
+	Entries    []model.DiaryEntry
+	NextCursor string
+	HasMore    bool
+}
+
+type DiaryResponse struct {
+	Success    bool               `json:"success,omitempty"`
+	Entries    []model.DiaryEntry `json:"entries"`
+	Count      int                `json:"count"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	HasMore    bool               `json:"has_more"`
+}
+
+type ListRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	IsPublic    *bool   `json:"is_public"`
+}
+
+type ListInput struct {
+	Name        string
+	Description *string
+	IsPublic    bool
+}
+
+type ListItemRequest struct {
+	MovieID *int    `json:"movie_id"`
+	Note    *string `json:"note"`
+}
+
+// ListItemUpdateRequest edits an item's note and/or moves it. Set Move to
+// place the item right after AfterMovieID, or first when AfterMovieID is nil.
+type ListItemUpdateRequest struct {
+	Note         *string `json:"note"`
+	Move         bool    `json:"move"`
+	AfterMovieID *int    `json:"after_movie_id"`
+}
+
+// ListItemInput is a sanitized ListItemUpdateRequest. UpdateNote is false
+// when the note was not part of the request and must be left alone.
+type ListItemInput struct {
+	Note         *string
+	UpdateNote   bool
+	Move         bool
+	AfterMovieID *int
+}
+
+type ListShareResponse struct {
+	Success  bool   `json:"success"`
+	Token    string `json:"token"`
+	ShareURL string `json:"share_url"`
+}
+
+type ListsResponse struct {
+	Success bool             `json:"success,omitempty"`
+	Lists   []model.UserList `json:"lists"`
+	Count   int              `json:"count"`
+}
+
+// AdminMovieRequest creates or edits a catalog movie. On update, nil fields
+// are left alone and an empty string clears an optional text field.
+type AdminMovieRequest struct {
+	TMDB_ID     *int     `json:"tmdb_id"`
+	Title       *string  `json:"title"`
+	Tagline     *string  `json:"tagline"`
+	ReleaseYear *int     `json:"release_year"`
+	Overview    *string  `json:"overview"`
+	Score       *float32 `json:"score"`
+	Popularity  *float32 `json:"popularity"`
+	Language    *string  `json:"language"`
+	PosterURL   *string  `json:"poster_url"`
+	TrailerURL  *string  `json:"trailer_url"`
+	// Links created together with a new movie, ignored on update
+	CatalogRelationsRequest
+}
+
+type MovieInput struct {
+	TMDB_ID     *int
+	Title       *string
+	Tagline     *string
+	ReleaseYear *int
+	Overview    *string
+	Score       *float32
+	Popularity  *float32
+	Language    *string
+	PosterURL   *string
+	TrailerURL  *string
+}
+
+// CatalogRelationsRequest names genres, actors and keywords to attach to or
+// detach from a movie. Keywords given as words are created when missing.
+type CatalogRelationsRequest struct {
+	GenreIDs   []int    `json:"genre_ids"`
+	ActorIDs   []int    `json:"actor_ids"`
+	KeywordIDs []int    `json:"keyword_ids"`
+	Keywords   []string `json:"keywords"`
+}
+
+type CatalogRelations struct {
+	GenreIDs   []int
+	ActorIDs   []int
+	KeywordIDs []int
+	Keywords   []string
+}
+
+func (c CatalogRelations) IsEmpty() bool {
+	return len(c.GenreIDs)+len(c.ActorIDs)+len(c.KeywordIDs)+len(c.Keywords) == 0
+}
+
+type CatalogHistoryPage struct {
+	Entries    []model.CatalogHistoryEntry `json:"entries"`
+	NextCursor string                      `json:"next_cursor,omitempty"`
+	HasMore    bool                        `json:"has_more"`
+}
+
+type CatalogChangeResponse struct {
+	Success bool                       `json:"success"`
+	Movie   *model.Movie               `json:"movie"`
+	Entry   *model.CatalogHistoryEntry `json:"history_entry"`
+}
+
+// CollectionImportCommitRequest confirms an import preview. Choices picks
+// the movie of an ambiguous row, or replaces the match of a matched one,
+// by row number. Skipped rows are left out.
+type CollectionImportCommitRequest struct {
+	Choices map[int]int `json:"choices"`
+	Skip    []int       `json:"skip"`
+}
+
+// CollectionImportItem is a movie saved by an import commit. Date is when it
+// was watched or added on the source service, now when unknown.
+type CollectionImportItem struct {
+	MovieID int
+	Date    time.Time
+}
+
+// CollectionImportResponse is an import with its preview split by match status.
+// The rows are only set once matching is done.
+type CollectionImportResponse struct {
+	Import    *model.CollectionImport     `json:"import"`
+	Matched   []model.CollectionImportRow `json:"matched"`
+	Ambiguous []model.CollectionImportRow `json:"ambiguous"`
+	Unmatched []model.CollectionImportRow `json:"unmatched"`
+}
+
+type CollectionImportCommitResponse struct {
+	Success bool `json:"success"`
+	// Selected movies, Added the ones that were not in the collection yet
+	Selected int `json:"selected"`
+	Added    int `json:"added"`
 }
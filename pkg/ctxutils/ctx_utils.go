@@ -22,6 +22,7 @@ const (
 	jsonWriterContextKey = contextKey("json_writer")
 	traceIDKey           = contextKey("trace_id")
 	requestIDKey         = contextKey("request_id")
+	clientInfoKey        = contextKey("client_info")
 )
 
 // SetLoggerAndJWInCtx returns a new context with logger and JSON writer stored.
@@ -136,6 +137,17 @@ func GetUser(ctx context.Context) (*common.UserContext, error) {
 	return user, nil
 }
 
+// SetClientInfo stores the device a request comes from
+func SetClientInfo(ctx context.Context, client common.ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey, client)
+}
+
+// GetClientInfo returns the device a request comes from, empty when unknown
+func GetClientInfo(ctx context.Context) common.ClientInfo {
+	client, _ := ctx.Value(clientInfoKey).(common.ClientInfo)
+	return client
+}
+
 func SetRequestID(ctx context.Context, id string) context.Context {
 	return context.WithValue(ctx, requestIDKey, id)
 }
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"multipass/pkg/common"
+)
+
+// maxUserAgentLength bounds the user agent stored with a session, in bytes
+const maxUserAgentLength = 512
+
+// GetClientInfo describes the device a request comes from. The IP is the
+// address of the direct peer.
+func GetClientInfo(r *http.Request) common.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	// Postgres rejects invalid UTF-8, and the cut must not split a character
+	userAgent := strings.ToValidUTF8(strings.TrimSpace(r.UserAgent()), "\uFFFD")
+	if len(userAgent) > maxUserAgentLength {
+		cut := maxUserAgentLength
+		for cut > 0 && !utf8.RuneStart(userAgent[cut]) {
+			cut--
+		}
+		userAgent = userAgent[:cut]
+	}
+
+	return common.ClientInfo{
+		UserAgent:   userAgent,
+		IPAddress:   ip,
+		DeviceLabel: DeviceLabel(userAgent),
+	}
+}
+
+// DeviceLabel names the browser and operating system of a user agent,
+// e.g. "Firefox on Windows".
+func DeviceLabel(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	// Order matters: Edge and Opera also claim Chrome, Chrome also claims Safari
+	browser := ""
+	switch {
+	case strings.Contains(ua, "edg/"), strings.Contains(ua, "edga/"), strings.Contains(ua, "edgios/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/"), strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"), strings.Contains(ua, "fxios/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/"), strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	// iOS and Android user agents also mention Mac OS X and Linux
+	system := ""
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		system = "iOS"
+	case strings.Contains(ua, "android"):
+		system = "Android"
+	case strings.Contains(ua, "windows"):
+		system = "Windows"
+	case strings.Contains(ua, "mac os x"), strings.Contains(ua, "macintosh"):
+		system = "macOS"
+	case strings.Contains(ua, "cros"):
+		system = "ChromeOS"
+	case strings.Contains(ua, "linux"):
+		system = "Linux"
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system + " device"
+	}
+	return "Unknown device"
+}
@@ -12,6 +12,8 @@ import (
 	"multipass/pkg/provider"
 )
 
+// DecodeRequest reads the JSON body of r into a T. On error it has already
+// written the error response, so callers only need to return.
 func DecodeRequest[T any](w http.ResponseWriter, r *http.Request, requestType string) (*T, error) {
 	const Max_Body_Size int64 = 1 << 20 // MB
 
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"multipass/pkg/common"
+)
+
+// GetPageParams reads the optional `cursor` and `limit` query parameters.
+// A missing limit falls back to common.DefaultPageLimit and values above
+// common.MaxPageLimit are clamped.
+func GetPageParams(r *http.Request) (common.PageParams, error) {
+	q := r.URL.Query()
+	page := common.PageParams{
+		Cursor: strings.TrimSpace(q.Get("cursor")),
+		Limit:  common.DefaultPageLimit,
+	}
+
+	if raw := strings.TrimSpace(q.Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return page, fmt.Errorf("invalid limit query param: %q", raw)
+		}
+		page.Limit = min(limit, common.MaxPageLimit)
+	}
+
+	return page, nil
+}
+
+// GetPathID reads a positive integer path value registered on the route pattern (e.g. {id}).
+func GetPathID(r *http.Request, key string) (int, error) {
+	id, err := strconv.Atoi(r.PathValue(key))
+	if err != nil {
+		return 0, err
+	}
+	if id < 1 {
+		return 0, fmt.Errorf("invalid %s path value: %d", key, id)
+	}
+	return id, nil
+}
+
+// EncodeCursor serializes v into an opaque, URL-safe cursor string.
+func EncodeCursor(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor into v.
+func DecodeCursor(cursor string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return fmt.Errorf("malformed cursor: %w", err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("malformed cursor payload: %w", err)
+	}
+	return nil
+}
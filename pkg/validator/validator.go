@@ -3,8 +3,11 @@ package validator
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"multipass/pkg/apperror"
 	"multipass/pkg/common"
@@ -181,6 +184,9 @@ func SanitizeLoginRequest(req *common.AuthRequest) (common.LoginInput, error) {
 	}, nil
 }
 
+// CollectionList targets one of the user's custom lists, picked by CollectionRequest.ListID.
+const CollectionList = "list"
+
 func SanitizeCollectionReq(req *common.CollectionRequest) (common.CollectionInput, error) {
 	if req.MovieID == nil {
 		return common.CollectionInput{}, apperror.ErrMissingRequiredField("MovieID", nil, nil, nil)
@@ -196,14 +202,361 @@ func SanitizeCollectionReq(req *common.CollectionRequest) (common.CollectionInpu
 
 	collection := strings.TrimSpace(*req.Collection)
 
-	if collection != "favorite" && collection != "watchlist" {
-		return common.CollectionInput{}, fmt.Errorf("invalid collection type, collection must be 'favorite' or 'watchlist'")
+	switch collection {
+	case "favorite", "watchlist", "watched":
+		if req.ListID != nil {
+			return common.CollectionInput{}, fmt.Errorf("list_id is only allowed with the '%s' collection", CollectionList)
+		}
+	case CollectionList:
+		// Ownership is checked when the list is locked for the change
+		if req.ListID == nil || *req.ListID <= 0 {
+			return common.CollectionInput{}, fmt.Errorf("list_id is required for the '%s' collection", CollectionList)
+		}
+	default:
+		return common.CollectionInput{}, fmt.Errorf("invalid collection type, collection must be 'favorite', 'watchlist', 'watched' or 'list'")
 	}
 
-	return common.CollectionInput{
+	input := common.CollectionInput{
 		MovieID:    *req.MovieID,
 		Collection: collection,
-	}, nil
+	}
+	if req.ListID != nil {
+		input.ListID = *req.ListID
+	}
+	return input, nil
+}
+
+/*
+▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩
+#    REVIEW REQUEST VALIDATION
+▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩
+*/
+
+const MaxReviewBodyLength = 10000
+
+// SanitizeReviewRequest validates the rating and trims the optional review text.
+// A blank body is stored as a rating without text.
+func SanitizeReviewRequest(req *common.ReviewRequest) (common.ReviewInput, error) {
+	if req.Rating == nil {
+		return common.ReviewInput{}, apperror.ErrMissingRequiredField("Rating", nil, nil, nil)
+	}
+	if *req.Rating < 1 || *req.Rating > 10 {
+		return common.ReviewInput{}, apperror.ErrInvalidReviewRating(nil, nil, common.Envelop{"rating": *req.Rating})
+	}
+
+	input := common.ReviewInput{Rating: *req.Rating}
+	if req.ContainsSpoilers != nil {
+		input.ContainsSpoilers = *req.ContainsSpoilers
+	}
+
+	if req.Body != nil {
+		body := strings.TrimSpace(*req.Body)
+		if utf8.RuneCountInString(body) > MaxReviewBodyLength {
+			return common.ReviewInput{}, apperror.ErrReviewTooLong(nil, nil, common.Envelop{"field": "body"})
+		}
+		if body != "" {
+			input.Body = &body
+		}
+	}
+
+	return input, nil
+}
+
+/*
+▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩
+#    DIARY REQUEST VALIDATION
+▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩
+*/
+
+const MaxDiaryNoteLength = 2000
+
+// SanitizeDiaryRequest validates a diary entry. requireMovie is false when
+// editing an existing entry, whose movie cannot change. The watch date
+// defaults to today and may not lie in the future; a day of slack covers
+// users ahead of the server's time zone.
+func SanitizeDiaryRequest(req *common.DiaryRequest, requireMovie bool) (common.DiaryInput, error) {
+	var input common.DiaryInput
+
+	if requireMovie {
+		if req.MovieID == nil || *req.MovieID <= 0 {
+			return common.DiaryInput{}, apperror.ErrMissingRequiredField("MovieID", nil, nil, nil)
+		}
+		input.MovieID = *req.MovieID
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	input.WatchedOn = today
+	if req.WatchedOn != nil && strings.TrimSpace(*req.WatchedOn) != "" {
+		watchedOn, err := time.Parse(time.DateOnly, strings.TrimSpace(*req.WatchedOn))
+		if err != nil {
+			return common.DiaryInput{}, apperror.ErrInvalidWatchDate(err, nil, common.Envelop{"watched_on": *req.WatchedOn})
+		}
+		if watchedOn.After(today.AddDate(0, 0, 1)) {
+			return common.DiaryInput{}, apperror.ErrInvalidWatchDate(nil, nil, common.Envelop{"watched_on": *req.WatchedOn})
+		}
+		input.WatchedOn = watchedOn
+	}
+
+	input.Rewatch = req.Rewatch
+
+	if req.Note != nil {
+		note := strings.TrimSpace(*req.Note)
+		if utf8.RuneCountInString(note) > MaxDiaryNoteLength {
+			return common.DiaryInput{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": "note", "max_length": MaxDiaryNoteLength})
+		}
+		if note != "" {
+			input.Note = &note
+		}
+	}
+
+	return input, nil
+}
+
+// ParseDiaryRange reads the optional inclusive from/to dates of a diary listing.
+func ParseDiaryRange(from, to string) (common.DiaryRange, error) {
+	var rng common.DiaryRange
+	for _, bound := range []struct {
+		raw string
+		dst **time.Time
+	}{{from, &rng.From}, {to, &rng.To}} {
+		if bound.raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, bound.raw)
+		if err != nil {
+			return common.DiaryRange{}, err
+		}
+		*bound.dst = &t
+	}
+
+	if rng.From != nil && rng.To != nil && rng.From.After(*rng.To) {
+		return common.DiaryRange{}, fmt.Errorf("from %s is after to %s", from, to)
+	}
+	return rng, nil
+}
+
+/*
+▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩
+#    LIST REQUEST VALIDATION
+▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩
+*/
+
+const (
+	MaxListNameLength        = 100
+	MaxListDescriptionLength = 1000
+	MaxListNoteLength        = 1000
+)
+
+// SanitizeListRequest validates the name, description and visibility of a list.
+func SanitizeListRequest(req *common.ListRequest) (common.ListInput, error) {
+	name, err := IsValidToProcess(req.Name, "Name", nil)
+	if err != nil {
+		return common.ListInput{}, err
+	}
+	if utf8.RuneCountInString(name) > MaxListNameLength {
+		return common.ListInput{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": "name", "max_length": MaxListNameLength})
+	}
+
+	input := common.ListInput{Name: name}
+	if req.IsPublic != nil {
+		input.IsPublic = *req.IsPublic
+	}
+
+	description, err := SanitizeOptionalText(req.Description, "description", MaxListDescriptionLength)
+	if err != nil {
+		return common.ListInput{}, err
+	}
+	input.Description = description
+
+	return input, nil
+}
+
+// SanitizeListItemRequest validates a movie being added to a list.
+func SanitizeListItemRequest(req *common.ListItemRequest) (int, *string, error) {
+	if req.MovieID == nil || *req.MovieID <= 0 {
+		return 0, nil, apperror.ErrMissingRequiredField("MovieID", nil, nil, nil)
+	}
+
+	note, err := SanitizeOptionalText(req.Note, "note", MaxListNoteLength)
+	if err != nil {
+		return 0, nil, err
+	}
+	return *req.MovieID, note, nil
+}
+
+// SanitizeListItemUpdate validates a note change and/or move of a list item.
+func SanitizeListItemUpdate(req *common.ListItemUpdateRequest, movieID int) (common.ListItemInput, error) {
+	if req.Note == nil && !req.Move {
+		return common.ListItemInput{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"reason": "nothing to update, send a note or move"})
+	}
+
+	input := common.ListItemInput{Move: req.Move}
+	if req.Note != nil {
+		note, err := SanitizeOptionalText(req.Note, "note", MaxListNoteLength)
+		if err != nil {
+			return common.ListItemInput{}, err
+		}
+		input.Note, input.UpdateNote = note, true
+	}
+
+	if req.Move && req.AfterMovieID != nil {
+		if *req.AfterMovieID <= 0 || *req.AfterMovieID == movieID {
+			return common.ListItemInput{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": "after_movie_id"})
+		}
+		input.AfterMovieID = req.AfterMovieID
+	}
+
+	return input, nil
+}
+
+// SanitizeOptionalText trims free text. Blank text becomes nil.
+func SanitizeOptionalText(v *string, field string, maxLength int) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	text := strings.TrimSpace(*v)
+	if utf8.RuneCountInString(text) > maxLength {
+		return nil, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": field, "max_length": maxLength})
+	}
+	if text == "" {
+		return nil, nil
+	}
+	return &text, nil
+}
+
+/*
+▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩
+#    CATALOG REQUEST VALIDATION
+▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩▩
+*/
+
+const (
+	MaxMovieTitleLength   = 500
+	MaxMovieTextLength    = 10000
+	MaxKeywordLength      = 100
+	MaxCatalogRelationIDs = 200
+	MinReleaseYear        = 1870
+)
+
+// SanitizeAdminMovieRequest validates a catalog movie. A new movie needs a
+// tmdb_id, title and release_year; an update only checks the fields it sets.
+func SanitizeAdminMovieRequest(req *common.AdminMovieRequest, create bool) (common.MovieInput, error) {
+	input := common.MovieInput{
+		TMDB_ID:     req.TMDB_ID,
+		ReleaseYear: req.ReleaseYear,
+		Score:       req.Score,
+		Popularity:  req.Popularity,
+	}
+
+	if create {
+		if req.TMDB_ID == nil {
+			return common.MovieInput{}, apperror.ErrMissingRequiredField("tmdb_id", nil, nil, nil)
+		}
+		if req.Title == nil {
+			return common.MovieInput{}, apperror.ErrMissingRequiredField("title", nil, nil, nil)
+		}
+		if req.ReleaseYear == nil {
+			return common.MovieInput{}, apperror.ErrMissingRequiredField("release_year", nil, nil, nil)
+		}
+	}
+
+	if req.TMDB_ID != nil && *req.TMDB_ID <= 0 {
+		return common.MovieInput{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": "tmdb_id"})
+	}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" || utf8.RuneCountInString(title) > MaxMovieTitleLength {
+			return common.MovieInput{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": "title", "max_length": MaxMovieTitleLength})
+		}
+		input.Title = &title
+	}
+	if req.Tagline != nil {
+		tagline := strings.TrimSpace(*req.Tagline)
+		if utf8.RuneCountInString(tagline) > MaxMovieTitleLength {
+			return common.MovieInput{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": "tagline", "max_length": MaxMovieTitleLength})
+		}
+		input.Tagline = &tagline
+	}
+	if req.ReleaseYear != nil && (*req.ReleaseYear < MinReleaseYear || *req.ReleaseYear > time.Now().Year()+10) {
+		return common.MovieInput{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": "release_year"})
+	}
+	if req.Score != nil && (*req.Score < 0 || *req.Score > 10) {
+		return common.MovieInput{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": "score", "range": "0-10"})
+	}
+	if req.Popularity != nil && *req.Popularity < 0 {
+		return common.MovieInput{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": "popularity"})
+	}
+
+	// Optional text: trimmed, and kept as "" so the store can clear the column
+	for _, f := range []struct {
+		name string
+		in   *string
+		out  **string
+		max  int
+		url  bool
+	}{
+		{"overview", req.Overview, &input.Overview, MaxMovieTextLength, false},
+		{"language", req.Language, &input.Language, 10, false},
+		{"poster_url", req.PosterURL, &input.PosterURL, 2048, true},
+		{"trailer_url", req.TrailerURL, &input.TrailerURL, 2048, true},
+	} {
+		if f.in == nil {
+			continue
+		}
+		text := strings.TrimSpace(*f.in)
+		if utf8.RuneCountInString(text) > f.max {
+			return common.MovieInput{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": f.name, "max_length": f.max})
+		}
+		if f.url && text != "" && !strings.HasPrefix(text, "https://") && !strings.HasPrefix(text, "http://") {
+			return common.MovieInput{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": f.name, "reason": "must be an http(s) URL"})
+		}
+		*f.out = &text
+	}
+
+	return input, nil
+}
+
+// SanitizeCatalogRelations validates and de-duplicates the genres, actors and keywords of a request.
+func SanitizeCatalogRelations(req *common.CatalogRelationsRequest) (common.CatalogRelations, error) {
+	var rel common.CatalogRelations
+
+	for _, f := range []struct {
+		name string
+		in   []int
+		out  *[]int
+	}{
+		{"genre_ids", req.GenreIDs, &rel.GenreIDs},
+		{"actor_ids", req.ActorIDs, &rel.ActorIDs},
+		{"keyword_ids", req.KeywordIDs, &rel.KeywordIDs},
+	} {
+		if len(f.in) > MaxCatalogRelationIDs {
+			return common.CatalogRelations{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": f.name, "max_items": MaxCatalogRelationIDs})
+		}
+		for _, id := range f.in {
+			if id <= 0 {
+				return common.CatalogRelations{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": f.name, "id": id})
+			}
+			if !slices.Contains(*f.out, id) {
+				*f.out = append(*f.out, id)
+			}
+		}
+	}
+
+	if len(req.Keywords) > MaxCatalogRelationIDs {
+		return common.CatalogRelations{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": "keywords", "max_items": MaxCatalogRelationIDs})
+	}
+	for _, word := range req.Keywords {
+		word = strings.TrimSpace(word)
+		if word == "" || utf8.RuneCountInString(word) > MaxKeywordLength {
+			return common.CatalogRelations{}, apperror.ErrRequestValidation(nil, nil, common.Envelop{"field": "keywords", "max_length": MaxKeywordLength})
+		}
+		if !slices.ContainsFunc(rel.Keywords, func(k string) bool { return strings.EqualFold(k, word) }) {
+			rel.Keywords = append(rel.Keywords, word)
+		}
+	}
+
+	return rel, nil
 }
 
 // SanitizeRefreshCookie checks for empty value (token string) (string, error)